@@ -0,0 +1,381 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"foodbuddy/internal/api"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/utils"
+	"math"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+const adminUsage = `usage:
+  foodbuddy admin create <email>
+  foodbuddy seed <file.yaml>
+  foodbuddy restaurant verify <id|email>
+  foodbuddy block user|restaurant <id|email> [-reason FRAUD] [-note text] [-for 72h]
+  foodbuddy unblock user|restaurant <id|email>
+  foodbuddy recompute ratings|wallets [-dry-run]`
+
+// operations subcommands, they work on the configured database and exit
+func runAdmin(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	switch {
+	case args[0] == "admin" && len(args) == 3 && args[1] == "create":
+		return createAdmin(db, args[2])
+
+	case args[0] == "seed" && len(args) == 2:
+		return runSeed(db, args[1])
+
+	case args[0] == "restaurant" && len(args) == 3 && args[1] == "verify":
+		return verifyRestaurant(db, args[2])
+
+	case args[0] == "block" && len(args) >= 3:
+		flags := flag.NewFlagSet("block", flag.ContinueOnError)
+		reason := flags.String("reason", model.BlockReasonOther, "FRAUD, ABUSE, POLICY_VIOLATION, PAYMENT_DEFAULT, FOOD_SAFETY or OTHER")
+		note := flags.String("note", "blocked from the command line", "shown to the account in the block notice")
+		duration := flags.Duration("for", 0, "lift the block after this long, rounded up to hours, never when 0")
+		if err := flags.Parse(args[3:]); err != nil {
+			return err
+		}
+		request := model.BlockAccountRequest{
+			Reason:        *reason,
+			Note:          *note,
+			DurationHours: uint(math.Ceil(duration.Hours())),
+		}
+		if err := utils.Validate(request); err != nil {
+			return err
+		}
+		return setBlocked(db, args[1], args[2], &request)
+
+	case args[0] == "unblock" && len(args) == 3:
+		return setBlocked(db, args[1], args[2], nil)
+
+	case args[0] == "recompute" && len(args) >= 2:
+		flags := flag.NewFlagSet("recompute", flag.ContinueOnError)
+		dryRun := flags.Bool("dry-run", false, "only print the differences")
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		switch args[1] {
+		case "ratings":
+			if err := recomputeRatings(db, *dryRun); err != nil || *dryRun {
+				return err
+			}
+			printCacheNote()
+			return nil
+		case "wallets":
+			return recomputeWallets(db, *dryRun)
+		}
+	}
+
+	return errors.New(adminUsage)
+}
+
+func createAdmin(db *gorm.DB, email string) error {
+	var admin model.Admin
+	err := db.Where("email = ?", email).First(&admin).Error
+	if err == nil {
+		fmt.Printf("%v is already an admin\n", email)
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	//admins sign in with an otp, the verification entry is created on the first login
+	admin = model.Admin{Email: email}
+	if err := db.Create(&admin).Error; err != nil {
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+	recordChange(db, audit.AdminCreate, audit.EntityAdmin, admin.ID, nil, admin)
+	fmt.Printf("created admin %v\n", email)
+	return nil
+}
+
+// cli:<os user>, the actor of the changes made by the commands
+func cliActor() string {
+	actor := audit.CLIActor
+	if current, err := user.Current(); err == nil {
+		actor += ":" + current.Username
+	}
+	return actor
+}
+
+// changes made here are audited like those of the admin endpoints, with the os user as the actor
+func recordChange(db *gorm.DB, action string, entityType string, entityID uint, before any, after any) {
+	entry := model.AuditLog{
+		ActorEmail: cliActor(),
+		ActorRole:  audit.CLIActor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   strconv.FormatUint(uint64(entityID), 10),
+	}
+	if err := audit.Write(db, entry, before, after); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write the audit log: %v\n", err)
+	}
+}
+
+func verifyRestaurant(db *gorm.DB, key string) error {
+	restaurant, err := findRestaurant(db, key)
+	if err != nil {
+		return err
+	}
+	if restaurant.VerificationStatus == model.VerificationStatusVerified {
+		fmt.Printf("restaurant %v is already verified\n", restaurant.ID)
+		return nil
+	}
+	//the same onboarding review the verify endpoint requires
+	pending, err := controllers.UnapprovedDocuments(db, restaurant.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the onboarding documents: %w", err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("restaurant %v can't be verified until its onboarding documents are approved, not approved: %v", restaurant.ID, strings.Join(pending, ", "))
+	}
+
+	if err := db.Model(&model.Restaurant{}).Where("id = ?", restaurant.ID).Update("verification_status", model.VerificationStatusVerified).Error; err != nil {
+		return fmt.Errorf("failed to verify restaurant: %w", err)
+	}
+	verified := restaurant
+	verified.VerificationStatus = model.VerificationStatusVerified
+	recordChange(db, audit.RestaurantVerify, audit.EntityRestaurant, restaurant.ID, restaurant, verified)
+
+	fmt.Printf("restaurant %v (%v) is verified\n", restaurant.ID, restaurant.Email)
+	printCacheNote()
+	return nil
+}
+
+// blocks with the request, or lifts the block when it is nil.
+// gateways aren't set up for the commands, the account gets its notice through /auth/block/notice
+func setBlocked(db *gorm.DB, kind string, key string, request *model.BlockAccountRequest) error {
+	blocked := request != nil
+	by := cliActor()
+	change := func(role string, id uint) error {
+		if blocked {
+			_, _, err := controllers.BlockAccount(db, role, id, *request, by)
+			return err
+		}
+		return controllers.LiftBlock(db, role, id, by)
+	}
+
+	action := "blocked"
+	userAction, restaurantAction := audit.UserBlock, audit.RestaurantBlock
+	if !blocked {
+		action = "unblocked"
+		userAction, restaurantAction = audit.UserUnblock, audit.RestaurantUnblock
+	}
+
+	switch kind {
+	case "user":
+		users := repository.New(db).Users
+		user, err := findUser(users, key)
+		if err != nil {
+			return err
+		}
+		if user.Blocked == blocked {
+			fmt.Printf("user %v is already %v\n", user.ID, action)
+			return nil
+		}
+		if err := change(model.UserRole, user.ID); err != nil {
+			return fmt.Errorf("failed to change the block status: %w", err)
+		}
+		changed := user
+		changed.Blocked = blocked
+		recordChange(db, userAction, audit.EntityUser, user.ID, user, changed)
+		fmt.Printf("user %v (%v) is %v\n", user.ID, user.Email, action)
+		return nil
+
+	case "restaurant":
+		restaurant, err := findRestaurant(db, key)
+		if err != nil {
+			return err
+		}
+		if restaurant.Blocked == blocked {
+			fmt.Printf("restaurant %v is already %v\n", restaurant.ID, action)
+			return nil
+		}
+		if err := change(model.RestaurantRole, restaurant.ID); err != nil {
+			return fmt.Errorf("failed to change the block status: %w", err)
+		}
+		changed := restaurant
+		changed.Blocked = blocked
+		recordChange(db, restaurantAction, audit.EntityRestaurant, restaurant.ID, restaurant, changed)
+		fmt.Printf("restaurant %v (%v) is %v\n", restaurant.ID, restaurant.Email, action)
+		printCacheNote()
+		return nil
+	}
+
+	return errors.New(adminUsage)
+}
+
+// product ratings rebuilt from the ratings left on order items
+func recomputeRatings(db *gorm.DB, dryRun bool) error {
+	var products []model.Product
+	if err := db.Order("id").Find(&products).Error; err != nil {
+		return fmt.Errorf("failed to fetch products: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PRODUCT\tNAME\tCOUNT\tAVERAGE")
+	changed := 0
+	for _, product := range products {
+		rating, err := controllers.ProductRatingOf(db, product.ID)
+		if err != nil {
+			return fmt.Errorf("failed to sum the ratings of product %v: %w", product.ID, err)
+		}
+		if product.RatingSum == rating.RatingSum && product.RatingCount == rating.RatingCount && product.AverageRating == rating.AverageRating {
+			continue
+		}
+		changed++
+		fmt.Fprintf(w, "%v\t%v\t%v -> %v\t%.2f -> %.2f\n", product.ID, product.Name, product.RatingCount, rating.RatingCount, product.AverageRating, rating.AverageRating)
+		if dryRun {
+			continue
+		}
+		if err := controllers.RecomputeProductRating(db, product.ID); err != nil {
+			return fmt.Errorf("failed to update product %v: %w", product.ID, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	printSummary("product ratings", changed, dryRun)
+	return nil
+}
+
+// wallet balances rebuilt from the wallet history, incoming minus outgoing
+func recomputeWallets(db *gorm.DB, dryRun bool) error {
+	balance := fmt.Sprintf("SUM(CASE WHEN type = '%v' THEN amount ELSE -amount END)", model.WalletIncoming)
+
+	var userTotals []struct {
+		UserID  uint
+		Balance float64
+	}
+	if err := db.Model(&model.UserWalletHistory{}).Select("user_id, " + balance + " AS balance").Group("user_id").Scan(&userTotals).Error; err != nil {
+		return fmt.Errorf("failed to sum the user wallet history: %w", err)
+	}
+	userBalance := make(map[uint]float64, len(userTotals))
+	for _, total := range userTotals {
+		userBalance[total.UserID] = controllers.RoundDecimalValue(total.Balance)
+	}
+
+	var restaurantTotals []struct {
+		RestaurantID uint
+		Balance      float64
+	}
+	if err := db.Model(&model.RestaurantWalletHistory{}).Select("restaurant_id, " + balance + " AS balance").Group("restaurant_id").Scan(&restaurantTotals).Error; err != nil {
+		return fmt.Errorf("failed to sum the restaurant wallet history: %w", err)
+	}
+	restaurantBalance := make(map[uint]float64, len(restaurantTotals))
+	for _, total := range restaurantTotals {
+		restaurantBalance[total.RestaurantID] = controllers.RoundDecimalValue(total.Balance)
+	}
+
+	var users []model.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to fetch users: %w", err)
+	}
+	var restaurants []model.Restaurant
+	if err := db.Order("id").Find(&restaurants).Error; err != nil {
+		return fmt.Errorf("failed to fetch restaurants: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OWNER\tID\tEMAIL\tBALANCE")
+	changed := 0
+	for _, user := range users {
+		want := userBalance[user.ID]
+		if controllers.RoundDecimalValue(user.WalletAmount) == want {
+			continue
+		}
+		changed++
+		fmt.Fprintf(w, "user\t%v\t%v\t%.2f -> %.2f\n", user.ID, user.Email, user.WalletAmount, want)
+		if dryRun {
+			continue
+		}
+		if err := db.Model(&model.User{}).Where("id = ?", user.ID).Update("wallet_amount", want).Error; err != nil {
+			return fmt.Errorf("failed to update user %v: %w", user.ID, err)
+		}
+	}
+	for _, restaurant := range restaurants {
+		want := restaurantBalance[restaurant.ID]
+		if controllers.RoundDecimalValue(restaurant.WalletAmount) == want {
+			continue
+		}
+		changed++
+		fmt.Fprintf(w, "restaurant\t%v\t%v\t%.2f -> %.2f\n", restaurant.ID, restaurant.Email, restaurant.WalletAmount, want)
+		if dryRun {
+			continue
+		}
+		if err := db.Model(&model.Restaurant{}).Where("id = ?", restaurant.ID).Update("wallet_amount", want).Error; err != nil {
+			return fmt.Errorf("failed to update restaurant %v: %w", restaurant.ID, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	printSummary("wallet balances", changed, dryRun)
+	return nil
+}
+
+func printSummary(what string, changed int, dryRun bool) {
+	switch {
+	case changed == 0:
+		fmt.Printf("%v are up to date\n", what)
+	case dryRun:
+		fmt.Printf("%v %v differ, run without -dry-run to update them\n", changed, what)
+	default:
+		fmt.Printf("updated %v %v\n", changed, what)
+	}
+}
+
+// the commands run outside the server, its cached public responses can't be invalidated from here
+func printCacheNote() {
+	fmt.Printf("a running server may serve cached public responses for up to %v before showing the change\n", api.CatalogueTTL)
+}
+
+// accounts are picked by numeric id or by email
+func findUser(users repository.UserRepo, key string) (model.User, error) {
+	var user model.User
+	var err error
+	if id, convErr := strconv.ParseUint(key, 10, 64); convErr == nil {
+		user, err = users.ByID(uint(id))
+	} else {
+		user, err = users.ByEmail(key)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return user, fmt.Errorf("user %v not found", key)
+	}
+	return user, err
+}
+
+func findRestaurant(db *gorm.DB, key string) (model.Restaurant, error) {
+	restaurants := repository.New(db).Restaurants
+	var restaurant model.Restaurant
+	var err error
+	if id, convErr := strconv.ParseUint(key, 10, 64); convErr == nil {
+		restaurant, err = restaurants.ByID(uint(id))
+	} else {
+		restaurant, err = restaurants.ByEmail(key)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return restaurant, fmt.Errorf("restaurant %v not found", key)
+	}
+	return restaurant, err
+}
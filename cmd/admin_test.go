@@ -0,0 +1,191 @@
+package main
+
+import (
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository/sqlite"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// the controller helpers used by seed work on database.DB, so it is swapped for the test
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "foodbuddy.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+	return db
+}
+
+func run(t *testing.T, db *gorm.DB, args ...string) {
+	t.Helper()
+	if err := runAdmin(db, args); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(model).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSeedDemo(t *testing.T) {
+	db := testDB(t)
+	run(t, db, "seed", "../seed/demo.yaml")
+
+	for _, c := range []struct {
+		model interface{}
+		want  int64
+	}{
+		{&model.Admin{}, 1},
+		{&model.Category{}, 3},
+		{&model.Restaurant{}, 2},
+		{&model.Product{}, 4},
+		{&model.User{}, 2},
+		{&model.Address{}, 2},
+		{&model.Order{}, 3},
+		{&model.CartItems{}, 0},
+	} {
+		if got := count(t, db, c.model); got != c.want {
+			t.Errorf("%T: got %v rows, want %v", c.model, got, c.want)
+		}
+	}
+
+	var biryani model.Product
+	if err := db.Where("name = ?", "Chicken Dum Biryani").First(&biryani).Error; err != nil {
+		t.Fatal(err)
+	}
+	if biryani.StockLeft != 47 {
+		t.Errorf("stock left = %v, want 47", biryani.StockLeft)
+	}
+	if biryani.RatingCount != 2 || biryani.AverageRating != 4.5 {
+		t.Errorf("rating = %v over %v, want 4.5 over 2", biryani.AverageRating, biryani.RatingCount)
+	}
+
+	var anu model.User
+	if err := db.Where("email = ?", "anu@foodbuddy.dev").First(&anu).Error; err != nil {
+		t.Fatal(err)
+	}
+	if anu.WalletAmount != 500 || anu.ReferralCode == "" {
+		t.Errorf("wallet = %v, referral code = %q", anu.WalletAmount, anu.ReferralCode)
+	}
+
+	//accounts and the catalogue are not duplicated on a second run
+	run(t, db, "seed", "../seed/demo.yaml")
+	if got := count(t, db, &model.Product{}); got != 4 {
+		t.Errorf("products after reseeding = %v, want 4", got)
+	}
+	if got := count(t, db, &model.User{}); got != 2 {
+		t.Errorf("users after reseeding = %v, want 2", got)
+	}
+}
+
+func TestAccountCommands(t *testing.T) {
+	db := testDB(t)
+	run(t, db, "seed", "../seed/demo.yaml")
+
+	run(t, db, "admin", "create", "ops@foodbuddy.dev")
+	if got := count(t, db, &model.Admin{}); got != 2 {
+		t.Errorf("admins = %v, want 2", got)
+	}
+
+	var restaurant model.Restaurant
+	db.Where("email = ?", "sweettruth@foodbuddy.dev").First(&restaurant)
+	//verified only once every onboarding document is approved, like through the api
+	db.Create(&model.RestaurantDocument{RestaurantID: restaurant.ID, Type: model.DocumentFSSAI, Status: model.DocumentStatusApproved})
+	db.Create(&model.RestaurantDocument{RestaurantID: restaurant.ID, Type: model.DocumentGST, Status: model.DocumentStatusPending})
+	if err := runAdmin(db, []string{"restaurant", "verify", itoa(restaurant.ID)}); err == nil || !strings.Contains(err.Error(), "GST, BANK") {
+		t.Errorf("verifying with pending documents = %v, want GST and BANK not approved", err)
+	}
+	db.Model(&model.RestaurantDocument{}).Where("restaurant_id = ?", restaurant.ID).Update("status", model.DocumentStatusApproved)
+	db.Create(&model.RestaurantDocument{RestaurantID: restaurant.ID, Type: model.DocumentBank, Status: model.DocumentStatusApproved})
+
+	run(t, db, "restaurant", "verify", "sweettruth@foodbuddy.dev")
+	db.First(&restaurant, restaurant.ID)
+	if restaurant.VerificationStatus != model.VerificationStatusVerified {
+		t.Errorf("verification status = %v", restaurant.VerificationStatus)
+	}
+
+	run(t, db, "block", "restaurant", itoa(restaurant.ID))
+	run(t, db, "block", "user", "rahul@foodbuddy.dev", "-reason", "PAYMENT_DEFAULT", "-for", "90m")
+	var user model.User
+	db.Where("email = ?", "rahul@foodbuddy.dev").First(&user)
+	db.First(&restaurant, restaurant.ID)
+	if !user.Blocked || !restaurant.Blocked {
+		t.Errorf("blocked user = %v, restaurant = %v", user.Blocked, restaurant.Blocked)
+	}
+	var block model.AccountBlock
+	db.Where("account_role = ? AND account_id = ?", model.UserRole, user.ID).First(&block)
+	if block.Reason != model.BlockReasonPayment || block.ExpiresAt == nil || time.Until(*block.ExpiresAt) < time.Hour || !strings.HasPrefix(block.BlockedBy, "cli") {
+		t.Errorf("block = %+v, want a payment default for two hours", block)
+	}
+
+	run(t, db, "unblock", "user", itoa(user.ID))
+	db.First(&user, user.ID)
+	db.First(&block, block.ID)
+	if user.Blocked || block.LiftedAt == nil {
+		t.Error("user is still blocked")
+	}
+	if err := runAdmin(db, []string{"block", "user", itoa(user.ID), "-reason", "BORED"}); err == nil {
+		t.Error("blocking for an unknown reason should fail")
+	}
+
+	//the seeded admin is created through the same command
+	var actions []string
+	db.Model(&model.AuditLog{}).Where("actor_role = ?", "cli").Order("id").Pluck("action", &actions)
+	if want := []string{"admin.create", "admin.create", "restaurant.verify", "restaurant.block", "user.block", "user.unblock"}; strings.Join(actions, " ") != strings.Join(want, " ") {
+		t.Errorf("audited actions = %v, want %v", actions, want)
+	}
+
+	if err := runAdmin(db, []string{"block", "user", "nobody@foodbuddy.dev"}); err == nil {
+		t.Error("blocking an unknown user should fail")
+	}
+	if err := runAdmin(db, []string{"recompute", "everything"}); err == nil {
+		t.Error("unknown recompute target should fail")
+	}
+}
+
+func TestRecompute(t *testing.T) {
+	db := testDB(t)
+	run(t, db, "seed", "../seed/demo.yaml")
+
+	db.Model(&model.User{}).Where("email = ?", "anu@foodbuddy.dev").Update("wallet_amount", 12)
+	db.Model(&model.Product{}).Where("name = ?", "Chicken Dum Biryani").Updates(map[string]interface{}{"rating_count": 9, "average_rating": 1})
+
+	run(t, db, "recompute", "wallets", "-dry-run")
+	var anu model.User
+	db.Where("email = ?", "anu@foodbuddy.dev").First(&anu)
+	if anu.WalletAmount != 12 {
+		t.Fatalf("dry run changed the wallet to %v", anu.WalletAmount)
+	}
+
+	run(t, db, "recompute", "wallets")
+	run(t, db, "recompute", "ratings")
+
+	db.First(&anu, anu.ID)
+	if anu.WalletAmount != 500 {
+		t.Errorf("wallet = %v, want 500 from the history", anu.WalletAmount)
+	}
+	var biryani model.Product
+	db.Where("name = ?", "Chicken Dum Biryani").First(&biryani)
+	if biryani.RatingCount != 2 || biryani.AverageRating != 4.5 {
+		t.Errorf("rating = %v over %v, want 4.5 over 2", biryani.AverageRating, biryani.RatingCount)
+	}
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
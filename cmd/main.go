@@ -1,41 +1,159 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"foodbuddy/internal/api"
+	"foodbuddy/internal/config"
+	"foodbuddy/internal/controllers"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/health"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/metrics"
+	"foodbuddy/internal/ratelimit"
+	"foodbuddy/internal/repository"
 	"foodbuddy/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func init() {
-	database.ConnectToDB() 
-	database.AutoMigrate()
-	fmt.Println("Database intitialization done")
-}
-
 func main() {
-	//start server with default logger and recovery
-	router := gin.Default()
+	//configuration is read once, flags come before the subcommand
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		logging.Fatal("failed to load configuration", "error", err)
+	}
+	if _, err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		logging.Fatal("failed to set up logging", "error", err)
+	}
+
+	database.ConnectToDB(cfg.Database)
+
+	//schema changes are applied with `foodbuddy migrate`, never at boot
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(database.DB, args[1:]); err != nil {
+			logging.Fatal("migration failed", "error", err)
+		}
+		return
+	}
+
+	if err := database.CheckSchema(database.DB); err != nil {
+		logging.Fatal("refusing to start", "error", err)
+	}
+
+	//admin, seed, block and recompute commands run against the migrated schema and exit
+	if len(args) > 0 {
+		if err := runAdmin(database.DB, args); err != nil {
+			logging.Fatal("command failed", "command", args[0], "error", err)
+		}
+		return
+	}
+
+	if sqlDB, err := database.DB.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB, cfg.Database.Name); err != nil {
+			slog.Warn("failed to register database metrics", "error", err)
+		}
+	}
+
+	utils.Configure(cfg)
+	controllers.Configure(cfg)
+	//payment, mail and image clients, tests swap them for fakes
+	gateway.Set(gateway.New(cfg))
+
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	//rate limit buckets live in this process unless replicas should share them through the database
+	switch cfg.RateLimit.Store {
+	case "database":
+		ratelimit.SetStore(ratelimit.NewDatabaseStore(database.DB))
+	case "off":
+		ratelimit.SetStore(nil)
+	}
+
+	//start server with request logging and recovery
+	router := gin.New()
+	//client ips feed the rate limiter, forwarded headers only count from known proxies
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logging.Fatal("invalid trusted proxies", "error", err)
+	}
+	router.Use(logging.Middleware(), metrics.Middleware(), gin.Recovery())
 	//load html from templates folder
 	// router.LoadHTMLGlob("./templates/*") 
 
-	router.Use(utils.RateLimitMiddleware())
 	router.Use(utils.CorsMiddleware())
 
+	//handlers backed by the repositories
+	h := controllers.NewHandler(repository.New(database.DB))
+
 	//access all the routes
-	api.ServerHealth(router)
-	api.PublicRoutes(router)
+	api.ServerHealth(router, database.DB, cfg.MetricsToken)
+	api.PublicRoutes(router, h)
 	api.AuthenticationRoutes(router)
-	api.AdminRoutes(router)
-	api.UserRoutes(router)
-	api.RestaurantRoutes(router)
+	api.AdminRoutes(router, h)
+	api.UserRoutes(router, h)
+	api.RestaurantRoutes(router, h)
 	api.AdditionalRoutes(router)
+	api.MediaRoutes(router, cfg.Storage)
+	api.OpenAPIRoutes(router)
 
-	err := router.Run(":8080")
-	if err != nil {
-		panic(err)
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	//background workers run until the requests are drained
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	health.RunWorker(workerCtx, "ratelimit-janitor", time.Minute, ratelimit.Sweep)
+	health.RunWorker(workerCtx, "block-expiry", time.Minute, controllers.ExpireBlocks)
+	health.RunWorker(workerCtx, "support-sla", time.Minute, controllers.FlagSupportSLABreaches)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", cfg.Server.Addr, "env", cfg.Env)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		logging.Fatal("server stopped", "error", err)
+	case <-signals.Done():
+	}
+
+	//fail readiness first so no new traffic is routed here, then drain in-flight requests
+	slog.Info("shutting down, draining requests")
+	health.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to drain requests", "error", err)
+	}
+
+	stopWorkers()
+	if err := health.WaitWorkers(shutdownCtx); err != nil {
+		slog.Error("background workers did not stop", "error", err)
+	}
+
+	if sqlDB, err := database.DB.DB(); err == nil {
+		sqlDB.Close()
 	}
+	slog.Info("shutdown complete")
 }
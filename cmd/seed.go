@@ -0,0 +1,449 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/model"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// demo data for a local environment, see seed/demo.yaml
+type seedFile struct {
+	Admins      []string         `yaml:"admins"`
+	Categories  []seedCategory   `yaml:"categories"`
+	Restaurants []seedRestaurant `yaml:"restaurants"`
+	Users       []seedUser       `yaml:"users"`
+	Orders      []seedOrder      `yaml:"orders"`
+}
+
+type seedCategory struct {
+	Name            string `yaml:"name"`
+	Description     string `yaml:"description"`
+	ImageURL        string `yaml:"image_url"`
+	OfferPercentage uint   `yaml:"offer_percentage"`
+}
+
+type seedRestaurant struct {
+	Name           string        `yaml:"name"`
+	Email          string        `yaml:"email"`
+	Password       string        `yaml:"password"`
+	Description    string        `yaml:"description"`
+	Address        string        `yaml:"address"`
+	PhoneNumber    uint          `yaml:"phone_number"`
+	ImageURL       string        `yaml:"image_url"`
+	CertificateURL string        `yaml:"certificate_url"`
+	Verified       bool          `yaml:"verified"`
+	Products       []seedProduct `yaml:"products"`
+}
+
+type seedProduct struct {
+	Name            string  `yaml:"name"`
+	Category        string  `yaml:"category"`
+	Description     string  `yaml:"description"`
+	ImageURL        string  `yaml:"image_url"`
+	Price           float64 `yaml:"price"`
+	OfferAmount     float64 `yaml:"offer_amount"`
+	PreparationTime float64 `yaml:"preparation_time"`
+	Stock           uint    `yaml:"stock"`
+	Veg             bool    `yaml:"veg"`
+}
+
+type seedUser struct {
+	Name        string        `yaml:"name"`
+	Email       string        `yaml:"email"`
+	Password    string        `yaml:"password"`
+	PhoneNumber string        `yaml:"phone_number"`
+	Wallet      float64       `yaml:"wallet"`
+	Addresses   []seedAddress `yaml:"addresses"`
+}
+
+type seedAddress struct {
+	AddressType  string `yaml:"type"`
+	PhoneNumber  uint   `yaml:"phone_number"`
+	StreetName   string `yaml:"street_name"`
+	StreetNumber string `yaml:"street_number"`
+	City         string `yaml:"city"`
+	State        string `yaml:"state"`
+	PostalCode   string `yaml:"postal_code"`
+}
+
+// cash on delivery orders placed through the cart, status defaults to INITIATED
+type seedOrder struct {
+	User       string          `yaml:"user"`
+	Restaurant string          `yaml:"restaurant"`
+	Status     string          `yaml:"status"`
+	Rating     float64         `yaml:"rating"`
+	Items      []seedOrderItem `yaml:"items"`
+}
+
+type seedOrderItem struct {
+	Product  string `yaml:"product"`
+	Quantity uint   `yaml:"quantity"`
+}
+
+var seedOrderStatuses = []string{
+	model.OrderStatusInitiated,
+	model.OrderStatusInPreparation,
+	model.OrderStatusPrepared,
+	model.OrderStatusOntheway,
+	model.OrderStatusDelivered,
+	model.OrderStatusCancelled,
+}
+
+// accounts, categories and products that already exist are skipped, orders are added on every run
+func runSeed(db *gorm.DB, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var seed seedFile
+	if err := yaml.Unmarshal(content, &seed); err != nil {
+		return fmt.Errorf("failed to parse %v: %w", path, err)
+	}
+
+	for _, email := range seed.Admins {
+		if err := createAdmin(db, email); err != nil {
+			return err
+		}
+	}
+
+	categories := make(map[string]uint)
+	for _, c := range seed.Categories {
+		category, err := seedCategoryRow(db, c)
+		if err != nil {
+			return err
+		}
+		categories[c.Name] = category.ID
+	}
+
+	for _, r := range seed.Restaurants {
+		if err := seedRestaurantRows(db, r, categories); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range seed.Users {
+		if err := seedUserRows(db, u); err != nil {
+			return err
+		}
+	}
+
+	for i, o := range seed.Orders {
+		if err := seedOrderRows(db, o); err != nil {
+			return fmt.Errorf("order %v: %w", i+1, err)
+		}
+	}
+
+	//ratings on the seeded orders are folded into the products the same way the api does
+	if len(seed.Orders) > 0 {
+		if err := recomputeRatings(db, false); err != nil {
+			return err
+		}
+	}
+
+	printCacheNote()
+	return nil
+}
+
+func seedCategoryRow(db *gorm.DB, c seedCategory) (model.Category, error) {
+	var category model.Category
+	err := db.Where("name = ?", c.Name).First(&category).Error
+	if err == nil {
+		return category, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return category, err
+	}
+
+	category = model.Category{
+		Name:            c.Name,
+		Description:     c.Description,
+		ImageURL:        c.ImageURL,
+		OfferPercentage: c.OfferPercentage,
+	}
+	if err := db.Create(&category).Error; err != nil {
+		return category, fmt.Errorf("failed to create category %v: %w", c.Name, err)
+	}
+	fmt.Printf("created category %v\n", c.Name)
+	return category, nil
+}
+
+func seedRestaurantRows(db *gorm.DB, r seedRestaurant, categories map[string]uint) error {
+	var restaurant model.Restaurant
+	err := db.Where("email = ?", r.Email).First(&restaurant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		salt, hash, err := controllers.HashPassword(r.Password)
+		if err != nil {
+			return fmt.Errorf("failed to hash the password of %v: %w", r.Email, err)
+		}
+
+		status := model.VerificationStatusPending
+		if r.Verified {
+			status = model.VerificationStatusVerified
+		}
+		restaurant = model.Restaurant{
+			Name:               r.Name,
+			Description:        r.Description,
+			Address:            r.Address,
+			Email:              r.Email,
+			PhoneNumber:        r.PhoneNumber,
+			ImageURL:           r.ImageURL,
+			CertificateURL:     r.CertificateURL,
+			VerificationStatus: status,
+			Salt:               salt,
+			HashedPassword:     hash,
+		}
+		if err := db.Create(&restaurant).Error; err != nil {
+			return fmt.Errorf("failed to create restaurant %v: %w", r.Email, err)
+		}
+		//restaurants log in once their email is verified
+		if err := seedVerifiedEmail(db, r.Email, model.RestaurantRole); err != nil {
+			return err
+		}
+		fmt.Printf("created restaurant %v\n", r.Email)
+	} else if err != nil {
+		return err
+	}
+
+	for _, p := range r.Products {
+		categoryID, ok := categories[p.Category]
+		if !ok {
+			return fmt.Errorf("product %v: category %v is not in the seed file", p.Name, p.Category)
+		}
+
+		var existing model.Product
+		err := db.Where("restaurant_id = ? AND name = ?", restaurant.ID, p.Name).First(&existing).Error
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		veg := model.NO
+		if p.Veg {
+			veg = model.YES
+		}
+		product := model.Product{
+			RestaurantID:    restaurant.ID,
+			CategoryID:      categoryID,
+			Name:            p.Name,
+			Description:     p.Description,
+			ImageURL:        p.ImageURL,
+			Price:           p.Price,
+			OfferAmount:     p.OfferAmount,
+			PreparationTime: p.PreparationTime,
+			MaxStock:        p.Stock,
+			StockLeft:       p.Stock,
+			Veg:             veg,
+		}
+		if err := db.Create(&product).Error; err != nil {
+			return fmt.Errorf("failed to create product %v: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func seedUserRows(db *gorm.DB, u seedUser) error {
+	var existing model.User
+	err := db.Where("email = ?", u.Email).First(&existing).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	salt, hash, err := controllers.HashPassword(u.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash the password of %v: %w", u.Email, err)
+	}
+	user := model.User{
+		Name:           u.Name,
+		Email:          u.Email,
+		PhoneNumber:    u.PhoneNumber,
+		LoginMethod:    model.EmailLoginMethod,
+		Salt:           salt,
+		HashedPassword: hash,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return fmt.Errorf("failed to create user %v: %w", u.Email, err)
+	}
+	if err := seedVerifiedEmail(db, u.Email, model.UserRole); err != nil {
+		return err
+	}
+	if _, err := controllers.GenerateReferralCodeForUser(u.Email); err != nil {
+		return fmt.Errorf("failed to create the referral code of %v: %w", u.Email, err)
+	}
+
+	//the opening balance goes through the wallet history so recompute wallets keeps it
+	if u.Wallet > 0 {
+		history := model.UserWalletHistory{
+			TransactionTime: time.Now(),
+			WalletPaymentID: uuid.New().String(),
+			UserID:          user.ID,
+			Type:            model.WalletIncoming,
+			Amount:          u.Wallet,
+			CurrentBalance:  u.Wallet,
+			Reason:          model.WalletTxTypeAdjustment,
+		}
+		if err := db.Create(&history).Error; err != nil {
+			return fmt.Errorf("failed to credit the wallet of %v: %w", u.Email, err)
+		}
+		if err := db.Model(&model.User{}).Where("id = ?", user.ID).Update("wallet_amount", u.Wallet).Error; err != nil {
+			return fmt.Errorf("failed to credit the wallet of %v: %w", u.Email, err)
+		}
+	}
+
+	for _, a := range u.Addresses {
+		address := model.Address{
+			UserID:       user.ID,
+			AddressType:  a.AddressType,
+			PhoneNumber:  a.PhoneNumber,
+			StreetName:   a.StreetName,
+			StreetNumber: a.StreetNumber,
+			City:         a.City,
+			State:        a.State,
+			PostalCode:   a.PostalCode,
+		}
+		if err := db.Create(&address).Error; err != nil {
+			return fmt.Errorf("failed to create an address of %v: %w", u.Email, err)
+		}
+	}
+
+	fmt.Printf("created user %v\n", u.Email)
+	return nil
+}
+
+func seedVerifiedEmail(db *gorm.DB, email string, role string) error {
+	verification := model.VerificationTable{
+		Email:              email,
+		Role:               role,
+		VerificationStatus: model.VerificationStatusVerified,
+	}
+	if err := db.Where("email = ? AND role = ?", email, role).Delete(&model.VerificationTable{}).Error; err != nil {
+		return err
+	}
+	if err := db.Create(&verification).Error; err != nil {
+		return fmt.Errorf("failed to verify %v: %w", email, err)
+	}
+	return nil
+}
+
+// the order goes through the user's cart like PlaceOrder, so totals, offers and stock match the api
+func seedOrderRows(db *gorm.DB, o seedOrder) error {
+	if o.Status == "" {
+		o.Status = model.OrderStatusInitiated
+	}
+	if !validSeedStatus(o.Status) {
+		return fmt.Errorf("unknown order status %v", o.Status)
+	}
+
+	var user model.User
+	if err := db.Where("email = ?", o.User).First(&user).Error; err != nil {
+		return fmt.Errorf("user %v: %w", o.User, err)
+	}
+	var restaurant model.Restaurant
+	if err := db.Where("email = ?", o.Restaurant).First(&restaurant).Error; err != nil {
+		return fmt.Errorf("restaurant %v: %w", o.Restaurant, err)
+	}
+	var address model.Address
+	if err := db.Where("user_id = ?", user.ID).Order("address_id").First(&address).Error; err != nil {
+		return fmt.Errorf("user %v needs an address to order: %w", o.User, err)
+	}
+
+	//start from an empty cart, CheckStock counts every item in it
+	if err := db.Where("user_id = ?", user.ID).Delete(&model.CartItems{}).Error; err != nil {
+		return err
+	}
+	for _, item := range o.Items {
+		var product model.Product
+		if err := db.Where("restaurant_id = ? AND name = ?", restaurant.ID, item.Product).First(&product).Error; err != nil {
+			return fmt.Errorf("product %v: %w", item.Product, err)
+		}
+		if err := db.Create(&model.CartItems{
+			UserID:       user.ID,
+			ProductID:    product.ID,
+			RestaurantID: restaurant.ID,
+			Quantity:     item.Quantity,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	ItemCount, ok := controllers.CheckStock(user.ID)
+	if !ok {
+		return errors.New("items are out of stock")
+	}
+	TotalAmount, ProductOffer, err := controllers.CalculateCartTotal(user.ID, restaurant.ID)
+	if err != nil {
+		return fmt.Errorf("failed to calculate the cart total: %w", err)
+	}
+
+	order := model.Order{
+		OrderID:            uuid.New().String(),
+		UserID:             user.ID,
+		RestaurantID:       restaurant.ID,
+		AddressID:          address.AddressID,
+		ItemCount:          ItemCount,
+		ProductOfferAmount: ProductOffer,
+		TotalAmount:        TotalAmount,
+		FinalAmount:        TotalAmount - ProductOffer,
+		PaymentMethod:      model.CashOnDelivery,
+		PaymentStatus:      model.CODStatusPending,
+		OrderedAt:          time.Now(),
+	}
+	if err := db.Create(&order).Error; err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	if !controllers.CartToOrderItems(user.ID, restaurant.ID, order) {
+		return errors.New("failed to transfer cart items to order")
+	}
+	if !controllers.DecrementStock(order.OrderID) {
+		return errors.New("failed to decrement order stock")
+	}
+
+	updates := map[string]interface{}{"order_status": o.Status}
+	if o.Status == model.OrderStatusDelivered {
+		updates["delivered_at"] = time.Now()
+	}
+	if o.Status == model.OrderStatusDelivered && o.Rating > 0 {
+		updates["order_rating"] = o.Rating
+	}
+	if err := db.Model(&model.OrderItem{}).Where("order_id = ?", order.OrderID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update the order status: %w", err)
+	}
+
+	switch o.Status {
+	case model.OrderStatusDelivered:
+		if err := db.Model(&model.Order{}).Where("order_id = ?", order.OrderID).Update("payment_status", model.CODStatusConfirmed).Error; err != nil {
+			return err
+		}
+	case model.OrderStatusCancelled:
+		var items []model.OrderItem
+		if err := db.Where("order_id = ?", order.OrderID).Find(&items).Error; err != nil {
+			return err
+		}
+		if !controllers.IncrementStock(items) {
+			return errors.New("failed to restock the cancelled order")
+		}
+	}
+
+	fmt.Printf("created order %v for %v, %v\n", order.OrderID, o.User, o.Status)
+	return nil
+}
+
+func validSeedStatus(status string) bool {
+	for _, s := range seedOrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
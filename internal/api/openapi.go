@@ -0,0 +1,733 @@
+package api
+
+import (
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/openapi"
+	"foodbuddy/internal/storage"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	specPath = "/api/v1/openapi.json"
+	docsPath = "/api/v1/docs/"
+)
+
+var info = openapi.Info{
+	Title:       "FoodBuddy API",
+	Version:     "1.0.0",
+	Description: "responses use the envelope {status, message, data, next_cursor, error}, see the readme for the error codes",
+}
+
+// response payloads the handlers build inline with gin.H
+type userLoginData struct {
+	User  model.User `json:"user"`
+	Token string     `json:"token"`
+}
+
+type restaurantLoginData struct {
+	Name               string `json:"name"`
+	Email              string `json:"email"`
+	Description        string `json:"description"`
+	Address            string `json:"address"`
+	PhoneNumber        uint   `json:"phone_number"`
+	ImageURL           string `json:"image_url"`
+	CertificateURL     string `json:"certificate_url"`
+	VerificationStatus string `json:"verification_status"`
+	Token              string `json:"token"`
+}
+
+type productsData struct {
+	Products []model.Product `json:"products"`
+}
+
+type usersData struct {
+	Users []model.BlockedUserResponse `json:"users"`
+}
+
+type blockedUsersData struct {
+	BlockedUsers []model.BlockedUserResponse `json:"blocked_users"`
+}
+
+type orderData struct {
+	OrderDetails model.Order `json:"order_details"`
+}
+
+type searchData struct {
+	Products    []model.SearchProductResult    `json:"products"`
+	Restaurants []model.SearchRestaurantResult `json:"restaurants"`
+	Facets      map[string]any                 `json:"facets"`
+	Total       int64                          `json:"total"`
+}
+
+type onboardingData struct {
+	VerificationStatus string                     `json:"verification_status"`
+	Documents          []model.RestaurantDocument `json:"documents"`
+	Missing            []string                   `json:"missing"`
+}
+
+type auditData struct {
+	Entries []model.AuditLog `json:"entries"`
+}
+
+type blockAppealsData struct {
+	Appeals []struct {
+		model.BlockAppeal
+		Block model.AccountBlock `json:"block"`
+	} `json:"appeals"`
+}
+
+type ticketData struct {
+	model.SupportTicket
+	ProductIDs []uint                 `json:"product_ids"`
+	Messages   []model.SupportMessage `json:"messages"`
+}
+
+type ticketsData struct {
+	Tickets []model.SupportTicket `json:"tickets"`
+}
+
+var (
+	ticketID      = []openapi.Param{{Name: "ticketid", Type: "integer", Required: true}}
+	ticketFilters = []openapi.Param{
+		{Name: "status", Description: "OPEN or RESOLVED"},
+		{Name: "breached", Type: "boolean", Description: "only tickets that missed a due time"},
+	}
+	ticketAttachment = []openapi.Param{
+		{Name: "file", Type: "file", Required: true, Description: "jpeg, png, webp or pdf up to 10 MB"},
+		{Name: "message", Description: "shown with the attachment"},
+	}
+)
+
+type reviewsData struct {
+	Reviews []model.RestaurantReview `json:"reviews"`
+}
+
+type publicReviewsData struct {
+	Summary struct {
+		AverageRating   float64 `json:"average_rating"`
+		RatingCount     uint    `json:"rating_count"`
+		FoodRating      float64 `json:"food_rating"`
+		PackagingRating float64 `json:"packaging_rating"`
+		DeliveryRating  float64 `json:"delivery_rating"`
+	} `json:"summary"`
+	Reviews []struct {
+		ReviewID        uint       `json:"review_id"`
+		Reviewer        string     `json:"reviewer"`
+		Rating          float64    `json:"rating"`
+		FoodRating      uint       `json:"food_rating"`
+		PackagingRating uint       `json:"packaging_rating"`
+		DeliveryRating  uint       `json:"delivery_rating"`
+		Text            string     `json:"text"`
+		HelpfulCount    uint       `json:"helpful_count"`
+		Reply           string     `json:"reply"`
+		RepliedAt       *time.Time `json:"replied_at"`
+		CreatedAt       time.Time  `json:"created_at"`
+	} `json:"reviews"`
+}
+
+var reviewID = []openapi.Param{{Name: "reviewid", Type: "integer", Required: true}}
+
+type reviewHistoryData struct {
+	Review    string                 `json:"review"`
+	Rating    float64                `json:"rating"`
+	Photos    []model.ReviewPhoto    `json:"photos"`
+	Revisions []model.ReviewRevision `json:"revisions"`
+}
+
+type campaignsData struct {
+	Campaigns []model.CouponCampaign `json:"campaigns"`
+}
+
+type campaignData struct {
+	Campaign model.CouponCampaign `json:"campaign"`
+	Report   model.CampaignReport `json:"report"`
+}
+
+type redemptionsData struct {
+	Redemptions []model.CouponRedemption `json:"redemptions"`
+}
+
+var campaignParam = []openapi.Param{{Name: "campaignid", Type: "integer", Required: true}}
+
+type couponRecommendationData struct {
+	RestaurantID       uint                         `json:"restaurant_id"`
+	TotalAmount        float64                      `json:"total_amount"`
+	ProductOfferAmount float64                      `json:"product_offer_amount"`
+	BestCoupon         string                       `json:"best_coupon"`
+	Eligible           []model.CouponRecommendation `json:"eligible"`
+	Ineligible         []model.CouponRecommendation `json:"ineligible"`
+}
+
+var reviewItem = []openapi.Param{
+	{Name: "order_id", Required: true},
+	{Name: "product_id", Type: "integer", Required: true},
+}
+
+var auditFilters = []openapi.Param{
+	{Name: "actor", Description: "email of the admin, cli:<user> for the command line"},
+	{Name: "action", Description: "like user.block or coupon.update"},
+	{Name: "entity_type", Description: "user, restaurant, restaurant_document, category, coupon, admin, block_appeal, support_ticket, restaurant_review or coupon_campaign"},
+	{Name: "entity_id"},
+	{Name: "from", Description: "date or rfc3339 time"},
+	{Name: "to", Description: "date or rfc3339 time, a date includes the whole day"},
+}
+
+type favouriteProductRequest struct {
+	ProductID uint `validate:"required,number" json:"product_id"`
+}
+
+// every registered route needs an operation here, TestSpecCoversRoutes fails otherwise
+var operations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/", Tag: "health", Summary: "Server status"},
+	{Method: http.MethodGet, Path: "/healthz", Tag: "health", Summary: "Liveness probe"},
+	{Method: http.MethodGet, Path: "/readyz", Tag: "health", Summary: "Readiness probe, checks the database, schema version and workers"},
+	{Method: http.MethodGet, Path: "/metrics", Tag: "health", Summary: "Prometheus metrics, needs the bearer token when one is configured", Produces: "text/plain"},
+	{Method: http.MethodGet, Path: specPath, Tag: "misc", Summary: "This OpenAPI document"},
+	{Method: http.MethodGet, Path: docsPath + "*file", Tag: "misc", Summary: "Swagger UI", Produces: "text/html"},
+	{Method: http.MethodGet, Path: "/api/v1/auth/admin/login", Tag: "auth", Summary: "Send a login link to an admin email",
+		Body: model.AdminLoginRequest{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/user/email/login", Tag: "auth", Summary: "Log in a user with email and password",
+		Body: model.EmailLoginRequest{},
+		Data: userLoginData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/user/email/signup", Tag: "auth", Summary: "Sign up a user with email and password",
+		Body: model.EmailSignupRequest{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/auth/google/login", Tag: "auth", Summary: "Redirect to the Google consent page"},
+	{Method: http.MethodGet, Path: "/api/v1/auth/verifyemail/:role/:email/:otp", Tag: "auth", Summary: "Verify an email with the mailed otp"},
+	{Method: http.MethodPost, Path: "/api/v1/auth/passwordreset/step1", Tag: "auth", Summary: "Mail a password reset link",
+		Body: model.Step1PasswordReset{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/auth/passwordreset", Tag: "auth", Summary: "Password reset form",
+		Query:    []openapi.Param{{Name: "email", Required: true}, {Name: "role", Required: true}, {Name: "token", Required: true}},
+		Produces: "text/html",
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/passwordreset/step2", Tag: "auth", Summary: "Set a new password with the reset token",
+		Body: model.Step2PasswordReset{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/restaurant/signup", Tag: "auth", Summary: "Sign up a restaurant",
+		Body: model.RestaurantSignupRequest{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/restaurant/login", Tag: "auth", Summary: "Log in a restaurant",
+		Body: model.RestaurantLoginRequest{},
+		Data: restaurantLoginData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/auth/block/status", Tag: "auth", Summary: "Block of an account by the token in its block notice, with the appeals made",
+		Query: []openapi.Param{{Name: "token", Required: true, Description: "from the block notice mail"}},
+		Data:  model.BlockStatusResponse{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/block/notice", Tag: "auth", Summary: "Mail the block notice again with a new token, the response is the same for every email",
+		Body: model.BlockNoticeRequest{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/auth/block/appeal", Tag: "auth", Summary: "Appeal a block, one pending appeal at a time",
+		Body: model.BlockAppealRequest{},
+		Data: model.BlockAppeal{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/googlecallback", Tag: "auth", Summary: "Google OAuth callback, logs the user in",
+		Query: []openapi.Param{{Name: "code", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/profile", Tag: "user", Summary: "Profile of the signed in user", Auth: model.UserRole},
+	{Method: http.MethodPost, Path: "/api/v1/user/edit", Tag: "user", Summary: "Update the profile of the signed in user", Auth: model.UserRole,
+		Body: model.UpdateUserInformation{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/wallet/all", Tag: "user", Summary: "Wallet balance and history", Auth: model.UserRole},
+	{Method: http.MethodGet, Path: "/api/v1/user/favorites/all", Tag: "user", Summary: "Favourite products", Auth: model.UserRole},
+	{Method: http.MethodPost, Path: "/api/v1/user/favorites/add", Tag: "user", Summary: "Add a favourite product", Auth: model.UserRole,
+		Body: favouriteProductRequest{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/favorites/delete", Tag: "user", Summary: "Remove a favourite product", Auth: model.UserRole,
+		Body: favouriteProductRequest{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/address/all", Tag: "user", Summary: "Saved addresses", Auth: model.UserRole},
+	{Method: http.MethodPost, Path: "/api/v1/user/address/add", Tag: "user", Summary: "Add an address, at most three per user", Auth: model.UserRole,
+		Body: model.Address{},
+	},
+	{Method: http.MethodPatch, Path: "/api/v1/user/address/edit", Tag: "user", Summary: "Edit an address", Auth: model.UserRole,
+		Body: model.EditUserAddress{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/address/delete", Tag: "user", Summary: "Delete an address", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "addressid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/cart/add", Tag: "user", Summary: "Add a product to the cart", Auth: model.UserRole,
+		Body: model.AddToCartReq{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/cart/cookingrequest", Tag: "user", Summary: "Add a cooking request to a cart item",
+		Body: model.AddCookingRequest{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/cart/all", Tag: "user", Summary: "Cart items and totals per restaurant", Auth: model.UserRole},
+	{Method: http.MethodDelete, Path: "/api/v1/user/cart/delete/", Tag: "user", Summary: "Clear the cart, or the items of one restaurant", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "restaurant_id", Type: "integer"}},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/cart/remove", Tag: "user", Summary: "Remove a product from the cart", Auth: model.UserRole,
+		Body: model.RemoveItem{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/user/cart/update/", Tag: "user", Summary: "Change the quantity of a cart item", Auth: model.UserRole,
+		Body: model.CartItems{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/coupon/best", Tag: "user", Summary: "Coupons of a restaurant ranked by savings on the cart, with the reason ineligible ones fail", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "restaurant_id", Type: "integer", Required: true}},
+		Data:  couponRecommendationData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/coupon/cart/", Tag: "user", Summary: "Apply a coupon on the cart of a restaurant", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "couponcode", Required: true}, {Name: "restaurant_id", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/cart/bundle/add", Tag: "user", Summary: "Add a combo bundle to the cart", Auth: model.UserRole,
+		Body: model.AddBundleToCartReq{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/cart/bundle/remove", Tag: "user", Summary: "Remove a combo bundle from the cart", Auth: model.UserRole,
+		Body: model.RemoveBundle{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/step1/placeorder", Tag: "user", Summary: "Place an order from the cart of a restaurant", Auth: model.UserRole,
+		Body: model.PlaceOrder{},
+		Data: orderData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/deliverycode", Tag: "user", Summary: "Mail the delivery verification code of an order", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "order_id", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/step2/initiatepayment", Tag: "user", Summary: "Start the online or wallet payment of an order",
+		Body: model.InitiatePayment{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/user/order/update/paymentmode", Tag: "user", Summary: "Switch the payment method of an order between COD and ONLINE", Auth: model.UserRole,
+		Body: model.ChangeOrderPaymentMode{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/step3/razorpaycallback/:orderid", Tag: "user", Summary: "Razorpay checkout callback",
+		Body: model.RazorpayPayment{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/step3/razorpaycallback/failed/:orderid", Tag: "user", Summary: "Razorpay failed payment redirect"},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/step3/stripecallback", Tag: "user", Summary: "Stripe checkout redirect",
+		Query: []openapi.Param{{Name: "session_id", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/cancel/online", Tag: "user", Summary: "Cancel an online paid order item, refunded to the wallet", Auth: model.UserRole,
+		Body: model.CancelOrderedProduct{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/cancel/cod", Tag: "user", Summary: "Cancel a COD order item", Auth: model.UserRole,
+		Body: model.CancelOrderedProduct{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/items", Tag: "user", Summary: "Order items of the user by order status", Auth: model.UserRole,
+		Query:     []openapi.Param{{Name: "order_status", Description: "one of the order statuses, every status when empty"}},
+		Paginated: true,
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/info", Tag: "user", Summary: "Order and its items",
+		Query: []openapi.Param{{Name: "order_id", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/invoice/", Tag: "user", Summary: "PDF invoice of an order",
+		Query:    []openapi.Param{{Name: "order_id", Required: true}},
+		Produces: "application/pdf",
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/paymenthistory", Tag: "user", Summary: "Payments of an order",
+		Body: model.PaymentDetailsByOrderID{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/verifypayment", Tag: "user", Summary: "Payment status of an online order", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "order_id", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/review", Tag: "user", Summary: "Review or edit the review of an item delivered in the review window", Auth: model.UserRole,
+		Body: model.UserReviewonOrderItem{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/rating", Tag: "user", Summary: "Rate or re-rate an item delivered in the review window", Auth: model.UserRole,
+		Body: model.UserRatingOrderItem{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/order/review", Tag: "user", Summary: "Delete the review, rating and photos of an order item", Auth: model.UserRole,
+		Query: reviewItem,
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/order/review/history", Tag: "user", Summary: "Current review of an order item with its photos and earlier versions", Auth: model.UserRole,
+		Query: reviewItem,
+		Data:  reviewHistoryData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/order/review/photo", Tag: "uploads", Summary: "Attach a photo to the review of an order item", Auth: model.UserRole,
+		Query: reviewItem,
+		Form:  []openapi.Param{{Name: "file", Type: "file", Required: true, Description: "jpeg, png or webp up to 8 MB"}},
+		Data:  model.ReviewPhoto{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/order/review/photo", Tag: "uploads", Summary: "Remove a photo from a review", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "photoid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/reviews", Tag: "reviews", Summary: "Review the restaurant of a delivered order, once per order", Auth: model.UserRole,
+		Body: model.RestaurantReviewRequest{},
+		Data: model.RestaurantReview{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/reviews/helpful", Tag: "reviews", Summary: "Mark a review as helpful", Auth: model.UserRole,
+		Query: reviewID,
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/user/reviews/helpful", Tag: "reviews", Summary: "Take back a helpful vote", Auth: model.UserRole,
+		Query: reviewID,
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/reviews/flag", Tag: "reviews", Summary: "Flag a review for moderation", Auth: model.UserRole,
+		Query: reviewID,
+		Body:  model.ReviewFlagRequest{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/support/tickets", Tag: "support", Summary: "Raise a ticket about items of an order", Auth: model.UserRole,
+		Body: model.RaiseTicketRequest{},
+		Data: ticketData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/support/tickets", Tag: "support", Summary: "Tickets raised by the user", Auth: model.UserRole,
+		Query:     ticketFilters,
+		Paginated: true,
+		Data:      ticketsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/support/ticket", Tag: "support", Summary: "Ticket with its items and conversation", Auth: model.UserRole,
+		Query: ticketID,
+		Data:  ticketData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/support/ticket/message", Tag: "support", Summary: "Add a message to an open ticket", Auth: model.UserRole,
+		Query: ticketID,
+		Body:  model.TicketMessageRequest{},
+		Data:  model.SupportMessage{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/support/ticket/attachment", Tag: "support", Summary: "Attach a photo or receipt to an open ticket", Auth: model.UserRole,
+		Query: ticketID,
+		Form:  ticketAttachment,
+		Data:  model.SupportMessage{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/referral/code", Tag: "user", Summary: "Referral code of the user", Auth: model.UserRole},
+	{Method: http.MethodPatch, Path: "/api/v1/user/referral/activate", Tag: "user", Summary: "Activate the referral code of another user", Auth: model.UserRole,
+		Query: []openapi.Param{{Name: "referralcode", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/user/referral/claim", Tag: "user", Summary: "Claim referral rewards to the wallet", Auth: model.UserRole},
+	{Method: http.MethodGet, Path: "/api/v1/user/referral/stats", Tag: "user", Summary: "Referral statistics and history", Auth: model.UserRole},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/edit", Tag: "restaurant", Summary: "Update the restaurant profile", Auth: model.RestaurantRole,
+		Body: model.RestaurantProfileUpdate{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/products/add", Tag: "restaurant", Summary: "Add a product", Auth: model.RestaurantRole,
+		Body: model.AddProductRequest{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/products/edit", Tag: "restaurant", Summary: "Edit a product", Auth: model.RestaurantRole,
+		Body: model.EditProductRequest{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/restaurants/products", Tag: "restaurant", Summary: "Delete a product", Auth: model.RestaurantRole,
+		Query: []openapi.Param{{Name: "productid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/products/image", Tag: "uploads", Summary: "Upload the image of a product as thumb, card and large variants", Auth: model.RestaurantRole,
+		Query: []openapi.Param{{Name: "productid", Type: "integer", Required: true}},
+		Form:  []openapi.Param{{Name: "file", Type: "file", Required: true, Description: "jpeg, png or webp up to 8 MB"}},
+		Data:  storage.Stored{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/certificate", Tag: "uploads", Summary: "Upload the food safety certificate of the restaurant", Auth: model.RestaurantRole,
+		Form: []openapi.Param{{Name: "file", Type: "file", Required: true, Description: "jpeg, png or pdf up to 10 MB"}},
+		Data: storage.Stored{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/onboarding", Tag: "onboarding", Summary: "Onboarding status and the current documents of the restaurant", Auth: model.RestaurantRole,
+		Data: onboardingData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/onboarding/documents", Tag: "onboarding", Summary: "Upload an onboarding document, replacing a pending or rejected one of the type", Auth: model.RestaurantRole,
+		Query: []openapi.Param{{Name: "type", Required: true, Description: "FSSAI, GST or BANK"}},
+		Form: []openapi.Param{
+			{Name: "file", Type: "file", Required: true, Description: "jpeg, png or pdf up to 10 MB"},
+			{Name: "reference", Required: true, Description: "fssai licence number, gstin or bank account number"},
+			{Name: "ifsc", Description: "ifsc code, required for BANK"},
+		},
+		Data: model.RestaurantDocument{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/onboarding/submit", Tag: "onboarding", Summary: "Send the uploaded documents for review", Auth: model.RestaurantRole,
+		Data: onboardingData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/bundles/add", Tag: "restaurant", Summary: "Add a combo bundle", Auth: model.RestaurantRole,
+		Body: model.AddBundleRequest{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/restaurants/bundles/edit", Tag: "restaurant", Summary: "Edit a combo bundle", Auth: model.RestaurantRole,
+		Body: model.EditBundleRequest{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/restaurants/bundles", Tag: "restaurant", Summary: "Delete a combo bundle", Auth: model.RestaurantRole,
+		Query: []openapi.Param{{Name: "bundleid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/order/history", Tag: "restaurant", Summary: "Order history of the restaurant, optionally by status", Auth: model.RestaurantRole,
+		Query:     []openapi.Param{{Name: "order_status", Description: "one of the order statuses, every status when empty"}},
+		Paginated: true,
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/order/confirmcod", Tag: "restaurant", Summary: "Confirm the cash payment of a COD order", Auth: model.RestaurantRole,
+		Body: model.ConfirmCODPayment{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/order/confirmdelivery", Tag: "restaurant", Summary: "Confirm delivery with the code of the customer",
+		Body: model.ConfirmDelivery{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/order/nextstatus", Tag: "restaurant", Summary: "Move an order item to its next status", Auth: model.RestaurantRole,
+		Body: model.UpdateOrderStatusForRestaurant{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/product/offer/add", Tag: "restaurant", Summary: "Add an offer amount to a product", Auth: model.RestaurantRole,
+		Body: model.AddOfferRequest{},
+		Data: model.Product{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/restaurants/product/offer/remove", Tag: "restaurant", Summary: "Remove the offer of a product", Auth: model.RestaurantRole,
+		Query: []openapi.Param{{Name: "productid", Type: "integer", Required: true}},
+		Data:  model.Product{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/orderitems/excel/all", Tag: "restaurant", Summary: "Order items as an excel sheet", Auth: model.RestaurantRole,
+		Produces: "text/csv",
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/orderitems/json/all", Tag: "restaurant", Summary: "Order items as json", Auth: model.RestaurantRole},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/report/all", Tag: "restaurant", Summary: "Sales report of the restaurant", Auth: model.RestaurantRole,
+		Body: model.RestaurantOverallSalesReport{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/wallet/all", Tag: "restaurant", Summary: "Wallet balance and history of the restaurant", Auth: model.RestaurantRole},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/coupon/create", Tag: "restaurant", Summary: "Create a coupon valid only on the restaurant's menu", Auth: model.RestaurantRole,
+		Body: model.CouponInventoryRequest{},
+	},
+	{Method: http.MethodPatch, Path: "/api/v1/restaurants/coupon/update", Tag: "restaurant", Summary: "Update a coupon the restaurant created", Auth: model.RestaurantRole,
+		Body: model.CouponInventoryRequest{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/coupon/all", Tag: "restaurant", Summary: "Coupons the restaurant created", Auth: model.RestaurantRole,
+		Data: []model.CouponInventory{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/reviews", Tag: "reviews", Summary: "Reviews of the restaurant including hidden ones, newest first", Auth: model.RestaurantRole,
+		Query:     []openapi.Param{{Name: "status", Description: "PUBLISHED or HIDDEN"}},
+		Paginated: true,
+		Data:      reviewsData{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/restaurants/reviews/reply", Tag: "reviews", Summary: "Reply to a review of the restaurant", Auth: model.RestaurantRole,
+		Query: reviewID,
+		Body:  model.ReviewReplyRequest{},
+		Data:  model.RestaurantReview{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/reviews/flag", Tag: "reviews", Summary: "Flag a review of the restaurant for moderation", Auth: model.RestaurantRole,
+		Query: reviewID,
+		Body:  model.ReviewFlagRequest{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/support/tickets", Tag: "support", Summary: "Tickets raised on orders of the restaurant", Auth: model.RestaurantRole,
+		Query:     ticketFilters,
+		Paginated: true,
+		Data:      ticketsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurants/support/ticket", Tag: "support", Summary: "Ticket with its items and conversation", Auth: model.RestaurantRole,
+		Query: ticketID,
+		Data:  ticketData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/support/ticket/message", Tag: "support", Summary: "Answer an open ticket, the first answer meets the first response due time", Auth: model.RestaurantRole,
+		Query: ticketID,
+		Body:  model.TicketMessageRequest{},
+		Data:  model.SupportMessage{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurants/support/ticket/attachment", Tag: "support", Summary: "Attach a photo or receipt to an open ticket", Auth: model.RestaurantRole,
+		Query: ticketID,
+		Form:  ticketAttachment,
+		Data:  model.SupportMessage{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/users", Tag: "admin", Summary: "List users", Auth: model.AdminRole,
+		Paginated: true,
+		Data:      usersData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/users/blocked", Tag: "admin", Summary: "List blocked users", Auth: model.AdminRole,
+		Data: blockedUsersData{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/users/block/", Tag: "admin", Summary: "Block a user with a reason, until unblocked or for duration_hours", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "userid", Type: "integer", Required: true}},
+		Body:  model.BlockAccountRequest{},
+		Data:  model.AccountBlock{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/users/unblock", Tag: "admin", Summary: "Unblock a user", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "userid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/admin/categories/add", Tag: "admin", Summary: "Add a category", Auth: model.AdminRole,
+		Body: model.AddCategoryRequest{},
+	},
+	{Method: http.MethodPatch, Path: "/api/v1/admin/categories/edit", Tag: "admin", Summary: "Edit a category", Auth: model.AdminRole,
+		Body: model.EditCategoryRequest{},
+	},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/categories/delete", Tag: "admin", Summary: "Delete an empty category", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "categoryid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/admin/categories/image", Tag: "uploads", Summary: "Upload the image of a category as thumb and card variants", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "categoryid", Type: "integer", Required: true}},
+		Form:  []openapi.Param{{Name: "file", Type: "file", Required: true, Description: "jpeg, png or webp up to 5 MB"}},
+		Data:  storage.Stored{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/restaurants", Tag: "admin", Summary: "List restaurants",
+		Paginated: true,
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/restaurants/block", Tag: "admin", Summary: "Block a restaurant with a reason, until unblocked or for duration_hours", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "restaurantid", Type: "integer", Required: true}},
+		Body:  model.BlockAccountRequest{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/restaurants/unblock", Tag: "admin", Summary: "Unblock a restaurant", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "restaurantid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/restaurants/verify/success", Tag: "admin", Summary: "Mark a restaurant as verified", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "restaurantid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/restaurants/verify/failed", Tag: "admin", Summary: "Mark a restaurant as unverified", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "restaurantid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/restaurants/onboarding", Tag: "onboarding", Summary: "Restaurants by onboarding status with their current documents", Auth: model.AdminRole,
+		Query:     []openapi.Param{{Name: "status", Description: "pending, in_review, rejected or verified, in_review when empty"}},
+		Paginated: true,
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/restaurants/onboarding/review", Tag: "onboarding", Summary: "Approve or reject a document, the restaurant is verified or rejected once all are reviewed", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "documentid", Type: "integer", Required: true}},
+		Body:  model.ReviewRestaurantDocument{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/reviews", Tag: "reviews", Summary: "Restaurant reviews for moderation, newest first", Auth: model.AdminRole,
+		Query: []openapi.Param{
+			{Name: "status", Description: "PUBLISHED or HIDDEN"},
+			{Name: "restaurant_id", Type: "integer"},
+			{Name: "flagged", Type: "boolean", Description: "only reviews flagged by users, restaurants or the profanity filter"},
+		},
+		Paginated: true,
+		Data:      reviewsData{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/reviews/moderate", Tag: "reviews", Summary: "Hide or unhide a review or clear its flags", Auth: model.AdminRole,
+		Query: reviewID,
+		Body:  model.ModerateReviewRequest{},
+		Data:  model.RestaurantReview{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/admin/coupon/create", Tag: "admin", Summary: "Create a coupon", Auth: model.AdminRole,
+		Body: model.CouponInventoryRequest{},
+	},
+	{Method: http.MethodPatch, Path: "/api/v1/admin/coupon/update", Tag: "admin", Summary: "Update a coupon", Auth: model.AdminRole,
+		Body: model.CouponInventoryRequest{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/admin/coupon/campaign/create", Tag: "admin", Summary: "Create a campaign of single-use codes sharing one rule set", Auth: model.AdminRole,
+		Body: model.CouponCampaignRequest{},
+		Data: model.CouponCampaign{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/coupon/campaigns", Tag: "admin", Summary: "Coupon campaigns, oldest first", Auth: model.AdminRole,
+		Paginated: true,
+		Data:      campaignsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/coupon/campaign", Tag: "admin", Summary: "Campaign with its redemptions, paid orders, revenue and discount given", Auth: model.AdminRole,
+		Query: campaignParam,
+		Data:  campaignData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/coupon/campaign/codes", Tag: "admin", Summary: "Codes of a campaign with their redemption counts as csv", Auth: model.AdminRole,
+		Query:    campaignParam,
+		Produces: "text/csv",
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/coupon/campaign/redemptions", Tag: "admin", Summary: "Redemptions of the codes of a campaign, oldest first", Auth: model.AdminRole,
+		Query:     append(campaignParam, openapi.Param{Name: "coupon_code", Description: "redemptions of this code only"}),
+		Paginated: true,
+		Data:      redemptionsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/appeals", Tag: "admin", Summary: "Block appeals by status with their block", Auth: model.AdminRole,
+		Query:     []openapi.Param{{Name: "status", Description: "PENDING, ACCEPTED or REJECTED, PENDING when empty"}},
+		Paginated: true,
+		Data:      blockAppealsData{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/appeals/review", Tag: "admin", Summary: "Accept or reject a block appeal, accepting lifts the block", Auth: model.AdminRole,
+		Query: []openapi.Param{{Name: "appealid", Type: "integer", Required: true}},
+		Body:  model.ReviewBlockAppeal{},
+		Data:  model.BlockAppeal{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/audit", Tag: "admin", Summary: "Audit log of privileged changes, oldest first", Auth: model.AdminRole,
+		Query:     auditFilters,
+		Paginated: true,
+		Data:      auditData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/audit/export", Tag: "admin", Summary: "Audit log entries matching the filters as csv", Auth: model.AdminRole,
+		Query:    auditFilters,
+		Produces: "text/csv",
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/support/tickets", Tag: "support", Summary: "All tickets, oldest first", Auth: model.AdminRole,
+		Query:     ticketFilters,
+		Paginated: true,
+		Data:      ticketsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/admin/support/ticket", Tag: "support", Summary: "Ticket with its items and conversation", Auth: model.AdminRole,
+		Query: ticketID,
+		Data:  ticketData{},
+	},
+	{Method: http.MethodPost, Path: "/api/v1/admin/support/ticket/message", Tag: "support", Summary: "Answer an open ticket", Auth: model.AdminRole,
+		Query: ticketID,
+		Body:  model.TicketMessageRequest{},
+		Data:  model.SupportMessage{},
+	},
+	{Method: http.MethodPut, Path: "/api/v1/admin/support/ticket/resolve", Tag: "support", Summary: "Resolve a ticket, a refund is credited to the user wallet", Auth: model.AdminRole,
+		Query: ticketID,
+		Body:  model.ResolveTicketRequest{},
+		Data:  ticketData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/restaurant/profile", Tag: "public", Summary: "Public profile of a restaurant",
+		Query: []openapi.Param{{Name: "id", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/restaurant/reviews", Tag: "public", Summary: "Published reviews of a restaurant with its rating summary",
+		Query: []openapi.Param{
+			{Name: "id", Type: "integer", Required: true},
+			{Name: "sort", Description: "recent, helpful, highest or lowest, recent when empty"},
+		},
+		Paginated: true,
+		Data:      publicReviewsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/coupon/all", Tag: "public", Summary: "List platform coupons and the coupons of a restaurant",
+		Query: []openapi.Param{{Name: "restaurant_id", Type: "integer", Description: "also list the coupons of this restaurant"}},
+		Data:  []model.CouponInventory{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/categories", Tag: "public", Summary: "List categories"},
+	{Method: http.MethodGet, Path: "/api/v1/public/categories/products", Tag: "public", Summary: "Categories with their products"},
+	{Method: http.MethodGet, Path: "/api/v1/public/products", Tag: "public", Summary: "List products",
+		Paginated: true,
+		Data:      productsData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/product/reviewandrating", Tag: "public", Summary: "Reviews and ratings of a product",
+		Query:     []openapi.Param{{Name: "product_id", Type: "integer", Required: true}},
+		Paginated: true,
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/restaurants", Tag: "public", Summary: "List verified restaurants",
+		Paginated: true,
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/restaurants/products/", Tag: "public", Summary: "Products of a restaurant",
+		Query: []openapi.Param{{Name: "restaurantid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/restaurants/bundles", Tag: "public", Summary: "Combo bundles of a restaurant",
+		Query: []openapi.Param{{Name: "restaurantid", Type: "integer", Required: true}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/products/onlyveg", Tag: "public", Summary: "Vegetarian products",
+		Data: []model.ProductResponse{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/products/newarrivals", Tag: "public", Summary: "Newest products",
+		Data: []model.ProductResponse{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/products/lowtohigh", Tag: "public", Summary: "Products by price, ascending"},
+	{Method: http.MethodGet, Path: "/api/v1/public/products/hightolow", Tag: "public", Summary: "Products by price, descending"},
+	{Method: http.MethodGet, Path: "/api/v1/public/products/offerproducts", Tag: "public", Summary: "Products with an offer",
+		Data: []model.Product{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/search", Tag: "public", Summary: "Search products and restaurants with filters and facets",
+		QueryStruct: model.SearchRequest{},
+		Paginated:   true,
+		Data:        searchData{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/report/products", Tag: "public", Summary: "Sales of a product",
+		Query: []openapi.Param{{Name: "productid", Type: "integer", Required: true}},
+		Data:  model.ProductSales{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/report/products/best", Tag: "public", Summary: "Best selling products",
+		Query: []openapi.Param{{Name: "index", Type: "integer", Description: "rank to start from"}},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/public/report/overallreport/all", Tag: "public", Summary: "Platform sales report",
+		Body: model.PlatformSalesReportInput{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/documentation", Tag: "misc", Summary: "Redirect to the Swagger UI"},
+	{Method: http.MethodGet, Path: "/api/v1/user/profileimage", Tag: "uploads", Summary: "Profile image upload form",
+		Produces: "text/html",
+	},
+	{Method: http.MethodPost, Path: "/api/v1/user/profileimage", Tag: "uploads", Summary: "Upload the profile image of the user", Auth: model.UserRole,
+		Form: []openapi.Param{{Name: "file", Type: "file", Required: true, Description: "jpeg, png or webp up to 5 MB"}},
+		Data: storage.Stored{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/restaurant/profileimage", Tag: "uploads", Summary: "Profile image upload form",
+		Produces: "text/html",
+	},
+	{Method: http.MethodPost, Path: "/api/v1/restaurant/profileimage", Tag: "uploads", Summary: "Upload the profile image of the restaurant", Auth: model.RestaurantRole,
+		Form: []openapi.Param{{Name: "file", Type: "file", Required: true, Description: "jpeg, png or webp up to 5 MB"}},
+		Data: storage.Stored{},
+	},
+	{Method: http.MethodGet, Path: "/api/v1/logout", Tag: "misc", Summary: "Log out and clear the jwt cookie"},
+	{Method: http.MethodGet, Path: storage.PrivatePath + "/*key", Tag: "uploads", Summary: "Restaurant document or ticket attachment, to admins and the restaurant or ticket it belongs to",
+		Auth: model.UserRole + ", " + model.RestaurantRole + " or " + model.AdminRole, Produces: "application/octet-stream",
+	},
+	{Method: http.MethodGet, Path: storage.MediaPath + "/*filepath", Tag: "uploads", Summary: "Files stored by the local storage backend",
+		Produces: "application/octet-stream",
+	},
+	{Method: http.MethodHead, Path: storage.MediaPath + "/*filepath", Tag: "uploads", Summary: "Files stored by the local storage backend",
+		Produces: "application/octet-stream",
+	},
+}
+
+// the spec and swagger ui, register last so the spec sees every route
+func OpenAPIRoutes(router *gin.Engine) {
+	router.GET(specPath, openapi.Handler(func() *openapi.Document {
+		doc, _, _ := openapi.Build(info, router.Routes(), operations)
+		return doc
+	}))
+	router.GET(docsPath+"*file", openapi.UI(specPath))
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"foodbuddy/internal/config"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/openapi"
+	"foodbuddy/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	h := controllers.NewHandler(repository.New(nil))
+	ServerHealth(router, nil, "")
+	PublicRoutes(router, h)
+	AuthenticationRoutes(router)
+	AdminRoutes(router, h)
+	UserRoutes(router, h)
+	RestaurantRoutes(router, h)
+	AdditionalRoutes(router)
+	MediaRoutes(router, config.Storage{Backend: "local", Dir: "."})
+	OpenAPIRoutes(router)
+	return router
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	router := testRouter()
+	_, undocumented, unregistered := openapi.Build(info, router.Routes(), operations)
+	for _, key := range undocumented {
+		t.Errorf("route %s has no operation in internal/api/openapi.go", key)
+	}
+	for _, key := range unregistered {
+		t.Errorf("operation %s does not match a registered route", key)
+	}
+}
+
+func TestSpecIsServed(t *testing.T) {
+	router := testRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, specPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("spec status %d", w.Code)
+	}
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("spec is not json: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("openapi version %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/api/v1/auth/verifyemail/{role}/{email}/{otp}"]["get"]; !ok {
+		t.Errorf("path parameters are not converted")
+	}
+
+	for _, path := range []string{docsPath, docsPath + "swagger-initializer.js", docsPath + "swagger-ui-bundle.js"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s status %d", path, w.Code)
+		}
+	}
+}
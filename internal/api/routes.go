@@ -1,60 +1,80 @@
 package api
 
 import (
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/config"
 	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/health"
+	"foodbuddy/internal/metrics"
+	"foodbuddy/internal/ratelimit"
+	"foodbuddy/internal/storage"
 	"foodbuddy/view"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func ServerHealth(router *gin.Engine) {
+func ServerHealth(router *gin.Engine, db *gorm.DB, metricsToken string) {
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": "server status ok",
 		})
 	})
+	router.GET("/healthz", health.Liveness)     //liveness probe
+	router.GET("/readyz", health.Readiness(db)) //readiness probe, database, schema version and workers
+	router.GET("/metrics", metrics.Handler(metricsToken))
 }
 func AuthenticationRoutes(router *gin.Engine) {
-	//admin
-	router.GET("/api/v1/auth/admin/login", controllers.AdminLogin) //
+	//login, signup and otp endpoints get a small budget against credential stuffing and mail flooding
+	authRoutes := router.Group("/api/v1/auth", ratelimit.Middleware(ratelimit.Auth))
+	{
+		//admin
+		authRoutes.GET("/admin/login", controllers.AdminLogin) //
+
+		//user
+		authRoutes.POST("/user/email/login", controllers.EmailLogin)   //
+		authRoutes.POST("/user/email/signup", controllers.EmailSignup) //
+		authRoutes.GET("/google/login", controllers.GoogleHandleLogin) //
 
-	//user
-	router.POST("/api/v1/auth/user/email/login", controllers.EmailLogin)   //
-	router.POST("/api/v1/auth/user/email/signup", controllers.EmailSignup) //
-	router.GET("/api/v1/auth/google/login", controllers.GoogleHandleLogin) //
-	router.GET("/api/v1/googlecallback", controllers.GoogleHandleCallback) //
+		//additional endpoints for email verification and password reset
+		authRoutes.GET("/verifyemail/:role/:email/:otp", controllers.VerifyEmail) //
 
-	//additional endpoints for email verification and password reset
-	router.GET("/api/v1/auth/verifyemail/:role/:email/:otp", controllers.VerifyEmail) //
+		authRoutes.POST("/passwordreset/step1", controllers.Step1PasswordReset) //
+		authRoutes.GET("/passwordreset", controllers.LoadPasswordReset)         //
+		authRoutes.POST("/passwordreset/step2", controllers.Step2PasswordReset) //
 
-	router.POST("/api/v1/auth/passwordreset/step1", controllers.Step1PasswordReset) //
-	router.GET("/api/v1/auth/passwordreset", controllers.LoadPasswordReset)         //
-	router.POST("/api/v1/auth/passwordreset/step2", controllers.Step2PasswordReset) //
+		//restaurant
+		authRoutes.POST("/restaurant/signup", controllers.RestaurantSignup) //
+		authRoutes.POST("/restaurant/login", controllers.RestaurantLogin)   //
 
-	//restaurant
-	router.POST("/api/v1/auth/restaurant/signup", controllers.RestaurantSignup) //
-	router.POST("/api/v1/auth/restaurant/login", controllers.RestaurantLogin)   //
+		//blocked accounts, the token comes from the block notice mail
+		authRoutes.GET("/block/status", controllers.GetBlockStatus)
+		authRoutes.POST("/block/notice", controllers.ResendBlockNotice)
+		authRoutes.POST("/block/appeal", controllers.AppealBlock)
+	}
+	router.GET("/api/v1/googlecallback", ratelimit.Middleware(ratelimit.Auth), controllers.GoogleHandleCallback) //
 }
 
-func UserRoutes(router *gin.Engine) {
-	userRoutes := router.Group("/api/v1/user")
+func UserRoutes(router *gin.Engine, h *controllers.Handler) {
+	userRoutes := router.Group("/api/v1/user", ratelimit.Middleware(ratelimit.Default))
 	{
 		// User Profile Management
-		userRoutes.GET("/profile", controllers.GetUserProfile)       //
-		userRoutes.POST("/edit", controllers.UpdateUserInformation)  //
-		userRoutes.GET("/wallet/all", controllers.GetUserWalletData) //
+		userRoutes.GET("/profile", h.GetUserProfile)       //
+		userRoutes.POST("/edit", h.UpdateUserInformation)  //
+		userRoutes.GET("/wallet/all", h.GetUserWalletData) //
 
 		// Favorite Products
-		userRoutes.GET("/favorites/all", controllers.GetUsersFavouriteProduct)     //
-		userRoutes.POST("/favorites/add", controllers.AddFavouriteProduct)         //
-		userRoutes.DELETE("/favorites/delete", controllers.RemoveFavouriteProduct) //
+		userRoutes.GET("/favorites/all", h.GetUsersFavouriteProduct)     //
+		userRoutes.POST("/favorites/add", h.AddFavouriteProduct)         //
+		userRoutes.DELETE("/favorites/delete", h.RemoveFavouriteProduct) //
 
 		// User Address Management
-		userRoutes.GET("/address/all", controllers.GetUserAddress)          //
-		userRoutes.POST("/address/add", controllers.AddUserAddress)         //
-		userRoutes.PATCH("/address/edit", controllers.EditUserAddress)      //
-		userRoutes.DELETE("/address/delete", controllers.DeleteUserAddress) //
+		userRoutes.GET("/address/all", h.GetUserAddress)          //
+		userRoutes.POST("/address/add", h.AddUserAddress)         //
+		userRoutes.PATCH("/address/edit", h.EditUserAddress)      //
+		userRoutes.DELETE("/address/delete", h.DeleteUserAddress) //
 
 		// Cart Management
 		userRoutes.POST("/cart/add", controllers.AddToCart) //
@@ -64,10 +84,13 @@ func UserRoutes(router *gin.Engine) {
 		userRoutes.DELETE("/cart/remove", controllers.RemoveItemFromCart) //
 		userRoutes.PUT("/cart/update/", controllers.UpdateQuantity)       //
 		userRoutes.GET("/coupon/cart/", controllers.ApplyCouponOnCart)    //
+		userRoutes.GET("/coupon/best", controllers.RecommendCoupons)      //restaurant_id in the query param
+		userRoutes.POST("/cart/bundle/add", controllers.AddBundleToCart)
+		userRoutes.DELETE("/cart/bundle/remove", controllers.RemoveBundleFromCart)
 
 		// Order Management
 		userRoutes.POST("/order/step1/placeorder", controllers.PlaceOrder)
-		userRoutes.GET("/order/deliverycode", controllers.SendOrderDeliveryVerificationCode)
+		userRoutes.GET("/order/deliverycode", ratelimit.Middleware(ratelimit.Auth), controllers.SendOrderDeliveryVerificationCode) //mails an otp
 		userRoutes.POST("/order/step2/initiatepayment", controllers.InitiatePayment)
 		userRoutes.PUT("/order/update/paymentmode", controllers.ChangeOrderPaymentMode) //orderid in the query param //CHANGE COD , ONLINE MODE
 		userRoutes.POST("/order/step3/razorpaycallback/:orderid", controllers.RazorPayGatewayCallback)
@@ -75,13 +98,30 @@ func UserRoutes(router *gin.Engine) {
 		userRoutes.GET("/order/step3/stripecallback", controllers.StripeCallback)
 		userRoutes.POST("/order/cancel/online", controllers.CancelOrderedProductOnline)
 		userRoutes.POST("/order/cancel/cod", controllers.CancelOrderedProductCOD)
-		userRoutes.GET("/order/items", controllers.UserOrderItems)
+		userRoutes.GET("/order/items", h.UserOrderItems)
 		userRoutes.GET("/order/info", controllers.GetOrderInfoByOrderIDasJSON)
 		userRoutes.GET("/order/invoice/", controllers.GetOrderInfoByOrderIDAndGeneratePDF)
-		userRoutes.GET("/order/paymenthistory", controllers.PaymentDetailsByOrderID)
+		userRoutes.GET("/order/paymenthistory", h.PaymentDetailsByOrderID)
 		userRoutes.GET("/order/verifypayment", controllers.VerifyOnlinePayment)
 		userRoutes.POST("/order/review", controllers.UserReviewonOrderItem)
 		userRoutes.POST("/order/rating", controllers.UserRatingOrderItem)
+		userRoutes.DELETE("/order/review", controllers.DeleteOrderItemReview)   //order_id and product_id in the query params
+		userRoutes.GET("/order/review/history", controllers.GetReviewHistory)   //order_id and product_id in the query params
+		userRoutes.POST("/order/review/photo", controllers.UploadReviewPhoto)   //order_id and product_id in the query params
+		userRoutes.DELETE("/order/review/photo", controllers.DeleteReviewPhoto) //photoid in the query param
+
+		// Restaurant Reviews
+		userRoutes.POST("/reviews", controllers.AddRestaurantReview)
+		userRoutes.POST("/reviews/helpful", controllers.MarkReviewHelpful)     //reviewid in the query param
+		userRoutes.DELETE("/reviews/helpful", controllers.UnmarkReviewHelpful) //reviewid in the query param
+		userRoutes.POST("/reviews/flag", controllers.FlagRestaurantReview)     //reviewid in the query param
+
+		// Support Tickets
+		userRoutes.POST("/support/tickets", controllers.RaiseSupportTicket)
+		userRoutes.GET("/support/tickets", controllers.ListSupportTickets)
+		userRoutes.GET("/support/ticket", controllers.GetSupportTicket)                   //ticketid in the query param
+		userRoutes.POST("/support/ticket/message", controllers.PostTicketMessage)         //ticketid in the query param
+		userRoutes.POST("/support/ticket/attachment", controllers.UploadTicketAttachment) //ticketid in the query param
 
 		// Referral System
 		userRoutes.GET("/referral/code", controllers.GetRefferalCode)
@@ -91,24 +131,36 @@ func UserRoutes(router *gin.Engine) {
 	}
 }
 
-func RestaurantRoutes(router *gin.Engine) {
-	restaurantRoutes := router.Group("/api/v1/restaurants")
+func RestaurantRoutes(router *gin.Engine, h *controllers.Handler) {
+	restaurantRoutes := router.Group("/api/v1/restaurants", ratelimit.Middleware(ratelimit.Default))
 	{
 		// Restaurant Management
-		restaurantRoutes.POST("/edit", controllers.EditRestaurant)       //update restaurant profile
-		restaurantRoutes.POST("/products/add", controllers.AddProduct)   //
-		restaurantRoutes.POST("/products/edit", controllers.EditProduct) //
-		restaurantRoutes.DELETE("/products", controllers.DeleteProduct)  //
+		restaurantRoutes.POST("/edit", controllers.EditRestaurant)               //update restaurant profile
+		restaurantRoutes.POST("/products/add", h.AddProduct)                     //
+		restaurantRoutes.POST("/products/edit", h.EditProduct)                   //
+		restaurantRoutes.DELETE("/products", h.DeleteProduct)                    //
+		restaurantRoutes.POST("/products/image", controllers.ProductImageUpload) //productid in the query param
+		restaurantRoutes.POST("/certificate", controllers.RestaurantCertificateUpload)
+
+		// Onboarding, products and orders need the documents approved
+		restaurantRoutes.GET("/onboarding", controllers.GetOnboardingStatus)
+		restaurantRoutes.POST("/onboarding/documents", controllers.UploadRestaurantDocument) //type in the query param
+		restaurantRoutes.POST("/onboarding/submit", controllers.SubmitOnboarding)
+
+		// Combo Bundles
+		restaurantRoutes.POST("/bundles/add", controllers.AddBundle)
+		restaurantRoutes.PUT("/bundles/edit", controllers.EditBundle)
+		restaurantRoutes.DELETE("/bundles", controllers.DeleteBundle) //bundleid in the query param
 
 		// Order History and Status Updates
-		restaurantRoutes.GET("/order/history", controllers.OrderHistoryRestaurants)            //without order_status and with
+		restaurantRoutes.GET("/order/history", h.OrderHistoryRestaurants)                      //without order_status and with
 		restaurantRoutes.POST("/order/confirmcod", controllers.ConfirmCODPayment)              //authentication for rest add rest id in the order
 		restaurantRoutes.POST("/order/confirmdelivery", controllers.DeliveryComplete)          //query param order_id,authentication
 		restaurantRoutes.POST("/order/nextstatus", controllers.UpdateOrderStatusForRestaurant) //authentication rest
 
 		// Product Offers
-		restaurantRoutes.POST("/product/offer/add", controllers.AddProductOffer)      //
-		restaurantRoutes.PUT("/product/offer/remove", controllers.RemoveProductOffer) //
+		restaurantRoutes.POST("/product/offer/add", h.AddProductOffer)      //
+		restaurantRoutes.PUT("/product/offer/remove", h.RemoveProductOffer) //
 
 		//orderitem information in excel
 		restaurantRoutes.GET("/orderitems/excel/all", controllers.OrderItemsCSVFileForRestaurant) //
@@ -119,74 +171,134 @@ func RestaurantRoutes(router *gin.Engine) {
 		//new customers this week
 
 		//restaurant wallet balance and history
-		restaurantRoutes.GET("/wallet/all", controllers.GetRestaurantWalletData) //
+		restaurantRoutes.GET("/wallet/all", h.GetRestaurantWalletData) //
+
+		// Coupons on the restaurant's own menu
+		restaurantRoutes.POST("/coupon/create", controllers.CreateRestaurantCoupon)
+		restaurantRoutes.PATCH("/coupon/update", controllers.UpdateRestaurantCoupon)
+		restaurantRoutes.GET("/coupon/all", controllers.GetRestaurantCoupons)
+
+		// Reviews of the restaurant
+		restaurantRoutes.GET("/reviews", controllers.ListOwnRestaurantReviews)
+		restaurantRoutes.PUT("/reviews/reply", controllers.ReplyToRestaurantReview) //reviewid in the query param
+		restaurantRoutes.POST("/reviews/flag", controllers.FlagRestaurantReview)    //reviewid in the query param
+
+		// Support Tickets raised on the restaurant's orders
+		restaurantRoutes.GET("/support/tickets", controllers.ListSupportTickets)
+		restaurantRoutes.GET("/support/ticket", controllers.GetSupportTicket)                   //ticketid in the query param
+		restaurantRoutes.POST("/support/ticket/message", controllers.PostTicketMessage)         //ticketid in the query param
+		restaurantRoutes.POST("/support/ticket/attachment", controllers.UploadTicketAttachment) //ticketid in the query param
 	}
 }
 
-func AdminRoutes(router *gin.Engine) {
-	adminRoutes := router.Group("/api/v1/admin")
+func AdminRoutes(router *gin.Engine, h *controllers.Handler) {
+	adminRoutes := router.Group("/api/v1/admin", ratelimit.Middleware(ratelimit.Default))
 	{
 		// User Management
 		//get profile info , update online stats
-		adminRoutes.GET("/users", controllers.GetUserList)                //
-		adminRoutes.GET("/users/blocked", controllers.GetBlockedUserList) //
-		adminRoutes.PUT("/users/block/", controllers.BlockUser)           //
-		adminRoutes.PUT("/users/unblock", controllers.UnblockUser)        //
+		adminRoutes.GET("/users", h.GetUserList)                //
+		adminRoutes.GET("/users/blocked", h.GetBlockedUserList) //
+		adminRoutes.PUT("/users/block/", h.BlockUser)           //
+		adminRoutes.PUT("/users/unblock", h.UnblockUser)        //
+		adminRoutes.GET("/appeals", controllers.ListBlockAppeals)
+		adminRoutes.PUT("/appeals/review", controllers.ReviewBlockAppeal) //appealid in the query param
 
 		// Category Management
-		adminRoutes.POST("/categories/add", controllers.AddCategory)         //
-		adminRoutes.PATCH("/categories/edit", controllers.EditCategory)      //
-		adminRoutes.DELETE("/categories/delete", controllers.DeleteCategory) //
+		adminRoutes.POST("/categories/add", controllers.AddCategory)           //
+		adminRoutes.PATCH("/categories/edit", controllers.EditCategory)        //
+		adminRoutes.DELETE("/categories/delete", controllers.DeleteCategory)   //
+		adminRoutes.POST("/categories/image", controllers.CategoryImageUpload) //categoryid in the query param
 
 		// Restaurant Management
-		adminRoutes.GET("/restaurants", controllers.GetRestaurants)
+		adminRoutes.GET("/restaurants", controllers.GetAllRestaurants)
 		adminRoutes.PUT("/restaurants/block", controllers.BlockRestaurant)     //
 		adminRoutes.PUT("/restaurants/unblock", controllers.UnblockRestaurant) //
 		adminRoutes.PUT("/restaurants/verify/success", controllers.VerifyRestaurant)
 		adminRoutes.PUT("/restaurants/verify/failed", controllers.RemoveVerifyStatusRestaurant)
+		adminRoutes.GET("/restaurants/onboarding", controllers.OnboardingQueue)
+		adminRoutes.PUT("/restaurants/onboarding/review", controllers.ReviewRestaurantDocument) //documentid in the query param
+
+		// Review Moderation, status, restaurant_id and flagged filters
+		adminRoutes.GET("/reviews", controllers.ListReviewsForModeration)
+		adminRoutes.PUT("/reviews/moderate", controllers.ModerateRestaurantReview) //reviewid in the query param
 
 		// Coupon Management
 		adminRoutes.POST("/coupon/create", controllers.CreateCoupon)  //
 		adminRoutes.PATCH("/coupon/update", controllers.UpdateCoupon) //
+		adminRoutes.POST("/coupon/campaign/create", controllers.CreateCouponCampaign)
+		adminRoutes.GET("/coupon/campaigns", controllers.ListCouponCampaigns)
+		adminRoutes.GET("/coupon/campaign", controllers.GetCouponCampaign)                   //campaignid in the query param
+		adminRoutes.GET("/coupon/campaign/codes", controllers.ExportCampaignCodes)           //campaignid in the query param
+		adminRoutes.GET("/coupon/campaign/redemptions", controllers.ListCampaignRedemptions) //campaignid in the query param
+
+		// Audit Log, actor, action, entity_type, entity_id, from and to filters
+		adminRoutes.GET("/audit", controllers.ListAuditLogs)
+		adminRoutes.GET("/audit/export", controllers.ExportAuditLogs)
+
+		// Support Tickets
+		adminRoutes.GET("/support/tickets", controllers.ListSupportTickets)
+		adminRoutes.GET("/support/ticket", controllers.GetSupportTicket)             //ticketid in the query param
+		adminRoutes.POST("/support/ticket/message", controllers.PostTicketMessage)   //ticketid in the query param
+		adminRoutes.PUT("/support/ticket/resolve", controllers.ResolveSupportTicket) //ticketid in the query param
 	}
 }
 
-func PublicRoutes(router *gin.Engine) {
+// public catalogue responses are cached this long, changes made outside the server show up once they expire
+const CatalogueTTL = 5 * time.Minute
+
+func PublicRoutes(router *gin.Engine, h *controllers.Handler) {
+	// public responses are cached and invalidated by the handlers that change the tagged data,
+	// stock changes invalidate the products tag, sales figures change with every order so reports rely on the shorter ttl
+	catalogue := func(tags ...string) gin.HandlerFunc { return cache.Middleware(CatalogueTTL, tags...) }
+	reports := cache.Middleware(time.Minute, cache.TagReports, cache.TagProducts, cache.TagCategories)
+
 	// Public API Endpoints
-	publicRoute := router.Group("/api/v1/public")
+	publicRoute := router.Group("/api/v1/public", ratelimit.Middleware(ratelimit.Public))
 	{
 		//get restaurant profile info
-		publicRoute.GET("/restaurant/profile", controllers.GetRestaurantProfile)
-		publicRoute.GET("/coupon/all", controllers.GetAllCoupons)                   //
-		publicRoute.GET("/categories", controllers.GetCategoryList)                 //
-		publicRoute.GET("/categories/products", controllers.GetCategoryProductList) //
-		publicRoute.GET("/products", controllers.GetProductList)                    //
-		publicRoute.GET("/product/reviewandrating", controllers.ListAllReviewsandRating)
-		publicRoute.GET("/restaurants", controllers.GetRestaurants)                          //
-		publicRoute.GET("/restaurants/products/", controllers.GetProductsByRestaurantID)     //
-		publicRoute.GET("/products/onlyveg", controllers.OnlyVegProducts)                    //
-		publicRoute.GET("/products/newarrivals", controllers.NewArrivals)                    //
-		publicRoute.GET("/products/lowtohigh", controllers.PriceLowToHigh)                   //
-		publicRoute.GET("/products/hightolow", controllers.PriceHighToLow)                   //
-		publicRoute.GET("/products/offerproducts", controllers.GetProductOffers)             //
-		publicRoute.GET("/report/products", controllers.ProductReport)                       //
-		publicRoute.GET("/report/products/best", controllers.BestSellingProducts)            //
-		publicRoute.GET("/report/overallreport/all", controllers.PlatformOverallSalesReport) //
+		publicRoute.GET("/restaurant/profile", catalogue(cache.TagRestaurants), controllers.GetRestaurantProfile)
+		publicRoute.GET("/restaurant/reviews", catalogue(cache.TagRestaurants), controllers.GetRestaurantReviews)                      //id and sort in the query params
+		publicRoute.GET("/coupon/all", catalogue(cache.TagCoupons), controllers.GetAllCoupons)                                         //
+		publicRoute.GET("/categories", catalogue(cache.TagCategories), controllers.GetCategoryList)                                    //
+		publicRoute.GET("/categories/products", catalogue(cache.TagCategories, cache.TagProducts), controllers.GetCategoryProductList) //
+		publicRoute.GET("/products", catalogue(cache.TagProducts), h.GetProductList)                                                   //
+		publicRoute.GET("/product/reviewandrating", catalogue(cache.TagProducts), controllers.ListAllReviewsandRating)
+		publicRoute.GET("/restaurants", catalogue(cache.TagRestaurants), controllers.GetRestaurants)         //
+		publicRoute.GET("/restaurants/products/", catalogue(cache.TagProducts), h.GetProductsByRestaurantID) //
+		publicRoute.GET("/restaurants/bundles", catalogue(cache.TagProducts), controllers.GetBundlesByRestaurantID)
+		publicRoute.GET("/products/onlyveg", catalogue(cache.TagProducts, cache.TagCategories, cache.TagRestaurants), h.OnlyVegProducts)            //
+		publicRoute.GET("/products/newarrivals", catalogue(cache.TagProducts, cache.TagCategories, cache.TagRestaurants), controllers.NewArrivals)  //
+		publicRoute.GET("/products/lowtohigh", catalogue(cache.TagProducts, cache.TagCategories, cache.TagRestaurants), controllers.PriceLowToHigh) //
+		publicRoute.GET("/products/hightolow", catalogue(cache.TagProducts, cache.TagCategories, cache.TagRestaurants), controllers.PriceHighToLow) //
+		publicRoute.GET("/products/offerproducts", catalogue(cache.TagProducts), h.GetProductOffers)                                                //
+		publicRoute.GET("/search", catalogue(cache.TagProducts, cache.TagCategories, cache.TagRestaurants), controllers.Search)                     //q with veg, category_id, restaurant_id, price, rating, offers_only filters
+		publicRoute.GET("/report/products", reports, controllers.ProductReport)                                                                     //
+		publicRoute.GET("/report/products/best", reports, controllers.BestSellingProducts)                                                          //
+		publicRoute.GET("/report/overallreport/all", reports, controllers.PlatformOverallSalesReport)                                               //
 
 	}
 }
 
 func AdditionalRoutes(router *gin.Engine) {
 	// Additional Endpoints
+	limited := ratelimit.Middleware(ratelimit.Default)
 	router.GET("/api/v1/documentation", APIDocumentation)
-	router.GET("/api/v1/user/profileimage", view.LoadUpload)                                 //
-	router.POST("/api/v1/user/profileimage", controllers.UserProfileImageUpload)             //
-	router.GET("/api/v1/restaurant/profileimage", view.LoadUpload)                           //
-	router.POST("/api/v1/restaurant/profileimage", controllers.RestaurantProfileImageUpload) //
-	router.GET("/api/v1/logout", controllers.Logout)                                         //
+	router.GET("/api/v1/user/profileimage", view.LoadUpload)                                          //
+	router.POST("/api/v1/user/profileimage", limited, controllers.UserProfileImageUpload)             //
+	router.GET("/api/v1/restaurant/profileimage", view.LoadUpload)                                    //
+	router.POST("/api/v1/restaurant/profileimage", limited, controllers.RestaurantProfileImageUpload) //
+	router.GET("/api/v1/logout", controllers.Logout)                                                  //
+	router.GET(storage.PrivatePath+"/*key", limited, controllers.ServePrivateFile)                    //documents and ticket attachments
+}
+
+// files of the local storage backend, cloudinary serves its own
+func MediaRoutes(router *gin.Engine, cfg config.Storage) {
+	if cfg.Backend != "local" {
+		return
+	}
+	router.Static(storage.MediaPath, cfg.Dir)
 }
 
 func APIDocumentation(c *gin.Context) {
-	url := "https://documenter.getpostman.com/view/32055383/2sA3e488Sh"
-	c.Redirect(http.StatusMovedPermanently, url)
+	c.Redirect(http.StatusMovedPermanently, docsPath)
 }
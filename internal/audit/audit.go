@@ -0,0 +1,195 @@
+// Package audit records who changed what through the privileged endpoints and commands.
+// Entries are only ever appended, see model.AuditLog.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/utils"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// actions
+const (
+	UserBlock            = "user.block"
+	UserUnblock          = "user.unblock"
+	RestaurantBlock      = "restaurant.block"
+	RestaurantUnblock    = "restaurant.unblock"
+	RestaurantVerify     = "restaurant.verify"
+	RestaurantUnverify   = "restaurant.unverify"
+	RestaurantDelete     = "restaurant.delete"
+	DocumentReview       = "restaurant_document.review"
+	RestaurantOnboarding = "restaurant.onboarding" //verified or rejected by the review of its last document
+	CategoryCreate       = "category.create"
+	CategoryUpdate       = "category.update"
+	CategoryDelete       = "category.delete"
+	CategoryImage        = "category.image"
+	CouponCreate         = "coupon.create"
+	CouponUpdate         = "coupon.update"
+	CampaignCreate       = "coupon_campaign.create"
+	AdminCreate          = "admin.create"
+	AppealReview         = "block_appeal.review"
+	TicketResolve        = "support_ticket.resolve"
+	ReviewModerate       = "restaurant_review.moderate"
+)
+
+// entity types
+const (
+	EntityUser       = "user"
+	EntityRestaurant = "restaurant"
+	EntityDocument   = "restaurant_document"
+	EntityCategory   = "category"
+	EntityCoupon     = "coupon"
+	EntityCampaign   = "coupon_campaign"
+	EntityAdmin      = "admin"
+	EntityAppeal     = "block_appeal"
+	EntityTicket     = "support_ticket"
+	EntityReview     = "restaurant_review"
+)
+
+// actor of changes made from the command line
+const CLIActor = "cli"
+
+// actor of changes made by the background workers, like expired blocks being lifted
+const SystemActor = "system"
+
+// field values are replaced by this in the changes
+const redacted = "[redacted]"
+
+// Change is the value of one field before and after, nil when the entity didn't exist
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Diff compares the json of before and after field by field, either may be nil.
+// Timestamps maintained by gorm are left out and secrets are redacted.
+func Diff(before any, after any) (map[string]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for name, value := range b {
+		if other, ok := a[name]; !ok || !reflect.DeepEqual(value, other) {
+			changes[name] = Change{Before: value, After: a[name]}
+		}
+	}
+	for name, value := range a {
+		if _, ok := b[name]; !ok {
+			changes[name] = Change{After: value}
+		}
+	}
+	for name, change := range changes {
+		if secret(name) {
+			changes[name] = Change{Before: mask(change.Before), After: mask(change.After)}
+		}
+	}
+	return changes, nil
+}
+
+func fields(value any) (map[string]any, error) {
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Pointer && reflect.ValueOf(value).IsNil()) {
+		return map[string]any{}, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("audited values should be structs: %w", err)
+	}
+	for name := range m {
+		switch normalize(name) {
+		case "createdat", "updatedat":
+			delete(m, name)
+		}
+	}
+	return m, nil
+}
+
+func normalize(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "")
+}
+
+func secret(name string) bool {
+	name = normalize(name)
+	if strings.Contains(name, "password") || strings.Contains(name, "salt") || strings.Contains(name, "otp") || strings.Contains(name, "token") {
+		return true
+	}
+	//bank account numbers and ifsc codes of onboarding documents
+	switch name {
+	case "reference", "ifsc", "accountnumber":
+		return true
+	}
+	return false
+}
+
+func mask(value any) any {
+	if value == nil {
+		return nil
+	}
+	return redacted
+}
+
+// ForExport returns the entry with every text cell a spreadsheet would evaluate as a formula
+// prefixed with a quote, exported logs carry values users chose like coupon codes and emails
+func ForExport(entry model.AuditLog) model.AuditLog {
+	for _, cell := range []*string{&entry.ActorEmail, &entry.ActorRole, &entry.Action, &entry.EntityType, &entry.EntityID, &entry.Changes, &entry.IP, &entry.RequestID} {
+		*cell = csvSafe(*cell)
+	}
+	return entry
+}
+
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Write appends an entry with the changes between before and after
+func Write(db *gorm.DB, entry model.AuditLog, before any, after any) error {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	entry.ID = 0
+	entry.Changes = string(raw)
+	return db.Create(&entry).Error
+}
+
+// Record appends an entry for a change made by the signed in caller of the request.
+// The change is already saved when it is recorded, so a failure is logged instead of failing the request.
+func Record(c *gin.Context, action string, entityType string, entityID any, before any, after any) {
+	email, role, _ := utils.GetJWTClaim(c)
+	entry := model.AuditLog{
+		ActorEmail: email,
+		ActorRole:  role,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		IP:         c.ClientIP(),
+		RequestID:  logging.RequestID(c),
+	}
+	if err := Write(database.DB, entry, before, after); err != nil {
+		logging.From(c).Error("failed to write the audit log", "action", action, "entity_type", entityType, "entity_id", entry.EntityID, "error", err)
+	}
+}
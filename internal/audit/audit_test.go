@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"foodbuddy/internal/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	before := model.Restaurant{ID: 7, Name: "Paragon", Blocked: false, HashedPassword: "old", Salt: "abc"}
+	before.UpdatedAt = time.Now()
+	after := before
+	after.Blocked = true
+	after.HashedPassword = "new"
+	after.UpdatedAt = before.UpdatedAt.Add(time.Minute)
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Change{
+		"Blocked":        {Before: false, After: true},
+		"HashedPassword": {Before: redacted, After: redacted},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+
+	created, err := Diff(nil, model.CouponInventory{CouponCode: "WELCOME", Percentage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := created["coupon_code"]; change.Before != nil || change.After != "WELCOME" {
+		t.Errorf("created coupon code = %+v", change)
+	}
+
+	deleted, err := Diff(&model.Category{Name: "Meals"}, (*model.Category)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := deleted["name"]; change.Before != "Meals" || change.After != nil {
+		t.Errorf("deleted category name = %+v", change)
+	}
+
+	if _, err := Diff("not a struct", nil); err == nil {
+		t.Errorf("a string was diffed")
+	}
+}
+
+func TestDiffMasksBankDetails(t *testing.T) {
+	before := model.RestaurantDocument{ID: 3, Type: model.DocumentBank, Reference: "123456789012", IFSC: "SBIN0001234", Status: model.DocumentStatusPending}
+	after := before
+	after.Status = model.DocumentStatusApproved
+
+	changes, err := Diff(nil, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"reference", "ifsc"} {
+		if changes[name].After != redacted {
+			t.Errorf("%v = %+v, want it redacted", name, changes[name])
+		}
+	}
+	if changes["status"].After != model.DocumentStatusApproved {
+		t.Errorf("status = %+v", changes["status"])
+	}
+}
+
+func TestForExport(t *testing.T) {
+	entry := ForExport(model.AuditLog{ActorEmail: "admin@foodbuddy.test", EntityID: "=HYPERLINK(\"http://evil.test\")", Action: "coupon.update", RequestID: "-1+1", IP: "@SUM(A1)", Changes: `{"a":1}`})
+	want := model.AuditLog{ActorEmail: "admin@foodbuddy.test", EntityID: "'=HYPERLINK(\"http://evil.test\")", Action: "coupon.update", RequestID: "'-1+1", IP: "'@SUM(A1)", Changes: `{"a":1}`}
+	if entry != want {
+		t.Errorf("exported = %+v, want %+v", entry, want)
+	}
+}
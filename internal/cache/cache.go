@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// tags group cached responses so a mutation can invalidate every response built from that data
+const (
+	TagProducts    = "products"
+	TagCategories  = "categories"
+	TagRestaurants = "restaurants"
+	TagCoupons     = "coupons"
+	TagReports     = "reports"
+)
+
+type Entry struct {
+	Status       int
+	ContentType  string
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+// Store keeps cached responses, implementations for external stores can share entries and tag
+// versions across replicas. Bumping the version of a tag makes every key built with the old version unreachable.
+type Store interface {
+	Get(key string) (Entry, bool)
+	Set(key string, entry Entry, ttl time.Duration)
+	Version(tag string) uint64
+	Bump(tag string)
+}
+
+var (
+	mu      sync.RWMutex
+	current Store = NewLRU(1000)
+)
+
+// replace the store used by the middleware and the invalidation helpers
+func SetStore(store Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = store
+}
+
+func getStore() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// invalidate the cached responses built from the tagged data
+func Invalidate(tags ...string) {
+	store := getStore()
+	for _, tag := range tags {
+		store.Bump(tag)
+	}
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLRU(t *testing.T) {
+	lru := NewLRU(2)
+	lru.Set("a", Entry{Body: []byte("a")}, time.Minute)
+	lru.Set("b", Entry{Body: []byte("b")}, time.Minute)
+	//reading a keeps it, b is the least recently used when c comes in
+	if _, ok := lru.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	lru.Set("c", Entry{Body: []byte("c")}, time.Minute)
+	if _, ok := lru.Get("b"); ok {
+		t.Error("b survived the eviction")
+	}
+	if entry, ok := lru.Get("a"); !ok || string(entry.Body) != "a" {
+		t.Errorf("a = %q, %v", entry.Body, ok)
+	}
+	if lru.Len() != 2 {
+		t.Errorf("len = %v, want 2", lru.Len())
+	}
+
+	//expired entries are dropped when read
+	lru.Set("a", Entry{}, -time.Second)
+	if _, ok := lru.Get("a"); ok || lru.Len() != 1 {
+		t.Errorf("expired entry was served, len = %v", lru.Len())
+	}
+
+	if lru.Version("products") != 0 {
+		t.Fatal("new tags should start at version 0")
+	}
+	lru.Bump("products")
+	if lru.Version("products") != 1 || lru.Version("coupons") != 0 {
+		t.Error("bump changed the wrong tag")
+	}
+}
+
+// router with a counting handler behind the middleware, on a fresh store
+func cachedRouter(t *testing.T, calls *int, status *int) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	SetStore(NewLRU(10))
+	t.Cleanup(func() { SetStore(NewLRU(1000)) })
+
+	router := gin.New()
+	router.GET("/products", Middleware(time.Minute, TagProducts, TagRestaurants), func(c *gin.Context) {
+		*calls++
+		c.JSON(*status, gin.H{"calls": *calls})
+	})
+	return router
+}
+
+func get(router *gin.Engine, url string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, url, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareInvalidation(t *testing.T) {
+	calls, status := 0, http.StatusOK
+	router := cachedRouter(t, &calls, &status)
+
+	first := get(router, "/products", nil)
+	if first.Header().Get("X-Cache") != "MISS" || first.Header().Get("ETag") == "" {
+		t.Fatalf("first response headers = %v", first.Header())
+	}
+	second := get(router, "/products", nil)
+	if second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() || calls != 1 {
+		t.Fatalf("second response = %v %s after %d calls", second.Header(), second.Body, calls)
+	}
+	//query params are part of the key
+	if get(router, "/products?limit=5", nil).Header().Get("X-Cache") != "MISS" {
+		t.Error("a different query was served from the cache")
+	}
+
+	//unrelated tags leave the entry, any tag of the response drops it
+	Invalidate(TagCoupons)
+	if get(router, "/products", nil).Header().Get("X-Cache") != "HIT" {
+		t.Error("invalidating another tag dropped the entry")
+	}
+	Invalidate(TagRestaurants)
+	if third := get(router, "/products", nil); third.Header().Get("X-Cache") != "MISS" || third.Header().Get("ETag") == first.Header().Get("ETag") {
+		t.Errorf("after invalidation = %v %s", third.Header(), third.Body)
+	}
+
+	//failures are not cached
+	status = http.StatusInternalServerError
+	Invalidate(TagProducts)
+	get(router, "/products", nil)
+	if res := get(router, "/products", nil); res.Code != http.StatusInternalServerError || res.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("failed response was cached: %d %v", res.Code, res.Header())
+	}
+}
+
+func TestMiddlewareNotModified(t *testing.T) {
+	calls, status := 0, http.StatusOK
+	router := cachedRouter(t, &calls, &status)
+
+	first := get(router, "/products", nil)
+	tag, modified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+
+	for name, header := range map[string]http.Header{
+		"matching etag":      {"If-None-Match": {tag}},
+		"weak etag in list":  {"If-None-Match": {`"other", W/` + tag}},
+		"any etag":           {"If-None-Match": {"*"}},
+		"not modified since": {"If-Modified-Since": {modified}},
+	} {
+		res := get(router, "/products", header)
+		if res.Code != http.StatusNotModified || res.Body.Len() != 0 || res.Header().Get("ETag") != tag {
+			t.Errorf("%v: %d %q", name, res.Code, res.Body)
+		}
+	}
+
+	//the etag wins over the date, and a stale one gets the body
+	res := get(router, "/products", http.Header{"If-None-Match": {`"stale"`}, "If-Modified-Since": {modified}})
+	if res.Code != http.StatusOK || res.Body.String() != first.Body.String() {
+		t.Errorf("stale etag: %d %q", res.Code, res.Body)
+	}
+	old := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	if res := get(router, "/products", http.Header{"If-Modified-Since": {old}}); res.Code != http.StatusOK {
+		t.Errorf("modified since an hour ago: %d", res.Code)
+	}
+
+	//the validators of a new response don't match the old etag
+	Invalidate(TagProducts)
+	if res := get(router, "/products", http.Header{"If-None-Match": {tag}}); res.Code != http.StatusOK {
+		t.Errorf("invalidated response answered %d", res.Code)
+	}
+}
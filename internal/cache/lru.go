@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruItem struct {
+	key     string
+	entry   Entry
+	expires time.Time
+}
+
+// in-memory LRU store, least recently used entries are evicted once capacity is reached
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+	versions map[string]uint64
+}
+
+func NewLRU(capacity int) *LRU {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		versions: make(map[string]uint64),
+	}
+}
+
+func (l *LRU) Get(key string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	item := element.Value.(*lruItem)
+	if time.Now().After(item.expires) {
+		l.order.Remove(element)
+		delete(l.items, key)
+		return Entry{}, false
+	}
+	l.order.MoveToFront(element)
+	return item.entry, true
+}
+
+func (l *LRU) Set(key string, entry Entry, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if element, ok := l.items[key]; ok {
+		element.Value = &lruItem{key: key, entry: entry, expires: time.Now().Add(ttl)}
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(&lruItem{key: key, entry: entry, expires: time.Now().Add(ttl)})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (l *LRU) Version(tag string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.versions[tag]
+}
+
+func (l *LRU) Bump(tag string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.versions[tag]++
+}
+
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
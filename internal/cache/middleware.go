@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// buffers the handler response so it can be stored and served with validators
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) { w.status = code }
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) { return w.body.Write(data) }
+
+func (w *bufferedWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+
+func (w *bufferedWriter) Status() int { return w.status }
+
+func (w *bufferedWriter) Size() int { return w.body.Len() }
+
+func (w *bufferedWriter) Written() bool { return w.body.Len() > 0 }
+
+// the key carries the current version of every tag, so invalidated entries are never read again
+func cacheKey(store Store, r *http.Request, tags []string) string {
+	var key strings.Builder
+	key.WriteString(r.URL.Path)
+	key.WriteString("?")
+	key.WriteString(r.URL.Query().Encode())
+	for _, tag := range tags {
+		key.WriteString("|")
+		key.WriteString(tag)
+		key.WriteString("=")
+		key.WriteString(strconv.FormatUint(store.Version(tag), 10))
+	}
+	return key.String()
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func notModified(r *http.Request, entry Entry) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == entry.ETag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since := r.Header.Get("If-Modified-Since"); since != "" {
+		if t, err := http.ParseTime(since); err == nil && !entry.LastModified.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func serve(c *gin.Context, entry Entry) {
+	if entry.Status != http.StatusOK {
+		c.Data(entry.Status, entry.ContentType, entry.Body)
+		return
+	}
+
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", entry.LastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-cache")
+	if notModified(c.Request, entry) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(entry.Status, entry.ContentType, entry.Body)
+}
+
+// cache successful GET responses for ttl, tags name the data the response is built from
+func Middleware(ttl time.Duration, tags ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		store := getStore()
+		key := cacheKey(store, c.Request, tags)
+		if entry, ok := store.Get(key); ok {
+			c.Header("X-Cache", "HIT")
+			serve(c, entry)
+			c.Abort()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		entry := Entry{
+			Status:       writer.status,
+			ContentType:  original.Header().Get("Content-Type"),
+			Body:         writer.body.Bytes(),
+			LastModified: time.Now().UTC().Truncate(time.Second),
+		}
+		entry.ETag = etag(entry.Body)
+		if entry.Status == http.StatusOK {
+			store.Set(key, entry, ttl)
+		}
+		c.Header("X-Cache", "MISS")
+		serve(c, entry)
+	}
+}
@@ -0,0 +1,352 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// profiles, production requires every external service to be configured
+const (
+	Development = "development"
+	Test        = "test"
+	Production  = "production"
+)
+
+type Config struct {
+	Env         string
+	ProjectRoot string
+	// bearer token required by /metrics, open when empty
+	MetricsToken string
+	Log          Log
+	Server       Server
+	RateLimit    RateLimit
+	Database     Database
+	JWT          JWT
+	Google       Google
+	Storage      Storage
+	Cloudinary   Cloudinary
+	Razorpay     Razorpay
+	Stripe       Stripe
+	SMTP         SMTP
+}
+
+type Log struct {
+	// debug, info, warn or error
+	Level string
+	// json or text
+	Format string
+}
+
+type Server struct {
+	// public host used in callback and email links
+	PublicHost        string
+	Addr              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// time the load balancer gets to stop routing to a draining instance before the listener closes
+	DrainDelay time.Duration
+	// time in-flight requests get to finish on shutdown
+	ShutdownTimeout time.Duration
+	// proxies whose X-Forwarded-For is believed, client ips come from the socket when empty
+	TrustedProxies []string
+}
+
+type RateLimit struct {
+	// memory keeps buckets per process, database shares them across replicas, off disables limiting
+	Store string
+}
+
+type Database struct {
+	User     string
+	Password string
+	Name     string
+	Host     string
+	Port     int
+}
+
+// dsn of the mysql server, without a database when name is empty
+func (d Database) DSN(name string) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true", d.User, d.Password, d.Host, d.Port, name)
+}
+
+type JWT struct {
+	Secret string
+}
+
+type Google struct {
+	ClientID     string
+	ClientSecret string
+}
+
+type Storage struct {
+	// cloudinary or local
+	Backend string
+	// directory the local backend writes to, served under /media
+	Dir string
+	// url the local files are linked with, /media on this server by default
+	PublicURL string
+	// directory the local backend keeps private uploads in, never served directly
+	PrivateDir string
+}
+
+type Cloudinary struct {
+	CloudName string
+	AccessKey string
+	SecretKey string
+}
+
+type Razorpay struct {
+	KeyID     string
+	KeySecret string
+}
+
+type Stripe struct {
+	SecretKey     string
+	WebhookSecret string
+}
+
+type SMTP struct {
+	From     string
+	Password string
+	Host     string
+	Port     int
+}
+
+func (s SMTP) Addr() string {
+	return s.Host + ":" + strconv.Itoa(s.Port)
+}
+
+// one configuration key, read from the environment or the env file
+type setting struct {
+	Key string
+	Str *string
+	Int *int
+	Dur *time.Duration
+	// comma separated list
+	List *[]string
+	// profiles that refuse to start without the key
+	RequiredIn []string
+}
+
+func (c *Config) settings() []setting {
+	all := []string{Development, Production}
+	prod := []string{Production}
+	return []setting{
+		{Key: "PROJECTROOT", Str: &c.ProjectRoot},
+		{Key: "LOG_LEVEL", Str: &c.Log.Level},
+		{Key: "LOG_FORMAT", Str: &c.Log.Format},
+		{Key: "METRICS_TOKEN", Str: &c.MetricsToken},
+		{Key: "SERVERIP", Str: &c.Server.PublicHost, RequiredIn: prod},
+		{Key: "SERVERADDR", Str: &c.Server.Addr},
+		{Key: "SERVER_READ_HEADER_TIMEOUT", Dur: &c.Server.ReadHeaderTimeout},
+		{Key: "SERVER_READ_TIMEOUT", Dur: &c.Server.ReadTimeout},
+		{Key: "SERVER_WRITE_TIMEOUT", Dur: &c.Server.WriteTimeout},
+		{Key: "SERVER_IDLE_TIMEOUT", Dur: &c.Server.IdleTimeout},
+		{Key: "SERVER_DRAIN_DELAY", Dur: &c.Server.DrainDelay},
+		{Key: "SERVER_SHUTDOWN_TIMEOUT", Dur: &c.Server.ShutdownTimeout},
+		{Key: "SERVER_TRUSTED_PROXIES", List: &c.Server.TrustedProxies},
+		{Key: "RATELIMIT_STORE", Str: &c.RateLimit.Store},
+		{Key: "DBUSER", Str: &c.Database.User, RequiredIn: all},
+		{Key: "DBPASSWORD", Str: &c.Database.Password},
+		{Key: "DBNAME", Str: &c.Database.Name, RequiredIn: all},
+		{Key: "DBHOST", Str: &c.Database.Host, RequiredIn: all},
+		{Key: "DBPORT", Int: &c.Database.Port},
+		{Key: "JWTSECRET", Str: &c.JWT.Secret, RequiredIn: all},
+		{Key: "CLIENTID", Str: &c.Google.ClientID, RequiredIn: prod},
+		{Key: "CLIENTSECRET", Str: &c.Google.ClientSecret, RequiredIn: prod},
+		{Key: "STORAGE_BACKEND", Str: &c.Storage.Backend},
+		{Key: "STORAGE_DIR", Str: &c.Storage.Dir},
+		{Key: "STORAGE_PUBLIC_URL", Str: &c.Storage.PublicURL},
+		{Key: "STORAGE_PRIVATE_DIR", Str: &c.Storage.PrivateDir},
+		{Key: "CLOUDNAME", Str: &c.Cloudinary.CloudName, RequiredIn: prod},
+		{Key: "CLOUDINARYACCESSKEY", Str: &c.Cloudinary.AccessKey, RequiredIn: prod},
+		{Key: "CLOUDINARYSECRETKEY", Str: &c.Cloudinary.SecretKey, RequiredIn: prod},
+		{Key: "RAZORPAY_KEY_ID", Str: &c.Razorpay.KeyID, RequiredIn: prod},
+		{Key: "RAZORPAY_KEY_SECRET", Str: &c.Razorpay.KeySecret, RequiredIn: prod},
+		{Key: "STRIPE_KEY", Str: &c.Stripe.SecretKey, RequiredIn: prod},
+		{Key: "STRIPE_WEBHOOK_SECRET", Str: &c.Stripe.WebhookSecret},
+		{Key: "SMTPFROM", Str: &c.SMTP.From},
+		{Key: "SMTPAPP", Str: &c.SMTP.Password, RequiredIn: prod},
+		{Key: "SMTPHOST", Str: &c.SMTP.Host},
+		{Key: "SMTPPORT", Int: &c.SMTP.Port},
+	}
+}
+
+// defaults shared by every profile, env, file and flags override them
+func defaults(env string) *Config {
+	cfg := &Config{
+		Env: env,
+		Log: Log{Level: "info", Format: "text"},
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		RateLimit: RateLimit{Store: "memory"},
+		Storage:   Storage{Backend: "local", Dir: "uploads", PublicURL: "/media", PrivateDir: "private_uploads"},
+		Database:  Database{Host: "mysql.foodbuddy", Port: 3306},
+		SMTP:      SMTP{From: "foodbuddycode@gmail.com", Host: "smtp.gmail.com", Port: 587},
+	}
+	switch env {
+	case Production:
+		cfg.Log.Format = "json"
+		cfg.Server.DrainDelay = 5 * time.Second
+		cfg.Storage.Backend = "cloudinary"
+	case Test:
+		cfg.JWT.Secret = "test-secret"
+	}
+	return cfg
+}
+
+// Load reads the configuration once at startup.
+// Precedence from lowest to highest: profile defaults, env file, environment, flags.
+// The profile comes from -env or APP_ENV and the env file from -config or CONFIG_FILE, .env by default.
+// Arguments left after the flags, like subcommands, are returned.
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("foodbuddy", flag.ContinueOnError)
+	envFlag := fs.String("env", "", "configuration profile: development, test or production")
+	fileFlag := fs.String("config", "", "path of the env file")
+	addrFlag := fs.String("addr", "", "address the http server listens on")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	file := firstNonEmpty(*fileFlag, os.Getenv("CONFIG_FILE"))
+	fileValues := map[string]string{}
+	if file != "" {
+		values, err := godotenv.Read(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+		fileValues = values
+	} else if values, err := godotenv.Read(".env"); err == nil {
+		fileValues = values
+	}
+
+	lookup := func(key string) (string, bool) {
+		if value, ok := os.LookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := fileValues[key]
+		return value, ok
+	}
+
+	env, _ := lookup("APP_ENV")
+	env = firstNonEmpty(*envFlag, env, Development)
+	if env != Development && env != Test && env != Production {
+		return nil, nil, fmt.Errorf("unknown configuration profile %q", env)
+	}
+
+	cfg := defaults(env)
+	var errs []string
+	for _, s := range cfg.settings() {
+		value, ok := lookup(s.Key)
+		if !ok || value == "" {
+			continue
+		}
+		if s.Dur != nil {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				errs = append(errs, s.Key+" should be a duration like 30s")
+				continue
+			}
+			*s.Dur = d
+			continue
+		}
+		if s.Int != nil {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, s.Key+" should be a number")
+				continue
+			}
+			*s.Int = n
+			continue
+		}
+		if s.List != nil {
+			*s.List = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*s.List = append(*s.List, item)
+				}
+			}
+			continue
+		}
+		*s.Str = value
+	}
+	if *addrFlag != "" {
+		cfg.Server.Addr = *addrFlag
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.New("invalid configuration: " + strings.Join(errs, ", "))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// every key required by the profile should be set
+func (c *Config) Validate() error {
+	var missing []string
+	for _, s := range c.settings() {
+		if !contains(s.RequiredIn, c.Env) {
+			continue
+		}
+		if (s.Str != nil && *s.Str == "") || (s.Int != nil && *s.Int == 0) {
+			missing = append(missing, s.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required configuration for the %s profile: %s", c.Env, strings.Join(missing, ", "))
+	}
+	if c.Database.Port <= 0 || c.SMTP.Port <= 0 {
+		return errors.New("invalid configuration: ports should be positive numbers")
+	}
+	if !contains([]string{"memory", "database", "off"}, c.RateLimit.Store) {
+		return errors.New("invalid configuration: RATELIMIT_STORE should be memory, database or off")
+	}
+	if !contains([]string{"cloudinary", "local"}, c.Storage.Backend) {
+		return errors.New("invalid configuration: STORAGE_BACKEND should be cloudinary or local")
+	}
+	//everything below the local directory is served publicly
+	if rel, err := filepath.Rel(c.Storage.Dir, c.Storage.PrivateDir); c.Storage.PrivateDir == "" || err != nil || !strings.HasPrefix(rel, "..") {
+		return errors.New("invalid configuration: STORAGE_PRIVATE_DIR should be outside STORAGE_DIR")
+	}
+	return nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Env == Production
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unsets every key the loader reads, they are restored after the test
+func isolate(t *testing.T) {
+	t.Helper()
+	keys := []string{"APP_ENV", "CONFIG_FILE"}
+	for _, s := range (&Config{}).settings() {
+		keys = append(keys, s.Key)
+	}
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func envFile(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "foodbuddy.env")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var development = []string{"DBUSER=foodbuddy", "DBNAME=foodbuddy", "JWTSECRET=secret"}
+
+func TestRequiredPerProfile(t *testing.T) {
+	for _, c := range []struct {
+		profile string
+		lines   []string
+		missing []string
+	}{
+		{Test, nil, nil},
+		{Development, nil, []string{"DBUSER", "DBNAME", "JWTSECRET"}},
+		{Development, development, nil},
+		{Production, development, []string{
+			"SERVERIP", "CLIENTID", "CLIENTSECRET", "CLOUDNAME", "CLOUDINARYACCESSKEY", "CLOUDINARYSECRETKEY",
+			"RAZORPAY_KEY_ID", "RAZORPAY_KEY_SECRET", "STRIPE_KEY", "SMTPAPP",
+		}},
+	} {
+		isolate(t)
+		_, _, err := Load([]string{"-env", c.profile, "-config", envFile(t, c.lines...)})
+		if len(c.missing) == 0 {
+			if err != nil {
+				t.Errorf("%v with %v: %v", c.profile, c.lines, err)
+			}
+			continue
+		}
+		want := "missing required configuration for the " + c.profile + " profile: " + strings.Join(c.missing, ", ")
+		if err == nil || err.Error() != want {
+			t.Errorf("%v with %v = %v, want %q", c.profile, c.lines, err, want)
+		}
+	}
+}
+
+func TestProfileDefaults(t *testing.T) {
+	isolate(t)
+	dev, _, err := Load([]string{"-config", envFile(t, development...)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.Env != Development || dev.Log.Format != "text" || dev.Storage.Backend != "local" || dev.Server.DrainDelay != 0 || dev.IsProduction() {
+		t.Errorf("development = %+v", dev)
+	}
+
+	lines := append([]string{
+		"SERVERIP=foodbuddy.example", "CLIENTID=id", "CLIENTSECRET=secret", "CLOUDNAME=cloud", "CLOUDINARYACCESSKEY=key",
+		"CLOUDINARYSECRETKEY=secret", "RAZORPAY_KEY_ID=key", "RAZORPAY_KEY_SECRET=secret", "STRIPE_KEY=key", "SMTPAPP=password",
+	}, development...)
+	prod, _, err := Load([]string{"-env", Production, "-config", envFile(t, lines...)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prod.IsProduction() || prod.Log.Format != "json" || prod.Storage.Backend != "cloudinary" || prod.Server.DrainDelay != 5*time.Second {
+		t.Errorf("production = %+v", prod)
+	}
+
+	if _, _, err := Load([]string{"-env", "staging"}); err == nil {
+		t.Error("unknown profile was accepted")
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	isolate(t)
+	file := envFile(t, append([]string{"APP_ENV=test", "SERVERADDR=:9000", "DBPORT=3307", "LOG_LEVEL=debug"}, development...)...)
+	t.Setenv("DBPORT", "3308")
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.0.0.1, ,10.0.0.2")
+
+	cfg, args, err := Load([]string{"-config", file, "-addr", ":7000", "seed", "demo.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the profile can come from the file, the environment beats the file and the flags beat both
+	if cfg.Env != Test || cfg.Log.Level != "debug" || cfg.Database.Port != 3308 || cfg.Server.Addr != ":7000" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Server.TrustedProxies, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("trusted proxies = %q", cfg.Server.TrustedProxies)
+	}
+	if !reflect.DeepEqual(args, []string{"seed", "demo.yaml"}) {
+		t.Errorf("args = %q", args)
+	}
+	if cfg.Database.DSN("foodbuddy") != "foodbuddy:@tcp(mysql.foodbuddy:3308)/foodbuddy?parseTime=true" {
+		t.Errorf("dsn = %v", cfg.Database.DSN("foodbuddy"))
+	}
+
+	if _, _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Error("a missing config file was accepted")
+	}
+}
+
+func TestInvalidValues(t *testing.T) {
+	for _, line := range []string{
+		"SERVER_READ_TIMEOUT=30",
+		"SERVER_IDLE_TIMEOUT=-1s",
+		"DBPORT=mysql",
+		"SMTPPORT=-25",
+		"RATELIMIT_STORE=redis",
+		"STORAGE_BACKEND=s3",
+		"STORAGE_PRIVATE_DIR=uploads/private",
+	} {
+		isolate(t)
+		if _, _, err := Load([]string{"-env", Test, "-config", envFile(t, line)}); err == nil {
+			t.Errorf("%v was accepted", line)
+		}
+	}
+}
@@ -3,9 +3,9 @@ package controllers
 import (
 	"errors"
 	"foodbuddy/internal/database"
-	"foodbuddy/internal/utils"
 	"foodbuddy/internal/model"
-	"net/http"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -33,19 +33,13 @@ func AdminLogin(c *gin.Context) {
 		Email string `json:"email" validate:"required,email"`
 	}
 	if err := c.BindJSON(&form); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "Failed to process the incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "Failed to process the incoming request")
 		return
 	}
 
 	// Validate the content of the JSON
 	if err := utils.Validate(form); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
@@ -53,17 +47,10 @@ func AdminLogin(c *gin.Context) {
 	var admin model.Admin
 	if tx := database.DB.Where("email = ?", form.Email).First(&admin); tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status":     false,
-				"message":    "Email not present in the admin table",
-			})
+			response.Error(c, response.CodeUnauthorized, "Email not present in the admin table")
 			return
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Database error",
-
-			})
+			response.Error(c, response.CodeInternal, "Database error")
 			return
 		}
 	}
@@ -79,19 +66,11 @@ func AdminLogin(c *gin.Context) {
 				VerificationStatus: model.VerificationStatusPending,
 			}
 			if err := database.DB.Create(&verification).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"status":     false,
-					"message":    "Failed to create admin verification entry",
-	
-				})
+				response.Error(c, response.CodeInternal, "Failed to create admin verification entry")
 				return
 			}
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Database error",
-
-			})
+			response.Error(c, response.CodeInternal, "Database error")
 			return
 		}
 	}
@@ -99,15 +78,9 @@ func AdminLogin(c *gin.Context) {
 	// Send OTP
 	err := SendOTP(c, form.Email, verification.OTPExpiry, verification.Role)
 	if err != nil {
-		c.JSON(http.StatusAlreadyReported, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-		})
+		response.Error(c, response.CodeInvalidState, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Verification link sent successfully. Please verify via that. Link expires soon .",
-	})
+	response.OK(c, "Verification link sent successfully. Please verify via that. Link expires soon .", nil)
 }
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"errors"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocarina/gocsv"
+	"gorm.io/gorm"
+)
+
+const auditExportBatch = 500
+
+// from and to take a date or an rfc3339 time, a date in to includes the whole day
+func parseAuditTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return t, errors.New("from and to should be a date like 2024-01-31 or an rfc3339 time")
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// audit entries matching the actor, action, entity_type, entity_id, from and to query params
+func auditQuery(c *gin.Context) (*gorm.DB, error) {
+	tx := database.DB.Model(&model.AuditLog{})
+	for param, column := range map[string]string{"actor": "actor_email", "action": "action", "entity_type": "entity_type", "entity_id": "entity_id"} {
+		if value := c.Query(param); value != "" {
+			tx = tx.Where(column+" = ?", value)
+		}
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseAuditTime(value, false)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where("created_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseAuditTime(value, true)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where("created_at <= ?", to)
+	}
+	return tx, nil
+}
+
+// admin
+func ListAuditLogs(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	tx, err := auditQuery(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	var Entries []model.AuditLog
+	if err := Page.Keyset(tx, "id").Find(&Entries).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve the audit log")
+		return
+	}
+	Entries, NextCursor := pagination.Trim(Page, Entries, func(e model.AuditLog) uint { return e.ID })
+
+	response.Page(c, "audit log retrieved", gin.H{"entries": Entries}, NextCursor)
+}
+
+// admin - same filters as the list, every matching entry is streamed as csv
+func ExportAuditLogs(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	tx, err := auditQuery(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	written := false
+	writeRows := func(rows []model.AuditLog) error {
+		if written {
+			return gocsv.MarshalWithoutHeaders(rows, c.Writer)
+		}
+		written = true
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="audit_log.csv"`)
+		return gocsv.Marshal(rows, c.Writer)
+	}
+
+	var Batch []model.AuditLog
+	err = tx.Order("id").FindInBatches(&Batch, auditExportBatch, func(*gorm.DB, int) error {
+		Rows := make([]model.AuditLog, 0, len(Batch))
+		for _, Entry := range Batch {
+			Rows = append(Rows, audit.ForExport(Entry))
+		}
+		return writeRows(Rows)
+	}).Error
+	if err == nil && !written {
+		err = writeRows([]model.AuditLog{})
+	}
+	if err != nil {
+		if !written {
+			response.Error(c, response.CodeInternal, "failed to export the audit log")
+			return
+		}
+		//the response is already partly sent, the client sees a truncated file
+		logging.From(c).Error("audit log export failed midway", "error", err)
+	}
+}
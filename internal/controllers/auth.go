@@ -6,13 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
 	"io"
 	"math/rand"
 	"net/http"
-	"net/smtp"
-	"os"
 	"strconv"
 	"time"
 
@@ -25,12 +26,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// redirect url and credentials are filled in by Configure
 var googleOauthConfig = &oauth2.Config{
-	RedirectURL:  fmt.Sprintf("http://%v/api/v1/googlecallback", utils.GetEnvVariables().ServerIP),
-	ClientID:     utils.GetEnvVariables().ClientID,
-	ClientSecret: utils.GetEnvVariables().ClientSecret,
-	Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email"},
-	Endpoint:     google.Endpoint,
+	Scopes:   []string{"https://www.googleapis.com/auth/userinfo.email"},
+	Endpoint: google.Endpoint,
 }
 
 func GoogleHandleLogin(c *gin.Context) {
@@ -42,47 +41,34 @@ func GoogleHandleLogin(c *gin.Context) {
 
 func GoogleHandleCallback(c *gin.Context) {
 	utils.NoCache(c)
-	fmt.Println("Starting to handle callback")
 	code := c.Query("code")
 
 	//check for code defined on googlehandlelogin still exists
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "missing code parameter",
-		})
+		response.Error(c, response.CodeBadRequest, "missing code parameter")
 		return
 	}
 
 	//exchange code for token, code is exchanged to make sure the state is same
 	token, err := googleOauthConfig.Exchange(context.Background(), code)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to exchange token",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to exchange token")
 		return
 	}
 
 	//use access token and get reponse of the user
-	response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
+	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + token.AccessToken)
 	if err != nil {
-		fmt.Println("google signup done")
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to get user information",
-		})
+		logging.From(c).Error("failed to get google user information", "error", err)
+		response.Error(c, response.CodeInternal, "failed to get user information")
 		return
 	}
-	defer response.Body.Close()
+	defer resp.Body.Close()
 
 	//read the content of the reponse.body
-	content, err := io.ReadAll(response.Body)
+	content, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to read user information",
-		})
+		response.Error(c, response.CodeInternal, "failed to read user information")
 		return
 	}
 
@@ -90,10 +76,7 @@ func GoogleHandleCallback(c *gin.Context) {
 	var User model.GoogleResponse
 	err = json.Unmarshal(content, &User)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to parse user information",
-		})
+		response.Error(c, response.CodeInternal, "failed to parse user information")
 		return
 	}
 
@@ -117,18 +100,12 @@ func GoogleHandleCallback(c *gin.Context) {
 		if err == gorm.ErrRecordNotFound {
 			// Create a new user
 			if err := database.DB.Create(&newUser).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"status":  false,
-					"message": "failed to create user through google sso",
-				})
+				response.Error(c, response.CodeInternal, "failed to create user through google sso")
 				return
 			}
 		} else {
 			// Handle case where user already exists but not found due to other errors
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  false,
-				"message": "failed to fetch user information",
-			})
+			response.Error(c, response.CodeInternal, "failed to fetch user information")
 			return
 		}
 	}
@@ -144,20 +121,14 @@ func GoogleHandleCallback(c *gin.Context) {
 
 	//check is the user is blocked by the admin
 	if existingUser.Blocked {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "user is unauthorized to access",
-		})
+		response.Error(c, response.CodeAccountBlocked, blockedMessage(database.DB, model.UserRole, existingUser.ID))
 		return
 	}
 
 	// Generate JWT and set cookie within GenerateJWT
 	tokenstring, err := GenerateJWT(c, newUser.Email, model.UserRole)
 	if tokenstring == "" || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "failed to create authorization token",
-		})
+		response.Error(c, response.CodeUnauthorized, "failed to create authorization token")
 		return
 	}
 
@@ -165,13 +136,9 @@ func GoogleHandleCallback(c *gin.Context) {
 	CreateReferralEntry(existingUser.ID)
 
 	// Return success response
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "login is successful",
-		"data": gin.H{
-			"user":  User,
-			"token": tokenstring,
-		},
+	response.OK(c, "login is successful", gin.H{
+		"user":  User,
+		"token": tokenstring,
 	})
 
 }
@@ -184,52 +151,32 @@ func EmailSignup(c *gin.Context) {
 	var EmailSignupRequest model.EmailSignupRequest
 
 	if err := c.BindJSON(&EmailSignupRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process the incoming request" + err.Error(),
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process the incoming request"+err.Error())
 		return
 	}
 
 	err := utils.Validate(EmailSignupRequest)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	//check if the password and the confirm password is correct
 	if EmailSignupRequest.Password != EmailSignupRequest.ConfirmPassword {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "passwords doesn't match",
-		})
+		response.Error(c, response.CodeBadRequest, "passwords doesn't match")
 		return
 	}
 
 	err = passwordvalidator.Validate(EmailSignupRequest.Password, model.PasswordEntropy)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
-	//create salt and add it to the password
-	Salt := utils.GenerateRandomString(7)
-	//salt+password
-	saltedPassword := Salt + EmailSignupRequest.Password
-
-	//hash the password
-	hash, err := bcrypt.GenerateFromPassword([]byte(saltedPassword), bcrypt.DefaultCost)
+	//hash the password with a fresh salt
+	Salt, hash, err := HashPassword(EmailSignupRequest.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to hash the password",
-		})
+		response.Error(c, response.CodeInternal, "failed to hash the password")
 		return
 	}
 
@@ -237,8 +184,8 @@ func EmailSignup(c *gin.Context) {
 	User := model.User{
 		Name:           EmailSignupRequest.Name,
 		Email:          EmailSignupRequest.Email,
-		PhoneNumber:    string(EmailSignupRequest.PhoneNumber),
-		HashedPassword: string(hash),
+		PhoneNumber:    strconv.FormatUint(uint64(EmailSignupRequest.PhoneNumber), 10),
+		HashedPassword: hash,
 		LoginMethod:    model.EmailLoginMethod,
 		Blocked:        false,
 		Salt:           Salt,
@@ -247,28 +194,19 @@ func EmailSignup(c *gin.Context) {
 	//check if the user exists on the database
 	tx := database.DB.Where("email =? AND deleted_at IS NULL", EmailSignupRequest.Email).First(&User)
 	if tx.Error != nil && tx.Error != gorm.ErrRecordNotFound {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to retreive information from the database",
-		})
+		response.Error(c, response.CodeInternal, "failed to retreive information from the database")
 		return
 
 	} else if tx.Error == gorm.ErrRecordNotFound {
 		// User does not exist, proceed to create
 		tx = database.DB.Create(&User)
 		if tx.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  false,
-				"message": "failed to create a new user",
-			})
+			response.Error(c, response.CodeInternal, "failed to create a new user")
 			return
 		}
 	} else {
 		// User already exists
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "user already exists",
-		})
+		response.Error(c, response.CodeConflict, "user already exists")
 		return
 	}
 
@@ -280,27 +218,20 @@ func EmailSignup(c *gin.Context) {
 	}
 
 	if err := database.DB.Create(&VerificationTable).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to process otp verification process",
-		})
+		response.Error(c, response.CodeInternal, "failed to process otp verification process")
 		return
 	}
 
 	SendOTP(c, User.Email, VerificationTable.OTPExpiry, model.UserRole)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Email login successful, please login to complete your email verification",
-		"data": gin.H{
-			"user": gin.H{
-				"name":         User.Name,
-				"email":        User.Email,
-				"phone_number": User.PhoneNumber,
-				"picture":      User.Picture,
-				"login_method": User.LoginMethod,
-				"block_status": User.Blocked,
-			},
+	response.OK(c, "Email login successful, please login to complete your email verification", gin.H{
+		"user": gin.H{
+			"name":         User.Name,
+			"email":        User.Email,
+			"phone_number": User.PhoneNumber,
+			"picture":      User.Picture,
+			"login_method": User.LoginMethod,
+			"block_status": User.Blocked,
 		},
 	})
 	c.Next()
@@ -310,20 +241,14 @@ func EmailLogin(c *gin.Context) {
 	var EmailLoginRequest model.EmailLoginRequest
 	//get the json from the request
 	if err := c.BindJSON(&EmailLoginRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process the incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process the incoming request")
 		return
 	}
 
 	//validate the content of the json
 	err := utils.Validate(EmailLoginRequest)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
@@ -331,28 +256,13 @@ func EmailLogin(c *gin.Context) {
 	var user model.User
 	tx := database.DB.Where("email =? AND deleted_at IS NULL", EmailLoginRequest.Email).First(&user)
 	if tx.Error != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid email or password",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid email or password")
 		return
 	}
 
 	//check if the login methods are the same as email, if google prompt to use google login
 	if user.LoginMethod != model.EmailLoginMethod {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "email uses another method for logging in, use google sso",
-		})
-		return
-	}
-
-	//check is the user is blocked by the admin
-	if user.Blocked {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "user is not authorized to access",
-		})
+		response.Error(c, response.CodeBadRequest, "email uses another method for logging in, use google sso")
 		return
 	}
 
@@ -363,10 +273,13 @@ func EmailLogin(c *gin.Context) {
 	err = bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(saltedPassword))
 	if err != nil {
 		//passwords do not match
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid email or password",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid email or password")
+		return
+	}
+
+	//check is the user is blocked by the admin, only told after the password matched
+	if user.Blocked {
+		response.Error(c, response.CodeAccountBlocked, blockedMessage(database.DB, model.UserRole, user.ID))
 		return
 	}
 
@@ -375,27 +288,18 @@ func EmailLogin(c *gin.Context) {
 	var VerificationTable model.VerificationTable
 
 	if err := database.DB.Where("email = ? AND role = ?", user.Email, model.UserRole).First(&VerificationTable).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to process email verification",
-		})
+		response.Error(c, response.CodeInternal, "failed to process email verification")
 		return
 	}
 
 	if VerificationTable.VerificationStatus != model.VerificationStatusVerified {
 		err := SendOTP(c, user.Email, VerificationTable.OTPExpiry, model.UserRole)
 		if err != nil {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"status":  false,
-				"message": err.Error(),
-			})
+			response.Error(c, response.CodeRateLimited, err.Error())
 			return
 		}
 
-		c.JSON(http.StatusAccepted, gin.H{
-			"status":  false,
-			"message": "please complete your email verification",
-		})
+		response.Error(c, response.CodeEmailNotVerified, "please complete your email verification, an otp was sent to your email")
 		return
 	}
 
@@ -403,28 +307,21 @@ func EmailLogin(c *gin.Context) {
 	tokenstring, err := GenerateJWT(c, user.Email, model.UserRole)
 
 	if tokenstring == "" || err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "Failed to create JWT token due to an internal server error.Try again",
-		})
+		response.Error(c, response.CodeInternal, "Failed to create JWT token due to an internal server error.Try again")
 		return
 	}
 
 	_, _ = GenerateReferralCodeForUser(user.Email)
 	CreateReferralEntry(user.ID)
 	// Return success response
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Email login successful.",
-		"data": gin.H{
-			"user": gin.H{
-				"name":         user.Name,
-				"email":        user.Email,
-				"phone_number": user.PhoneNumber,
-				"picture":      user.Picture,
-				"login_method": user.LoginMethod,
-				"block_status": user.Blocked,
-			},
+	response.OK(c, "Email login successful.", gin.H{
+		"user": gin.H{
+			"name":         user.Name,
+			"email":        user.Email,
+			"phone_number": user.PhoneNumber,
+			"picture":      user.Picture,
+			"login_method": user.LoginMethod,
+			"block_status": user.Blocked,
 		},
 	})
 
@@ -458,11 +355,8 @@ func SendOTP(c *gin.Context, to string, otpexpiry uint64, role string) error {
 
 	// fmt.Printf("Sending mail because OTP has expired: %v\n", expiryTime)
 
-	from := "foodbuddycode@gmail.com"
-	appPassword := os.Getenv("SMTPAPP")
-	auth := smtp.PlainAuth("", from, appPassword, "smtp.gmail.com")
 
-	url := fmt.Sprintf("https://%v/api/v1/auth/verifyemail/%v/%v/%v", utils.GetEnvVariables().ServerIP, role, to, otp)
+	url := fmt.Sprintf("https://%v/api/v1/auth/verifyemail/%v/%v/%v", appConfig.Server.PublicHost, role, to, otp)
 
 	htmlContent := fmt.Sprintf(`
 	<!DOCTYPE html>
@@ -500,7 +394,7 @@ func SendOTP(c *gin.Context, to string, otpexpiry uint64, role string) error {
 		htmlContent)
 
 	// Send the email
-	err := smtp.SendMail("smtp.gmail.com:587", auth, from, []string{to}, msg)
+	err := gateway.Get().Mail.Send([]string{to}, msg)
 	if err != nil {
 		return errors.New("failed to send email")
 	}
@@ -528,10 +422,7 @@ func VerifyEmail(c *gin.Context) {
 	entityOTP, _ := strconv.Atoi(c.Param("otp"))
 
 	if entityRole == "" || entityEmail == "" || entityOTP == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process incoming request")
 		return
 	}
 
@@ -539,42 +430,27 @@ func VerifyEmail(c *gin.Context) {
 
 	tx := database.DB.Where("email = ? AND role = ?", entityEmail, entityRole).First(&VerificationTable)
 	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve  information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to retrieve  information")
 		return
 	}
 
 	if VerificationTable.VerificationStatus == model.VerificationStatusVerified {
-		c.JSON(http.StatusIMUsed, gin.H{
-			"status":  false,
-			"message": "already verified",
-		})
+		response.Error(c, response.CodeInvalidState, "already verified")
 		return
 	}
 
 	if VerificationTable.OTP == 0 {
-		c.JSON(http.StatusAlreadyReported, gin.H{
-			"status":  false,
-			"message": "please login once again to verify your email",
-		})
+		response.Error(c, response.CodeInvalidState, "please login once again to verify your email")
 		return
 	}
 
 	if VerificationTable.OTPExpiry < uint64(time.Now().Unix()) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "otp has expired ,please login once again to verify your otp",
-		})
+		response.Error(c, response.CodeBadRequest, "otp has expired ,please login once again to verify your otp")
 		return
 	}
 
 	if VerificationTable.OTP != uint64(entityOTP) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "otp is invalid",
-		})
+		response.Error(c, response.CodeBadRequest, "otp is invalid")
 		return
 	}
 
@@ -582,10 +458,7 @@ func VerifyEmail(c *gin.Context) {
 
 	tx = database.DB.Where("email = ? AND role = ?", entityEmail, entityRole).Updates(&VerificationTable)
 	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to verify email, please try again",
-		})
+		response.Error(c, response.CodeInternal, "failed to verify email, please try again")
 		return
 	}
 	var token string
@@ -593,19 +466,12 @@ func VerifyEmail(c *gin.Context) {
 
 	token, err = GenerateJWT(c, entityEmail, entityRole)
 	if token == "" || err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to generate token, please try again",
-		})
+		response.Error(c, response.CodeInternal, "failed to generate token, please try again")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Email verification is Successful",
-		"data": gin.H{
-			"token": token,
-		},
+	response.OK(c, "Email verification is Successful", gin.H{
+		"token": token,
 	})
 }
 
@@ -618,7 +484,7 @@ func GenerateJWT(c *gin.Context, email string, role string) (string, error) {
 	})
 
 	//sign and get the complete encoded token as a string using the secret
-	tokenString, err := token.SignedString([]byte(utils.GetEnvVariables().JWTSecret))
+	tokenString, err := token.SignedString([]byte(appConfig.JWT.Secret))
 	if err != nil {
 		return "", err
 	}
@@ -642,7 +508,7 @@ func VerifyJWT(c *gin.Context, role string, useremail string) error {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(utils.GetEnvVariables().JWTSecret), nil
+		return []byte(appConfig.JWT.Secret), nil
 	})
 
 	if err != nil {
@@ -697,11 +563,7 @@ func EmailFromUserID(UserID uint) (string, bool) {
 // removing cookie "authorization"
 func Logout(c *gin.Context) {
 	utils.RemoveCookies(c)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "successfully logged out",
-		"ok":      true,
-	})
-	c.Next()
+	response.OK(c, "successfully logged out", nil)
 }
 
 func VerifyUserPasswordReset(email string) bool {
@@ -723,3 +585,13 @@ func VerifyRestaurantPasswordReset(email string) bool {
 
 	return err == nil
 }
+
+// salt + password hashed with bcrypt, logins compare against Salt + password
+func HashPassword(Password string) (Salt string, Hash string, err error) {
+	Salt = utils.GenerateRandomString(7)
+	hash, err := bcrypt.GenerateFromPassword([]byte(Salt+Password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return Salt, string(hash), nil
+}
@@ -0,0 +1,464 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// lifted_by of blocks that ran out
+const BlockExpiryActor = "expiry"
+
+var ErrNotBlocked = errors.New("account is not blocked")
+
+// accounts are blocked through the blocked column of their own table
+func blockedModel(Role string) (interface{}, error) {
+	switch Role {
+	case model.UserRole:
+		return &model.User{}, nil
+	case model.RestaurantRole:
+		return &model.Restaurant{}, nil
+	}
+	return nil, fmt.Errorf("accounts with the role %q can't be blocked", Role)
+}
+
+func hashAppealToken(Token string) string {
+	sum := sha256.Sum256([]byte(Token))
+	return hex.EncodeToString(sum[:])
+}
+
+// a new appeal token for the block, only its hash is kept
+func newAppealToken(tx *gorm.DB, Block *model.AccountBlock) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	Token := hex.EncodeToString(raw)
+	Block.AppealTokenHash = hashAppealToken(Token)
+	if Block.ID != 0 {
+		if err := tx.Model(Block).UpdateColumn("appeal_token_hash", Block.AppealTokenHash).Error; err != nil {
+			return "", err
+		}
+	}
+	return Token, nil
+}
+
+// BlockAccount sets the blocked flag and records why, the returned token is mailed for the appeal
+func BlockAccount(db *gorm.DB, Role string, AccountID uint, Request model.BlockAccountRequest, By string) (model.AccountBlock, string, error) {
+	Model, err := blockedModel(Role)
+	if err != nil {
+		return model.AccountBlock{}, "", err
+	}
+	Block := model.AccountBlock{
+		AccountRole: Role,
+		AccountID:   AccountID,
+		Reason:      Request.Reason,
+		Note:        Request.Note,
+		BlockedBy:   By,
+	}
+	if Request.DurationHours > 0 {
+		Expiry := time.Now().Add(time.Duration(Request.DurationHours) * time.Hour)
+		Block.ExpiresAt = &Expiry
+	}
+	Token, err := newAppealToken(db, &Block)
+	if err != nil {
+		return model.AccountBlock{}, "", err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(Model).Where("id = ?", AccountID).UpdateColumn("blocked", true).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Block).Error
+	})
+	return Block, Token, err
+}
+
+// LiftBlock clears the blocked flag and closes the open blocks of the account
+func LiftBlock(db *gorm.DB, Role string, AccountID uint, By string) error {
+	Model, err := blockedModel(Role)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(Model).Where("id = ?", AccountID).UpdateColumn("blocked", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.AccountBlock{}).
+			Where("account_role = ? AND account_id = ? AND lifted_at IS NULL", Role, AccountID).
+			Updates(map[string]interface{}{"lifted_at": time.Now(), "lifted_by": By}).Error
+	})
+}
+
+// ActiveBlock is the latest block of the account that isn't lifted yet
+func ActiveBlock(db *gorm.DB, Role string, AccountID uint) (model.AccountBlock, error) {
+	var Block model.AccountBlock
+	err := db.Where("account_role = ? AND account_id = ? AND lifted_at IS NULL", Role, AccountID).
+		Order("id DESC").First(&Block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Block, ErrNotBlocked
+	}
+	return Block, err
+}
+
+// message shown on a refused login, the reason and until when
+func blockedMessage(db *gorm.DB, Role string, AccountID uint) string {
+	Block, err := ActiveBlock(db, Role, AccountID)
+	if err != nil {
+		return "account is blocked"
+	}
+	Message := "account is blocked for " + strings.ToLower(strings.ReplaceAll(Block.Reason, "_", " "))
+	if Block.ExpiresAt != nil {
+		Message += " until " + Block.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return Message + ", see the block notice mail to appeal"
+}
+
+// plain text notice with the token the account appeals with
+func blockNotice(Name string, Block model.AccountBlock, Token string) []byte {
+	Body := "Hi " + Name + ",\n\nYour FoodBuddy account is blocked.\n\nReason: " + Block.Reason + "\n"
+	if Block.Note != "" {
+		Body += "Note: " + Block.Note + "\n"
+	}
+	if Block.ExpiresAt != nil {
+		Body += "The block is lifted on " + Block.ExpiresAt.UTC().Format(time.RFC1123) + ".\n"
+	}
+	Body += "\nTo see the status of the block or to appeal it, use this token: " + Token + "\n"
+	return []byte("MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\r\n" +
+		"Subject: FoodBuddy Account Blocked\r\n\r\n" + Body)
+}
+
+func sendBlockNotice(c *gin.Context, Email string, Name string, Block model.AccountBlock, Token string) {
+	if err := gateway.Get().Mail.Send([]string{Email}, blockNotice(Name, Block, Token)); err != nil {
+		logging.From(c).Warn("failed to send the block notice", "block_id", Block.ID, "error", err)
+	}
+}
+
+// email and name of the blocked account
+func accountContact(db *gorm.DB, Role string, AccountID uint) (string, string, error) {
+	switch Role {
+	case model.UserRole:
+		var User model.User
+		err := db.First(&User, AccountID).Error
+		return User.Email, User.Name, err
+	case model.RestaurantRole:
+		var Restaurant model.Restaurant
+		err := db.First(&Restaurant, AccountID).Error
+		return Restaurant.Email, Restaurant.Name, err
+	}
+	return "", "", fmt.Errorf("unknown role %q", Role)
+}
+
+// worker - lifts the blocks that ran out, the change is audited with the system as the actor
+func ExpireBlocks(ctx context.Context) error {
+	var Expired []model.AccountBlock
+	if err := database.DB.WithContext(ctx).
+		Where("lifted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Find(&Expired).Error; err != nil {
+		return err
+	}
+
+	for _, Block := range Expired {
+		if err := LiftBlock(database.DB.WithContext(ctx), Block.AccountRole, Block.AccountID, BlockExpiryActor); err != nil {
+			return fmt.Errorf("failed to lift block %v: %w", Block.ID, err)
+		}
+		Action, Entity := audit.UserUnblock, audit.EntityUser
+		if Block.AccountRole == model.RestaurantRole {
+			Action, Entity = audit.RestaurantUnblock, audit.EntityRestaurant
+			cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+		}
+		entry := model.AuditLog{
+			ActorEmail: audit.SystemActor,
+			ActorRole:  audit.SystemActor,
+			Action:     Action,
+			EntityType: Entity,
+			EntityID:   strconv.FormatUint(uint64(Block.AccountID), 10),
+		}
+		if err := audit.Write(database.DB, entry, map[string]bool{"blocked": true}, map[string]bool{"blocked": false}); err != nil {
+			slog.Error("failed to write the audit log", "action", Action, "block_id", Block.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// block found by the appeal token
+func blockByToken(Token string) (model.AccountBlock, bool) {
+	var Block model.AccountBlock
+	if Token == "" {
+		return Block, false
+	}
+	if err := database.DB.Where("appeal_token_hash = ?", hashAppealToken(Token)).First(&Block).Error; err != nil {
+		return Block, false
+	}
+	return Block, true
+}
+
+// public - the block of the token, with the appeals made against it
+func GetBlockStatus(c *gin.Context) {
+	Block, ok := blockByToken(c.Query("token"))
+	if !ok {
+		response.Error(c, response.CodeNotFound, "no block found for the token")
+		return
+	}
+	var Appeals []model.BlockAppeal
+	if err := database.DB.Where("block_id = ?", Block.ID).Order("id").Find(&Appeals).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the appeals")
+		return
+	}
+
+	Status := model.BlockStatusResponse{
+		Reason:    Block.Reason,
+		Note:      Block.Note,
+		ExpiresAt: Block.ExpiresAt,
+		Active:    Block.LiftedAt == nil,
+		Appeals:   []model.BlockAppealStatus{},
+	}
+	for _, Appeal := range Appeals {
+		Status.Appeals = append(Status.Appeals, model.BlockAppealStatus{Status: Appeal.Status, Response: Appeal.Response})
+	}
+	response.OK(c, "block status retrieved", Status)
+}
+
+// public - a new token is mailed to a blocked account, the response doesn't tell whether the account exists
+func ResendBlockNotice(c *gin.Context) {
+	var Request model.BlockNoticeRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var AccountID uint
+	var Name string
+	switch Request.Role {
+	case model.UserRole:
+		var User model.User
+		if err := database.DB.Where("email = ? AND blocked = ?", Request.Email, true).First(&User).Error; err == nil {
+			AccountID, Name = User.ID, User.Name
+		}
+	case model.RestaurantRole:
+		var Restaurant model.Restaurant
+		if err := database.DB.Where("email = ? AND blocked = ?", Request.Email, true).First(&Restaurant).Error; err == nil {
+			AccountID, Name = Restaurant.ID, Restaurant.Name
+		}
+	}
+	if AccountID != 0 {
+		if Block, err := ActiveBlock(database.DB, Request.Role, AccountID); err == nil {
+			Token, err := newAppealToken(database.DB, &Block)
+			if err != nil {
+				logging.From(c).Error("failed to renew the appeal token", "block_id", Block.ID, "error", err)
+			} else {
+				sendBlockNotice(c, Request.Email, Name, Block, Token)
+			}
+		}
+	}
+
+	response.OK(c, "if the account is blocked, the notice is sent to its email", nil)
+}
+
+// public - one pending appeal per block, the token comes from the notice mail
+func AppealBlock(c *gin.Context) {
+	var Request model.BlockAppealRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	Block, ok := blockByToken(Request.Token)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "no block found for the token")
+		return
+	}
+	if Block.LiftedAt != nil {
+		response.Error(c, response.CodeInvalidState, "block is already lifted")
+		return
+	}
+	var Pending int64
+	if err := database.DB.Model(&model.BlockAppeal{}).Where("block_id = ? AND status = ?", Block.ID, model.AppealStatusPending).Count(&Pending).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the appeals")
+		return
+	}
+	if Pending > 0 {
+		response.Error(c, response.CodeConflict, "an appeal is already waiting for review")
+		return
+	}
+
+	Appeal := model.BlockAppeal{
+		BlockID:     Block.ID,
+		AccountRole: Block.AccountRole,
+		AccountID:   Block.AccountID,
+		Message:     Request.Message,
+		Status:      model.AppealStatusPending,
+	}
+	if err := database.DB.Create(&Appeal).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the appeal")
+		return
+	}
+
+	response.OK(c, "appeal submitted for review", Appeal)
+}
+
+type appealEntry struct {
+	model.BlockAppeal
+	Block model.AccountBlock `json:"block"`
+}
+
+// admin - appeals with their block, pending ones unless ?status says otherwise
+func ListBlockAppeals(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	Status := strings.ToUpper(c.DefaultQuery("status", model.AppealStatusPending))
+	switch Status {
+	case model.AppealStatusPending, model.AppealStatusAccepted, model.AppealStatusRejected:
+	default:
+		response.Error(c, response.CodeBadRequest, "invalid status")
+		return
+	}
+
+	var Appeals []model.BlockAppeal
+	if err := Page.Keyset(database.DB.Where("status = ?", Status), "id").Find(&Appeals).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve data from the database")
+		return
+	}
+	Appeals, NextCursor := pagination.Trim(Page, Appeals, func(a model.BlockAppeal) uint { return a.ID })
+
+	IDs := make([]uint, 0, len(Appeals))
+	for _, Appeal := range Appeals {
+		IDs = append(IDs, Appeal.BlockID)
+	}
+	var Blocks []model.AccountBlock
+	if err := database.DB.Where("id IN ?", IDs).Find(&Blocks).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the blocks")
+		return
+	}
+	ByID := make(map[uint]model.AccountBlock, len(Blocks))
+	for _, Block := range Blocks {
+		ByID[Block.ID] = Block
+	}
+
+	Queue := make([]appealEntry, 0, len(Appeals))
+	for _, Appeal := range Appeals {
+		Queue = append(Queue, appealEntry{BlockAppeal: Appeal, Block: ByID[Appeal.BlockID]})
+	}
+
+	response.Page(c, "block appeals retrieved", gin.H{"appeals": Queue}, NextCursor)
+}
+
+// admin - appealid in the query param, an accepted appeal lifts the block
+func ReviewBlockAppeal(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+
+	AppealID, err := strconv.Atoi(c.Query("appealid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid appeal ID")
+		return
+	}
+	var Request model.ReviewBlockAppeal
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var Appeal model.BlockAppeal
+	if err := database.DB.First(&Appeal, AppealID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "appeal not found")
+		return
+	}
+	if Appeal.Status != model.AppealStatusPending {
+		response.Error(c, response.CodeInvalidState, "appeal is already "+strings.ToLower(Appeal.Status))
+		return
+	}
+	var Block model.AccountBlock
+	if err := database.DB.First(&Block, Appeal.BlockID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "block not found")
+		return
+	}
+
+	Now := time.Now()
+	Before := Appeal
+	Appeal.Status = Request.Status
+	Appeal.Response = Request.Response
+	Appeal.ReviewedBy = email
+	Appeal.ReviewedAt = &Now
+	Lift := Request.Status == model.AppealStatusAccepted && Block.LiftedAt == nil
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&Appeal).Error; err != nil {
+			return err
+		}
+		if Lift {
+			return LiftBlock(tx, Block.AccountRole, Block.AccountID, email)
+		}
+		return nil
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the review")
+		return
+	}
+	audit.Record(c, audit.AppealReview, audit.EntityAppeal, Appeal.ID, Before, Appeal)
+	if Lift {
+		Action, Entity := audit.UserUnblock, audit.EntityUser
+		if Block.AccountRole == model.RestaurantRole {
+			Action, Entity = audit.RestaurantUnblock, audit.EntityRestaurant
+			cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+		}
+		audit.Record(c, Action, Entity, Block.AccountID, map[string]bool{"blocked": true}, map[string]bool{"blocked": false})
+	}
+
+	if Email, Name, err := accountContact(database.DB, Block.AccountRole, Block.AccountID); err == nil {
+		Decision := "Your appeal is rejected, the block stays in place."
+		if Appeal.Status == model.AppealStatusAccepted {
+			Decision = "Your appeal is accepted and the block is lifted, you can sign in again."
+		}
+		msg := []byte("MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\r\n" +
+			"Subject: FoodBuddy Block Appeal\r\n\r\n" +
+			"Hi " + Name + ",\n\n" + Decision + "\n\n" + Appeal.Response + "\n")
+		if err := gateway.Get().Mail.Send([]string{Email}, msg); err != nil {
+			logging.From(c).Warn("failed to send the appeal decision", "appeal_id", Appeal.ID, "error", err)
+		}
+	}
+
+	response.OK(c, "appeal reviewed", Appeal)
+}
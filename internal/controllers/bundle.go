@@ -0,0 +1,475 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bundle along with its component products, products are in the same order as Bundle.Items
+func GetBundleComponents(BundleID uint) (model.Bundle, []model.Product, error) {
+	var Bundle model.Bundle
+	if err := database.DB.Preload("Items").Where("id = ?", BundleID).First(&Bundle).Error; err != nil {
+		return Bundle, nil, errors.New("bundle not found")
+	}
+
+	Products := make([]model.Product, 0, len(Bundle.Items))
+	for _, item := range Bundle.Items {
+		var Product model.Product
+		if err := database.DB.Where("id = ?", item.ProductID).First(&Product).Error; err != nil {
+			return Bundle, nil, fmt.Errorf("component product %v of the bundle is not available", item.ProductID)
+		}
+		Products = append(Products, Product)
+	}
+	return Bundle, Products, nil
+}
+
+// sum of the component prices of one bundle
+func BundleListPrice(Bundle model.Bundle, Products []model.Product) (ListPrice float64) {
+	for i, item := range Bundle.Items {
+		ListPrice += Products[i].Price * float64(item.Quantity)
+	}
+	return ListPrice
+}
+
+// number of bundles that can be sold with the stock left on each component
+func BundleStockLeft(Bundle model.Bundle, Products []model.Product) uint {
+	var StockLeft uint
+	for i, item := range Bundle.Items {
+		if item.Quantity == 0 {
+			continue
+		}
+		available := Products[i].StockLeft / item.Quantity
+		if i == 0 || available < StockLeft {
+			StockLeft = available
+		}
+	}
+	return StockLeft
+}
+
+// order lines for the components of the bundle, the bundle discount is allocated
+// across the components by their share of the list price so that refunds of a single component stay correct
+func BundleOrderLines(BundleID uint, Quantity uint) ([]model.OrderItem, error) {
+	Bundle, Products, err := GetBundleComponents(BundleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ListPrice := BundleListPrice(Bundle, Products)
+	if ListPrice <= 0 {
+		return nil, errors.New("bundle has no priced components")
+	}
+	Discount := ListPrice - Bundle.BundlePrice
+
+	var OrderItems []model.OrderItem
+	var allocated float64
+	for i, item := range Bundle.Items {
+		Amount := Products[i].Price * float64(item.Quantity)
+
+		//last component takes the remainder so that the allocation adds up to the discount
+		share := RoundDecimalValue(Discount * (Amount / ListPrice))
+		if i == len(Bundle.Items)-1 {
+			share = RoundDecimalValue(Discount - allocated)
+		}
+		allocated += share
+
+		OrderItems = append(OrderItems, model.OrderItem{
+			ProductID:          item.ProductID,
+			RestaurantID:       Bundle.RestaurantID,
+			Quantity:           item.Quantity * Quantity,
+			Amount:             Amount * float64(Quantity),
+			ProductOfferAmount: share * float64(Quantity),
+		})
+	}
+	return OrderItems, nil
+}
+
+// amount and offer of a cart row, bundle savings are counted as product offer
+func CartItemPrice(item model.CartItems) (Amount float64, Offer float64, err error) {
+	if item.BundleID != 0 {
+		Bundle, Products, err := GetBundleComponents(item.BundleID)
+		if err != nil {
+			return 0, 0, err
+		}
+		ListPrice := BundleListPrice(Bundle, Products)
+		return ListPrice * float64(item.Quantity), (ListPrice - Bundle.BundlePrice) * float64(item.Quantity), nil
+	}
+
+	var Product model.Product
+	if err := database.DB.Where("id = ?", item.ProductID).First(&Product).Error; err != nil {
+		return 0, 0, errors.New("failed to fetch product information")
+	}
+	return Product.Price * float64(item.Quantity), Product.OfferAmount * float64(item.Quantity), nil
+}
+
+// check the components of a bundle request belongs to the restaurant and return the list price
+func ValidateBundleItems(RestaurantID uint, Items []model.BundleItemRequest) (float64, error) {
+	var ListPrice float64
+	seen := make(map[uint]bool)
+	for _, item := range Items {
+		if seen[item.ProductID] {
+			return 0, fmt.Errorf("product %v is added more than once, increase its quantity instead", item.ProductID)
+		}
+		seen[item.ProductID] = true
+
+		var Product model.Product
+		if err := database.DB.Where("id = ? AND restaurant_id = ?", item.ProductID, RestaurantID).First(&Product).Error; err != nil {
+			return 0, fmt.Errorf("product %v doesn't exist in this restaurant", item.ProductID)
+		}
+		ListPrice += Product.Price * float64(item.Quantity)
+	}
+	return ListPrice, nil
+}
+
+func BundleItemsFromRequest(BundleID uint, Items []model.BundleItemRequest) []model.BundleItem {
+	var BundleItems []model.BundleItem
+	for _, item := range Items {
+		BundleItems = append(BundleItems, model.BundleItem{
+			BundleID:  BundleID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		})
+	}
+	return BundleItems
+}
+
+func AddBundle(c *gin.Context) {
+	//check restaurant api authentication
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	JWTRestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeBadRequest, "failed to get restaurant information")
+		return
+	}
+
+	var Request model.AddBundleRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to process request")
+		return
+	}
+
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	if !RestaurantVerified(JWTRestaurantID) {
+		response.Error(c, response.CodeForbidden, "complete the onboarding to add bundles")
+		return
+	}
+
+	ListPrice, err := ValidateBundleItems(JWTRestaurantID, Request.Items)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	if Request.BundlePrice >= ListPrice {
+		response.Error(c, response.CodeBadRequest, fmt.Sprintf("bundle price should be less than the price of its products: %v", ListPrice))
+		return
+	}
+
+	Bundle := model.Bundle{
+		RestaurantID: JWTRestaurantID,
+		Name:         Request.Name,
+		Description:  Request.Description,
+		ImageURL:     Request.ImageURL,
+		BundlePrice:  Request.BundlePrice,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Bundle).Error; err != nil {
+			return err
+		}
+		return tx.Create(BundleItemsFromRequest(Bundle.ID, Request.Items)).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to create bundle")
+		return
+	}
+
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully added new bundle", gin.H{
+		"id":         Bundle.ID,
+		"list_price": ListPrice,
+		"bundle":     Request,
+	})
+}
+
+func EditBundle(c *gin.Context) {
+	//check restaurant api authentication
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	JWTRestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeBadRequest, "failed to get restaurant information")
+		return
+	}
+
+	var Request model.EditBundleRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to process request")
+		return
+	}
+
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var Bundle model.Bundle
+	if err := database.DB.Preload("Items").Where("id = ? AND restaurant_id = ?", Request.BundleID, JWTRestaurantID).First(&Bundle).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "bundle not found in this restaurant")
+		return
+	}
+
+	Items := Request.Items
+	if len(Items) == 0 {
+		for _, item := range Bundle.Items {
+			Items = append(Items, model.BundleItemRequest{ProductID: item.ProductID, Quantity: item.Quantity})
+		}
+	}
+
+	ListPrice, err := ValidateBundleItems(JWTRestaurantID, Items)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	if Request.Name != "" {
+		Bundle.Name = Request.Name
+	}
+	if Request.Description != "" {
+		Bundle.Description = Request.Description
+	}
+	if Request.ImageURL != "" {
+		Bundle.ImageURL = Request.ImageURL
+	}
+	if Request.BundlePrice != 0 {
+		Bundle.BundlePrice = Request.BundlePrice
+	}
+
+	if Bundle.BundlePrice >= ListPrice {
+		response.Error(c, response.CodeBadRequest, fmt.Sprintf("bundle price should be less than the price of its products: %v", ListPrice))
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Save(&Bundle).Error; err != nil {
+			return err
+		}
+		if len(Request.Items) == 0 {
+			return nil
+		}
+		if err := tx.Where("bundle_id = ?", Bundle.ID).Delete(&model.BundleItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(BundleItemsFromRequest(Bundle.ID, Request.Items)).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to update bundle")
+		return
+	}
+
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully updated the bundle", gin.H{
+		"id":           Bundle.ID,
+		"list_price":   ListPrice,
+		"bundle_price": Bundle.BundlePrice,
+	})
+}
+
+func DeleteBundle(c *gin.Context) {
+	//check restaurant api authentication
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	JWTRestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeBadRequest, "failed to get restaurant information")
+		return
+	}
+
+	BundleID, err := strconv.Atoi(c.Query("bundleid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid bundle ID")
+		return
+	}
+
+	var Bundle model.Bundle
+	if err := database.DB.Where("id = ? AND restaurant_id = ?", BundleID, JWTRestaurantID).First(&Bundle).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "bundle not found in this restaurant")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&Bundle).Error; err != nil {
+			return err
+		}
+		//bundle can no longer be checked out from the carts
+		return tx.Where("bundle_id = ?", Bundle.ID).Delete(&model.CartItems{}).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "unable to delete the bundle from the database")
+		return
+	}
+
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully deleted the bundle", nil)
+}
+
+// public - bundles of the restaurant with derived stock and savings
+func GetBundlesByRestaurantID(c *gin.Context) {
+	RestaurantID, err := strconv.Atoi(c.Query("restaurantid"))
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "invalid restaurant ID")
+		return
+	}
+
+	var Bundles []model.Bundle
+	if err := database.DB.Scopes(repository.ListedProducts).Where("restaurant_id = ?", RestaurantID).Find(&Bundles).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve bundles")
+		return
+	}
+
+	var Response []gin.H
+	for _, v := range Bundles {
+		Bundle, Products, err := GetBundleComponents(v.ID)
+		if err != nil {
+			//a component was removed from the menu
+			continue
+		}
+		ListPrice := BundleListPrice(Bundle, Products)
+		Response = append(Response, gin.H{
+			"bundle":       Bundle,
+			"products":     Products,
+			"list_price":   ListPrice,
+			"bundle_price": Bundle.BundlePrice,
+			"savings":      RoundDecimalValue(ListPrice - Bundle.BundlePrice),
+			"stock_left":   BundleStockLeft(Bundle, Products),
+		})
+	}
+
+	response.OK(c, "successfully retrieved bundles", gin.H{
+		"bundles": Response,
+	})
+}
+
+func AddBundleToCart(c *gin.Context) {
+	// Check user API authentication
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, _ := UserIDfromEmail(email)
+
+	var Request model.AddBundleToCartReq
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "Failed to fetch incoming request. Please provide valid JSON data.")
+		return
+	}
+
+	if err := utils.Validate(&Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	Bundle, Products, err := GetBundleComponents(Request.BundleID)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	if !RestaurantVerified(Bundle.RestaurantID) {
+		response.Error(c, response.CodeForbidden, "restaurant is not taking orders")
+		return
+	}
+
+	var CartItem model.CartItems
+	err = database.DB.Where("user_id = ? AND bundle_id = ?", UserID, Request.BundleID).First(&CartItem).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		response.Error(c, response.CodeInternal, "Failed to fetch items of the user. Please provide a valid user ID.")
+		return
+	}
+	exists := err == nil
+
+	Quantity := CartItem.Quantity + Request.Quantity
+	if Quantity > model.MaxUserQuantity {
+		message := fmt.Sprintf("Requested quantity exceeds allowed limit. Maximum quantity per cart:  %v", model.MaxUserQuantity)
+		response.Error(c, response.CodeConflict, message)
+		return
+	}
+
+	if StockLeft := BundleStockLeft(Bundle, Products); Quantity > StockLeft {
+		message := fmt.Sprintf("Requested quantity exceeds available stock. Available stock: %v", StockLeft)
+		response.Error(c, response.CodeOutOfStock, message)
+		return
+	}
+
+	if exists {
+		err = database.DB.Model(&model.CartItems{}).Where("user_id = ? AND bundle_id = ?", UserID, Request.BundleID).Update("quantity", Quantity).Error
+	} else {
+		err = database.DB.Create(&model.CartItems{
+			UserID:       UserID,
+			BundleID:     Request.BundleID,
+			RestaurantID: Bundle.RestaurantID,
+			Quantity:     Quantity,
+		}).Error
+	}
+	if err != nil {
+		response.Error(c, response.CodeInternal, "Failed to update cart items. Please try again later.")
+		return
+	}
+
+	response.OK(c, "Bundle successfully added to cart", nil)
+}
+
+func RemoveBundleFromCart(c *gin.Context) {
+	//check user api authentication
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, _ := UserIDfromEmail(email)
+
+	var Request model.RemoveBundle
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
+		return
+	}
+
+	if err := utils.Validate(&Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	result := database.DB.Where("user_id = ? AND bundle_id = ?", UserID, Request.BundleID).Delete(&model.CartItems{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		response.Error(c, response.CodeNotFound, "bundle is not present in the cart")
+		return
+	}
+
+	response.OK(c, "Removed the Bundle Successfully", nil)
+}
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"foodbuddy/internal/database"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -20,70 +20,47 @@ func AddToCart(c *gin.Context) {
 	// Check user API authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
 	// Bind the JSON
 	var Request model.AddToCartReq
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "Failed to fetch incoming request. Please provide valid JSON data.",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "Failed to fetch incoming request. Please provide valid JSON data.")
 		return
 	}
 
 	if err := utils.Validate(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusBadRequest,
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	var Product model.Product
 	if err := database.DB.Where("id = ?", Request.ProductID).First(&Product).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "Failed to fetch product information. Please ensure the specified product exists.",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "Failed to fetch product information. Please ensure the specified product exists.")
+		return
+	}
+	if !RestaurantVerified(Product.RestaurantID) {
+		response.Error(c, response.CodeForbidden, "restaurant is not taking orders")
 		return
 	}
 
 	if Request.Quantity > Product.StockLeft {
 		message := fmt.Sprintf("Requested quantity exceeds available stock. Available stock: %v", Product.StockLeft)
-		c.JSON(http.StatusConflict, gin.H{
-			"status":     false,
-			"message":    message,
-			"error_code": http.StatusConflict,
-		})
+		response.Error(c, response.CodeOutOfStock, message)
 		return
 	}
 	if Request.Quantity > model.MaxUserQuantity {
 		message := fmt.Sprintf("Requested quantity exceeds allowed limit. Maximum quantity per cart:  %v", model.MaxUserQuantity)
-		c.JSON(http.StatusConflict, gin.H{
-			"status":     false,
-			"message":    message,
-			"error_code": http.StatusConflict,
-		})
+		response.Error(c, response.CodeConflict, message)
 		return
 	}
 
 	var CartItem model.CartItems
 	if err := database.DB.Where("user_id = ? AND product_id = ?", UserID, Request.ProductID).First(&CartItem).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Failed to fetch items of the user. Please provide a valid user ID.",
-				"error_code": http.StatusInternalServerError,
-			})
+			response.Error(c, response.CodeInternal, "Failed to fetch items of the user. Please provide a valid user ID.")
 			return
 		}
 
@@ -96,20 +73,12 @@ func AddToCart(c *gin.Context) {
 		AddCartItems.CookingRequest = Request.CookingRequest
 
 		if err := database.DB.Create(&AddCartItems).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Failed to update cart items. Please try again later.",
-				"error_code": http.StatusInternalServerError,
-			})
+			response.Error(c, response.CodeInternal, "Failed to update cart items. Please try again later.")
 			return
 		}
 	} else {
 		if CartItem.Quantity+Request.Quantity > model.MaxUserQuantity {
-			c.JSON(http.StatusConflict, gin.H{
-				"status":     false,
-				"message":    "Total of Requested and Current need of quantity exceeds the max user quantity",
-				"error_code": http.StatusConflict,
-			})
+			response.Error(c, response.CodeConflict, "Total of Requested and Current need of quantity exceeds the max user quantity")
 			return
 		}
 
@@ -120,19 +89,12 @@ func AddToCart(c *gin.Context) {
 		}
 
 		if err := database.DB.Where("user_id = ? AND product_id = ?", UserID, Request.ProductID).Updates(&CartItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Failed to update cart items. Please try again later.",
-				"error_code": http.StatusInternalServerError,
-			})
+			response.Error(c, response.CodeInternal, "Failed to update cart items. Please try again later.")
 			return
 		}
 
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Product successfully added to cart",
-	})
+	response.OK(c, "Product successfully added to cart", nil)
 }
 
 // get cart total by restaurant
@@ -141,10 +103,7 @@ func GetCartTotal(c *gin.Context) {
 	// Check user API authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
@@ -152,20 +111,12 @@ func GetCartTotal(c *gin.Context) {
 	var CartItems []model.CartItems
 
 	if err := database.DB.Where("user_id = ?", UserID).Find(&CartItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "Failed to fetch cart items. Please try again later.",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "Failed to fetch cart items. Please try again later.")
 		return
 	}
 
 	if len(CartItems) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    "Your cart is empty.",
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, "Your cart is empty.")
 		return
 	}
 
@@ -178,17 +129,13 @@ func GetCartTotal(c *gin.Context) {
 
 	// Iterate through cart items and calculate totals per restaurant
 	for _, item := range CartItems {
-		var Product model.Product
-		if err := database.DB.Where("id = ?", item.ProductID).First(&Product).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":     false,
-				"message":    "Failed to fetch product information. Please try again later.",
-				"error_code": http.StatusNotFound,
-			})
+		Amount, Offer, err := CartItemPrice(item)
+		if err != nil {
+			response.Error(c, response.CodeNotFound, "Failed to fetch product information. Please try again later.")
 			return
 		}
 
-		restaurantID := Product.RestaurantID
+		restaurantID := item.RestaurantID
 		if _, exists := cartTotals[restaurantID]; !exists {
 			cartTotals[restaurantID] = struct {
 				Items       []model.CartItems
@@ -199,8 +146,8 @@ func GetCartTotal(c *gin.Context) {
 
 		if val, ok := cartTotals[restaurantID]; ok {
 			val.Items = append(val.Items, item)
-			val.ProductOffer += Product.OfferAmount * float64(item.Quantity)
-			val.TotalAmount += float64(Product.Price) * float64(item.Quantity)
+			val.ProductOffer += Offer
+			val.TotalAmount += Amount
 			cartTotals[restaurantID] = val
 		} else {
 			cartTotals[restaurantID] = struct {
@@ -209,8 +156,8 @@ func GetCartTotal(c *gin.Context) {
 				TotalAmount  float64
 			}{
 				Items:       []model.CartItems{item},
-				ProductOffer: Product.OfferAmount * float64(item.Quantity),
-				TotalAmount:  float64(Product.Price) * float64(item.Quantity),
+				ProductOffer: Offer,
+				TotalAmount:  Amount,
 			}
 		}
 
@@ -228,11 +175,7 @@ func GetCartTotal(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data":   responseData,
-		"message": "Cart items retrieved successfully",
-	})
+	response.OK(c, "Cart items retrieved successfully", responseData)
 }
 
 
@@ -242,10 +185,7 @@ func ClearCart(c *gin.Context) {
 	// Check user API authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
@@ -255,32 +195,18 @@ func ClearCart(c *gin.Context) {
 		// Clear entire cart
 		var CartItems model.CartItems
 		if err := database.DB.Where("user_id = ?", UserID).Delete(&CartItems).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Failed to delete cart items. Please try again later.",
-				"error_code": http.StatusInternalServerError,
-			})
+			response.Error(c, response.CodeInternal, "Failed to delete cart items. Please try again later.")
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"status":  true,
-			"message": "Deleted entire cart of the User",
-		})
+		response.OK(c, "Deleted entire cart of the User", nil)
 	} else {
 		// Clear cart for specific restaurant
 		var CartItems model.CartItems
 		if err := database.DB.Where("user_id = ? AND restaurant_id = ?", UserID, restaurantID).Delete(&CartItems).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":     false,
-				"message":    "Failed to delete cart items for the specified restaurant. Please try again later.",
-				"error_code": http.StatusInternalServerError,
-			})
+			response.Error(c, response.CodeInternal, "Failed to delete cart items for the specified restaurant. Please try again later.")
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"status":  true,
-			"message": "Deleted cart items for the specified restaurant",
-		})
+		response.OK(c, "Deleted cart items for the specified restaurant", nil)
 	}
 }
 
@@ -288,122 +214,74 @@ func RemoveItemFromCart(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
 	//bindthe json
 	var CartItems model.RemoveItem
 	if err := c.BindJSON(&CartItems); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind the json",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
 		return
 	}
 	//validate
 	if err := utils.Validate(&CartItems); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusBadRequest,
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	var CartItem model.CartItems
 	if err := database.DB.Where("user_id = ? AND product_id = ?", UserID, CartItems.ProductID).First(&CartItem).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
 	//if yes, remove the item
 	if err := database.DB.Where("user_id = ? AND product_id = ?", UserID, CartItems.ProductID).Delete(&CartItem).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Removed the Item Successfully",
-	})
+	response.OK(c, "Removed the Item Successfully", nil)
 }
 
 func UpdateQuantity(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
 	//bindthe json
 	var CartItems model.CartItems
 	if err := c.BindJSON(&CartItems); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind the json",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
 		return
 	}
 	CartItems.UserID = UserID
 	//validate
 	if err := utils.Validate(&CartItems); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusBadRequest,
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	var CartItem model.CartItems
 	if err := database.DB.Where("user_id = ? AND product_id = ?", CartItems.UserID, CartItems.ProductID).First(&CartItem).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
 
 	if CartItems.Quantity > model.MaxUserQuantity {
 		message := fmt.Sprintf("Requested quantity exceeds allowed limit. Maximum quantity per cart:  %v", model.MaxUserQuantity)
-		c.JSON(http.StatusConflict, gin.H{
-			"status":     false,
-			"message":    message,
-			"error_code": http.StatusConflict,
-		})
+		response.Error(c, response.CodeConflict, message)
 		return
 	}
 
 	//update quantity
 	if err := database.DB.Where("user_id = ? AND product_id = ?", CartItems.UserID, CartItems.ProductID).Updates(&CartItems).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Updated the Quantity Successfully",
-	})
+	response.OK(c, "Updated the Quantity Successfully", nil)
 }
 
 // calculate cart total by restaurant_id
@@ -419,12 +297,12 @@ func CalculateCartTotal(userID uint,RestaurantID uint) (TotalAmount float64, Pro
 	}
 
 	for _, item := range cartItems {
-		var product model.Product
-		if err := database.DB.Where("id = ?", item.ProductID).First(&product).Error; err != nil {
-			return 0, 0, errors.New("failed to fetch product information")
+		Amount, Offer, err := CartItemPrice(item)
+		if err != nil {
+			return 0, 0, err
 		}
-		TotalAmount += (product.Price) * float64(item.Quantity)
-		ProductOffer += product.OfferAmount * float64(item.Quantity)
+		TotalAmount += Amount
+		ProductOffer += Offer
 	}
 
 	return TotalAmount, ProductOffer, nil
@@ -433,7 +311,7 @@ func CalculateCartTotal(userID uint,RestaurantID uint) (TotalAmount float64, Pro
 func AddCookingRequest(c *gin.Context) {
 	var Request model.AddCookingRequest
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "provide product_id, cooking_request in the payload"})
+		response.Error(c, response.CodeBadRequest, "provide product_id, cooking_request in the payload")
 		return
 	}
 
@@ -441,16 +319,16 @@ func AddCookingRequest(c *gin.Context) {
 	words := strings.Fields(Request.CookingRequest)
 	wordCount := len(words)
 	if wordCount < 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "cooking_request must contain atleast 2 words"})
+		response.Error(c, response.CodeBadRequest, "cooking_request must contain atleast 2 words")
 		return
 	}
 
 	//update the cart with cooking_request
 	if err := database.DB.Where("product_id = ?", Request.ProductID).Updates(model.CartItems{CookingRequest: Request.CookingRequest}).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "cart is empty or the specified product is not in this cart,please make sure the product exists"})
+		response.Error(c, response.CodeNotFound, "cart is empty or the specified product is not in this cart,please make sure the product exists")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "successfully updated cooking request"})
+	response.OK(c, "successfully updated cooking request", nil)
 
 }
@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
 	"foodbuddy/internal/database"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"net/http"
 	"strconv"
 	"strings"
 
@@ -22,29 +25,19 @@ func GetCategoryList(c *gin.Context) { //public
 
 	tx := database.DB.Model(&model.Category{}).Select("id, name, description, image_url, offer_percentage").Find(&categories)
 	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve data from the database, or the data doesn't exist",
-		})
+		response.Error(c, response.CodeNotFound, "failed to retrieve data from the database, or the data doesn't exist")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "category list is fetched successfully",
-		"data": gin.H{
-			"categorylist": categories,
-		},
+	response.OK(c, "category list is fetched successfully", gin.H{
+		"categorylist": categories,
 	})
 }
 
 func GetCategoryProductList(c *gin.Context) { //public
 	var categories []model.Category
-	if err := database.DB.Preload("Products").Find(&categories).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "make sure products are added in their respective catgories inorder to be displayed here",
-		})
+	if err := database.DB.Preload("Products", repository.ListedProducts).Find(&categories).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "make sure products are added in their respective catgories inorder to be displayed here")
 		return
 	}
 
@@ -82,12 +75,8 @@ func GetCategoryProductList(c *gin.Context) { //public
 		transformedCategories[i] = transformedCategory
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "category list with products is fetched successfully",
-		"data": gin.H{
-			"categorylist": transformedCategories,
-		},
+	response.OK(c, "category list with products is fetched successfully", gin.H{
+		"categorylist": transformedCategories,
 	})
 }
 func AddCategory(c *gin.Context) { //admin
@@ -98,28 +87,18 @@ func AddCategory(c *gin.Context) { //admin
 	//check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process incoming request")
 		return
 	}
 
 	//validate the struct body
 	if err := utils.Validate(Request); err != nil {
-		//add json response
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": err,
-		})
+		response.Invalid(c, err)
 		return
 	}
 
@@ -127,19 +106,13 @@ func AddCategory(c *gin.Context) { //admin
 	wordCount := len(words)
 
 	if wordCount < 10 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "description must be a minimum of 10 words",
-		})
+		response.Error(c, response.CodeInternal, "description must be a minimum of 10 words")
 		return
 	}
 
 	// Check if the category is already present
 	if err := database.DB.Where("name = ?", Request.Name).First(&existingcategory).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "category already exists",
-		})
+		response.Error(c, response.CodeConflict, "category already exists")
 		return
 	}
 
@@ -150,20 +123,16 @@ func AddCategory(c *gin.Context) { //admin
 	}
 
 	if err := database.DB.Save(&createCategory).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "unable to add new category, server error ",
-		})
+		response.Error(c, response.CodeInternal, "unable to add new category, server error ")
 		return
 
 	}
+	audit.Record(c, audit.CategoryCreate, audit.EntityCategory, createCategory.ID, nil, createCategory)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successully added a new category",
-		"data": gin.H{
-			"category": Request,
-		},
+	cache.Invalidate(cache.TagCategories)
+
+	response.OK(c, "successully added a new category", gin.H{
+		"category": Request,
 	})
 }
 
@@ -174,39 +143,28 @@ func EditCategory(c *gin.Context) {
 	// Check admin role
 	_, role, err := utils.GetJWTClaim(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "error while validating admin role",
-		})
+		response.Error(c, response.CodeInternal, "error while validating admin role")
 		return
 	}
 	if role != model.AdminRole {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request: admin role required",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request: admin role required")
 		return
 	}
 
 	// Bind JSON request
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process incoming request")
 		return
 	}
 
 	// Fetch existing category
 	if err := database.DB.First(&existingcategory, Request.ID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "category not found",
-		})
+		response.Error(c, response.CodeNotFound, "category not found")
 		return
 	}
 
 	// Update fields if changed
+	before := existingcategory
 	if Request.Name != existingcategory.Name {
 		existingcategory.Name = Request.Name
 	}
@@ -215,18 +173,15 @@ func EditCategory(c *gin.Context) {
 
 	// Perform update
 	if err := database.DB.Save(&existingcategory).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update category details",
-		})
+		response.Error(c, response.CodeInternal, "failed to update category details")
 		return
 	}
+	audit.Record(c, audit.CategoryUpdate, audit.EntityCategory, existingcategory.ID, before, existingcategory)
 
 	// Success response
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully updated category",
-	})
+	cache.Invalidate(cache.TagCategories, cache.TagProducts)
+
+	response.OK(c, "successfully updated category", nil)
 }
 
 func DeleteCategory(c *gin.Context) { //admin
@@ -238,47 +193,35 @@ func DeleteCategory(c *gin.Context) { //admin
 	//check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	categoryID, err := strconv.Atoi(catergoryIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid category ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid category ID")
 		return
 	}
 
 	if err := database.DB.First(&category, categoryID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to fetch category from the database",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch category from the database")
 		return
 	}
 	var productCount int64
 	if result := database.DB.Model(&model.Product{}).Where("category_id = ?", categoryID).Count(&productCount); result.RowsAffected > 0 {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "category contains products, change the category of these products before using this endpoint"})
+		response.Error(c, response.CodeInvalidState, "category contains products, change the category of these products before using this endpoint")
 		return
 	}
 
 	if err := database.DB.Delete(&category, categoryID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to delete category from the database",
-		})
+		response.Error(c, response.CodeInternal, "failed to delete category from the database")
 		return
 	}
+	audit.Record(c, audit.CategoryDelete, audit.EntityCategory, category.ID, category, nil)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Successfully deleted category from the database",
-	})
+	cache.Invalidate(cache.TagCategories, cache.TagProducts)
+
+	response.OK(c, "Successfully deleted category from the database", nil)
 }
 
 func GetCategoryOfferfromProductID(ProductID uint) (bool, uint, uint) { //ok,id,offerpercentage
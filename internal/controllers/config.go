@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"fmt"
+	"foodbuddy/internal/config"
+)
+
+// configuration loaded once at startup, set by Configure before the routes are served
+var appConfig = &config.Config{}
+
+func Configure(cfg *config.Config) {
+	appConfig = cfg
+
+	googleOauthConfig.RedirectURL = fmt.Sprintf("http://%v/api/v1/googlecallback", cfg.Server.PublicHost)
+	googleOauthConfig.ClientID = cfg.Google.ClientID
+	googleOauthConfig.ClientSecret = cfg.Google.ClientSecret
+
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocarina/gocsv"
+	"gorm.io/gorm"
+)
+
+const campaignCodeBatch = 500
+
+// no 0, O, 1 or I so the codes can be read out, 32 characters keeps the bytes unbiased
+const campaignCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func randomCampaignCode(Prefix string) (string, error) {
+	raw := make([]byte, model.CampaignCodeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	for i := range raw {
+		raw[i] = campaignCodeAlphabet[int(raw[i])%len(campaignCodeAlphabet)]
+	}
+	return Prefix + "-" + string(raw), nil
+}
+
+// Count codes with the prefix that no coupon uses yet
+func generateCampaignCodes(Prefix string, Count uint) ([]string, error) {
+	Codes := make(map[string]bool, Count)
+	for attempt := 0; uint(len(Codes)) < Count; attempt++ {
+		if attempt == 5 {
+			return nil, fmt.Errorf("failed to generate %v unique codes", Count)
+		}
+		var Fresh []string
+		for uint(len(Codes)) < Count {
+			Code, err := randomCampaignCode(Prefix)
+			if err != nil {
+				return nil, err
+			}
+			if !Codes[Code] {
+				Codes[Code] = true
+				Fresh = append(Fresh, Code)
+			}
+		}
+		//drop the ones taken by existing coupons and generate again
+		for start := 0; start < len(Fresh); start += campaignCodeBatch {
+			end := min(start+campaignCodeBatch, len(Fresh))
+			var Taken []string
+			if err := database.DB.Model(&model.CouponInventory{}).Where("coupon_code IN ?", Fresh[start:end]).Pluck("coupon_code", &Taken).Error; err != nil {
+				return nil, err
+			}
+			for _, Code := range Taken {
+				delete(Codes, Code)
+			}
+		}
+	}
+	List := make([]string, 0, len(Codes))
+	for Code := range Codes {
+		List = append(List, Code)
+	}
+	return List, nil
+}
+
+// admin - generates code_count single-use codes sharing the rules of the request
+func CreateCouponCampaign(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+
+	var Request model.CouponCampaignRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
+		return
+	}
+	//codes are generated, the prefix stands in for the code while the rules are checked
+	Request.CouponCode = Request.CodePrefix
+	Request.MaximumUsage = 1
+	Request.TotalUsageLimit = 1
+
+	if err := utils.Validate(&Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	if time.Now().Unix()+12*3600 > int64(Request.Expiry) {
+		response.Error(c, response.CodeBadRequest, "please change the expiry time that is more than a day")
+		return
+	}
+
+	Template, err := couponFromRequest(Request.CouponInventoryRequest, 0)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	Prefix := strings.ToUpper(Request.CodePrefix)
+	Codes, err := generateCampaignCodes(Prefix, Request.CodeCount)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to generate the coupon codes")
+		return
+	}
+
+	Campaign := model.CouponCampaign{
+		Name:        Request.Name,
+		Description: Request.Description,
+		CodePrefix:  Prefix,
+		CodeCount:   Request.CodeCount,
+		CreatedBy:   email,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Campaign).Error; err != nil {
+			return err
+		}
+		Coupons := make([]model.CouponInventory, 0, len(Codes))
+		for _, Code := range Codes {
+			Coupon := Template
+			Coupon.CouponCode = Code
+			Coupon.CampaignID = Campaign.ID
+			Coupon.Targets = make([]model.CouponTarget, len(Template.Targets))
+			for i, Target := range Template.Targets {
+				Target.CouponCode = Code
+				Coupon.Targets[i] = Target
+			}
+			Coupons = append(Coupons, Coupon)
+		}
+		return tx.CreateInBatches(&Coupons, campaignCodeBatch).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to create the campaign")
+		return
+	}
+	audit.Record(c, audit.CampaignCreate, audit.EntityCampaign, Campaign.ID, nil, Campaign)
+
+	response.OK(c, fmt.Sprintf("campaign created with %v codes", len(Codes)), Campaign)
+}
+
+// admin
+func ListCouponCampaigns(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	var Campaigns []model.CouponCampaign
+	if err := Page.Keyset(database.DB.Model(&model.CouponCampaign{}), "id").Find(&Campaigns).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve the campaigns")
+		return
+	}
+	Campaigns, NextCursor := pagination.Trim(Page, Campaigns, func(Campaign model.CouponCampaign) uint { return Campaign.ID })
+
+	response.Page(c, "campaigns retrieved", gin.H{"campaigns": Campaigns}, NextCursor)
+}
+
+// campaign of the campaignid query param, the error response is sent when there is none
+func campaignFromQuery(c *gin.Context) (model.CouponCampaign, bool) {
+	var Campaign model.CouponCampaign
+	CampaignID, err := strconv.Atoi(c.Query("campaignid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid campaign ID")
+		return Campaign, false
+	}
+	if err := database.DB.Where("id = ?", CampaignID).First(&Campaign).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "campaign not found")
+		return Campaign, false
+	}
+	return Campaign, true
+}
+
+// redemptions of the campaign and the orders they brought, only paid orders count towards revenue
+func campaignReport(CampaignID uint) (model.CampaignReport, error) {
+	var Report model.CampaignReport
+	if err := database.DB.Model(&model.CouponInventory{}).Where("campaign_id = ?", CampaignID).Count(&Report.Codes).Error; err != nil {
+		return Report, err
+	}
+	if err := database.DB.Model(&model.CouponInventory{}).Where("campaign_id = ? AND redeemed_count > 0", CampaignID).Count(&Report.RedeemedCodes).Error; err != nil {
+		return Report, err
+	}
+	if err := database.DB.Model(&model.CouponRedemption{}).Where("campaign_id = ?", CampaignID).Count(&Report.Redemptions).Error; err != nil {
+		return Report, err
+	}
+	var Paid struct {
+		Orders   int64
+		Revenue  float64
+		Discount float64
+	}
+	if err := database.DB.Model(&model.CouponRedemption{}).
+		Select("COUNT(DISTINCT orders.order_id) AS orders, COALESCE(SUM(orders.final_amount), 0) AS revenue, COALESCE(SUM(orders.coupon_discount_amount), 0) AS discount").
+		Joins("JOIN orders ON orders.order_id = coupon_redemptions.order_id").
+		Where("coupon_redemptions.campaign_id = ? AND orders.payment_status IN ?", CampaignID, paidOrderStatuses).
+		Scan(&Paid).Error; err != nil {
+		return Report, err
+	}
+	Report.Orders = Paid.Orders
+	Report.Revenue = RoundDecimalValue(Paid.Revenue)
+	Report.Discount = RoundDecimalValue(Paid.Discount)
+	if Report.Discount > 0 {
+		Report.RevenuePerDiscount = RoundDecimalValue(Report.Revenue / Report.Discount)
+	}
+	return Report, nil
+}
+
+// admin - campaignid in the query param, the campaign with its redemptions, orders, revenue and discount
+func GetCouponCampaign(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Campaign, ok := campaignFromQuery(c)
+	if !ok {
+		return
+	}
+	Report, err := campaignReport(Campaign.ID)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to build the campaign report")
+		return
+	}
+
+	response.OK(c, "campaign retrieved", gin.H{
+		"campaign": Campaign,
+		"report":   Report,
+	})
+}
+
+// admin - campaignid in the query param, every code of the campaign streamed as csv
+func ExportCampaignCodes(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Campaign, ok := campaignFromQuery(c)
+	if !ok {
+		return
+	}
+
+	written := false
+	writeRows := func(rows []model.CampaignCode) error {
+		if written {
+			return gocsv.MarshalWithoutHeaders(rows, c.Writer)
+		}
+		written = true
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="campaign_%v_codes.csv"`, Campaign.ID))
+		return gocsv.Marshal(rows, c.Writer)
+	}
+
+	var Batch []model.CouponInventory
+	err = database.DB.Where("campaign_id = ?", Campaign.ID).Order("coupon_code").FindInBatches(&Batch, campaignCodeBatch, func(*gorm.DB, int) error {
+		Rows := make([]model.CampaignCode, 0, len(Batch))
+		for _, Coupon := range Batch {
+			Rows = append(Rows, model.CampaignCode{CouponCode: Coupon.CouponCode, StartsAt: Coupon.StartsAt, Expiry: Coupon.Expiry, RedeemedCount: Coupon.RedeemedCount})
+		}
+		return writeRows(Rows)
+	}).Error
+	if err == nil && !written {
+		err = writeRows([]model.CampaignCode{})
+	}
+	if err != nil {
+		if !written {
+			response.Error(c, response.CodeInternal, "failed to export the campaign codes")
+			return
+		}
+		//the response is already partly sent, the client sees a truncated file
+		logging.From(c).Error("campaign code export failed midway", "campaign_id", Campaign.ID, "error", err)
+	}
+}
+
+// admin - campaignid in the query param, optionally coupon_code for a single code
+func ListCampaignRedemptions(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	Campaign, ok := campaignFromQuery(c)
+	if !ok {
+		return
+	}
+
+	tx := database.DB.Model(&model.CouponRedemption{}).Where("campaign_id = ?", Campaign.ID)
+	if Code := c.Query("coupon_code"); Code != "" {
+		tx = tx.Where("coupon_code = ?", Code)
+	}
+	var Redemptions []model.CouponRedemption
+	if err := Page.Keyset(tx, "id").Find(&Redemptions).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve the redemptions")
+		return
+	}
+	Redemptions, NextCursor := pagination.Trim(Page, Redemptions, func(Redemption model.CouponRedemption) uint { return Redemption.ID })
+
+	response.Page(c, "redemptions retrieved", gin.H{"redemptions": Redemptions}, NextCursor)
+}
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/model"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrCouponNotFound = errors.New("coupon not found")
+
+// orders that got paid or will be paid on delivery
+var paidOrderStatuses = []string{model.OnlinePaymentConfirmed, model.CODStatusPending, model.CODStatusConfirmed}
+
+// rule of the coupon the cart or order doesn't meet, the message is shown to the user as it is
+type CouponRuleError string
+
+func (e CouponRuleError) Error() string {
+	return string(e)
+}
+
+// line of a cart or order as the coupon rules see it, bundles are split into their products
+type CouponLine struct {
+	ProductID  uint
+	CategoryID uint
+	Quantity   uint
+	Amount     float64
+	Offer      float64
+}
+
+// coupon that passed every rule with the discount it gives, eligible holds the products it applies to
+type CouponQuote struct {
+	Coupon   model.CouponInventory
+	Discount float64
+	Eligible map[uint]bool
+}
+
+// lines of the user's cart at the restaurant
+func CartCouponLines(UserID uint, RestaurantID uint) ([]CouponLine, error) {
+	var CartItems []model.CartItems
+	if err := database.DB.Where("user_id = ? AND restaurant_id = ?", UserID, RestaurantID).Find(&CartItems).Error; err != nil {
+		return nil, err
+	}
+	var Lines []CouponLine
+	for _, item := range CartItems {
+		if item.BundleID != 0 {
+			BundleLines, err := BundleOrderLines(item.BundleID, item.Quantity)
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range BundleLines {
+				Lines = append(Lines, CouponLine{ProductID: line.ProductID, Quantity: line.Quantity, Amount: line.Amount, Offer: line.ProductOfferAmount})
+			}
+			continue
+		}
+		var Product model.Product
+		if err := database.DB.Where("id = ?", item.ProductID).First(&Product).Error; err != nil {
+			return nil, errors.New("failed to fetch product information")
+		}
+		Lines = append(Lines, CouponLine{
+			ProductID:  Product.ID,
+			CategoryID: Product.CategoryID,
+			Quantity:   item.Quantity,
+			Amount:     Product.Price * float64(item.Quantity),
+			Offer:      Product.OfferAmount * float64(item.Quantity),
+		})
+	}
+	return Lines, withCategories(Lines)
+}
+
+// fill in the category of lines that came from bundles
+func withCategories(Lines []CouponLine) error {
+	var ProductIDs []uint
+	for _, line := range Lines {
+		if line.CategoryID == 0 {
+			ProductIDs = append(ProductIDs, line.ProductID)
+		}
+	}
+	if len(ProductIDs) == 0 {
+		return nil
+	}
+	var Products []model.Product
+	if err := database.DB.Where("id IN ?", ProductIDs).Find(&Products).Error; err != nil {
+		return err
+	}
+	Categories := make(map[uint]uint, len(Products))
+	for _, Product := range Products {
+		Categories[Product.ID] = Product.CategoryID
+	}
+	for i := range Lines {
+		if Lines[i].CategoryID == 0 {
+			Lines[i].CategoryID = Categories[Lines[i].ProductID]
+		}
+	}
+	return nil
+}
+
+// products of the lines the coupon applies to, every product when it has no targets
+func couponEligible(Coupon model.CouponInventory, Lines []CouponLine) map[uint]bool {
+	Products := map[uint]bool{}
+	Categories := map[uint]bool{}
+	for _, Target := range Coupon.Targets {
+		switch Target.TargetType {
+		case model.CouponTargetProduct:
+			Products[Target.TargetID] = true
+		case model.CouponTargetCategory:
+			Categories[Target.TargetID] = true
+		}
+	}
+	Eligible := map[uint]bool{}
+	for _, line := range Lines {
+		if len(Coupon.Targets) == 0 || Products[line.ProductID] || Categories[line.CategoryID] {
+			Eligible[line.ProductID] = true
+		}
+	}
+	return Eligible
+}
+
+// EvaluateCoupon checks every rule of the coupon for the user ordering the lines from the restaurant.
+// the order being placed is left out when the first order rule counts earlier orders.
+// rule failures are CouponRuleError, a missing coupon is ErrCouponNotFound
+func EvaluateCoupon(Code string, UserID uint, RestaurantID uint, Lines []CouponLine, OrderID string) (CouponQuote, error) {
+	var Coupon model.CouponInventory
+	if err := database.DB.Preload("Targets").Where("coupon_code = ?", Code).First(&Coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return CouponQuote{Coupon: Coupon}, ErrCouponNotFound
+		}
+		return CouponQuote{Coupon: Coupon}, err
+	}
+	return evaluateCoupon(Coupon, UserID, RestaurantID, Lines, OrderID)
+}
+
+// rules of a coupon loaded with its targets
+func evaluateCoupon(Loaded model.CouponInventory, UserID uint, RestaurantID uint, Lines []CouponLine, OrderID string) (CouponQuote, error) {
+	Quote := CouponQuote{Coupon: Loaded}
+	Coupon := &Quote.Coupon
+	Code := Coupon.CouponCode
+
+	Now := time.Now()
+	if Coupon.RestaurantID != 0 && Coupon.RestaurantID != RestaurantID {
+		return Quote, CouponRuleError("coupon is only valid on orders from the restaurant that issued it")
+	}
+	if Now.Unix() < int64(Coupon.StartsAt) {
+		return Quote, CouponRuleError(fmt.Sprintf("coupon is valid from %v", time.Unix(int64(Coupon.StartsAt), 0).Format(time.RFC1123)))
+	}
+	if Now.Unix() > int64(Coupon.Expiry) {
+		return Quote, CouponRuleError("coupon has expired")
+	}
+	if Coupon.TotalUsageLimit > 0 && Coupon.RedeemedCount >= Coupon.TotalUsageLimit {
+		return Quote, CouponRuleError("coupon is fully redeemed")
+	}
+
+	var Usage model.CouponUsage
+	err := database.DB.Where("user_id = ? AND coupon_code = ?", UserID, Code).First(&Usage).Error
+	if err == nil && Usage.UsageCount >= Coupon.MaximumUsage {
+		return Quote, CouponRuleError("coupon usage limit reached")
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return Quote, err
+	}
+
+	if Coupon.NewUserDays > 0 || Coupon.FirstOrderOnly {
+		var User model.User
+		if err := database.DB.Where("id = ?", UserID).First(&User).Error; err != nil {
+			return Quote, err
+		}
+		if Coupon.NewUserDays > 0 && Now.Sub(User.CreatedAt) > time.Duration(Coupon.NewUserDays)*24*time.Hour {
+			return Quote, CouponRuleError(fmt.Sprintf("coupon is only for accounts created in the last %v days", Coupon.NewUserDays))
+		}
+	}
+	if Coupon.FirstOrderOnly {
+		//orders that never got paid don't count
+		var Orders int64
+		if err := database.DB.Model(&model.Order{}).
+			Where("user_id = ? AND order_id <> ? AND payment_status IN ?", UserID, OrderID, paidOrderStatuses).
+			Count(&Orders).Error; err != nil {
+			return Quote, err
+		}
+		if Orders > 0 {
+			return Quote, CouponRuleError("coupon is only valid on the first order")
+		}
+	}
+
+	Quote.Eligible = couponEligible(*Coupon, Lines)
+	if len(Quote.Eligible) == 0 {
+		return Quote, CouponRuleError("none of the items are eligible for this coupon")
+	}
+	var Subtotal, Payable float64
+	for _, line := range Lines {
+		if Quote.Eligible[line.ProductID] {
+			Subtotal += line.Amount
+			Payable += line.Amount - line.Offer
+		}
+	}
+	if Subtotal < Coupon.MinimumAmount {
+		return Quote, CouponRuleError(fmt.Sprintf("minimum of %v is needed for using this coupon", Coupon.MinimumAmount))
+	}
+
+	if Coupon.DiscountType == model.CouponDiscountFlat {
+		Quote.Discount = Coupon.FlatAmount
+	} else {
+		Quote.Discount = Subtotal * float64(Coupon.Percentage) / 100
+	}
+	if Coupon.MaxDiscount > 0 && Quote.Discount > Coupon.MaxDiscount {
+		Quote.Discount = Coupon.MaxDiscount
+	}
+	//the coupon never takes an item below zero
+	if Quote.Discount > Payable {
+		Quote.Discount = Payable
+	}
+	Quote.Discount = RoundDecimalValue(Quote.Discount)
+	return Quote, nil
+}
+
+// RankCoupons evaluates every coupon outside campaigns usable at the restaurant against the lines, the eligible ones
+// come first by savings and the rest carry the rule they fail
+func RankCoupons(UserID uint, RestaurantID uint, Lines []CouponLine) ([]model.CouponRecommendation, []model.CouponRecommendation, error) {
+	var Coupons []model.CouponInventory
+	//campaign codes are handed out, they are never recommended
+	if err := database.DB.Preload("Targets").Where("campaign_id = 0 AND (restaurant_id = 0 OR restaurant_id = ?)", RestaurantID).
+		Order("coupon_code").Find(&Coupons).Error; err != nil {
+		return nil, nil, err
+	}
+	var Total, Offer float64
+	for _, line := range Lines {
+		Total += line.Amount
+		Offer += line.Offer
+	}
+
+	Eligible := []model.CouponRecommendation{}
+	Ineligible := []model.CouponRecommendation{}
+	for _, Coupon := range Coupons {
+		Recommendation := model.CouponRecommendation{
+			CouponCode:   Coupon.CouponCode,
+			RestaurantID: Coupon.RestaurantID,
+			DiscountType: Coupon.DiscountType,
+			Expiry:       Coupon.Expiry,
+		}
+		Quote, err := evaluateCoupon(Coupon, UserID, RestaurantID, Lines, "")
+		var RuleError CouponRuleError
+		if errors.As(err, &RuleError) {
+			Recommendation.Reason = RuleError.Error()
+			Ineligible = append(Ineligible, Recommendation)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		Recommendation.Discount = Quote.Discount
+		Recommendation.FinalAmount = RoundDecimalValue(Total - Offer - Quote.Discount)
+		Eligible = append(Eligible, Recommendation)
+	}
+	//coupon code breaks ties so the order is stable
+	sort.SliceStable(Eligible, func(i, j int) bool {
+		return Eligible[i].Discount > Eligible[j].Discount
+	})
+	return Eligible, Ineligible, nil
+}
+
+// coupon discount of each order item, split by what is left to pay on the items the coupon applies to,
+// so no item is discounted below zero
+func couponShares(Order model.Order, OrderItems []model.OrderItem) (map[uint]float64, error) {
+	Shares := make(map[uint]float64, len(OrderItems))
+	if Order.CouponCode == "" || Order.CouponDiscountAmount == 0 {
+		return Shares, nil
+	}
+	var Coupon model.CouponInventory
+	if err := database.DB.Preload("Targets").Where("coupon_code = ?", Order.CouponCode).First(&Coupon).Error; err != nil {
+		return nil, err
+	}
+
+	Lines := make([]CouponLine, 0, len(OrderItems))
+	for _, item := range OrderItems {
+		Lines = append(Lines, CouponLine{ProductID: item.ProductID, Quantity: item.Quantity, Amount: item.Amount, Offer: item.ProductOfferAmount})
+	}
+	if err := withCategories(Lines); err != nil {
+		return nil, err
+	}
+	Eligible := couponEligible(Coupon, Lines)
+
+	var Payable float64
+	var Last uint
+	for _, line := range Lines {
+		if Eligible[line.ProductID] && line.Amount > line.Offer {
+			Payable += line.Amount - line.Offer
+			Last = line.ProductID
+		}
+	}
+	if Payable <= 0 {
+		return Shares, nil
+	}
+	//last line takes the rounding remainder so that the shares add up to the discount
+	var allocated float64
+	for _, line := range Lines {
+		if !Eligible[line.ProductID] || line.Amount <= line.Offer {
+			continue
+		}
+		share := RoundDecimalValue(Order.CouponDiscountAmount * ((line.Amount - line.Offer) / Payable))
+		if line.ProductID == Last {
+			share = RoundDecimalValue(Order.CouponDiscountAmount - allocated)
+		}
+		allocated += share
+		Shares[line.ProductID] = share
+	}
+	return Shares, nil
+}
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
 	"foodbuddy/internal/database"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"net/http"
 	"strconv"
 	"time"
 
@@ -13,95 +16,167 @@ import (
 	"gorm.io/gorm"
 )
 
+// coupon with its targets from the request, RestaurantID is 0 for platform coupons and
+// restricts the products to that restaurant's menu otherwise
+func couponFromRequest(Request model.CouponInventoryRequest, RestaurantID uint) (model.CouponInventory, error) {
+	Coupon := model.CouponInventory{
+		CouponCode:      Request.CouponCode,
+		Expiry:          Request.Expiry,
+		StartsAt:        Request.StartsAt,
+		DiscountType:    Request.DiscountType,
+		Percentage:      Request.Percentage,
+		FlatAmount:      Request.FlatAmount,
+		MaxDiscount:     Request.MaxDiscount,
+		MaximumUsage:    Request.MaximumUsage,
+		TotalUsageLimit: Request.TotalUsageLimit,
+		MinimumAmount:   float64(Request.MinimumAmount),
+		FirstOrderOnly:  Request.FirstOrderOnly,
+		NewUserDays:     Request.NewUserDays,
+		RestaurantID:    RestaurantID,
+	}
+	if Coupon.DiscountType == "" {
+		Coupon.DiscountType = model.CouponDiscountPercentage
+	}
+
+	switch Coupon.DiscountType {
+	case model.CouponDiscountPercentage:
+		if Request.Percentage > model.CouponDiscountPercentageLimit {
+			return Coupon, errors.New("coupon discount percentage should not exceed more than " + strconv.Itoa(model.CouponDiscountPercentageLimit))
+		}
+		Coupon.FlatAmount = 0
+	case model.CouponDiscountFlat:
+		if Request.FlatAmount > float64(Request.MinimumAmount) {
+			return Coupon, errors.New("flat_amount should not be more than the minimum_amount")
+		}
+		Coupon.Percentage = 0
+	}
+	if Request.StartsAt != 0 && Request.StartsAt >= Request.Expiry {
+		return Coupon, errors.New("starts_at should be before the expiry")
+	}
+
+	for _, ProductID := range Request.ProductIDs {
+		tx := database.DB.Where("id = ?", ProductID)
+		if RestaurantID != 0 {
+			tx = tx.Where("restaurant_id = ?", RestaurantID)
+		}
+		var Product model.Product
+		if err := tx.First(&Product).Error; err != nil {
+			return Coupon, fmt.Errorf("product %v doesn't exist", ProductID)
+		}
+		Coupon.Targets = append(Coupon.Targets, model.CouponTarget{CouponCode: Coupon.CouponCode, TargetType: model.CouponTargetProduct, TargetID: ProductID})
+	}
+	for _, CategoryID := range Request.CategoryIDs {
+		var Category model.Category
+		if err := database.DB.Where("id = ?", CategoryID).First(&Category).Error; err != nil {
+			return Coupon, fmt.Errorf("category %v doesn't exist", CategoryID)
+		}
+		Coupon.Targets = append(Coupon.Targets, model.CouponTarget{CouponCode: Coupon.CouponCode, TargetType: model.CouponTargetCategory, TargetID: CategoryID})
+	}
+	return Coupon, nil
+}
+
 // create coupons -admin side
 func CreateCoupon(c *gin.Context) { //admin
 	// check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
+	createCoupon(c, 0)
+}
+
+// restaurant - coupon limited to the restaurant's own menu
+func CreateRestaurantCoupon(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+	createCoupon(c, RestaurantID)
+}
+
+func createCoupon(c *gin.Context, RestaurantID uint) {
 	var Request model.CouponInventoryRequest
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind the json",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
 		return
 	}
 
 	if err := utils.Validate(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	if CheckCouponExists(Request.CouponCode) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "coupon code already exists",
-		})
-		return
-	}
-
-	if Request.Percentage > model.CouponDiscountPercentageLimit {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "coupon discount percentage should not exceed more than " + strconv.Itoa(model.CouponDiscountPercentageLimit),
-		})
+		response.Error(c, response.CodeConflict, "coupon code already exists")
 		return
 	}
 
 	if time.Now().Unix()+12*3600 > int64(Request.Expiry) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "please change the expiry time that is more than a day",
-		})
+		response.Error(c, response.CodeBadRequest, "please change the expiry time that is more than a day")
 		return
 	}
 
-	Coupon := model.CouponInventory{
-		CouponCode:    Request.CouponCode,
-		Expiry:        Request.Expiry,
-		Percentage:    Request.Percentage,
-		MaximumUsage:  Request.MaximumUsage,
-		MinimumAmount: float64(Request.MinimumAmount),
+	Coupon, err := couponFromRequest(Request, RestaurantID)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
 	}
 
 	if err := database.DB.Create(&Coupon).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to create coupon",
-		})
+		response.Error(c, response.CodeInternal, "failed to create coupon")
 		return
 	}
+	audit.Record(c, audit.CouponCreate, audit.EntityCoupon, Coupon.CouponCode, nil, Coupon)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully created coupon",
-	})
+	cache.Invalidate(cache.TagCoupons)
+
+	response.OK(c, "successfully created coupon", nil)
 }
 
+// public - platform coupons and, with restaurant_id, the coupons of that restaurant
 func GetAllCoupons(c *gin.Context) { //public
 	var Coupons []model.CouponInventory
 
-	if err := database.DB.Find(&Coupons).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to fetch coupon details",
-		})
+	//campaign codes are handed out, listing them would give them away
+	tx := database.DB.Preload("Targets").Where("campaign_id = 0")
+	if RestaurantID := c.Query("restaurant_id"); RestaurantID != "" {
+		tx = tx.Where("restaurant_id = 0 OR restaurant_id = ?", RestaurantID)
+	}
+	if err := tx.Find(&Coupons).Error; err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to fetch coupon details")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data":   Coupons,
-	})
+	response.OK(c, "successfully retrieved coupons", Coupons)
+}
+
+// restaurant - coupons the restaurant created
+func GetRestaurantCoupons(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+
+	var Coupons []model.CouponInventory
+	if err := database.DB.Preload("Targets").Where("restaurant_id = ?", RestaurantID).Find(&Coupons).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch coupon details")
+		return
+	}
+
+	response.OK(c, "successfully retrieved coupons", Coupons)
 }
 
 // update coupon
@@ -109,181 +184,219 @@ func UpdateCoupon(c *gin.Context) { //admin
 	// check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	updateCoupon(c, 0)
+}
+
+// restaurant - only the restaurant's own coupons
+func UpdateRestaurantCoupon(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
 		return
 	}
+	updateCoupon(c, RestaurantID)
+}
+
+// RestaurantID is 0 for admins, who can update any coupon
+func updateCoupon(c *gin.Context, RestaurantID uint) {
 	var request model.CouponInventoryRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind the json",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
 		return
 	}
 
 	if err := utils.Validate(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	var existingCoupon model.CouponInventory
-	err = database.DB.Where("coupon_code = ?", request.CouponCode).First(&existingCoupon).Error
+	tx := database.DB.Preload("Targets").Where("coupon_code = ?", request.CouponCode)
+	if RestaurantID != 0 {
+		tx = tx.Where("restaurant_id = ?", RestaurantID)
+	}
+	err := tx.First(&existingCoupon).Error
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":  false,
-				"message": "coupon not found",
-			})
+			response.Error(c, response.CodeNotFound, "coupon not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to find coupon",
-		})
+		response.Error(c, response.CodeInternal, "failed to find coupon")
 		return
 	}
 
-	existingCoupon.Expiry = request.Expiry
-	existingCoupon.Percentage = request.Percentage
-	existingCoupon.MaximumUsage = request.MaximumUsage
-
-	if err := database.DB.Save(&existingCoupon).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update coupon",
-		})
+	//the owner, the campaign and the redemptions so far stay
+	updated, err := couponFromRequest(request, existingCoupon.RestaurantID)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
+	updated.RedeemedCount = existingCoupon.RedeemedCount
+	updated.CampaignID = existingCoupon.CampaignID
+	//campaign codes stay single-use
+	if updated.CampaignID != 0 {
+		updated.MaximumUsage = 1
+		updated.TotalUsageLimit = 1
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully updated coupon",
+	before := existingCoupon
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("coupon_code = ?", updated.CouponCode).Delete(&model.CouponTarget{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&updated).Error
 	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to update coupon")
+		return
+	}
+	audit.Record(c, audit.CouponUpdate, audit.EntityCoupon, updated.CouponCode, before, updated)
+
+	cache.Invalidate(cache.TagCoupons)
+
+	response.OK(c, "successfully updated coupon", nil)
 }
 
 func ApplyCouponOnCart(c *gin.Context) { //user
 	// check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
 	CouponCode := c.Query("couponcode")
-	RestaurantID := c.Query("restaurant_id")
+	RestaurantID, _ := strconv.Atoi(c.Query("restaurant_id"))
 
-	if CouponCode == "" || RestaurantID == "" || RestaurantID == "0" {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "provide couponcode and restaurant_id in the query params"})
+	if CouponCode == "" || RestaurantID == 0 {
+		response.Error(c, response.CodeInternal, "provide couponcode and restaurant_id in the query params")
 		return
 	}
 
 	var CartItems []model.CartItems
 
 	if err := database.DB.Where("user_id = ? AND restaurant_id = ?", UserID, RestaurantID).Find(&CartItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "Failed to fetch cart items. Please try again later.",
-		})
+		response.Error(c, response.CodeInternal, "Failed to fetch cart items. Please try again later.")
 		return
 	}
 
 	if len(CartItems) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "Your cart is empty.",
-		})
+		response.Error(c, response.CodeNotFound, "Your cart is empty.")
+		return
+	}
+
+	Lines, err := CartCouponLines(UserID, uint(RestaurantID))
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "Failed to fetch product information. Please try again later.")
 		return
 	}
 
 	// Total price of the cart
 	var sum, ProductOfferAmount float64
-	for _, item := range CartItems {
-		var Product model.Product
-		if err := database.DB.Where("id = ?", item.ProductID).First(&Product).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":  false,
-				"message": "Failed to fetch product information. Please try again later.",
-			})
-			return
-		}
+	for _, line := range Lines {
+		ProductOfferAmount += line.Offer
+		sum += line.Amount
+	}
 
-		ProductOfferAmount += float64(Product.OfferAmount) * float64((item.Quantity))
-		sum += ((Product.Price) * float64(item.Quantity))
+	// the same rules placing the order checks
+	Quote, err := EvaluateCoupon(CouponCode, UserID, uint(RestaurantID), Lines, "")
+	var RuleError CouponRuleError
+	switch {
+	case errors.Is(err, ErrCouponNotFound):
+		response.Error(c, response.CodeNotFound, "Invalid coupon code. Please check and try again.")
+		return
+	case errors.As(err, &RuleError):
+		response.Error(c, response.CodeBadRequest, RuleError.Error())
+		return
+	case err != nil:
+		response.Error(c, response.CodeInternal, "failed to check the coupon")
+		return
 	}
 
-	// Apply coupon if provided
-	var CouponDiscount float64
-	var FinalAmount float64
-	if CouponCode != "" {
-		var coupon model.CouponInventory
-		if err := database.DB.Where("coupon_code = ?", CouponCode).First(&coupon).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":  false,
-				"message": "Invalid coupon code. Please check and try again.",
-			})
-			return
-		}
+	CouponDiscount := Quote.Discount
+	FinalAmount := sum - (CouponDiscount + ProductOfferAmount)
 
-		// Check coupon expiration
-		if time.Now().Unix() > int64(coupon.Expiry) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status":  false,
-				"message": "The coupon has expired.",
-			})
-			return
-		}
+	response.OK(c, "Cart items retrieved successfully", gin.H{
+		"restaurant_id":        RestaurantID,
+		"cart_items":           CartItems,
+		"total_amount":         sum,
+		"coupon_discount":      CouponDiscount,
+		"product_offer_amount": ProductOfferAmount,
+		"final_amount":         FinalAmount,
+	})
+}
 
-		//check minimum amount
-		if sum < coupon.MinimumAmount {
-			errmsg := fmt.Sprintf("minimum of %v is needed for using this coupon", coupon.MinimumAmount)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status":  false,
-				"message": errmsg,
-			})
-			return
-		}
+// user - restaurant_id in the query param, every coupon of the restaurant checked against the cart
+func RecommendCoupons(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, _ := UserIDfromEmail(email)
+	RestaurantID, _ := strconv.Atoi(c.Query("restaurant_id"))
+	if RestaurantID == 0 {
+		response.Error(c, response.CodeBadRequest, "provide restaurant_id in the query param")
+		return
+	}
 
-		// Check coupon usage
-		var usage model.CouponUsage
-		if err := database.DB.Where("user_id = ? AND coupon_code = ?", UserID, CouponCode).First(&usage).Error; err == nil {
-			if usage.UsageCount >= coupon.MaximumUsage {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"status":  false,
-					"message": "The coupon usage limit has been reached.",
-				})
-				return
-			}
-		}
+	Lines, err := CartCouponLines(UserID, uint(RestaurantID))
+	if err != nil {
+		response.Error(c, response.CodeInternal, "Failed to fetch cart items. Please try again later.")
+		return
+	}
+	if len(Lines) == 0 {
+		response.Error(c, response.CodeNotFound, "Your cart is empty.")
+		return
+	}
+
+	Eligible, Ineligible, err := RankCoupons(UserID, uint(RestaurantID), Lines)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to check the coupons")
+		return
+	}
+	var sum, ProductOfferAmount float64
+	for _, line := range Lines {
+		sum += line.Amount
+		ProductOfferAmount += line.Offer
+	}
+	var Best string
+	if len(Eligible) > 0 {
+		Best = Eligible[0].CouponCode
+	}
 
-		// Calculate discount
-		CouponDiscount = float64(sum) * (float64(coupon.Percentage) / 100.0)
-		FinalAmount = sum - (CouponDiscount + ProductOfferAmount)
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data": gin.H{
-			"restaurant_id":        RestaurantID,
-			"cart_items":           CartItems,
-			"total_amount":         sum,
-			"coupon_discount":      CouponDiscount,
-			"product_offer_amount": ProductOfferAmount,
-			"final_amount":         FinalAmount,
-		},
-		"message": "Cart items retrieved successfully",
+	response.OK(c, "coupons ranked by savings", gin.H{
+		"restaurant_id":        RestaurantID,
+		"total_amount":         sum,
+		"product_offer_amount": ProductOfferAmount,
+		"best_coupon":          Best,
+		"eligible":             Eligible,
+		"ineligible":           Ineligible,
 	})
 }
 
+// coupon saving the most on the user's cart at the restaurant, empty when none applies
+func BestCoupon(UserID uint, RestaurantID uint) (string, error) {
+	Lines, err := CartCouponLines(UserID, RestaurantID)
+	if err != nil {
+		return "", err
+	}
+	Eligible, _, err := RankCoupons(UserID, RestaurantID, Lines)
+	if err != nil || len(Eligible) == 0 {
+		return "", err
+	}
+	return Eligible[0].CouponCode, nil
+}
+
 func ApplyCouponToOrder(order model.Order, UserID uint, CouponCode string) (bool, string, model.Order) {
 
 	if order.CouponCode != "" {
@@ -291,72 +404,98 @@ func ApplyCouponToOrder(order model.Order, UserID uint, CouponCode string) (bool
 		return false, errMsg, order
 	}
 
-	var coupon model.CouponInventory
-	if err := database.DB.Where("coupon_code = ?", CouponCode).First(&coupon).Error; err != nil {
-		return false, "coupon not found", order
-	}
-
-	if time.Now().Unix() > int64(coupon.Expiry) {
-		return false, "coupon has expired", order
+	//the cart is still there, its items become the order items after this
+	Lines, err := CartCouponLines(UserID, order.RestaurantID)
+	if err != nil {
+		return false, "failed to fetch the cart items", order
 	}
-
-	var couponUsage model.CouponUsage
-	err := database.DB.Where("coupon_code = ? AND user_id = ?", CouponCode, UserID).First(&couponUsage).Error
-
-	if err == nil {
-		if couponUsage.UsageCount >= coupon.MaximumUsage {
-			return false, "coupon usage limit reached", order
-		}
-	} else if err != gorm.ErrRecordNotFound {
+	Quote, err := EvaluateCoupon(CouponCode, UserID, order.RestaurantID, Lines, order.OrderID)
+	var RuleError CouponRuleError
+	switch {
+	case errors.Is(err, ErrCouponNotFound), errors.As(err, &RuleError):
+		return false, err.Error(), order
+	case err != nil:
 		return false, "database error", order
 	}
 
-	//check minimum amount
-	if order.TotalAmount < coupon.MinimumAmount {
-		errmsg := fmt.Sprintf("minimum of %v is needed for using this coupon", coupon.MinimumAmount)
-		return false, errmsg, order
-	}
-
-	discountAmount := order.TotalAmount * float64(coupon.Percentage) / 100
-	finalAmount := order.TotalAmount - (discountAmount + order.ProductOfferAmount)
-
 	order.CouponCode = CouponCode
-	order.CouponDiscountAmount = discountAmount
-	order.FinalAmount = finalAmount
+	order.CouponDiscountAmount = Quote.Discount
+	order.FinalAmount = order.TotalAmount - (Quote.Discount + order.ProductOfferAmount)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		//claim a redemption, a concurrent order may have taken the last one
+		claim := tx.Model(&model.CouponInventory{}).
+			Where("coupon_code = ? AND (total_usage_limit = 0 OR redeemed_count < total_usage_limit)", CouponCode).
+			UpdateColumn("redeemed_count", gorm.Expr("redeemed_count + 1"))
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return CouponRuleError("coupon is fully redeemed")
+		}
 
-	if err := database.DB.Where("order_id = ?", order.OrderID).Updates(&order).Error; err != nil {
-		return false, "failed to apply coupon to order", order
-	}
+		if err := tx.Where("order_id = ?", order.OrderID).Updates(&order).Error; err != nil {
+			return err
+		}
 
-	if err == gorm.ErrRecordNotFound {
-		couponUsage = model.CouponUsage{
-			UserID:     UserID,
+		Redemption := model.CouponRedemption{
 			CouponCode: CouponCode,
-			UsageCount: 1,
+			CampaignID: Quote.Coupon.CampaignID,
+			OrderID:    order.OrderID,
+			UserID:     UserID,
+			Discount:   Quote.Discount,
 		}
-		if err := database.DB.Create(&couponUsage).Error; err != nil {
-			return false, "failed to create coupon usage record", order
+		if err := tx.Create(&Redemption).Error; err != nil {
+			return err
 		}
-	} else {
-		couponUsage.UsageCount++
-		if err := database.DB.Where("user_id = ? AND coupon_code = ?", order.UserID, order.CouponCode).Save(&couponUsage).Error; err != nil {
-			return false, "failed to update coupon usage record", order
+
+		var couponUsage model.CouponUsage
+		err := tx.Where("user_id = ? AND coupon_code = ?", UserID, CouponCode).First(&couponUsage).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.CouponUsage{UserID: UserID, CouponCode: CouponCode, UsageCount: 1}).Error
+		}
+		if err != nil {
+			return err
 		}
+		couponUsage.UsageCount++
+		return tx.Where("user_id = ? AND coupon_code = ?", UserID, CouponCode).Save(&couponUsage).Error
+	})
+	if errors.As(err, &RuleError) {
+		return false, RuleError.Error(), order
+	}
+	if err != nil {
+		return false, "failed to apply coupon to order", order
 	}
 
 	return true, "coupon applied successfully", order
 }
 
+// ReleaseCouponClaim gives back the redemption ApplyCouponToOrder claimed for an order that was never placed,
+// the coupon usage limits count it again
+func ReleaseCouponClaim(OrderID string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var Redemptions []model.CouponRedemption
+		if err := tx.Where("order_id = ?", OrderID).Find(&Redemptions).Error; err != nil {
+			return err
+		}
+		for _, Redemption := range Redemptions {
+			if err := tx.Model(&model.CouponInventory{}).Where("coupon_code = ? AND redeemed_count > 0", Redemption.CouponCode).
+				UpdateColumn("redeemed_count", gorm.Expr("redeemed_count - 1")).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.CouponUsage{}).Where("user_id = ? AND coupon_code = ? AND usage_count > 0", Redemption.UserID, Redemption.CouponCode).
+				UpdateColumn("usage_count", gorm.Expr("usage_count - 1")).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("order_id = ?", OrderID).Delete(&model.CouponRedemption{}).Error
+	})
+}
+
 func CheckCouponExists(code string) bool {
-	var Coupons []model.CouponInventory
-	if err := database.DB.Find(&Coupons).Error; err != nil {
+	var Count int64
+	if err := database.DB.Model(&model.CouponInventory{}).Where("coupon_code = ?", code).Count(&Count).Error; err != nil {
 		return false
 	}
-	fmt.Println(&Coupons)
-	for _, c := range Coupons {
-		if c.CouponCode == code {
-			return true
-		}
-	}
-	return false
+	return Count > 0
 }
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"foodbuddy/internal/repository"
+)
+
+// handlers that reach the data through the repositories instead of the global database.DB,
+// built once in main and shared by the routes
+type Handler struct {
+	Users       repository.UserRepo
+	Restaurants repository.RestaurantRepo
+	Products    repository.ProductRepo
+	Categories  repository.CategoryRepo
+	Orders      repository.OrderRepo
+	Wallets     repository.WalletRepo
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{
+		Users:       repos.Users,
+		Restaurants: repos.Restaurants,
+		Products:    repos.Products,
+		Categories:  repos.Categories,
+		Orders:      repos.Orders,
+		Wallets:     repos.Wallets,
+	}
+}
+
+func (h *Handler) userIDfromEmail(Email string) (uint, bool) {
+	User, err := h.Users.ByEmail(Email)
+	if err != nil {
+		return 0, false
+	}
+	return User.ID, true
+}
+
+func (h *Handler) restIDfromEmail(Email string) (uint, bool) {
+	Restaurant, err := h.Restaurants.ByEmail(Email)
+	if err != nil {
+		return 0, false
+	}
+	return Restaurant.ID, true
+}
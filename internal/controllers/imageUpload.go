@@ -1,93 +1,204 @@
 package controllers
 
 import (
-	"log"
-	"net/http"
-
+	"errors"
+	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
 	"foodbuddy/internal/database"
-	"foodbuddy/internal/utils"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"foodbuddy/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserProfileImageUpload(c *gin.Context) {
-	form, err := c.MultipartForm()
+// store the "file" field of the multipart form as kind, the error response is sent when it fails
+func uploadFormFile(c *gin.Context, kind storage.Kind) (storage.Stored, bool) {
+	//room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, kind.MaxBytes+1<<20)
+
+	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		log.Printf("Failed to get multipart form: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get multipart form"})
-		return
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Error(c, response.CodeTooLarge, fmt.Sprintf("file should be at most %v MB", kind.MaxBytes>>20))
+			return storage.Stored{}, false
+		}
+		response.Error(c, response.CodeBadRequest, "No file uploaded")
+		return storage.Stored{}, false
 	}
 
-	// Assuming there's only one file uploaded
-	fileHeaders := form.File["file"] // Replace "yourFieldName" with the actual field name used in the form
-	if len(fileHeaders) > 0 {
-		fileHeader := fileHeaders[0]
-		imageURL, err := utils.ImageUpload(fileHeader)
-		if err != nil {
-			log.Printf("Error uploading image: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
-			return
-		}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logging.From(c).Error("failed to open uploaded file", "error", err)
+		response.Error(c, response.CodeBadRequest, "failed to read the uploaded file")
+		return storage.Stored{}, false
+	}
+	defer file.Close()
 
-		// fmt.Println(imageURL)
-		// c.Redirect(http.StatusMovedPermanently, imageURL)
-		email, role, err := utils.GetJWTClaim(c)
-		if role != model.UserRole || err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status":  false,
-				"message": "unauthorized request",
-			})
-			return
-		}
-		UserID, _ := UserIDfromEmail(email)
-		if err:=database.DB.Model(&model.User{}).Where("id = ?",UserID).Update("picture",imageURL).Error;err!=nil{
-			c.JSON(http.StatusInternalServerError,gin.H{"status":false,"message":"failed to add image, please try again"+err.Error()})
-		}
+	var blob storage.Blob = gateway.Get().Images
+	if kind.Private {
+		blob = gateway.Get().Documents
+	}
+	stored, err := storage.Save(c.Request.Context(), blob, kind, file)
+	switch {
+	case errors.Is(err, storage.ErrTooLarge):
+		response.Error(c, response.CodeTooLarge, fmt.Sprintf("file should be at most %v MB", kind.MaxBytes>>20))
+		return storage.Stored{}, false
+	case errors.Is(err, storage.ErrUnsupported), errors.Is(err, storage.ErrEmpty):
+		response.Error(c, response.CodeUnsupportedType, "file should be one of "+strings.Join(kind.Types, ", "))
+		return storage.Stored{}, false
+	case err != nil:
+		logging.From(c).Error("failed to store upload", "kind", kind.Name, "error", err)
+		response.Error(c, response.CodeUpstream, "Failed to upload image")
+		return storage.Stored{}, false
+	}
+	return stored, true
+}
+
+func UserProfileImageUpload(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, _ := UserIDfromEmail(email)
 
-		c.JSON(http.StatusOK,gin.H{"status":"success","message":"profile image uploaded"})
+	stored, ok := uploadFormFile(c, storage.ProfileImage)
+	if !ok {
+		return
+	}
 
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+	if err := database.DB.Model(&model.User{}).Where("id = ?", UserID).Update("picture", stored.URL).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to add image, please try again")
+		return
 	}
+
+	response.OK(c, "profile image uploaded", stored)
 }
 
 func RestaurantProfileImageUpload(c *gin.Context) {
-	form, err := c.MultipartForm()
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestID, _ := RestIDfromEmail(email)
+
+	stored, ok := uploadFormFile(c, storage.ProfileImage)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&model.Restaurant{}).Where("id = ?", RestID).Update("image_url", stored.URL).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to add image, please try again")
+		return
+	}
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "profile image uploaded", stored)
+}
+
+// restaurant - productid in the query param
+func ProductImageUpload(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestID, _ := RestIDfromEmail(email)
+
+	ProductID, err := strconv.Atoi(c.Query("productid"))
 	if err != nil {
-		log.Printf("Failed to get multipart form: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to get multipart form"})
+		response.Error(c, response.CodeBadRequest, "invalid product ID")
+		return
+	}
+	ok, Product := CheckProduct(ProductID)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "product is not present in the database")
+		return
+	}
+	if Product.RestaurantID != RestID {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request, product doesn't belong to this restaurant")
 		return
 	}
 
-	fileHeaders := form.File["file"]
-	if len(fileHeaders) > 0 {
-		fileHeader := fileHeaders[0]
-		imageURL, err := utils.ImageUpload(fileHeader)
-		if err != nil {
-			log.Printf("Error uploading image: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
-			return
-		}
+	stored, ok := uploadFormFile(c, storage.ProductImage)
+	if !ok {
+		return
+	}
 
-		// fmt.Println(imageURL)
-		// c.Redirect(http.StatusMovedPermanently, imageURL)
-		email, role, err := utils.GetJWTClaim(c)
-		if role != model.RestaurantRole || err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"status":  false,
-				"message": "unauthorized request",
-			})
-			return
-		}
-		RestID, _ := RestIDfromEmail(email)
-		if err:=database.DB.Model(&model.Restaurant{}).Where("id = ?",RestID).Update("image_url",imageURL).Error;err!=nil{
-			c.JSON(http.StatusInternalServerError,gin.H{"status":false,"message":"failed to add image, please try again"+err.Error()})
-		}
+	if err := database.DB.Model(&model.Product{}).Where("id = ?", Product.ID).Update("image_url", stored.URL).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to update the product image")
+		return
+	}
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "product image uploaded", stored)
+}
+
+// admin - categoryid in the query param
+func CategoryImageUpload(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+
+	CategoryID, err := strconv.Atoi(c.Query("categoryid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid category ID")
+		return
+	}
+	var Category model.Category
+	if err := database.DB.First(&Category, CategoryID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "category not found")
+		return
+	}
 
-		c.JSON(http.StatusOK,gin.H{"status":"success","message":"profile image uploaded"})
+	stored, ok := uploadFormFile(c, storage.CategoryImage)
+	if !ok {
+		return
+	}
 
-	} else {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+	if err := database.DB.Model(&model.Category{}).Where("id = ?", Category.ID).Update("image_url", stored.URL).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to update the category image")
+		return
 	}
+	updated := Category
+	updated.ImageURL = stored.URL
+	audit.Record(c, audit.CategoryImage, audit.EntityCategory, Category.ID, Category, updated)
+	cache.Invalidate(cache.TagCategories, cache.TagProducts)
+
+	response.OK(c, "category image uploaded", stored)
+}
+
+// restaurant - food safety certificate as an image or a pdf
+func RestaurantCertificateUpload(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestID, _ := RestIDfromEmail(email)
+
+	stored, ok := uploadFormFile(c, storage.Certificate)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Model(&model.Restaurant{}).Where("id = ?", RestID).Update("certificate_url", stored.URL).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to update the certificate")
+		return
+	}
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "certificate uploaded", stored)
 }
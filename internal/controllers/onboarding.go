@@ -0,0 +1,448 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"foodbuddy/internal/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// documents a restaurant needs approved before it can list products and take orders
+var RequiredDocuments = []string{model.DocumentFSSAI, model.DocumentGST, model.DocumentBank}
+
+var (
+	fssaiPattern   = regexp.MustCompile(`^\d{14}$`)
+	gstinPattern   = regexp.MustCompile(`^\d{2}[A-Z]{5}\d{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+	accountPattern = regexp.MustCompile(`^\d{9,18}$`)
+	ifscPattern    = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+)
+
+// check the number written on the document before the file is stored
+func validDocumentReference(Type string, Reference string, IFSC string) error {
+	switch Type {
+	case model.DocumentFSSAI:
+		if !fssaiPattern.MatchString(Reference) {
+			return errors.New("fssai licence number should be 14 digits")
+		}
+	case model.DocumentGST:
+		if !gstinPattern.MatchString(Reference) {
+			return errors.New("invalid gstin")
+		}
+	case model.DocumentBank:
+		if !accountPattern.MatchString(Reference) {
+			return errors.New("bank account number should be 9 to 18 digits")
+		}
+		if !ifscPattern.MatchString(IFSC) {
+			return errors.New("invalid ifsc code")
+		}
+	default:
+		return fmt.Errorf("document type should be one of %v", strings.Join(RequiredDocuments, ", "))
+	}
+	return nil
+}
+
+// latest document of each type of the restaurants, superseded ones are left out
+func CurrentDocuments(tx *gorm.DB, RestaurantIDs ...uint) (map[uint]map[string]model.RestaurantDocument, error) {
+	var Documents []model.RestaurantDocument
+	if err := tx.Where("restaurant_id IN ? AND status <> ?", RestaurantIDs, model.DocumentStatusSuperseded).Order("id").Find(&Documents).Error; err != nil {
+		return nil, err
+	}
+	Current := make(map[uint]map[string]model.RestaurantDocument, len(RestaurantIDs))
+	for _, ID := range RestaurantIDs {
+		Current[ID] = map[string]model.RestaurantDocument{}
+	}
+	for _, Document := range Documents {
+		Current[Document.RestaurantID][Document.Type] = Document
+	}
+	return Current, nil
+}
+
+// required documents of the restaurant that aren't approved yet, a restaurant is only verified when there are none.
+// both the verify endpoint and the admin command check it
+func UnapprovedDocuments(tx *gorm.DB, RestaurantID uint) ([]string, error) {
+	Current, err := CurrentDocuments(tx, RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+	Pending := []string{}
+	for _, Type := range RequiredDocuments {
+		if Current[RestaurantID][Type].Status != model.DocumentStatusApproved {
+			Pending = append(Pending, Type)
+		}
+	}
+	return Pending, nil
+}
+
+// required documents in order, missing ones are returned separately
+func documentList(Current map[string]model.RestaurantDocument) ([]model.RestaurantDocument, []string) {
+	Documents := []model.RestaurantDocument{}
+	Missing := []string{}
+	for _, Type := range RequiredDocuments {
+		if Document, ok := Current[Type]; ok {
+			Documents = append(Documents, Document)
+		} else {
+			Missing = append(Missing, Type)
+		}
+	}
+	return Documents, Missing
+}
+
+// products of restaurants that are not verified can't be listed or ordered
+func RestaurantVerified(RestaurantID uint) bool {
+	var Restaurant model.Restaurant
+	if err := database.DB.Select("verification_status").Where("id = ?", RestaurantID).First(&Restaurant).Error; err != nil {
+		return false
+	}
+	return Restaurant.VerificationStatus == model.VerificationStatusVerified
+}
+
+// status mails are best effort, the change is already saved when they are sent
+func notifyOnboarding(c *gin.Context, Restaurant model.Restaurant, subject string, body string) {
+	msg := []byte("MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\r\n" +
+		"Subject: FoodBuddy " + subject + "\r\n\r\n" +
+		"Hi " + Restaurant.Name + ",\n\n" + body + "\n")
+	if err := gateway.Get().Mail.Send([]string{Restaurant.Email}, msg); err != nil {
+		logging.From(c).Warn("failed to send onboarding mail", "restaurant_id", Restaurant.ID, "error", err)
+	}
+}
+
+// restaurant - type in the query param, reference (and ifsc for bank) as form fields with the file
+func UploadRestaurantDocument(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	var Restaurant model.Restaurant
+	if err := database.DB.Where("email = ?", email).First(&Restaurant).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+
+	//the form fields are read before the file, so the body is capped here already
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, storage.Document.MaxBytes+1<<20)
+	Type := strings.ToUpper(c.Query("type"))
+	Reference := strings.ToUpper(strings.TrimSpace(c.PostForm("reference")))
+	IFSC := strings.ToUpper(strings.TrimSpace(c.PostForm("ifsc")))
+	if err := validDocumentReference(Type, Reference, IFSC); err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	if Type != model.DocumentBank {
+		IFSC = ""
+	}
+
+	switch Restaurant.VerificationStatus {
+	case model.VerificationStatusInReview:
+		response.Error(c, response.CodeInvalidState, "documents are in review, wait for the review to finish")
+		return
+	case model.VerificationStatusVerified:
+		response.Error(c, response.CodeInvalidState, "restaurant is already verified, contact support to change its documents")
+		return
+	}
+
+	Current, err := CurrentDocuments(database.DB, Restaurant.ID)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the documents")
+		return
+	}
+	Previous, exists := Current[Restaurant.ID][Type]
+	if exists && Previous.Status == model.DocumentStatusApproved {
+		response.Error(c, response.CodeInvalidState, "document is already approved")
+		return
+	}
+
+	stored, ok := uploadFormFile(c, storage.Document)
+	if !ok {
+		return
+	}
+
+	Document := model.RestaurantDocument{
+		RestaurantID: Restaurant.ID,
+		Type:         Type,
+		Reference:    Reference,
+		IFSC:         IFSC,
+		FileURL:      stored.URL,
+		Status:       model.DocumentStatusPending,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if exists {
+			if err := tx.Model(&model.RestaurantDocument{}).Where("id = ?", Previous.ID).Update("status", model.DocumentStatusSuperseded).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&Document).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the document")
+		return
+	}
+
+	response.OK(c, "document uploaded, submit the onboarding once every document is uploaded", Document)
+}
+
+// restaurant
+func GetOnboardingStatus(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	var Restaurant model.Restaurant
+	if err := database.DB.Where("email = ?", email).First(&Restaurant).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+
+	Current, err := CurrentDocuments(database.DB, Restaurant.ID)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the documents")
+		return
+	}
+	Documents, Missing := documentList(Current[Restaurant.ID])
+
+	response.OK(c, "onboarding status retrieved", gin.H{
+		"verification_status": Restaurant.VerificationStatus,
+		"documents":           Documents,
+		"missing":             Missing,
+	})
+}
+
+// restaurant - send the uploaded documents for review
+func SubmitOnboarding(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	var Restaurant model.Restaurant
+	if err := database.DB.Where("email = ?", email).First(&Restaurant).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+	if Restaurant.VerificationStatus != model.VerificationStatusPending && Restaurant.VerificationStatus != model.VerificationStatusRejected {
+		response.Error(c, response.CodeInvalidState, "onboarding can't be submitted while the restaurant is "+Restaurant.VerificationStatus)
+		return
+	}
+
+	Current, err := CurrentDocuments(database.DB, Restaurant.ID)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the documents")
+		return
+	}
+	Documents, Missing := documentList(Current[Restaurant.ID])
+	if len(Missing) > 0 {
+		response.Error(c, response.CodeInvalidState, "upload the missing documents first: "+strings.Join(Missing, ", "))
+		return
+	}
+	for _, Document := range Documents {
+		if Document.Status == model.DocumentStatusRejected {
+			response.Error(c, response.CodeInvalidState, "upload a new "+Document.Type+" document, the current one was rejected")
+			return
+		}
+	}
+
+	if err := database.DB.Model(&model.Restaurant{}).Where("id = ?", Restaurant.ID).Update("verification_status", model.VerificationStatusInReview).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to submit the onboarding")
+		return
+	}
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+	notifyOnboarding(c, Restaurant, "Onboarding Submitted", "We received your documents and will let you know once they are reviewed.")
+
+	response.OK(c, "onboarding submitted for review", gin.H{
+		"verification_status": model.VerificationStatusInReview,
+		"documents":           Documents,
+	})
+}
+
+type onboardingEntry struct {
+	RestaurantID       uint                       `json:"restaurant_id"`
+	Name               string                     `json:"name"`
+	Email              string                     `json:"email"`
+	VerificationStatus string                     `json:"verification_status"`
+	Documents          []model.RestaurantDocument `json:"documents"`
+	Missing            []string                   `json:"missing"`
+}
+
+// admin - restaurants waiting for review, status in the query param picks another stage
+func OnboardingQueue(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	Status := c.DefaultQuery("status", model.VerificationStatusInReview)
+	switch Status {
+	case model.VerificationStatusPending, model.VerificationStatusInReview, model.VerificationStatusRejected, model.VerificationStatusVerified:
+	default:
+		response.Error(c, response.CodeBadRequest, "invalid status")
+		return
+	}
+
+	var Restaurants []model.Restaurant
+	if err := Page.Keyset(database.DB.Where("verification_status = ?", Status), "id").Find(&Restaurants).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve data from the database")
+		return
+	}
+	Restaurants, NextCursor := pagination.Trim(Page, Restaurants, func(r model.Restaurant) uint { return r.ID })
+
+	IDs := make([]uint, 0, len(Restaurants))
+	for _, Restaurant := range Restaurants {
+		IDs = append(IDs, Restaurant.ID)
+	}
+	Current, err := CurrentDocuments(database.DB, IDs...)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the documents")
+		return
+	}
+
+	Queue := make([]onboardingEntry, 0, len(Restaurants))
+	for _, Restaurant := range Restaurants {
+		Documents, Missing := documentList(Current[Restaurant.ID])
+		Queue = append(Queue, onboardingEntry{
+			RestaurantID:       Restaurant.ID,
+			Name:               Restaurant.Name,
+			Email:              Restaurant.Email,
+			VerificationStatus: Restaurant.VerificationStatus,
+			Documents:          Documents,
+			Missing:            Missing,
+		})
+	}
+
+	response.Page(c, "onboarding queue retrieved", gin.H{"restaurants": Queue}, NextCursor)
+}
+
+// admin - documentid in the query param, the restaurant is verified or rejected once every document is reviewed
+func ReviewRestaurantDocument(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+
+	DocumentID, err := strconv.Atoi(c.Query("documentid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid document ID")
+		return
+	}
+	var Request model.ReviewRestaurantDocument
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var Document model.RestaurantDocument
+	if err := database.DB.First(&Document, DocumentID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "document not found")
+		return
+	}
+	var Restaurant model.Restaurant
+	if err := database.DB.First(&Restaurant, Document.RestaurantID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "restaurant not found")
+		return
+	}
+	if Restaurant.VerificationStatus != model.VerificationStatusInReview {
+		response.Error(c, response.CodeInvalidState, "restaurant is not in review")
+		return
+	}
+	if Document.Status != model.DocumentStatusPending {
+		response.Error(c, response.CodeInvalidState, "document is already "+strings.ToLower(Document.Status))
+		return
+	}
+
+	Now := time.Now()
+	DocumentBefore, RestaurantBefore := Document, Restaurant
+	Document.Status = Request.Status
+	Document.Comment = Request.Comment
+	Document.ReviewedBy = email
+	Document.ReviewedAt = &Now
+
+	var Documents []model.RestaurantDocument
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&Document).Error; err != nil {
+			return err
+		}
+		Current, err := CurrentDocuments(tx, Restaurant.ID)
+		if err != nil {
+			return err
+		}
+		Current[Restaurant.ID][Document.Type] = Document
+		Documents, _ = documentList(Current[Restaurant.ID])
+
+		Status := model.VerificationStatusVerified
+		for _, Reviewed := range Documents {
+			if Reviewed.Status == model.DocumentStatusPending {
+				return nil
+			}
+			if Reviewed.Status == model.DocumentStatusRejected {
+				Status = model.VerificationStatusRejected
+			}
+		}
+		Updates := map[string]interface{}{"verification_status": Status}
+		if Status == model.VerificationStatusVerified {
+			Updates["certificate_url"] = Current[Restaurant.ID][model.DocumentFSSAI].FileURL
+		}
+		if err := tx.Model(&model.Restaurant{}).Where("id = ?", Restaurant.ID).Updates(Updates).Error; err != nil {
+			return err
+		}
+		if Status == model.VerificationStatusVerified {
+			Restaurant.CertificateURL = Current[Restaurant.ID][model.DocumentFSSAI].FileURL
+		}
+		Restaurant.VerificationStatus = Status
+		return nil
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the review")
+		return
+	}
+	audit.Record(c, audit.DocumentReview, audit.EntityDocument, Document.ID, DocumentBefore, Document)
+	if Restaurant.VerificationStatus != RestaurantBefore.VerificationStatus {
+		audit.Record(c, audit.RestaurantOnboarding, audit.EntityRestaurant, Restaurant.ID, RestaurantBefore, Restaurant)
+	}
+
+	switch Restaurant.VerificationStatus {
+	case model.VerificationStatusVerified:
+		cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+		notifyOnboarding(c, Restaurant, "Onboarding Approved", "Your documents are approved, you can now add products and take orders.")
+	case model.VerificationStatusRejected:
+		cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+		var Reasons []string
+		for _, Reviewed := range Documents {
+			if Reviewed.Status == model.DocumentStatusRejected {
+				Reasons = append(Reasons, Reviewed.Type+": "+Reviewed.Comment)
+			}
+		}
+		notifyOnboarding(c, Restaurant, "Onboarding Needs Changes",
+			"Some documents were rejected, upload them again and resubmit the onboarding.\n\n"+strings.Join(Reasons, "\n"))
+	}
+
+	response.OK(c, "document reviewed", gin.H{
+		"document":            Document,
+		"verification_status": Restaurant.VerificationStatus,
+	})
+}
@@ -3,13 +3,18 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"foodbuddy/internal/cache"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/metrics"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
+	"log/slog"
+	"math"
 	"math/rand"
-	"net/http"
-	"net/smtp"
-	"os"
 	"regexp"
 	"time"
 
@@ -49,11 +54,11 @@ func CreateOrderID(UserID uint) (string, error) {
 }
 
 func ValidRestaurant(RestauarantID uint) (model.Restaurant, bool) {
-	var response model.Restaurant
-	if err := database.DB.Where("id = ?", RestauarantID).First(&response).Error; err != nil {
-		return response, false
+	var restaurant model.Restaurant
+	if err := database.DB.Where("id = ?", RestauarantID).First(&restaurant).Error; err != nil {
+		return restaurant, false
 	}
-	return model.Restaurant{}, true
+	return restaurant, true
 }
 
 func ValidAddress(UserID uint, AddressID uint) bool {
@@ -71,16 +76,33 @@ func CheckStock(UserID uint) (ItemCount uint, ok bool) {
 	if err := database.DB.Where("user_id = ?", UserID).Find(&CartItems).Error; err != nil {
 		return ItemCount, false
 	}
+
+	//bundles draw from the stock of their components, so sum up the need per product
+	required := make(map[uint]uint)
 	for _, v := range CartItems {
+		if v.BundleID != 0 {
+			Bundle, _, err := GetBundleComponents(v.BundleID)
+			if err != nil {
+				return ItemCount, false
+			}
+			for _, item := range Bundle.Items {
+				required[item.ProductID] += item.Quantity * v.Quantity
+				ItemCount += item.Quantity * v.Quantity
+			}
+			continue
+		}
+		required[v.ProductID] += v.Quantity
+		ItemCount += v.Quantity
+	}
+
+	for ProductID, Quantity := range required {
 		var Product model.Product
-		if err := database.DB.Where("id = ?", v.ProductID).First(&Product).Error; err != nil {
+		if err := database.DB.Where("id = ?", ProductID).First(&Product).Error; err != nil {
 			return ItemCount, false
 		}
-		if v.Quantity > Product.StockLeft {
+		if Quantity > Product.StockLeft {
 			return ItemCount, false
 		}
-
-		ItemCount += v.Quantity
 	}
 	return ItemCount, true
 }
@@ -92,17 +114,49 @@ func CartToOrderItems(UserID uint, RestaurantID uint, Order model.Order) bool {
 		return false
 	}
 
-	fmt.Printf("OrderID: %s\nUserID: %d\nAddressID: %d\nItemCount: %d\nCouponCode: %s\nCouponDiscountAmount: %.2f\nProductOfferAmount: %.2f\nTotalAmount: %.2f\nFinalAmount: %.2f\nPaymentMethod: %s\nPaymentStatus: %s\nOrderedAt: %v\n",
-		Order.OrderID, Order.UserID, Order.AddressID, Order.ItemCount, Order.CouponCode, Order.CouponDiscountAmount, Order.ProductOfferAmount, Order.TotalAmount, Order.FinalAmount, Order.PaymentMethod, Order.PaymentStatus, Order.OrderedAt)
+	slog.Debug("creating order items", "order_id", Order.OrderID, "user_id", Order.UserID, "item_count", Order.ItemCount,
+		"coupon_code", Order.CouponCode, "final_amount", Order.FinalAmount, "payment_method", Order.PaymentMethod)
+
+	//order items are keyed by order_id and product_id, so bundle components are merged
+	//with the same product ordered on its own
+	var OrderItems []model.OrderItem
+	index := make(map[uint]int)
+	addOrderItem := func(OrderItem model.OrderItem) {
+		if i, ok := index[OrderItem.ProductID]; ok {
+			OrderItems[i].Quantity += OrderItem.Quantity
+			OrderItems[i].Amount += OrderItem.Amount
+			OrderItems[i].ProductOfferAmount += OrderItem.ProductOfferAmount
+			if OrderItems[i].CookingRequest == "" {
+				OrderItems[i].CookingRequest = OrderItem.CookingRequest
+			}
+			return
+		}
+		index[OrderItem.ProductID] = len(OrderItems)
+		OrderItems = append(OrderItems, OrderItem)
+	}
 
 	for _, v := range CartItems {
 
+		if v.BundleID != 0 {
+			Lines, err := BundleOrderLines(v.BundleID, v.Quantity)
+			if err != nil {
+				return false
+			}
+			for _, OrderItem := range Lines {
+				OrderItem.OrderID = Order.OrderID
+				OrderItem.UserID = UserID
+				OrderItem.OrderStatus = model.OrderStatusInitiated
+				addOrderItem(OrderItem)
+			}
+			continue
+		}
+
 		var Product model.Product
 		if err := database.DB.Where("id = ?", v.ProductID).First(&Product).Error; err != nil {
 			return false
 		}
 
-		OrderItem := model.OrderItem{
+		addOrderItem(model.OrderItem{
 			OrderID:            Order.OrderID,
 			UserID:             UserID,
 			ProductID:          v.ProductID,
@@ -112,16 +166,20 @@ func CartToOrderItems(UserID uint, RestaurantID uint, Order model.Order) bool {
 			CookingRequest:     v.CookingRequest,
 			OrderStatus:        model.OrderStatusInitiated,
 			RestaurantID:       RestaurantIDByProductID(v.ProductID),
-		}
+		})
+	}
+
+	//coupon discount is split over the items the coupon applies to
+	CouponShares, err := couponShares(Order, OrderItems)
+	if err != nil {
+		return false
+	}
 
+	for _, OrderItem := range OrderItems {
 		//after offer and coupon deduction amount
-		//get ratio for coupon reduction
-		fmt.Println(Order)
-		couponDeduct := Order.CouponDiscountAmount * (float64(OrderItem.Quantity) / float64(Order.ItemCount))
+		couponDeduct := CouponShares[OrderItem.ProductID]
 		afterDeduct := OrderItem.Amount - (OrderItem.ProductOfferAmount + couponDeduct)
 
-		fmt.Println("coupon deduct is : ", couponDeduct)
-		fmt.Println(OrderItem)
 		OrderItem.AfterDeduction = afterDeduct
 
 		if err := database.DB.Create(&OrderItem).Error; err != nil {
@@ -143,76 +201,53 @@ func PlaceOrder(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
 
 	var PlaceOrder model.PlaceOrder
 	if err := c.BindJSON(&PlaceOrder); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind the json",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
 		return
 	}
 	PlaceOrder.UserID = UserID
 
 	if err := utils.Validate(&PlaceOrder); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusBadRequest,
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	if !CheckUser(PlaceOrder.UserID) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    "user doesn't exist, please verify user id",
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, "user doesn't exist, please verify user id")
 		return
 	}
 
 	if !ValidAddress(PlaceOrder.UserID, PlaceOrder.AddressID) {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":     false,
-			"message":    "invalid address, please retry with user's address",
-			"error_code": http.StatusConflict,
-		})
+		response.Error(c, response.CodeConflict, "invalid address, please retry with user's address")
 		return
 	}
 
 	var Restaurant model.Restaurant
 	Restaurant, restExist := ValidRestaurant(PlaceOrder.RestaurantID)
 	if !restExist {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":     false,
-			"message":    "invalid restaurant_id, please ensure restauarant exists",
-			"error_code": http.StatusConflict,
-		})
+		response.Error(c, response.CodeConflict, "invalid restaurant_id, please ensure restauarant exists")
 		return
 	}
 
 	if Restaurant.Blocked {
-		c.JSON(http.StatusConflict, gin.H{"status": false, "message": "cannot place orders of blocked restaurants", "error_code": http.StatusConflict})
+		response.Error(c, response.CodeForbidden, "cannot place orders of blocked restaurants")
+		return
+	}
+	if Restaurant.VerificationStatus != model.VerificationStatusVerified {
+		response.Error(c, response.CodeForbidden, "restaurant is not taking orders")
 		return
 	}
 
 	ItemCount, ok := CheckStock(PlaceOrder.UserID)
 
 	if !ok {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":     false,
-			"message":    "items in the cart are out of stock, please update the cart to ensure all items are in stock",
-			"error_code": http.StatusConflict,
-		})
+		response.Error(c, response.CodeOutOfStock, "items in the cart are out of stock, please update the cart to ensure all items are in stock")
 		return
 	}
 
@@ -229,25 +264,19 @@ func PlaceOrder(c *gin.Context) {
 
 	TotalAmount, ProductOffer, err := CalculateCartTotal(PlaceOrder.UserID, PlaceOrder.RestaurantID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "failed to calculate cart total or the cart is empty",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "failed to calculate cart total or the cart is empty")
 		return
 	}
 
 	if PlaceOrder.PaymentMethod == model.CashOnDelivery && TotalAmount >= model.CODMaximumAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "please switch to ONLINE payment for total amounts greater than or equal to 1000",
-		})
+		response.Error(c, response.CodeBadRequest, "please switch to ONLINE payment for total amounts greater than or equal to 1000")
 		return
 	}
 
 	order := model.Order{
 		OrderID:            OrderID,
 		UserID:             PlaceOrder.UserID,
+		RestaurantID:       PlaceOrder.RestaurantID,
 		AddressID:          PlaceOrder.AddressID,
 		ItemCount:          ItemCount,
 		ProductOfferAmount: float64(ProductOffer),
@@ -263,13 +292,19 @@ func PlaceOrder(c *gin.Context) {
 		order.PaymentStatus = model.OnlinePaymentPending
 	}
 
+	//the best coupon is left out rather than failing the order when it can't be applied
+	AutoApplied := false
+	if PlaceOrder.CouponCode == "" && PlaceOrder.AutoApplyCoupon {
+		if PlaceOrder.CouponCode, err = BestCoupon(PlaceOrder.UserID, PlaceOrder.RestaurantID); err != nil {
+			response.Error(c, response.CodeInternal, "failed to check the coupons")
+			return
+		}
+		AutoApplied = PlaceOrder.CouponCode != ""
+	}
+
 	// Attempt to create order record
 	if err := database.DB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "failed to create order",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "failed to create order")
 		return
 	}
 
@@ -277,25 +312,31 @@ func PlaceOrder(c *gin.Context) {
 	if PlaceOrder.CouponCode != "" {
 		var success bool
 		var msg string
+		placed := order
 		success, msg, order = ApplyCouponToOrder(order, PlaceOrder.UserID, PlaceOrder.CouponCode)
-		if !success {
+		if !success && AutoApplied {
+			order = placed
+		} else if !success {
 			database.DB.Where("order_id = ?", OrderID).Delete(&order)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status":  false,
-				"message": msg,
-			})
+			response.Error(c, response.CodeBadRequest, msg)
 			return
 		}
 	}
 
+	// order that can't be placed after all, the coupon redemption it claimed is given back
+	discard := func() {
+		if order.CouponCode != "" {
+			if err := ReleaseCouponClaim(OrderID); err != nil {
+				logging.From(c).Error("failed to release the coupon of a discarded order", "order_id", OrderID, "coupon_code", order.CouponCode, "error", err)
+			}
+		}
+		database.DB.Where("order_id = ?", OrderID).Delete(&order)
+	}
+
 	// Transfer cart items to order
 	if !CartToOrderItems(PlaceOrder.UserID, PlaceOrder.RestaurantID, order) {
-		database.DB.Where("order_id = ?", OrderID).Delete(&order)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "failed to transfer cart items to order",
-			"error_code": http.StatusInternalServerError,
-		})
+		discard()
+		response.Error(c, response.CodeInternal, "failed to transfer cart items to order")
 		return
 	}
 
@@ -303,31 +344,22 @@ func PlaceOrder(c *gin.Context) {
 	if PlaceOrder.PaymentMethod == model.CashOnDelivery {
 		if !DecrementStock(OrderID) {
 			// Rollback order creation, coupon application, and cart items transfer if stock decrement fails
-			database.DB.Where("order_id = ?", OrderID).Delete(&order)
-			c.JSON(http.StatusConflict, gin.H{
-				"status":  false,
-				"message": "failed to decrement order stock",
-			})
+			discard()
+			response.Error(c, response.CodeOutOfStock, "failed to decrement order stock")
 			return
 		}
 	}
 
 	// Fetch final order details
 	if err := database.DB.Where("order_id = ?", OrderID).First(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "failed fetch order details",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "failed fetch order details")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Order is successfully created",
-		"data": gin.H{
-			"order_details": order,
-		},
+	metrics.OrderPlaced(order.PaymentMethod)
+
+	response.OK(c, "Order is successfully created", gin.H{
+		"order_details": order,
 	})
 }
 
@@ -337,38 +369,24 @@ func InitiatePayment(c *gin.Context) {
 	// Get order id from request body
 	var initiatePayment model.InitiatePayment
 	if err := c.BindJSON(&initiatePayment); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "Failed to bind the JSON",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "Failed to bind the JSON")
 		return
 	}
 
 	// Check if payment status is confirmed
 	var Order []model.Order
 	if err := database.DB.Where("order_id = ?", initiatePayment.OrderID).Find(&Order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "Failed to get payment information",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "Failed to get payment information")
 		return
 	}
 
 	for _, v := range Order {
 		if v.PaymentStatus == string(model.OnlinePaymentConfirmed) {
-			c.JSON(http.StatusAlreadyReported, gin.H{
-				"status":  true,
-				"message": "Payment already done",
-			})
+			response.OK(c, "Payment already done", nil)
 			return
 		}
 		if v.PaymentStatus == string(model.CODStatusPending) || v.PaymentStatus == string(model.CODStatusConfirmed) {
-			c.JSON(http.StatusAlreadyReported, gin.H{
-				"status":  true,
-				"message": "Customer chose payment via COD",
-			})
+			response.OK(c, "Customer chose payment via COD", nil)
 			return
 		}
 
@@ -378,11 +396,7 @@ func InitiatePayment(c *gin.Context) {
 	var order model.Order
 	if err := database.DB.Where("order_id = ?", initiatePayment.OrderID).First(&order).Error; err != nil {
 		PaymentFailedOrderTable(initiatePayment.OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "Failed to fetch order information",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "Failed to fetch order information")
 		return
 	}
 
@@ -409,150 +423,113 @@ func CheckUser(UserID uint) bool {
 
 // active orders of restaurants
 // restaurant
-func OrderHistoryRestaurants(c *gin.Context) {
+func (h *Handler) OrderHistoryRestaurants(c *gin.Context) {
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	RestaurantID, ok := RestIDfromEmail(email)
+	RestaurantID, ok := h.restIDfromEmail(email)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve restaurant information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
+
 	//Restaurant id, if order status is provided use it or get the whole history
 	Request := c.Query("order_status")
 
-	var OrderItems []model.OrderItem
-	if Request != "" {
-		//condition one order status not empty
-		//return all the orders with order_id, restaurant_id,order_status is met with the condition
-		if err := database.DB.Where("restaurant_id = ? AND order_status = ?", RestaurantID, Request).Find(&OrderItems).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":  false,
-				"message": "failed to fetch orders assigned to this restaurant",
-			})
-			return
-		}
-	} else {
-		//condition two order status empty
-		//return all the orders with order_id, restaurant_id is met with the condition
-		if err := database.DB.Where("restaurant_id = ?", RestaurantID).Find(&OrderItems).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":  false,
-				"message": "failed to fetch orders assigned to this restaurant",
-			})
-			return
-		}
+	OrderItems, err := h.Orders.RestaurantItems(RestaurantID, Request, Page)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to fetch orders assigned to this restaurant")
+		return
 	}
+	OrderItems, NextCursor := pagination.Trim(Page, OrderItems, nil)
 
 	for i := 0; i < len(OrderItems); i++ {
 		OrderItems[i].AfterDeduction = RoundDecimalValue(OrderItems[i].AfterDeduction)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"message": gin.H{
-			"orderhistory": OrderItems,
-		},
-	})
+	Response, err := Page.Project(OrderItems)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	response.Page(c, "successfully retrieved order history", gin.H{
+		"orderhistory": Response,
+	}, NextCursor)
 }
 
 // user
-func UserOrderItems(c *gin.Context) {
+func (h *Handler) UserOrderItems(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, _ := h.userIDfromEmail(email)
+
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
 
-	//same like restaurant
+	//same like restaurant, without order_id all the order items of the user are listed
 	var Request model.UserOrderHistory
 	Request.OrderID = c.Query("order_id")
 	Request.UserID = UserID
-	var OrderItems []model.OrderItem
 
-	if Request.OrderID != "" {
-		if err := database.DB.Where("user_id = ? AND order_id = ?", Request.UserID, Request.OrderID).Find(&OrderItems).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status":  true,
-				"message": "failed to fetch specified orderitems",
-			})
-			return
-		}
+	OrderItems, err := h.Orders.UserItems(Request.UserID, Request.OrderID, Page)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to fetch specified orderitems")
+		return
 	}
+	OrderItems, NextCursor := pagination.Trim(Page, OrderItems, nil)
 
 	for i := 0; i < len(OrderItems); i++ {
 		OrderItems[i].AfterDeduction = RoundDecimalValue(OrderItems[i].AfterDeduction)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully fetched order items based on order status",
-		"data": gin.H{
-			"orderhistory": OrderItems,
-		},
-	})
+	Response, err := Page.Project(OrderItems)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	response.Page(c, "successfully fetched order items based on order status", gin.H{
+		"orderhistory": Response,
+	}, NextCursor)
 }
 
-func PaymentDetailsByOrderID(c *gin.Context) {
+func (h *Handler) PaymentDetailsByOrderID(c *gin.Context) {
 
 	var Request model.PaymentDetailsByOrderID
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind request",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
 		return
 	}
 
-	var PaymentDetails []model.Payment
-	if Request.PaymentStatus != "" {
-		if err := database.DB.Where("order_id = ? AND payment_status = ?", Request.OrderID, Request.PaymentStatus).Find(&PaymentDetails).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  true,
-				"message": "failed to fetch payment information",
-			})
-			return
-		}
-
-	} else {
-		if err := database.DB.Where("order_id = ?", Request.OrderID).Find(&PaymentDetails).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  true,
-				"message": "failed to fetch payment information",
-			})
-			return
-		}
+	PaymentDetails, err := h.Orders.Payments(Request.OrderID, Request.PaymentStatus)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch payment information")
+		return
 	}
 
 	if len(PaymentDetails) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  true,
-			"message": "failed to fetch payment information of this order_id",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch payment information of this order_id")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Successfully retrieved payment information",
-		"data": gin.H{
-			"paymentdetails": PaymentDetails,
-		},
+	response.OK(c, "Successfully retrieved payment information", gin.H{
+		"paymentdetails": PaymentDetails,
 	})
 }
 
@@ -561,46 +538,30 @@ func UpdateOrderStatusForRestaurant(c *gin.Context) {
 	var Request model.UpdateOrderStatusForRestaurant
 
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind request",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
 		return
 	}
 
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	RestaurantID, ok := RestIDfromEmail(email)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var OrderItemDetail model.OrderItem
 	if err := database.DB.Where("order_id = ? AND product_id = ?", Request.OrderID, Request.ProductID).First(&OrderItemDetail).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to fetch order information for the specific product",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch order information for the specific product")
 		return
 	}
 
 	if RestaurantID != OrderItemDetail.RestaurantID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -612,7 +573,6 @@ func UpdateOrderStatusForRestaurant(c *gin.Context) {
 		OrderTransition := []string{model.OrderStatusInPreparation, model.OrderStatusPrepared, model.OrderStatusOntheway}
 
 		//get current index of the status transition
-		fmt.Println(OrderItemDetail.OrderStatus)
 		var orderIndex int
 		for i, v := range OrderTransition {
 			if OrderItemDetail.OrderStatus == v {
@@ -623,35 +583,23 @@ func UpdateOrderStatusForRestaurant(c *gin.Context) {
 
 		//check if transition ended
 		if orderIndex == len(OrderTransition)-1 {
-			c.JSON(http.StatusNotFound, gin.H{
-				"status":     false,
-				"message":    "Reached maximum level of order transition",
-				"error_code": http.StatusNotFound,
-			})
+			response.Error(c, response.CodeNotFound, "Reached maximum level of order transition")
 			return
 		}
 
 		NextOrderStatus = OrderTransition[orderIndex+1]
-		fmt.Println(NextOrderStatus)
+		logging.From(c).Debug("order status transition", "order_id", OrderItemDetail.OrderID, "product_id", OrderItemDetail.ProductID, "from", OrderItemDetail.OrderStatus, "to", NextOrderStatus)
 	}
 
 	//update the new status to the orderitem table
 	if err := database.DB.Model(&model.OrderItem{}).Where("order_id = ? AND product_id = ?", Request.OrderID, Request.ProductID).Update("order_status", NextOrderStatus).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    "failed to update order status for the specific product",
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, "failed to update order status for the specific product")
 		return
 	}
 
 	OrderItemDetail.OrderStatus = NextOrderStatus
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Successfully changed to next order status",
-		"data": gin.H{
-			"orderdetails": OrderItemDetail,
-		},
+	response.OK(c, "Successfully changed to next order status", gin.H{
+		"orderdetails": OrderItemDetail,
 	})
 }
 
@@ -667,55 +615,36 @@ func UserIDfromOrderID(OrderID string) (uint, bool) {
 func CancelOrderedProductOnline(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	JWTUserID, _ := UserIDfromEmail(email)
 
 	var Request model.CancelOrderedProduct
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind request",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
 		return
 	}
 
 	oUserID, _ := UserIDfromOrderID(Request.OrderID)
 
 	if JWTUserID != oUserID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var order model.Order
 	if err := database.DB.Where("order_id =?", Request.OrderID).First(&order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to fetch order information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch order information")
 		return
 	}
 	if order.PaymentMethod != model.OnlinePayment {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{
-			"status":  false,
-			"message": "order payment method is not ONLINE",
-		})
+		response.Error(c, response.CodeInvalidState, "order payment method is not ONLINE")
 		return
 	}
 
 	if order.PaymentStatus != model.OnlinePaymentConfirmed {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "order has not received the payment, hence cannot initiate the cancellation",
-		})
+		response.Error(c, response.CodeNotFound, "order has not received the payment, hence cannot initiate the cancellation")
 		return
 	}
 
@@ -723,194 +652,223 @@ func CancelOrderedProductOnline(c *gin.Context) {
 	if Request.ProductId != 0 {
 		// Fetch individual product
 		if err := database.DB.Where("order_id =? AND product_id =? AND order_status = ?", Request.OrderID, Request.ProductId, model.OrderStatusInitiated).Find(&OrderItems).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch the order item"})
+			response.Error(c, response.CodeNotFound, "failed to fetch the order item")
 			return
 		}
 	} else {
 		// Fetch all orders
 		if err := database.DB.Where("order_id =? AND order_status= ?", Request.OrderID, model.OrderStatusInitiated).Find(&OrderItems).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch the order item"})
+			response.Error(c, response.CodeNotFound, "failed to fetch the order item")
 			return
 		}
 	}
 
 	if len(OrderItems) == 0 {
-		c.JSON(http.StatusConflict, gin.H{"status": false, "message": "No eligible items found for cancellation"})
+		response.Error(c, response.CodeConflict, "No eligible items found for cancellation")
 		return
 	}
 
-	// Update order status to cancelled
-	for _, item := range OrderItems {
-		item.OrderStatus = model.OrderStatusCancelled
-		if err := database.DB.Where("order_id = ? AND product_id = ?", item.OrderID, item.ProductID).Updates(&item).Error; err != nil {
-			c.JSON(http.StatusConflict, gin.H{"status": false, "message": "failed to do cancellation"})
+	// Update order status to cancelled, on the slice itself so IncrementStock sees the new status
+	for i := range OrderItems {
+		OrderItems[i].OrderStatus = model.OrderStatusCancelled
+		if err := database.DB.Where("order_id = ? AND product_id = ?", OrderItems[i].OrderID, OrderItems[i].ProductID).Updates(&OrderItems[i]).Error; err != nil {
+			response.Error(c, response.CodeConflict, "failed to do cancellation")
+			return
 		}
 	}
 
 	done := IncrementStock(OrderItems)
 	if !done {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":  false,
-			"message": "failed to increment order stock",
-		})
+		response.Error(c, response.CodeConflict, "failed to increment order stock")
 		return
 	}
 
 	done = ProvideWalletRefundToUser(order.UserID, OrderItems)
 	if !done {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":  false,
-			"message": "failed to refund to the wallet",
-		})
+		response.Error(c, response.CodeConflict, "failed to refund to the wallet")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully cancelled the order",
-		"data": gin.H{
-			"order_id": Request.OrderID,
-		},
+	response.OK(c, "successfully cancelled the order", gin.H{
+		"order_id": Request.OrderID,
 	})
 }
 
 func CancelOrderedProductCOD(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	JWTUserID, _ := UserIDfromEmail(email)
 
 	var Request model.CancelOrderedProduct
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "failed to bind request",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
 		return
 	}
 
 	oUserID, _ := UserIDfromOrderID(Request.OrderID)
 
 	if JWTUserID != oUserID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var order model.Order
 	if err := database.DB.Where("order_id =?", Request.OrderID).First(&order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to fetch order information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch order information")
 		return
 	}
 	if order.PaymentMethod != model.CashOnDelivery {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{
-			"status":  false,
-			"message": "order payment method is not COD",
-		})
+		response.Error(c, response.CodeInvalidState, "order payment method is not COD")
 		return
 	}
 
 	if order.PaymentMethod != model.CashOnDelivery {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "order is not COD, hence cannot initiate the cancellation",
-		})
+		response.Error(c, response.CodeNotFound, "order is not COD, hence cannot initiate the cancellation")
 		return
 	}
 
 	var OrderItems []model.OrderItem
 	// Fetch all orders
 	if err := database.DB.Where("order_id =? AND order_status IN (?,?)", Request.OrderID, model.OrderStatusProcessing, model.OrderStatusInPreparation).Find(&OrderItems).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch the order item"})
+		response.Error(c, response.CodeNotFound, "failed to fetch the order item")
 		return
 	}
 
 	if len(OrderItems) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "No eligible items found for cancellation,order item should be either in Processing or Preparation stage"})
+		response.Error(c, response.CodeNotFound, "No eligible items found for cancellation,order item should be either in Processing or Preparation stage")
 		return
 	}
 
-	// Update order status to cancelled
-	for _, item := range OrderItems {
-		item.OrderStatus = model.OrderStatusCancelled
-		if err := database.DB.Where("order_id = ? AND product_id = ?", item.OrderID, item.ProductID).Updates(&item).Error; err != nil {
-			c.JSON(http.StatusConflict, gin.H{"status": false, "message": "failed to do cancellation"})
+	// Update order status to cancelled, on the slice itself so IncrementStock sees the new status
+	for i := range OrderItems {
+		OrderItems[i].OrderStatus = model.OrderStatusCancelled
+		if err := database.DB.Where("order_id = ? AND product_id = ?", OrderItems[i].OrderID, OrderItems[i].ProductID).Updates(&OrderItems[i]).Error; err != nil {
+			response.Error(c, response.CodeConflict, "failed to do cancellation")
+			return
 		}
 	}
 
 	done := IncrementStock(OrderItems)
 	if !done {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":  false,
-			"message": "failed to increment order stock",
-		})
+		response.Error(c, response.CodeConflict, "failed to increment order stock")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully cancelled the order",
-		"data": gin.H{
-			"order_id": Request.OrderID,
-		},
+	response.OK(c, "successfully cancelled the order", gin.H{
+		"order_id": Request.OrderID,
 	})
 }
 
+// refunds are capped per order item by what it paid and what was already refunded on it
+var ErrRefundExceedsPaid = errors.New("refund is more than what is left to refund on the items")
+
+// the whole remainder of the items, when they are cancelled
 func ProvideWalletRefundToUser(UserID uint, OrderItems []model.OrderItem) bool {
-	// Fetch the order using the first item's order ID to get the coupon discount amount
+	var sum float64
+	for _, item := range OrderItems {
+		sum += item.AfterDeduction - item.RefundedAmount
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return ProvidePartialWalletRefund(tx, UserID, OrderItems, RoundDecimalValue(sum), model.WalletTxTypeOrderRefund)
+	})
+	return err == nil
+}
+
+// ProvidePartialWalletRefund credits Amount to the user wallet and takes it back from the restaurants,
+// split over the items in proportion to what is left to refund on each, the last item takes the rounding difference.
+// It runs on tx, the caller owns the transaction so a failure leaves no wallet touched.
+func ProvidePartialWalletRefund(tx *gorm.DB, UserID uint, OrderItems []model.OrderItem, Amount float64, Reason string) error {
+	if len(OrderItems) == 0 {
+		return errors.New("no items to refund")
+	}
+	if Amount <= 0 {
+		return nil
+	}
+	// Fetch the order using the first item's order ID
 	var Order model.Order
-	if err := database.DB.Where("order_id =?", OrderItems[0].OrderID).First(&Order).Error; err != nil {
-		return false
+	if err := tx.Where("order_id =?", OrderItems[0].OrderID).First(&Order).Error; err != nil {
+		return err
+	}
+	// the refunded amounts are read again, the slice may be older than an earlier refund
+	ProductIDs := make([]uint, 0, len(OrderItems))
+	for _, item := range OrderItems {
+		ProductIDs = append(ProductIDs, item.ProductID)
+	}
+	var Items []model.OrderItem
+	if err := tx.Where("order_id = ? AND product_id IN ?", Order.OrderID, ProductIDs).Order("product_id").Find(&Items).Error; err != nil {
+		return err
+	}
+	var left float64
+	for _, item := range Items {
+		left += item.AfterDeduction - item.RefundedAmount
+	}
+	if Amount > left+0.005 {
+		return ErrRefundExceedsPaid
 	}
 	var sum float64
 
 	// Iterate over each item to calculate individual refunds
-	for _, item := range OrderItems {
+	for i, item := range Items {
+		remaining := RoundDecimalValue(item.AfterDeduction - item.RefundedAmount)
+		share := remaining
+		if Amount < left-0.005 {
+			share = RoundDecimalValue(remaining * Amount / left)
+			if i == len(Items)-1 {
+				share = math.Min(RoundDecimalValue(Amount-sum), remaining)
+			}
+		}
+		if share <= 0 {
+			continue
+		}
+
+		// the item keeps what was refunded on it, the condition stops a concurrent refund going past it
+		Claim := tx.Model(&model.OrderItem{}).
+			Where("order_id = ? AND product_id = ? AND refunded_amount + ? <= after_deduction + 0.005", item.OrderID, item.ProductID, share).
+			UpdateColumn("refunded_amount", gorm.Expr("refunded_amount + ?", share))
+		if Claim.Error != nil {
+			return Claim.Error
+		}
+		if Claim.RowsAffected == 0 {
+			return ErrRefundExceedsPaid
+		}
 
 		// Fetch and update the restaurant's wallet amount
 		var Restaurant model.Restaurant
-		if err := database.DB.Where("id =?", item.RestaurantID).First(&Restaurant).Error; err != nil {
-			return false
+		if err := tx.Where("id =?", item.RestaurantID).First(&Restaurant).Error; err != nil {
+			return err
 		}
 
-		Restaurant.WalletAmount -= (item.AfterDeduction)
+		Restaurant.WalletAmount -= share
 
 		rWalletHistory := model.RestaurantWalletHistory{
 			TransactionTime: time.Now(),
 			Type:            model.WalletOutgoing,
 			OrderID:         item.OrderID,
 			RestaurantID:    item.RestaurantID,
-			Amount:          item.AfterDeduction,
+			Amount:          share,
 			CurrentBalance:  Restaurant.WalletAmount,
 			Reason:          "Order Refund",
 		}
 
-		if !CreateRestaurantWalletHistory(rWalletHistory) {
-			return false
+		if err := tx.Create(&rWalletHistory).Error; err != nil {
+			return err
 		}
 
-		if err := database.DB.Save(&Restaurant).Error; err != nil {
-			return false
+		if err := tx.Save(&Restaurant).Error; err != nil {
+			return err
 		}
+		metrics.WalletTransaction(metrics.OwnerRestaurant, false, rWalletHistory.Reason, rWalletHistory.Amount)
 
-		sum += item.AfterDeduction
+		sum += share
 	}
 
 	// Update the user's wallet amount
 	var User model.User
-	if err := database.DB.Where("id =?", UserID).First(&User).Error; err != nil {
-		return false
+	if err := tx.Where("id =?", UserID).First(&User).Error; err != nil {
+		return err
 	}
 	User.WalletAmount += (sum)
 
@@ -920,19 +878,20 @@ func ProvideWalletRefundToUser(UserID uint, OrderItems []model.OrderItem) bool {
 	WalletHistory.Amount = float64(sum)
 	WalletHistory.UserID = UserID
 	WalletHistory.OrderID = Order.OrderID
-	WalletHistory.Reason = model.WalletTxTypeOrderRefund
+	WalletHistory.Reason = Reason
 	WalletHistory.CurrentBalance = User.WalletAmount
 	WalletHistory.Type = model.WalletIncoming
 
-	if err := database.DB.Create(&WalletHistory).Error; err != nil {
-		return false
+	if err := tx.Create(&WalletHistory).Error; err != nil {
+		return err
 	}
 
-	if err := database.DB.Save(&User).Error; err != nil {
-		return false
+	if err := tx.Save(&User).Error; err != nil {
+		return err
 	}
+	metrics.WalletTransaction(metrics.OwnerUser, true, WalletHistory.Reason, WalletHistory.Amount)
 
-	return true
+	return nil
 }
 
 func SplitMoneyToRestaurants(OrderID string) bool {
@@ -981,6 +940,8 @@ func SplitMoneyToRestaurants(OrderID string) bool {
 }
 
 func IncrementStock(OrderItems []model.OrderItem) bool {
+	//listed stock changes, so cached product lists are dropped even when a later item fails
+	defer cache.Invalidate(cache.TagProducts)
 
 	//loop and get the cancelled orders
 	for _, v := range OrderItems {
@@ -1007,169 +968,113 @@ func DecrementStock(OrderID string) bool {
 		return false
 	}
 
-	//loop and get the cancelled orders
-	for _, v := range OrderItems {
-		if v.OrderStatus == model.OrderStatusProcessing || v.OrderStatus == model.OrderStatusInPreparation {
-			//get product id
-			var Product model.Product
-			if err := database.DB.Where("id = ?", v.ProductID).First(&Product).Error; err != nil {
-				return false
+	//bundles are stored as their component items, so each component is decremented on its own.
+	//all of them in one transaction so a component out of stock leaves the others untouched
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, v := range OrderItems {
+			if v.OrderStatus != model.OrderStatusInitiated && v.OrderStatus != model.OrderStatusProcessing && v.OrderStatus != model.OrderStatusInPreparation {
+				continue
 			}
-			//update stock left by decrementing it by producty.stockleft - v.quantity
-			Product.StockLeft -= v.Quantity
-			if Product.StockLeft <= 0 {
-				return false
+			//decrement in the query itself only when enough is left, concurrent orders can't both take the last items
+			result := tx.Model(&model.Product{}).Where("id = ? AND stock_left >= ?", v.ProductID, v.Quantity).Update("stock_left", gorm.Expr("stock_left - ?", v.Quantity))
+			if result.Error != nil {
+				return result.Error
 			}
-			if err := database.DB.Updates(&Product).Error; err != nil {
-				return false
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("product %v is out of stock", v.ProductID)
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return false
 	}
+	cache.Invalidate(cache.TagProducts)
 	return true
 }
 
-// user - check userid by order.userid
+// user - add or edit the review of a delivered item, the previous review is kept in its history
 func UserReviewonOrderItem(c *gin.Context) {
 	//check user api authentication
-	email, role, err := utils.GetJWTClaim(c)
-	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+	UserID, ok := reviewer(c)
+	if !ok {
 		return
 	}
-	JWTUserID, _ := UserIDfromEmail(email)
 
 	//orderid, productid,review text
 	var Request model.UserReviewonOrderItem
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
 		return
 	}
-	oUserID, _ := UserIDfromOrderID(Request.OrderID)
-	if JWTUserID != oUserID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
 		return
 	}
 
-	//get orderitem
-	var OrderItem model.OrderItem
-	if err := database.DB.Where("order_id = ? AND product_id = ?", Request.OrderID, Request.ProductID).First(&OrderItem).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retreive the order item",
-		})
+	//delivered and still within the review window
+	OrderItem, ok := reviewableItem(c, UserID, Request.OrderID, Request.ProductID)
+	if !ok {
 		return
 	}
-	//check delivered
-	if OrderItem.OrderStatus != model.OrderStatusDelivered {
-		c.JSON(http.StatusConflict, gin.H{"status": false, "message": "reviews can only be added after order is delivered"})
+	if OrderItem.OrderReview == Request.ReviewText {
+		response.OK(c, "successfully added the review", nil)
 		return
 	}
-	//check review text
-	OrderItem.OrderReview = Request.ReviewText
-	if err := database.DB.Where("order_id = ? AND product_id = ?", Request.OrderID, Request.ProductID).Updates(&OrderItem).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to add order review, please try again"})
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return saveItemReview(tx, OrderItem, Request.ReviewText, OrderItem.OrderRating)
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to add order review, please try again")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "successfully added the review"})
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully added the review", nil)
 }
 
-// user - check userid by order.userid
+// user - add or change the rating of a delivered item, the product rating is recomputed from every rated item
 func UserRatingOrderItem(c *gin.Context) {
 	//check user api authentication
-	email, role, err := utils.GetJWTClaim(c)
-	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+	UserID, ok := reviewer(c)
+	if !ok {
 		return
 	}
-	JWTUserID, _ := UserIDfromEmail(email)
 
 	//get the orderid,productid,rating
 	var Request model.UserRatingOrderItem
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "failed to bind the json"})
-		return
-	}
-
-	oUserID, _ := UserIDfromOrderID(Request.OrderID)
-	if JWTUserID != oUserID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the json")
 		return
 	}
-
-	if Request.UserRating <= 0 && Request.UserRating > 5 {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "enter a valid rating between 1 to 5"})
-		return
-	}
-
-	//check if user already rated
-	var OrderItem model.OrderItem
-	if err := database.DB.Where("order_id = ? AND product_id = ?", Request.OrderID, Request.ProductID).First(&OrderItem).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "failed to retrieve order information"})
-		return
-	}
-
-	if OrderItem.OrderStatus != model.OrderStatusDelivered {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "user can only rate an order after delivery"})
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
 		return
 	}
 
-	if OrderItem.OrderRating != 0 {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "user already rated the order"})
+	OrderItem, ok := reviewableItem(c, UserID, Request.OrderID, Request.ProductID)
+	if !ok {
 		return
 	}
 
-	//get the count of the product rating and current avg rating
-	//use rating_sum + add_new_rating /(rating_count+1)\
-	//update the product rating,count etc
-	//update the rating on order items
-	tx := database.DB.Begin()
-
-	if err := tx.Model(&model.OrderItem{}).Where("order_id = ? AND product_id = ?", Request.OrderID, Request.ProductID).Update("order_rating", Request.UserRating).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to update order rating in the order item table for the specified product"})
+	//a new rating replaces the old one, the old one goes to the review history
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return saveItemReview(tx, OrderItem, OrderItem.OrderReview, Request.UserRating)
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to update product rating")
 		return
 	}
 	var product model.Product
-	if err := tx.Where("id = ?", Request.ProductID).First(&product).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "product not found"})
+	if err := database.DB.Where("id = ?", Request.ProductID).First(&product).Error; err != nil {
+		response.Error(c, response.CodeInternal, "product not found")
 		return
 	}
 
-	//update the products rating sum and count
-	newRatingSum := product.RatingSum + Request.UserRating
-	newRatingCount := product.RatingCount + 1
-	newAverageRating := newRatingSum / float64(newRatingCount)
-
-	if err := tx.Model(&product).Updates(model.Product{RatingSum: newRatingSum, RatingCount: newRatingCount, AverageRating: newAverageRating}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to update product rating"})
-		return
-	}
+	cache.Invalidate(cache.TagProducts)
 
-	//commit the tx
-	if err := tx.Commit().Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to update product rating"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "successfully updated rating", "new_average_rating": newAverageRating})
+	response.OK(c, "successfully updated rating", gin.H{"new_average_rating": product.AverageRating})
 }
 
 func UpdatePaymentGatewayMethod(OrderID string, PaymentGateway string) bool {
@@ -1184,25 +1089,19 @@ func GetOrderInfoByOrderIDasJSON(c *gin.Context) {
 
 	OrderID := c.Query("order_id")
 	if OrderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "order_id is empty,mention order_id as query params"})
+		response.Error(c, response.CodeBadRequest, "order_id is empty,mention order_id as query params")
 		return
 	}
 
 	var Order model.Order
 	if err := database.DB.Where("order_id = ?", OrderID).First(&Order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "order_id is not present",
-		})
+		response.Error(c, response.CodeNotFound, "order_id is not present")
 		return
 	}
 
 	var OrderItems []model.OrderItem
 	if err := database.DB.Where("order_id = ?", OrderID).Find(&OrderItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "no order items for order_id in the table,make sure the order contains order items",
-		})
+		response.Error(c, response.CodeInternal, "no order items for order_id in the table,make sure the order contains order items")
 		return
 	}
 
@@ -1210,20 +1109,16 @@ func GetOrderInfoByOrderIDasJSON(c *gin.Context) {
 		OrderItems[i].AfterDeduction = RoundDecimalValue(OrderItems[i].AfterDeduction)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Successfully retrieved OrderInformation",
-		"data": gin.H{
-			"order_information": Order,
-			"items_ordered":     OrderItems,
-		},
+	response.OK(c, "Successfully retrieved OrderInformation", gin.H{
+		"order_information": Order,
+		"items_ordered":     OrderItems,
 	})
 }
 
 func CancelCODOrder(c *gin.Context) {
 	OrderID := c.Query("order_id")
 	if OrderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "order_id is empty,mention order_id as query params"})
+		response.Error(c, response.CodeBadRequest, "order_id is empty,mention order_id as query params")
 		return
 	}
 
@@ -1231,12 +1126,12 @@ func CancelCODOrder(c *gin.Context) {
 func SendOrderDeliveryVerificationCode(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "unauthorized request"})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	OrderID := c.Query("order_id")
 	if OrderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": "order_id is empty,mention order_id as query params"})
+		response.Error(c, response.CodeBadRequest, "order_id is empty,mention order_id as query params")
 		return
 	}
 
@@ -1244,17 +1139,17 @@ func SendOrderDeliveryVerificationCode(c *gin.Context) {
 	JWTUserID, _ := UserIDfromEmail(email)
 
 	if JWTUserID != oUserID {
-		c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "unauthorized request"})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	otp, err := SendOrderDeliveryCode(OrderID)
 	if err != nil {
-		c.JSON(http.StatusNotImplemented, gin.H{"status": false, "message": err.Error()})
+		response.Error(c, response.CodeInvalidState, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": false, "message": "otp is sent in mail", "otp": otp})
+	response.OK(c, "otp is sent in mail", gin.H{"otp": otp})
 }
 
 // function that sends the code
@@ -1287,9 +1182,6 @@ func SendOrderDeliveryCode(OrderID string) (int64, error) {
 
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	DeliveryVerification.OTP = uint(r.Intn(900000) + 100000)
-	from := "foodbuddycode@gmail.com"
-	appPassword := os.Getenv("SMTPAPP")
-	auth := smtp.PlainAuth("", from, appPassword, "smtp.gmail.com")
 	htmlContent := fmt.Sprintf(`
 	<!DOCTYPE html>
 	<html lang="en">
@@ -1327,7 +1219,7 @@ func SendOrderDeliveryCode(OrderID string) (int64, error) {
 	UserID, _ := UserIDfromOrderID(OrderID)
 	var User model.User
 	database.DB.Where("id = ?", UserID).First(&User)
-	err := smtp.SendMail("smtp.gmail.com:587", auth, from, []string{User.Email}, []byte(msg))
+	err := gateway.Get().Mail.Send([]string{User.Email}, []byte(msg))
 	if err != nil {
 		return 0, errors.New("failed to send email")
 	}
@@ -1343,16 +1235,13 @@ func SendOrderDeliveryCode(OrderID string) (int64, error) {
 func ConfirmCODPayment(c *gin.Context) {
 	var Request model.ConfirmCODPayment
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "provide order_id in the json payload" + err.Error()})
+		response.Error(c, response.CodeBadRequest, "provide order_id in the json payload"+err.Error())
 		return
 	}
 
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -1361,82 +1250,84 @@ func ConfirmCODPayment(c *gin.Context) {
 	//get order information
 	var Order model.Order
 	if err := database.DB.Where("order_id = ?", Request.OrderID).First(&Order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "order_id is not present"})
+		response.Error(c, response.CodeNotFound, "order_id is not present")
 		return
 	}
 
 	if RestID != Order.RestaurantID {
-		c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "unauthorized request,order doesnt belong to this restaurant"})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request,order doesnt belong to this restaurant")
 		return
 	}
 
 	//check if the order is COD
 	if Order.PaymentMethod != model.CashOnDelivery {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "order_id given is not COD"})
+		response.Error(c, response.CodeInvalidState, "order_id given is not COD")
 		return
 	}
 
 	if Order.PaymentStatus == model.CODStatusConfirmed {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "order_id payment already done"})
+		response.Error(c, response.CodeInvalidState, "order_id payment already done")
 		return
 	}
 
 	Order.PaymentStatus = model.CODStatusConfirmed
 	if err := database.DB.Where("order_id = ?", Request.OrderID).Updates(&Order).Error; err != nil {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "failed to update COD payment status to paid, please try again"})
+		response.Error(c, response.CodeInvalidState, "failed to update COD payment status to paid, please try again")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "order COD payment confirmed"})
+	response.OK(c, "order COD payment confirmed", nil)
 
 }
 
 func DeliveryComplete(c *gin.Context) {
 	var Request model.ConfirmDelivery
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "order_id and delivery_otp should be present on the json payload"})
+		response.Error(c, response.CodeNotFound, "order_id and delivery_otp should be present on the json payload")
 		return
 	}
 
 	// Get order information
 	var Order model.Order
 	if err := database.DB.Where("order_id = ?", Request.OrderID).First(&Order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "order_id is not present"})
+		response.Error(c, response.CodeNotFound, "order_id is not present")
 		return
 	}
 
 	if Order.PaymentStatus != model.OnlinePaymentConfirmed && Order.PaymentStatus != model.CODStatusConfirmed {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "payment should be confirmed before using this endpoint"})
+		response.Error(c, response.CodeInvalidState, "payment should be confirmed before using this endpoint")
 		return
 	}
 
 	var OrderItems []model.OrderItem
 	if err := database.DB.Where("order_id = ?", Request.OrderID).Find(&OrderItems).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch order items"})
+		response.Error(c, response.CodeNotFound, "failed to fetch order items")
 		return
 	}
 
 	var DeliveryVerification model.DeliveryVerification
 	if err := database.DB.Where("order_id = ?", Request.OrderID).First(&DeliveryVerification).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch delivery verification information"})
+		response.Error(c, response.CodeNotFound, "failed to fetch delivery verification information")
 		return
 	}
 
 	if Request.DeliveryOTP != DeliveryVerification.OTP {
-		c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "delivery_otp provided doesnt match with database"})
+		response.Error(c, response.CodeUnauthorized, "delivery_otp provided doesnt match with database")
 		return
 	}
 
+	DeliveredAt := time.Now()
 	for _, item := range OrderItems {
 		if item.OrderStatus != model.OrderStatusCancelled {
 			item.OrderStatus = model.OrderStatusDelivered
+			item.DeliveredAt = &DeliveredAt
 			if err := database.DB.Where("order_id = ? AND product_id = ?", item.OrderID, item.ProductID).Save(&item).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to update order item status"})
+				response.Error(c, response.CodeInternal, "failed to update order item status")
 				return
 			}
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "order status updated to delivered"})
+	response.OK(c, "order status updated to delivered", nil)
 
 }
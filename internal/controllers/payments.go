@@ -6,18 +6,19 @@ import (
 	"encoding/hex"
 	"fmt"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/metrics"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
 	"math"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/razorpay/razorpay-go"
 	"github.com/stripe/stripe-go/v78"
-	"github.com/stripe/stripe-go/v78/checkout/session"
 )
 
 func RoundDecimalValue(value float64) float64 {
@@ -27,8 +28,7 @@ func RoundDecimalValue(value float64) float64 {
 
 func HandleRazorpay(c *gin.Context, initiatePayment model.InitiatePayment, order model.Order) {
 	// Create Razorpay order
-	fmt.Println(initiatePayment, order)
-	client := razorpay.NewClient(os.Getenv("RAZORPAY_KEY_ID"), os.Getenv("RAZORPAY_KEY_SECRET"))
+	logging.From(c).Info("creating razorpay order", "order_id", initiatePayment.OrderID, "amount", order.FinalAmount)
 	data := map[string]interface{}{
 		"amount":          order.FinalAmount * 100, // Amount in paisa
 		"currency":        "INR",
@@ -36,15 +36,13 @@ func HandleRazorpay(c *gin.Context, initiatePayment model.InitiatePayment, order
 		"payment_capture": 1,
 	}
 
-	fmt.Println(data)
-	rzpOrder, err := client.Order.Create(data, nil)
+	start := time.Now()
+	rzpOrder, err := gateway.Get().Razorpay.CreateOrder(data)
+	metrics.GatewayCall(metrics.GatewayRazorpay, "create_order", start, err)
 	if err != nil {
+		logging.From(c).Error("failed to create razorpay order", "order_id", initiatePayment.OrderID, "error", err)
 		PaymentFailedOrderTable(initiatePayment.OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    err.Error(),
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeUpstream, "failed to create razorpay order")
 		return
 	}
 
@@ -58,21 +56,17 @@ func HandleRazorpay(c *gin.Context, initiatePayment model.InitiatePayment, order
 	}
 	if err := database.DB.Create(&PaymentDetails).Error; err != nil {
 		PaymentFailedOrderTable(initiatePayment.OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "Failed to add Payment order details",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "Failed to add Payment order details")
 		return
 	}
 
-	callbackurl := fmt.Sprintf("https://%v/api/v1/user/order/step3/razorpaycallback/%v", utils.GetEnvVariables().ServerIP, initiatePayment.OrderID)
-	cancelurl := fmt.Sprintf("https://%v/api/v1/user/order/step3/razorpaycallback/failed/%v", utils.GetEnvVariables().ServerIP, initiatePayment.OrderID)
+	callbackurl := fmt.Sprintf("https://%v/api/v1/user/order/step3/razorpaycallback/%v", appConfig.Server.PublicHost, initiatePayment.OrderID)
+	cancelurl := fmt.Sprintf("https://%v/api/v1/user/order/step3/razorpaycallback/failed/%v", appConfig.Server.PublicHost, initiatePayment.OrderID)
 
 	responseData := map[string]interface{}{
 		"razorpay_order_id": rzpOrder["id"],
 		"amount":            rzpOrder["amount"],
-		"key":               os.Getenv("RAZORPAY_KEY_ID"),
+		"key":               appConfig.Razorpay.KeyID,
 		"callbackurl":       callbackurl,
 		"cancelurl":         cancelurl,
 	}
@@ -83,36 +77,26 @@ func HandleRazorpay(c *gin.Context, initiatePayment model.InitiatePayment, order
 func RazorPayGatewayCallback(c *gin.Context) {
 
 	OrderID := c.Param("orderid")
-	fmt.Println(OrderID)
+	logging.From(c).Info("razorpay payment callback", "order_id", OrderID)
 	if OrderID == "" {
 
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to get orderid",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to get orderid")
 		return
 	}
 
 	var RazorpayPayment model.RazorpayPayment
 	if err := c.ShouldBind(&RazorpayPayment); err != nil {
 		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind Razorpay payment details" + err.Error(),
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind Razorpay payment details"+err.Error())
 		return
 	}
 
-	fmt.Println(RazorpayPayment)
-
 	// Now you can proceed with your verification logic
-	if !verifyRazorpaySignature(RazorpayPayment.OrderID, RazorpayPayment.PaymentID, RazorpayPayment.Signature, os.Getenv("RAZORPAY_KEY_SECRET")) {
+	if !verifyRazorpaySignature(RazorpayPayment.OrderID, RazorpayPayment.PaymentID, RazorpayPayment.Signature, appConfig.Razorpay.KeySecret) {
+		metrics.Payment(metrics.GatewayRazorpay, false)
 		PaymentFailedOrderTable(OrderID)
 		PaymentFailedPaymentTable(RazorpayPayment.OrderID)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to verify",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to verify")
 		return
 	}
 
@@ -125,66 +109,57 @@ func RazorPayGatewayCallback(c *gin.Context) {
 	if err := database.DB.Where("order_id = ? AND razorpay_order_id = ?", OrderID, PaymentDetails.RazorpayOrderID).Updates(&PaymentDetails).Error; err != nil {
 		PaymentFailedOrderTable(OrderID)
 		PaymentFailedPaymentTable(RazorpayPayment.OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update payment informations",
-		})
+		response.Error(c, response.CodeInternal, "failed to update payment informations")
 		return
 	}
 
-	var Order model.Order
-	if err := database.DB.Model(&Order).Where("order_id = ?", OrderID).Update("payment_status", model.OnlinePaymentConfirmed).Error; err != nil {
+	metrics.Payment(metrics.GatewayRazorpay, true)
+
+	confirmed, err := confirmOnlinePayment(OrderID)
+	if err != nil {
 		PaymentFailedOrderTable(OrderID)
 		PaymentFailedPaymentTable(RazorpayPayment.OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update payment informations",
+		response.Error(c, response.CodeInternal, "failed to update payment informations")
+		return
+	}
+	if !confirmed {
+		response.OK(c, "payment is already confirmed", gin.H{
+			"paymentdata": RazorpayPayment,
 		})
 		return
 	}
 
 	//update all the orderitems as intiated
 	if err := database.DB.Model(&model.OrderItem{}).Where("order_id = ?", OrderID).Update("order_status", model.OrderStatusInitiated).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update order status",
-		})
+		response.Error(c, response.CodeInternal, "failed to update order status")
 		return
 	}
 
 	//decrement stock based on orderid
 	done := DecrementStock(OrderID)
 	if !done {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to decrement order stock",
-		})
+		response.Error(c, response.CodeInternal, "failed to decrement order stock")
 		return
 	}
 
 	//update payment for each restaurant by splitting payment for each restaurant
 	done = SplitMoneyToRestaurants(OrderID)
 	if !done {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to split payment for restaurant",
-		})
+		response.Error(c, response.CodeInternal, "failed to split payment for restaurant")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data": gin.H{
-			"paymentdata": RazorpayPayment,
-		},
+	response.OK(c, "payment successful", gin.H{
+		"paymentdata": RazorpayPayment,
 	})
 }
 
 func RazorPayFailed(c *gin.Context) {
 	OrderID := c.Param("orderid")
-	fmt.Println(OrderID)
+	logging.From(c).Warn("razorpay payment failed", "order_id", OrderID)
+	metrics.Payment(metrics.GatewayRazorpay, false)
 	if OrderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "failed to get orderid"})
+		response.Error(c, response.CodeBadRequest, "failed to get orderid")
 		return
 	}
 
@@ -193,7 +168,6 @@ func RazorPayFailed(c *gin.Context) {
 }
 
 func HandleStripe(c *gin.Context, initiatePayment model.InitiatePayment, order model.Order) {
-	stripe.Key = os.Getenv("STRIPE_KEY")
 
 	totalAmount := order.FinalAmount * 100 // Convert to paise, same as Razorpay
 
@@ -214,13 +188,16 @@ func HandleStripe(c *gin.Context, initiatePayment model.InitiatePayment, order m
 		},
 		Metadata:   map[string]string{"order_id": order.OrderID},
 		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
-		SuccessURL: stripe.String(fmt.Sprintf("https://%v/api/v1/user/order/step3/stripecallback?session_id={CHECKOUT_SESSION_ID}", utils.GetEnvVariables().ServerIP)),
-		CancelURL:  stripe.String(fmt.Sprintf("https://%v/api/v1/user/order/step3/stripecallback?session_id={CHECKOUT_SESSION_ID}", utils.GetEnvVariables().ServerIP)),
+		SuccessURL: stripe.String(fmt.Sprintf("https://%v/api/v1/user/order/step3/stripecallback?session_id={CHECKOUT_SESSION_ID}", appConfig.Server.PublicHost)),
+		CancelURL:  stripe.String(fmt.Sprintf("https://%v/api/v1/user/order/step3/stripecallback?session_id={CHECKOUT_SESSION_ID}", appConfig.Server.PublicHost)),
 	}
 
-	s, err := session.New(params)
+	start := time.Now()
+	s, err := gateway.Get().Stripe.NewCheckoutSession(params)
+	metrics.GatewayCall(metrics.GatewayStripe, "create_session", start, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logging.From(c).Error("failed to create stripe session", "error", err)
+		response.Error(c, response.CodeUpstream, "failed to create stripe checkout session")
 		return
 	}
 
@@ -231,37 +208,31 @@ func HandleStripe(c *gin.Context, initiatePayment model.InitiatePayment, order m
 	}
 
 	if err := database.DB.Create(&StripePaymentDetail).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  "error",
-			"message": "failed to store stripe payment information",
-			"error":   err.Error(),
-		})
+		response.Internal(c, "failed to store stripe payment information", err)
 		return
 	}
 
 	// Return the URL to the client
-	c.JSON(http.StatusSeeOther, gin.H{"url": s.URL})
+	response.OK(c, "continue the payment on stripe", gin.H{"url": s.URL})
 }
 
 func StripeCallback(c *gin.Context) {
 	sessionID := c.Query("session_id")
 
 	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing session_id"})
+		response.Error(c, response.CodeBadRequest, "Missing session_id")
 		return
 	}
 
 	// Set your Stripe secret key
-	stripe.Key = os.Getenv("STRIPE_KEY")
 
 	//using session id get the stripe session info, payment information and its id
-	stripeSession, err := session.Get(sessionID, nil)
+	start := time.Now()
+	stripeSession, err := gateway.Get().Stripe.GetCheckoutSession(sessionID)
+	metrics.GatewayCall(metrics.GatewayStripe, "get_session", start, err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  "error",
-			"message": "Failed to retrieve session details from Stripe",
-			"error":   err.Error(),
-		})
+		logging.From(c).Error("failed to retrieve stripe session", "error", err)
+		response.Error(c, response.CodeUpstream, "Failed to retrieve session details from Stripe")
 		return
 	}
 
@@ -271,75 +242,55 @@ func StripeCallback(c *gin.Context) {
 		StripePaymentID: stripeSession.PaymentIntent.ID,
 	}
 
+	metrics.Payment(metrics.GatewayStripe, stripeSession.PaymentStatus == "paid")
+
 	if stripeSession.PaymentStatus == "paid" {
 		StripePayment.PaymentStatus = model.OnlinePaymentConfirmed
 		if err := database.DB.Where("stripe_session_id = ?", stripeSession.ID).Updates(&StripePayment).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  "error",
-				"message": "Failed to update status of payment",
-				"error":   err.Error(),
-			})
+			response.Internal(c, "Failed to update status of payment", err)
 			return
 		}
 
-		//update payment status on order as well
-		if err := database.DB.Model(&model.Order{}).Where("order_id = ?", OrderID).Update("payment_status", model.OnlinePaymentConfirmed).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  "error",
-				"message": "Failed to update status of payment",
-				"error":   err.Error(),
-			})
+		//update payment status on order as well, a reloaded success page finds it confirmed already
+		confirmed, err := confirmOnlinePayment(OrderID)
+		if err != nil {
+			response.Internal(c, "Failed to update status of payment", err)
 			return
 		}
+		if confirmed {
 			//update all the orderitems as intiated
-		if err := database.DB.Model(&model.OrderItem{}).Where("order_id = ?", OrderID).Update("order_status", model.OrderStatusInitiated).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  false,
-				"message": "failed to update order status",
-			})
-			return
-		}
-		//decrement stock based on orderid
-		done := DecrementStock(OrderID)
-		if !done {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  false,
-				"message": "failed to decrement order stock",
-			})
-			return
-		}
-
-		//update payment for each restaurant by splitting payment for each restaurant
-		done = SplitMoneyToRestaurants(OrderID)
-		if !done {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  false,
-				"message": "failed to split payment for restaurant",
-			})
-			return
+			if err := database.DB.Model(&model.OrderItem{}).Where("order_id = ?", OrderID).Update("order_status", model.OrderStatusInitiated).Error; err != nil {
+				response.Error(c, response.CodeInternal, "failed to update order status")
+				return
+			}
+			//decrement stock based on orderid
+			done := DecrementStock(OrderID)
+			if !done {
+				response.Error(c, response.CodeInternal, "failed to decrement order stock")
+				return
+			}
+
+			//update payment for each restaurant by splitting payment for each restaurant
+			done = SplitMoneyToRestaurants(OrderID)
+			if !done {
+				response.Error(c, response.CodeInternal, "failed to split payment for restaurant")
+				return
+			}
 		}
 	} else {
 		StripePayment.PaymentStatus = model.OnlinePaymentFailed
 		if err := database.DB.Where("stripe_payment_id = ?", stripeSession.PaymentIntent.ID).Updates(&StripePayment).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  "error",
-				"message": "Failed to update status of payment",
-				"error":   err.Error(),
-			})
+			response.Internal(c, "Failed to update status of payment", err)
 			return
 		}
 		if err := database.DB.Model(&model.Payment{}).Where("order_id = ?", OrderID).Update("payment_status", model.OnlinePaymentFailed).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  "error",
-				"message": "Failed to update status of payment",
-				"error":   err.Error(),
-			})
+			response.Internal(c, "Failed to update status of payment", err)
 			return
 		}
 
 	}
 
-	response := gin.H{
+	paymentData := gin.H{
 		"message": "Payment complete",
 		"status":  "complete",
 		"stripe": gin.H{
@@ -357,11 +308,8 @@ func StripeCallback(c *gin.Context) {
 		},
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data": gin.H{
-			"paymentdata": response,
-		},
+	response.OK(c, "payment successful", gin.H{
+		"paymentdata": paymentData,
 	})
 }
 
@@ -386,71 +334,65 @@ func PaymentFailedPaymentTable(RazorpayOrderID string) bool {
 	return true
 }
 
-func GetUserWalletData(c *gin.Context) {
+func (h *Handler) GetUserWalletData(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
 
-	var User model.User
-	if err := database.DB.Where("id = ?", UserID).First(&User).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": false, "message": "failed to get wallet balance",
-		})
+	User, err := h.Users.ByEmail(email)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get wallet balance")
 		return
 	}
 
-	var Result []model.UserWalletHistory
-	if err := database.DB.Where("user_id = ?", UserID).Find(&Result).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": false, "message": "failed to get wallet history",
-		})
+	Result, err := h.Wallets.UserHistory(User.ID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get wallet history")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true, "data": gin.H{
-			"walletbalance": User.WalletAmount,
-			"history":       Result,
-		},
+	response.OK(c, "successfully retrieved wallet", gin.H{
+		"walletbalance": User.WalletAmount,
+		"history":       Result,
 	})
 
 }
 
+
 func HandleWalletPayment(OrderID string, UserID uint, c *gin.Context) {
 	// Verify if user has sufficient wallet balance
 	var user model.User
 	if err := database.DB.Where("id = ?", UserID).First(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "failed to fetch user details",
-			"error_code": http.StatusInternalServerError,
-		})
+		response.Error(c, response.CodeInternal, "failed to fetch user details")
 		return
 	}
 
 	var order model.Order
 	if err := database.DB.Where("order_id = ?", OrderID).First(&order).Error; err != nil {
 		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to fetch order information",
-		})
+		response.Error(c, response.CodeInternal, "failed to fetch order information")
 		return
 	}
 
 	if float64(user.WalletAmount) < order.FinalAmount {
+		metrics.Payment(metrics.GatewayWallet, false)
 		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusPaymentRequired, gin.H{
-			"status":  false,
-			"message": "insufficient wallet balance",
-		})
+		response.Error(c, response.CodeInsufficientFunds, "insufficient wallet balance")
+		return
+	}
+
+	//the order is confirmed before the wallet is touched, a second payment of the same order stops here
+	confirmed, err := confirmOnlinePayment(OrderID)
+	if err != nil {
+		PaymentFailedOrderTable(OrderID)
+		response.Error(c, response.CodeInternal, "failed to update payment status")
+		return
+	}
+	if !confirmed {
+		response.Error(c, response.CodeInvalidState, "payment is already confirmed")
 		return
 	}
 
@@ -458,10 +400,7 @@ func HandleWalletPayment(OrderID string, UserID uint, c *gin.Context) {
 	newBalance := float64(user.WalletAmount) - order.FinalAmount
 	if err := database.DB.Model(&user).Update("wallet_amount", newBalance).Error; err != nil {
 		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to deduct wallet balance",
-		})
+		response.Error(c, response.CodeInternal, "failed to deduct wallet balance")
 		return
 	}
 
@@ -479,12 +418,11 @@ func HandleWalletPayment(OrderID string, UserID uint, c *gin.Context) {
 
 	if err := database.DB.Create(&walletHistory).Error; err != nil {
 		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to record wallet transaction",
-		})
+		response.Error(c, response.CodeInternal, "failed to record wallet transaction")
 		return
 	}
+	metrics.WalletTransaction(metrics.OwnerUser, false, walletHistory.Reason, walletHistory.Amount)
+	metrics.Payment(metrics.GatewayWallet, true)
 
 	// Update payment status
 	PaymentDetails := model.Payment{
@@ -494,138 +432,120 @@ func HandleWalletPayment(OrderID string, UserID uint, c *gin.Context) {
 	}
 	if err := database.DB.Model(&model.Payment{}).Where("order_id = ?", OrderID).Updates(&PaymentDetails).Error; err != nil {
 		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update payment information",
-		})
+		response.Error(c, response.CodeInternal, "failed to update payment information")
 		return
 	}
 
-	if err := database.DB.Model(&order).Where("order_id = ?", OrderID).Update("payment_status", model.OnlinePaymentConfirmed).Error; err != nil {
-		PaymentFailedOrderTable(OrderID)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update payment status",
-		})
-		return
-	}
 
 	// Decrement stock based on order ID
 	if !DecrementStock(OrderID) {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to decrement order stock",
-		})
+		response.Error(c, response.CodeInternal, "failed to decrement order stock")
 		return
 	}
 
 	// Split payment for each restaurant
 	if !SplitMoneyToRestaurants(OrderID) {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to split payment for restaurants",
-		})
+		response.Error(c, response.CodeInternal, "failed to split payment for restaurants")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data": gin.H{
-			"payment": OrderID + " Status : Payment Confirmed, Payment Method :" + model.Wallet,
-		},
+	response.OK(c, "payment successful", gin.H{
+		"payment": OrderID + " Status : Payment Confirmed, Payment Method :" + model.Wallet,
 	})
 }
 
+// confirmOnlinePayment moves the order to ONLINE_CONFIRMED and reports whether this call moved it,
+// replayed callbacks find it confirmed and must not take the stock or pay the restaurants again
+func confirmOnlinePayment(OrderID string) (bool, error) {
+	result := database.DB.Model(&model.Order{}).Where("order_id = ? AND payment_status <> ?", OrderID, model.OnlinePaymentConfirmed).
+		Update("payment_status", model.OnlinePaymentConfirmed)
+	return result.RowsAffected == 1, result.Error
+}
+
 func CreateRestaurantWalletHistory(r model.RestaurantWalletHistory) bool {
 	if err := database.DB.Create(&r).Error; err != nil {
 		return false
 	}
+	metrics.WalletTransaction(metrics.OwnerRestaurant, r.Type == model.WalletIncoming, r.Reason, r.Amount)
 	return true
 }
 
 func VerifyOnlinePayment(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	UserID, _ := UserIDfromEmail(email)
 	OrderID := c.Query("order_id")
 	if OrderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "please provide the orderid in the query"})
+		response.Error(c, response.CodeBadRequest, "please provide the orderid in the query")
 		return
 	}
 
 	oUserID, _ := UserIDfromOrderID(OrderID)
 	if UserID != oUserID {
-		c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "please provide orderid from the orders you have initiated"})
+		response.Error(c, response.CodeUnauthorized, "please provide orderid from the orders you have initiated")
 		return
 	}
 
 	var PaymentInfo model.Payment
 	if err := database.DB.Where("order_id = ? AND payment_status = ?", OrderID, model.OnlinePaymentConfirmed).First(&PaymentInfo).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "online payment is not done"})
+		response.Error(c, response.CodeNotFound, "online payment is not done")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "online payment done",
-		"data":    PaymentInfo,
-	})
+	response.OK(c, "online payment done", PaymentInfo)
 }
 
 func ChangeOrderPaymentMode(c *gin.Context) { //check if payment confirmed, change the order items payment status to cod pending
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "unauthorized request"})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	JWTUserID, _ := UserIDfromEmail(email)
 
 	var Request model.ChangeOrderPaymentMode
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "make sure the order_id and the payment_mode exist on the json payload"})
+		response.Error(c, response.CodeBadRequest, "make sure the order_id and the payment_mode exist on the json payload")
 		return
 	}
 	//check if the order is of the user who is sending the request
 	oUserID, _ := UserIDfromOrderID(Request.OrderID)
 	if JWTUserID != oUserID {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "unauthorized request"})
+		response.Error(c, response.CodeBadRequest, "unauthorized request")
 		return
 	}
 
 	//check if the request is having COD or ONLINE
 	if Request.PaymentMethod != model.CashOnDelivery && Request.PaymentMethod != model.OnlinePayment {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "payment method should be either COD or ONLINE"})
+		response.Error(c, response.CodeNotFound, "payment method should be either COD or ONLINE")
 		return
 	}
 
 	//retreive the order
 	var Order model.Order
 	if err := database.DB.Where("order_id = ?", Request.OrderID).First(&Order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "order_id doesn't exist or failed to retrieve the order_id, try again"})
+		response.Error(c, response.CodeNotFound, "order_id doesn't exist or failed to retrieve the order_id, try again")
 		return
 	}
 
 	//check
 	if Request.PaymentMethod == model.CashOnDelivery && Order.TotalAmount >= model.CODMaximumAmount {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "switch to ONLINE payment for total amounts greater than or equal to 1000"})
+		response.Error(c, response.CodeInvalidState, "switch to ONLINE payment for total amounts greater than or equal to 1000")
 		return
 	}
 
 	//check if its already the same requested payment method
 	if Request.PaymentMethod == Order.PaymentMethod {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "payment method is already : " + Request.PaymentMethod})
+		response.Error(c, response.CodeInvalidState, "payment method is already : "+Request.PaymentMethod)
 		return
 	}
 
 	if Order.PaymentStatus == model.OnlinePaymentConfirmed || Order.PaymentStatus == model.CODStatusConfirmed {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{"status": false, "message": "payment is already done, cannot update the payment status"})
+		response.Error(c, response.CodeInvalidState, "payment is already done, cannot update the payment status")
 		return
 	}
 
@@ -634,20 +554,19 @@ func ChangeOrderPaymentMode(c *gin.Context) { //check if payment confirmed, chan
 	if Request.PaymentMethod == model.CashOnDelivery { //COD
 		Order.PaymentStatus = model.CODStatusPending
 		if err := database.DB.Where("order_id = ?", Request.OrderID).Updates(&Order).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to change payment method"})
+			response.Error(c, response.CodeInternal, "failed to change payment method")
 			return
 		}
-		c.JSON(http.StatusOK,
-			gin.H{"status": false, "message": "payment method changed to COD and the order is updated to COD_PENDING", "order_details": Order})
+		response.OK(c, "payment method changed to COD and the order is updated to COD_PENDING", gin.H{"order_details": Order})
 		return
 	}
 
 	//ONLINE
 	Order.PaymentStatus = model.OnlinePaymentPending
 	if err := database.DB.Where("order_id = ?", Request.OrderID).Updates(&Order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to change payment method"})
+		response.Error(c, response.CodeInternal, "failed to change payment method")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": false, "message": "payment method changed to " + Request.PaymentMethod})
+	response.OK(c, "payment method changed to "+Request.PaymentMethod, gin.H{"order_details": Order})
 }
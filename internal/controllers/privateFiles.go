@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"foodbuddy/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// whether the caller may see the private upload linked with url, only the records that link it decide
+func privateFileVisible(url string, key string, role string, email string) bool {
+	switch {
+	case strings.HasPrefix(key, storage.Document.Name+"/"):
+		if role == model.AdminRole {
+			return true
+		}
+		RestaurantID, ok := RestIDfromEmail(email)
+		if role != model.RestaurantRole || !ok {
+			return false
+		}
+		var Count int64
+		err := database.DB.Model(&model.RestaurantDocument{}).Where("file_url = ? AND restaurant_id = ?", url, RestaurantID).Count(&Count).Error
+		return err == nil && Count > 0
+	case strings.HasPrefix(key, storage.TicketAttachment.Name+"/"):
+		var Message model.SupportMessage
+		if err := database.DB.Where("attachment_url = ?", url).First(&Message).Error; err != nil {
+			return false
+		}
+		var Ticket model.SupportTicket
+		if err := database.DB.First(&Ticket, Message.TicketID).Error; err != nil {
+			return false
+		}
+		return ticketVisible(Ticket, role, email)
+	}
+	return false
+}
+
+// user, restaurant and admin - restaurant documents to admins and their restaurant,
+// ticket attachments to whoever can see the ticket
+func ServePrivateFile(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	url := storage.PrivatePath + c.Param("key")
+	key, ok := storage.PrivateKey(url)
+	//files of others look like they don't exist
+	if !ok || !privateFileVisible(url, key, role, email) {
+		response.Error(c, response.CodeNotFound, "file not found")
+		return
+	}
+
+	content, err := gateway.Get().Documents.Open(c.Request.Context(), key)
+	if err != nil {
+		logging.From(c).Error("failed to open private file", "key", key, "error", err)
+		response.Error(c, response.CodeNotFound, "file not found")
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, -1, storage.ContentType(key), content, map[string]string{
+		"Cache-Control":          "private, no-store",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -1,152 +1,125 @@
 package controllers
 
 import (
+	"errors"
+	"foodbuddy/internal/cache"
 	"foodbuddy/internal/database"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 // public
-func GetProductList(c *gin.Context) {
-	var Products []model.Product
-
-	tx := database.DB.Select("*").Find(&Products)
-	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve data from the database, or the product doesn't exist",
-		})
+func (h *Handler) GetProductList(c *gin.Context) {
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully retrieved products",
-		"data": gin.H{
-			"products": Products,
-		},
-	})
+	Products, err := h.Products.List(Page)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve data from the database, or the product doesn't exist")
+		return
+	}
+
+	Products, NextCursor := pagination.Trim(Page, Products, func(p model.Product) uint { return p.ID })
+	Response, err := Page.Project(Products)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	response.Page(c, "successfully retrieved products", gin.H{
+		"products": Response,
+	}, NextCursor)
 }
 
 // public
-func GetProductsByRestaurantID(c *gin.Context) {
+func (h *Handler) GetProductsByRestaurantID(c *gin.Context) {
 	restaurantIDStr := c.Query("restaurantid")
 	restaurantID, err := strconv.Atoi(restaurantIDStr)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "invalid restaurant ID",
-		})
+		response.Error(c, response.CodeNotFound, "invalid restaurant ID")
 		return
 	}
 
-	var products []model.Product
-	if err := database.DB.Where("restaurant_id =?", restaurantID).Find(&products).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to retrieve products",
-		})
+	products, err := h.Products.ListByRestaurant(uint(restaurantID))
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve products")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully retrieved products",
-		"data": gin.H{
-			"products": products,
-		},
+	response.OK(c, "successfully retrieved products", gin.H{
+		"products": products,
 	})
 }
 
 // restuarant id
-func AddProduct(c *gin.Context) {
+func (h *Handler) AddProduct(c *gin.Context) {
 
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	JWTRestaurantID, ok := RestIDfromEmail(email)
+	JWTRestaurantID, ok := h.restIDfromEmail(email)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to get restaurant information",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to get restaurant information")
 		return
 	}
 	// Bind JSON
 	var Request model.AddProductRequest
 
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process request")
 		return
 	}
 
 	if err := utils.Validate(Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	if Request.Veg != model.YES && Request.Veg != model.NO {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "please specify if the product is vegetarian by 'YES' or 'NO' ",
-		})
+		response.Error(c, response.CodeBadRequest, "please specify if the product is vegetarian by 'YES' or 'NO' ")
 		return
 	}
 
 	if Request.Price < Request.OfferAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "offer amount should not be more than the product price",
-		})
+		response.Error(c, response.CodeBadRequest, "offer amount should not be more than the product price")
 		return
 	}
 
 	// Check if the restaurant ID is correct and present in the database
-	var restaurant model.Restaurant
-	if err := database.DB.First(&restaurant, JWTRestaurantID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "restaurant not found",
-		})
+	restaurant, err := h.Restaurants.ByID(JWTRestaurantID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "restaurant not found")
+		return
+	}
+	if restaurant.VerificationStatus != model.VerificationStatusVerified {
+		response.Error(c, response.CodeForbidden, "complete the onboarding to add products")
 		return
 	}
 
 	// Check if the category is present
-	var category model.Category
-	if err := database.DB.First(&category, Request.CategoryID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "category doesn't exist",
-		})
+	if _, err := h.Categories.ByID(Request.CategoryID); err != nil {
+		response.Error(c, response.CodeNotFound, "category doesn't exist")
 		return
 	}
 
 	// Check if the product name already exists within the same restaurant
-	var existingProduct model.Product
-	if err := database.DB.Where("name =? AND restaurant_id =? AND deleted_at IS NULL", Request.Name, JWTRestaurantID).First(&existingProduct).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "product with the same name already exists in this restaurant",
-		})
+	if _, err := h.Products.ByName(JWTRestaurantID, Request.Name); err == nil {
+		response.Error(c, response.CodeConflict, "product with the same name already exists in this restaurant")
 		return
 	}
-	existingProduct = model.Product{
+	existingProduct := model.Product{
 		RestaurantID: JWTRestaurantID,
 		CategoryID:   Request.CategoryID,
 		Name:         Request.Name,
@@ -158,96 +131,65 @@ func AddProduct(c *gin.Context) {
 		Veg:          Request.Veg,
 	}
 
-	if err := database.DB.Create(&existingProduct).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to create product",
-		})
+	if err := h.Products.Create(&existingProduct); err != nil {
+		response.Error(c, response.CodeInternal, "failed to create product")
 		return
 	}
 
 	// Return a success response
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully added new product",
-		"data": gin.H{
-			"id":      existingProduct.ID,
-			"product": Request,
-		},
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully added new product", gin.H{
+		"id":      existingProduct.ID,
+		"product": Request,
 	})
 }
 
 // restaurant id
-func EditProduct(c *gin.Context) {
+func (h *Handler) EditProduct(c *gin.Context) {
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	JWTRestaurantID, ok := RestIDfromEmail(email)
+	JWTRestaurantID, ok := h.restIDfromEmail(email)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to get restaurant information",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to get restaurant information")
 		return
 	}
 
 	// Bind JSON
 	var Request model.EditProductRequest
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process request")
 		return
 	}
 
 	if err := utils.Validate(Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
-	//check jwt rest id and product rest id
-	pRestID := RestaurantIDByProductID(Request.ProductID)
-	if JWTRestaurantID != pRestID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request, product is not yours",
-		})
+	// Check if the product exists by id
+	existingProduct, err := h.Products.ByID(Request.ProductID)
+	//check jwt rest id and product rest id, a missing product belongs to no one
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, response.CodeBadRequest, "failed to fetch product from the database")
 		return
 	}
-
-	// Check if the product exists by id
-	var existingProduct model.Product
-	if err := database.DB.Where("id = ?", Request.ProductID).First(&existingProduct).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to fetch product from the database",
-		})
+	if JWTRestaurantID != existingProduct.RestaurantID {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request, product is not yours")
 		return
 	}
 
 	if Request.Veg != model.YES && Request.Veg != model.NO {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "please specify if the product is vegetarian by 'YES' or 'NO' ",
-		})
+		response.Error(c, response.CodeBadRequest, "please specify if the product is vegetarian by 'YES' or 'NO' ")
 		return
 	}
 
 	if Request.Price < Request.OfferAmount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "offer amount should not be more than the product price",
-		})
+		response.Error(c, response.CodeBadRequest, "offer amount should not be more than the product price")
 		return
 	}
 
@@ -260,40 +202,29 @@ func EditProduct(c *gin.Context) {
 	existingProduct.Veg = Request.Veg
 
 	// Update product details
-	if err := database.DB.Where("id = ?", Request.ProductID).Updates(&existingProduct).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update product",
-		})
+	if err := h.Products.Update(&existingProduct); err != nil {
+		response.Error(c, response.CodeInternal, "failed to update product")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully updated product information",
-		"data": gin.H{
-			"product": Request,
-		},
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully updated product information", gin.H{
+		"product": Request,
 	})
 }
 
 // restaurant id
-func DeleteProduct(c *gin.Context) {
+func (h *Handler) DeleteProduct(c *gin.Context) {
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	JWTRestaurantID, ok := RestIDfromEmail(email)
+	JWTRestaurantID, ok := h.restIDfromEmail(email)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to get restaurant information",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to get restaurant information")
 		return
 	}
 
@@ -301,58 +232,41 @@ func DeleteProduct(c *gin.Context) {
 	productIDStr := c.Query("productid")
 	productID, err := strconv.Atoi(productIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid product ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid product ID")
 		return
 	}
 	ProductRestaurantID := RestaurantIDByProductID(uint(productID))
 
 	if JWTRestaurantID != ProductRestaurantID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	// Check if the product exists by id
-	var product model.Product
-	if err := database.DB.First(&product, productID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "product is not present in the database",
-		})
+	if _, err := h.Products.ByID(uint(productID)); err != nil {
+		response.Error(c, response.CodeNotFound, "product is not present in the database")
 		return
 	}
 
 	//delete the product
-	if err := database.DB.Delete(&product, productID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "unable to delete the product from the database",
-		})
+	if err := h.Products.Delete(uint(productID)); err != nil {
+		response.Error(c, response.CodeInternal, "unable to delete the product from the database")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully deleted the product",
-	})
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully deleted the product", nil)
 }
 
 // user id
-func GetUsersFavouriteProduct(c *gin.Context) {
+func (h *Handler) GetUsersFavouriteProduct(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 
 	// //checking the user sending is performing in his/her account..
 	// _, ok := EmailFromUserID(UserID)
@@ -365,159 +279,105 @@ func GetUsersFavouriteProduct(c *gin.Context) {
 	// 	return
 	// }
 
-	var FavouriteProducts []model.FavouriteProduct
-
-	if err := database.DB.Where("user_id =?", UserID).Find(&FavouriteProducts).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "the user ID doesn't exist in the database",
-		})
+	FavouriteProducts, err := h.Products.Favourites(UserID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "the user ID doesn't exist in the database")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":         true,
-		"message":        "successfully retrieved favourite products",
+	response.OK(c, "successfully retrieved favourite products", gin.H{
 		"favourite_list": FavouriteProducts,
 	})
 }
 
 // user id
-func AddFavouriteProduct(c *gin.Context) {
+func (h *Handler) AddFavouriteProduct(c *gin.Context) {
 
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 
 	var request struct {
 		ProductID uint `validate:"required,number" json:"product_id"`
 	}
 
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind the JSON",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the JSON")
 		return
 	}
 
 	// Extracted validation logic for clarity
 	if err := utils.Validate(request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err,
-		})
+		response.Invalid(c, err)
 		return
 	}
 
-	var existingFavouriteProduct model.FavouriteProduct
-	var userinfo model.User
-	var productinfo model.Product
-
 	// Check if the user exists
-	if err := database.DB.Where("id =?", UserID).First(&userinfo).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "user not found",
-		})
+	if _, err := h.Users.ByID(UserID); err != nil {
+		response.Error(c, response.CodeBadRequest, "user not found")
 		return
 	}
 
 	// Check if the product exists
-	if err := database.DB.Where("id =?", request.ProductID).First(&productinfo).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "product not found",
-		})
+	if _, err := h.Products.ByID(request.ProductID); err != nil {
+		response.Error(c, response.CodeBadRequest, "product not found")
 		return
 	}
 
 	// Check if the favorite product combination already exists
-	if err := database.DB.Where("user_id =? AND product_id =?", UserID, request.ProductID).First(&existingFavouriteProduct).Error; err == nil {
+	if _, err := h.Products.Favourite(UserID, request.ProductID); err == nil {
 		// If there's no error, it means the favorite product already exists
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "favorite product already exists",
-		})
+		response.Error(c, response.CodeConflict, "favorite product already exists")
 		return
 	}
 
 	// If everything checks out, add the favorite product
-	if err := database.DB.Create(&model.FavouriteProduct{UserID: UserID, ProductID: request.ProductID}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to add favorite product",
-		})
+	if err := h.Products.AddFavourite(UserID, request.ProductID); err != nil {
+		response.Error(c, response.CodeInternal, "failed to add favorite product")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "favorite product added successfully",
-	})
+	response.OK(c, "favorite product added successfully", nil)
 }
 
 // user id
-func RemoveFavouriteProduct(c *gin.Context) {
+func (h *Handler) RemoveFavouriteProduct(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 
 	var request struct {
 		ProductID uint `validate:"required,number" json:"product_id"`
 	}
 
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind the JSON",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the JSON")
 		return
 	}
 
 	// Extracted validation logic for clarity
 	if err := utils.Validate(request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "unauthorized user",
-		})
+		response.Invalid(c, err)
 		return
 	}
 
-	var existingFavouriteProduct model.FavouriteProduct
-
-	if err := database.DB.Where(&model.FavouriteProduct{UserID: UserID, ProductID: request.ProductID}).First(&existingFavouriteProduct).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "favorite product doesn't exist",
-		})
+	if _, err := h.Products.Favourite(UserID, request.ProductID); err != nil {
+		response.Error(c, response.CodeBadRequest, "favorite product doesn't exist")
 		return
 	}
-	if err := database.DB.Where("user_id =? AND product_id =?", UserID, request.ProductID).Delete(&model.FavouriteProduct{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to delete favorite product",
-		})
+	if err := h.Products.RemoveFavourite(UserID, request.ProductID); err != nil {
+		response.Error(c, response.CodeInternal, "failed to delete favorite product")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "favorite product deleted successfully",
-	})
+	response.OK(c, "favorite product deleted successfully", nil)
 }
 
 func RestaurantIDByProductID(ProductID uint) uint {
@@ -529,37 +389,28 @@ func RestaurantIDByProductID(ProductID uint) uint {
 	return Product.RestaurantID
 }
 
-func OnlyVegProducts(c *gin.Context) {
-	var products []model.Product
-
-	tx := database.DB.Where("veg = ?", model.YES).
-		Order("price ASC").
-		Find(&products)
-
-	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get product information",
-		})
+func (h *Handler) OnlyVegProducts(c *gin.Context) {
+	products, err := h.Products.Veg()
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get product information")
 		return
 	}
 
-	var response []model.ProductResponse
+	var productList []model.ProductResponse
 	for _, product := range products {
-		var dbCategory model.Category
-		var dbRestaurant model.Restaurant
-
-		if err := database.DB.Where("id = ?", product.CategoryID).First(&dbCategory).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get category information"})
+		dbCategory, err := h.Categories.ByID(product.CategoryID)
+		if err != nil {
+			response.Error(c, response.CodeNotFound, "failed to get category information")
 			return
 		}
 
-		if err := database.DB.Where("id = ?", product.RestaurantID).First(&dbRestaurant).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get restaurant information"})
+		dbRestaurant, err := h.Restaurants.ByID(product.RestaurantID)
+		if err != nil {
+			response.Error(c, response.CodeNotFound, "failed to get restaurant information")
 			return
 		}
 
-		response = append(response, model.ProductResponse{
+		productList = append(productList, model.ProductResponse{
 			ID:             product.ID,
 			RestaurantName: dbRestaurant.Name,
 			CategoryName:   dbCategory.Name,
@@ -573,117 +424,89 @@ func OnlyVegProducts(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "data": response})
+	response.OK(c, "successfully retrieved veg products", productList)
 }
 
-func AddProductOffer(c *gin.Context) {
+func (h *Handler) AddProductOffer(c *gin.Context) {
 	var request model.AddOfferRequest
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "Invalid request body: ",
-		})
+		response.Error(c, response.CodeBadRequest, "Invalid request body: ")
 		return
 	}
 
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	pRestID := RestaurantIDByProductID(request.ProductID)
-	RestID, _ := RestIDfromEmail(email)
+	RestID, _ := h.restIDfromEmail(email)
 	if pRestID != RestID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "StatusUnauthorized",
-		})
+		response.Error(c, response.CodeUnauthorized, "StatusUnauthorized")
 		return
 	}
 
-	exist, Product := CheckProduct(int(request.ProductID))
-	if !exist {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to find the product",
-		})
+	Product, err := h.Products.ByID(request.ProductID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to find the product")
+		return
+	}
+
+	if request.OfferAmount > Product.Price {
+		response.Error(c, response.CodeBadRequest, "offer amount should not be more than the product price")
 		return
 	}
 
 	Product.OfferAmount = request.OfferAmount
-	if err := database.DB.Model(&Product).Where("id = ?", request.ProductID).Update("offer_amount", request.OfferAmount).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to add the offer amount",
-		})
+	if err := h.Products.SetOffer(Product.ID, request.OfferAmount); err != nil {
+		response.Error(c, response.CodeInternal, "failed to add the offer amount")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully added the offer amount",
-		"data":    Product,
-	})
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully added the offer amount", Product)
 }
 
-func RemoveProductOffer(c *gin.Context) {
+func (h *Handler) RemoveProductOffer(c *gin.Context) {
 
 	ProductID, err := strconv.Atoi(c.Query("productid"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid ProductID",
-		})
+		response.Error(c, response.CodeBadRequest, "Invalid ProductID")
 		return
 	}
 
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	pRestID := RestaurantIDByProductID(uint(ProductID))
-	RestID, _ := RestIDfromEmail(email)
+	RestID, _ := h.restIDfromEmail(email)
 	if pRestID != RestID {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "StatusUnauthorized",
-		})
+		response.Error(c, response.CodeUnauthorized, "StatusUnauthorized")
 		return
 	}
 
-	exist, Product := CheckProduct(ProductID)
-	if !exist {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to find the product",
-		})
+	Product, err := h.Products.ByID(uint(ProductID))
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to find the product")
 		return
 	}
 
 	Product.OfferAmount = 0
-	if err := database.DB.Model(&Product).Where("id = ?", ProductID).Update("offer_amount", 0).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to remove the offer amount",
-		})
+	if err := h.Products.SetOffer(Product.ID, 0); err != nil {
+		response.Error(c, response.CodeInternal, "failed to remove the offer amount")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully removed the offer amount",
-		"data":    Product,
-	})
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "successfully removed the offer amount", Product)
 
 }
 
@@ -698,48 +521,81 @@ func CheckProduct(ProductID int) (bool, model.Product) {
 
 }
 
-func GetProductOffers(c *gin.Context) {
+func (h *Handler) GetProductOffers(c *gin.Context) {
 	//get products with more than 0 in offer_amount
-	var Products []model.Product
-
-	if err := database.DB.Where("offer_amount > ?", 0).Find(&Products).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status": false, "message": "failed to get product details",
-		})
+	Products, err := h.Products.WithOffers()
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get product details")
 
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true, "data": Products,
-	})
+	response.OK(c, "successfully retrieved products with offers", Products)
 }
 
 func ListAllReviewsandRating(c *gin.Context) {
     ProductID := c.Query("product_id")
 
     if ProductID == "" {
-        c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "provide product_id in the query params"})
+        response.Error(c, response.CodeBadRequest, "provide product_id in the query params")
+        return
+    }
+
+    Page, err := pagination.FromContext(c)
+    if err != nil {
+        response.Error(c, response.CodeBadRequest, err.Error())
         return
     }
 
+    // only items with either a review or a rating
     var orderItems []model.OrderItem
-    if err := database.DB.Where("product_id = ?", ProductID).Find(&orderItems).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to fetch reviews and ratings"})
+    tx := database.DB.Where("product_id = ? AND (order_review <> '' OR order_rating <> 0)", ProductID)
+    if err := Page.Offset(tx, "order_id ASC").Find(&orderItems).Error; err != nil {
+        response.Error(c, response.CodeInternal, "failed to fetch reviews and ratings")
+        return
+    }
+    orderItems, NextCursor := pagination.Trim(Page, orderItems, nil)
+
+    // photos and edit history of the reviews on this page
+    OrderIDs := make([]string, 0, len(orderItems))
+    for _, item := range orderItems {
+        OrderIDs = append(OrderIDs, item.OrderID)
+    }
+    var Photos []model.ReviewPhoto
+    if err := database.DB.Where("product_id = ? AND order_id IN ?", ProductID, OrderIDs).Order("id").Find(&Photos).Error; err != nil {
+        response.Error(c, response.CodeInternal, "failed to fetch review photos")
         return
     }
+    PhotosByOrder := map[string][]string{}
+    for _, Photo := range Photos {
+        PhotosByOrder[Photo.OrderID] = append(PhotosByOrder[Photo.OrderID], Photo.URL)
+    }
+    var Edited []string
+    if err := database.DB.Model(&model.ReviewRevision{}).Where("product_id = ? AND order_id IN ?", ProductID, OrderIDs).Distinct().Pluck("order_id", &Edited).Error; err != nil {
+        response.Error(c, response.CodeInternal, "failed to fetch review history")
+        return
+    }
+    EditedOrders := map[string]bool{}
+    for _, OrderID := range Edited {
+        EditedOrders[OrderID] = true
+    }
 
-    var response []map[string]interface{}
+    var productList []map[string]interface{}
     for _, item := range orderItems {
-        // Check if either review or rating is non-null
-        if item.OrderReview != "" || item.OrderRating != 0 {
-            response = append(response, map[string]interface{}{
-                "userid":    item.UserID,
-                "rating":    item.OrderRating,
-                "review":    item.OrderReview,
-            })
-        }
+        productList = append(productList, map[string]interface{}{
+            "userid":    item.UserID,
+            "rating":    item.OrderRating,
+            "review":    item.OrderReview,
+            "photos":    PhotosByOrder[item.OrderID],
+            "edited":    EditedOrders[item.OrderID],
+        })
+    }
+
+    projected, err := Page.Project(productList)
+    if err != nil {
+        response.Error(c, response.CodeBadRequest, err.Error())
+        return
     }
 
-    c.JSON(http.StatusOK, gin.H{"status": true, "data": response})
+    response.Page(c, "successfully retrieved reviews and ratings", projected, NextCursor)
 }
\ No newline at end of file
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"fmt"
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"foodbuddy/internal/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// order item of the user that can still be reviewed, delivered within ReviewWindowDays,
+// the error response is sent when it can't
+func reviewableItem(c *gin.Context, UserID uint, OrderID string, ProductID uint) (model.OrderItem, bool) {
+	var OrderItem model.OrderItem
+	if err := database.DB.Where("order_id = ? AND product_id = ? AND user_id = ?", OrderID, ProductID, UserID).First(&OrderItem).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retreive the order item")
+		return OrderItem, false
+	}
+	if OrderItem.OrderStatus != model.OrderStatusDelivered {
+		response.Error(c, response.CodeInvalidState, "reviews can only be added after order is delivered")
+		return OrderItem, false
+	}
+	if OrderItem.DeliveredAt == nil || time.Since(*OrderItem.DeliveredAt) > model.ReviewWindowDays*24*time.Hour {
+		response.Error(c, response.CodeInvalidState, fmt.Sprintf("reviews are accepted for %v days after delivery", model.ReviewWindowDays))
+		return OrderItem, false
+	}
+	return OrderItem, true
+}
+
+// user id of the caller when it is a user, the error response is sent when it isn't
+func reviewer(c *gin.Context) (uint, bool) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return 0, false
+	}
+	UserID, ok := UserIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve user information")
+		return 0, false
+	}
+	return UserID, true
+}
+
+// replace the review and rating of the item, the previous ones are kept as a revision
+// and the product rating is recomputed when the rating changed
+func saveItemReview(tx *gorm.DB, OrderItem model.OrderItem, Review string, Rating float64) error {
+	if OrderItem.OrderReview != "" || OrderItem.OrderRating != 0 {
+		Revision := model.ReviewRevision{
+			OrderID:   OrderItem.OrderID,
+			ProductID: OrderItem.ProductID,
+			UserID:    OrderItem.UserID,
+			Review:    OrderItem.OrderReview,
+			Rating:    OrderItem.OrderRating,
+		}
+		if err := tx.Create(&Revision).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&model.OrderItem{}).Where("order_id = ? AND product_id = ?", OrderItem.OrderID, OrderItem.ProductID).
+		Updates(map[string]interface{}{"order_review": Review, "order_rating": Rating}).Error; err != nil {
+		return err
+	}
+	if Rating == OrderItem.OrderRating {
+		return nil
+	}
+	return RecomputeProductRating(tx, OrderItem.ProductID)
+}
+
+// rating aggregates of a product as stored on it
+type ProductRating struct {
+	RatingSum     float64
+	RatingCount   uint
+	AverageRating float64
+}
+
+// rating aggregates of the product from every rated order item
+func ProductRatingOf(tx *gorm.DB, ProductID uint) (ProductRating, error) {
+	var Rating ProductRating
+	if err := tx.Model(&model.OrderItem{}).
+		Select("COALESCE(SUM(order_rating), 0) AS rating_sum, COUNT(*) AS rating_count").
+		Where("product_id = ? AND order_rating > 0", ProductID).
+		Scan(&Rating).Error; err != nil {
+		return Rating, err
+	}
+	if Rating.RatingCount > 0 {
+		Rating.AverageRating = Rating.RatingSum / float64(Rating.RatingCount)
+	}
+	return Rating, nil
+}
+
+// rebuild the rating aggregates of the product, used by reviews and the recompute-ratings command
+func RecomputeProductRating(tx *gorm.DB, ProductID uint) error {
+	Rating, err := ProductRatingOf(tx, ProductID)
+	if err != nil {
+		return err
+	}
+	//a map so zero values are written as well
+	return tx.Model(&model.Product{}).Where("id = ?", ProductID).Updates(map[string]interface{}{
+		"rating_sum":     Rating.RatingSum,
+		"rating_count":   Rating.RatingCount,
+		"average_rating": Rating.AverageRating,
+	}).Error
+}
+
+// order_id and product_id query params of the review routes
+func reviewItemQuery(c *gin.Context) (string, uint, bool) {
+	OrderID := c.Query("order_id")
+	ProductID, err := strconv.Atoi(c.Query("product_id"))
+	if OrderID == "" || err != nil {
+		response.Error(c, response.CodeBadRequest, "provide order_id and product_id in the query params")
+		return "", 0, false
+	}
+	return OrderID, uint(ProductID), true
+}
+
+// user - order_id and product_id in the query params, removes the review, the rating and the photos
+func DeleteOrderItemReview(c *gin.Context) {
+	UserID, ok := reviewer(c)
+	if !ok {
+		return
+	}
+	OrderID, ProductID, ok := reviewItemQuery(c)
+	if !ok {
+		return
+	}
+	OrderItem, ok := reviewableItem(c, UserID, OrderID, ProductID)
+	if !ok {
+		return
+	}
+	if OrderItem.OrderReview == "" && OrderItem.OrderRating == 0 {
+		response.Error(c, response.CodeNotFound, "the item has no review")
+		return
+	}
+
+	var Photos []model.ReviewPhoto
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := saveItemReview(tx, OrderItem, "", 0); err != nil {
+			return err
+		}
+		if err := tx.Where("order_id = ? AND product_id = ?", OrderID, ProductID).Find(&Photos).Error; err != nil {
+			return err
+		}
+		return tx.Where("order_id = ? AND product_id = ?", OrderID, ProductID).Delete(&model.ReviewPhoto{}).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to delete the review")
+		return
+	}
+	//the files go once the rows are gone, a failed delete leaves both
+	for _, Photo := range Photos {
+		storage.Remove(c.Request.Context(), gateway.Get().Images, storage.ReviewPhoto, Photo.URL)
+	}
+
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "review deleted", nil)
+}
+
+// user - order_id and product_id in the query params, at most ReviewPhotoLimit photos per item
+func UploadReviewPhoto(c *gin.Context) {
+	UserID, ok := reviewer(c)
+	if !ok {
+		return
+	}
+	OrderID, ProductID, ok := reviewItemQuery(c)
+	if !ok {
+		return
+	}
+	if _, ok := reviewableItem(c, UserID, OrderID, ProductID); !ok {
+		return
+	}
+	var Photos int64
+	if err := database.DB.Model(&model.ReviewPhoto{}).Where("order_id = ? AND product_id = ?", OrderID, ProductID).Count(&Photos).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the review photos")
+		return
+	}
+	if Photos >= model.ReviewPhotoLimit {
+		response.Error(c, response.CodeConflict, fmt.Sprintf("a review can have at most %v photos", model.ReviewPhotoLimit))
+		return
+	}
+
+	stored, ok := uploadFormFile(c, storage.ReviewPhoto)
+	if !ok {
+		return
+	}
+	Photo := model.ReviewPhoto{
+		OrderID:   OrderID,
+		ProductID: ProductID,
+		UserID:    UserID,
+		URL:       stored.URL,
+		ThumbURL:  stored.Variants["thumb"],
+	}
+	if err := database.DB.Create(&Photo).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the review photo")
+		return
+	}
+
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "photo added to the review", Photo)
+}
+
+// user - photoid in the query param
+func DeleteReviewPhoto(c *gin.Context) {
+	UserID, ok := reviewer(c)
+	if !ok {
+		return
+	}
+	PhotoID, err := strconv.Atoi(c.Query("photoid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid photo ID")
+		return
+	}
+	var Photo model.ReviewPhoto
+	if err := database.DB.Where("id = ? AND user_id = ?", PhotoID, UserID).First(&Photo).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "photo not found")
+		return
+	}
+	if _, ok := reviewableItem(c, UserID, Photo.OrderID, Photo.ProductID); !ok {
+		return
+	}
+	if err := database.DB.Delete(&Photo).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to delete the photo")
+		return
+	}
+	storage.Remove(c.Request.Context(), gateway.Get().Images, storage.ReviewPhoto, Photo.URL)
+
+	cache.Invalidate(cache.TagProducts)
+
+	response.OK(c, "photo removed from the review", nil)
+}
+
+// user - order_id and product_id in the query params, earlier versions of the review newest first
+func GetReviewHistory(c *gin.Context) {
+	UserID, ok := reviewer(c)
+	if !ok {
+		return
+	}
+	OrderID, ProductID, ok := reviewItemQuery(c)
+	if !ok {
+		return
+	}
+	var OrderItem model.OrderItem
+	if err := database.DB.Where("order_id = ? AND product_id = ? AND user_id = ?", OrderID, ProductID, UserID).First(&OrderItem).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retreive the order item")
+		return
+	}
+	Revisions := []model.ReviewRevision{}
+	if err := database.DB.Where("order_id = ? AND product_id = ?", OrderID, ProductID).Order("id DESC").Find(&Revisions).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the review history")
+		return
+	}
+	Photos := []model.ReviewPhoto{}
+	if err := database.DB.Where("order_id = ? AND product_id = ?", OrderID, ProductID).Order("id").Find(&Photos).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the review photos")
+		return
+	}
+
+	response.OK(c, "review history retrieved", gin.H{
+		"review":    OrderItem.OrderReview,
+		"rating":    OrderItem.OrderRating,
+		"photos":    Photos,
+		"revisions": Revisions,
+	})
+}
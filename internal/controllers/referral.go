@@ -4,9 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/metrics"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,52 +18,34 @@ func GetRefferalCode(c *gin.Context) {
 
 	email, role, _ := utils.GetJWTClaim(c)
 	if role != model.UserRole {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var User model.User
 	if err := database.DB.Where("email = ?", email).First(&User).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user information")
 		return
 	}
 
 	if User.ReferralCode != "" {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  true,
-			"message": "referral code : " + User.ReferralCode,
-		})
+		response.OK(c, "referral code : "+User.ReferralCode, nil)
 		return
 	}
 
 	refCode := utils.GenerateRandomString(5)
 
 	if err := database.DB.Model(&User).Where("email = ?", email).Update("referral_code", refCode).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to generate referral code",
-		})
+		response.Error(c, response.CodeNotFound, "failed to generate referral code")
 		return
 	}
 
 	if !CreateReferralEntry(User.ID) {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to save referral history",
-		})
+		response.Error(c, response.CodeNotFound, "failed to save referral history")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "referral code : " + refCode,
-	})
+	response.OK(c, "referral code : "+refCode, nil)
 
 }
 
@@ -70,77 +53,48 @@ func ActivateReferral(c *gin.Context) {
 	RefCode := c.Query("referralcode")
 	email, role, _ := utils.GetJWTClaim(c)
 	if role != model.UserRole {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var User model.User
 	if err := database.DB.Where("email =?", email).First(&User).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user information")
 		return
 	}
 
 	if err := database.DB.Model(&model.User{}).Where("referral_code = ?", RefCode).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "the referral code doesnt exist",
-		})
+		response.Error(c, response.CodeNotFound, "the referral code doesnt exist")
 		return
 	}
 
-	fmt.Println(User)
-
 	var UserReferralHistory model.UserReferralHistory
 	if err := database.DB.Where("referral_code =?", User.ReferralCode).First(&UserReferralHistory).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user referral information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user referral information")
 		return
 	}
 
 	if UserReferralHistory.ReferredBy != "" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "user already been referred",
-		})
+		response.Error(c, response.CodeConflict, "user already been referred")
 		return
 	}
 
 	if UserReferralHistory.ReferredBy == RefCode {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "user's cannot refer each other",
-		})
+		response.Error(c, response.CodeNotFound, "user's cannot refer each other")
 		return
 	}
 
 	if RefCode == User.ReferralCode {
-		c.JSON(http.StatusMethodNotAllowed, gin.H{
-			"status":  false,
-			"message": "usage of same referral code restricted",
-		})
+		response.Error(c, response.CodeInvalidState, "usage of same referral code restricted")
 		return
 	}
 
-	if err := database.DB.Where("referral_code =?", User.ReferralCode).First(&UserReferralHistory).Update("referred_by", RefCode).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update user referral information",
-		})
+	if err := database.DB.Model(&model.UserReferralHistory{}).Where("referral_code =?", User.ReferralCode).Update("referred_by", RefCode).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to update user referral information")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully finished refer process",
-	})
+	response.OK(c, "successfully finished refer process", nil)
 }
 func GenerateReferralCodeForUser(email string) (string, error) {
 	var User model.User
@@ -190,28 +144,19 @@ func CreateReferralEntry(UserID uint) bool {
 func ClaimReferralRewards(c *gin.Context) {
 	email, role, _ := utils.GetJWTClaim(c)
 	if role != model.UserRole {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var User model.User
 	if err := database.DB.Where("email =?", email).First(&User).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user information")
 		return
 	}
 
 	var Referrals []model.UserReferralHistory
 	if err := database.DB.Where("referred_by =? AND refer_claimed =?", User.ReferralCode, false).Find(&Referrals).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user refer history",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user refer history")
 		return
 	}
 
@@ -224,20 +169,12 @@ func ClaimReferralRewards(c *gin.Context) {
 
 	if eligibleClaims < model.ReferralClaimLimit {
 		errMsg := fmt.Sprintf("need a minimum of %v referrals with at least one order delivered to claim reward", model.ReferralClaimLimit)
-		c.JSON(http.StatusMethodNotAllowed, gin.H{
-			"status":  false,
-			"message": errMsg,
-		})
+		response.Error(c, response.CodeInvalidState, errMsg)
 		return
 	}
 
-	fmt.Println(User)
-
 	if err := database.DB.Model(&model.UserReferralHistory{}).Where("referred_by =? AND refer_claimed =?", User.ReferralCode, false).Update("refer_claimed", 1).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update user refer history",
-		})
+		response.Error(c, response.CodeInternal, "failed to update user refer history")
 		return
 	}
 
@@ -254,58 +191,39 @@ func ClaimReferralRewards(c *gin.Context) {
 
 	User.WalletAmount += float64(PossibleClaimAmount)
 	if err := database.DB.Updates(&User).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update user",
-		})
+		response.Error(c, response.CodeInternal, "failed to update user")
 		return
 	}
 
 	if err := database.DB.Create(&UserWalletHistory).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to  create user refer history",
-		})
+		response.Error(c, response.CodeInternal, "failed to  create user refer history")
 		return
 	}
+	metrics.WalletTransaction(metrics.OwnerUser, true, UserWalletHistory.Reason, UserWalletHistory.Amount)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data": gin.H{
-			"message":            "successfully claimed referral reward",
-			"eligible_referrals": eligibleClaims,
-			"claim_refund":       PossibleClaimAmount,
-		},
+	response.OK(c, "successfully claimed referral rewards", gin.H{
+		"message":            "successfully claimed referral reward",
+		"eligible_referrals": eligibleClaims,
+		"claim_refund":       PossibleClaimAmount,
 	})
 }
 
 func GetReferralStats(c *gin.Context) {
 	email, role, _ := utils.GetJWTClaim(c)
 	if role != model.UserRole {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	var User model.User
 	if err := database.DB.Where("email =?", email).First(&User).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user information")
 		return
 	}
 
-	fmt.Println(User)
-
 	var CompleteReferrals []model.UserReferralHistory
 	if err := database.DB.Where("referred_by = ?", User.ReferralCode).Find(&CompleteReferrals).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user refer history",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user refer history")
 		return
 	}
 
@@ -322,20 +240,14 @@ func GetReferralStats(c *gin.Context) {
 
 	var claimsDone int64
 	if err := database.DB.Model(&model.UserReferralHistory{}).Where("referred_by = ? AND refer_claimed = ?", User.ReferralCode, true).Count(&claimsDone).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to retrieve total claims",
-		})
+		response.Error(c, response.CodeInternal, "failed to retrieve total claims")
 		return
 	}
 
 	var totalClaimedAmount float64
 	var userWalletHistories []model.UserWalletHistory
 	if err := database.DB.Where("reason = ? AND user_id =?", model.WalletTxTypeReferralReward, User.ID).Find(&userWalletHistories).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to retrieve total claimed amount",
-		})
+		response.Error(c, response.CodeInternal, "failed to retrieve total claimed amount")
 		return
 	}
 
@@ -343,7 +255,6 @@ func GetReferralStats(c *gin.Context) {
 		totalClaimedAmount += history.Amount
 	}
 
-	fmt.Println("length is  :", len(CompleteReferrals))
 	var IneligibleReferrals int
 	if len(CompleteReferrals) == 0 {
 		IneligibleReferrals = 0
@@ -362,9 +273,8 @@ func GetReferralStats(c *gin.Context) {
 		"total_claim_amount_received": totalClaimedAmount,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":           true,
-		"data":             referralStats,
+	response.OK(c, "successfully retrieved referral stats", gin.H{
+		"stats":            referralStats,
 		"referral_history": CompleteReferrals,
 	})
 }
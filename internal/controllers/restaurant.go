@@ -1,12 +1,15 @@
 package controllers
 
 import (
-	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/logging"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"log"
-	"net/http"
 	"os"
 	"strconv"
 
@@ -22,37 +25,25 @@ func RestaurantSignup(c *gin.Context) {
 	// bind json to struct
 	var restaurantSignup model.RestaurantSignupRequest
 	if err := c.BindJSON(&restaurantSignup); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to process the request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to process the request")
 		return
 	}
 
 	// validate input
 	if err := utils.Validate(&restaurantSignup); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	//check if the password and the confirm password is correct
 	if restaurantSignup.Password != restaurantSignup.ConfirmPassword {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "passwords doesn't match",
-		})
+		response.Error(c, response.CodeBadRequest, "passwords doesn't match")
 		return
 	}
 
 	err := passwordvalidator.Validate(restaurantSignup.Password, model.PasswordEntropy)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
@@ -60,10 +51,7 @@ func RestaurantSignup(c *gin.Context) {
 	var verification model.VerificationTable
 	tx := database.DB.Where("email = ? AND role = ?", restaurantSignup.Email, model.RestaurantRole).First(&verification)
 	if tx.Error != nil && tx.Error != gorm.ErrRecordNotFound {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "database error",
-		})
+		response.Error(c, response.CodeInternal, "database error")
 		return
 	} else if tx.Error == gorm.ErrRecordNotFound {
 		// create new entry in verification table
@@ -74,30 +62,19 @@ func RestaurantSignup(c *gin.Context) {
 		}
 		tx = database.DB.Create(&verification)
 		if tx.Error != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  false,
-				"message": "failed to create restaurant verification entry",
-			})
+			response.Error(c, response.CodeInternal, "failed to create restaurant verification entry")
 			return
 		}
 	} else {
 		// email already exists
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "restaurant email already exists",
-		})
+		response.Error(c, response.CodeConflict, "restaurant email already exists")
 		return
 	}
 
 	// generate salt and hash password
-	salt := utils.GenerateRandomString(7)
-	saltedPassword := salt + restaurantSignup.Password
-	hash, err := bcrypt.GenerateFromPassword([]byte(saltedPassword), bcrypt.DefaultCost)
+	salt, hash, err := HashPassword(restaurantSignup.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to process the request",
-		})
+		response.Error(c, response.CodeInternal, "failed to process the request")
 		return
 	}
 
@@ -113,33 +90,28 @@ func RestaurantSignup(c *gin.Context) {
 		VerificationStatus: model.VerificationStatusPending,
 		Blocked:            false,
 		Salt:               salt,
-		HashedPassword:     string(hash),
+		HashedPassword:     hash,
 	}
 
 	// save to database
 	if err := database.DB.Create(&restaurant).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to save restaurant data",
-		})
+		response.Error(c, response.CodeInternal, "failed to save restaurant data")
 		return
 	}
 
 	// respond with success
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "restaurant signup successful",
-		"data": gin.H{
-			"name":                restaurant.Name,
-			"description":         restaurant.Description,
-			"address":             restaurant.Address,
-			"email":               restaurant.Email,
-			"phone_number":        restaurant.PhoneNumber,
-			"image_url":           restaurant.ImageURL,
-			"certificate_url":     restaurant.CertificateURL,
-			"verification_status": restaurant.VerificationStatus,
-			"blocked":             restaurant.Blocked,
-		},
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "restaurant signup successful", gin.H{
+		"name":                restaurant.Name,
+		"description":         restaurant.Description,
+		"address":             restaurant.Address,
+		"email":               restaurant.Email,
+		"phone_number":        restaurant.PhoneNumber,
+		"image_url":           restaurant.ImageURL,
+		"certificate_url":     restaurant.CertificateURL,
+		"verification_status": restaurant.VerificationStatus,
+		"blocked":             restaurant.Blocked,
 	})
 }
 
@@ -150,113 +122,96 @@ func RestaurantLogin(c *gin.Context) {
 	var existingRestaurant model.Restaurant
 
 	if err := c.BindJSON(&restaurantLogin); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "Failed to process the incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "Failed to process the incoming request")
 		return
 	}
 
 	// Validate
 	if err := utils.Validate(&restaurantLogin); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	// Check email on restaurant DB
 	if err := database.DB.Where("email = ?", restaurantLogin.Email).First(&existingRestaurant).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "Error fetching restaurant details",
-		})
-		return
-	}
-
-	// Check block and admin verification status
-	if existingRestaurant.Blocked {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "Restaurant not authorized to access the route",
-		})
+		response.Error(c, response.CodeInternal, "Error fetching restaurant details")
 		return
 	}
 
 	// Check password by salt and password
 	password := []byte(existingRestaurant.Salt + restaurantLogin.Password)
 	if err := bcrypt.CompareHashAndPassword([]byte(existingRestaurant.HashedPassword), password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "Invalid credentials",
-		})
+		response.Error(c, response.CodeUnauthorized, "Invalid credentials")
+		return
+	}
+
+	// Check block status, only told after the password matched
+	if existingRestaurant.Blocked {
+		response.Error(c, response.CodeAccountBlocked, blockedMessage(database.DB, model.RestaurantRole, existingRestaurant.ID))
 		return
 	}
 
 	// Check email verification status using verification table
 	var verificationTable model.VerificationTable
 	if err := database.DB.Where("email = ?", restaurantLogin.Email).First(&verificationTable).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "Failed to fetch email verification status",
-		})
+		response.Error(c, response.CodeInternal, "Failed to fetch email verification status")
 		return
 	}
 
 	if verificationTable.VerificationStatus != model.VerificationStatusVerified {
 		if err := SendOTP(c, restaurantLogin.Email, verificationTable.OTPExpiry, model.RestaurantRole); err != nil {
-			c.JSON(http.StatusAlreadyReported, gin.H{
-				"status":  false,
-				"message": err.Error(),
-			})
+			response.Error(c, response.CodeInvalidState, err.Error())
 			return
 		}
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "Please verify your email to continue",
-		})
+		response.Error(c, response.CodeEmailNotVerified, "Please verify your email to continue")
 		return
 	}
 
 	token, err := GenerateJWT(c, existingRestaurant.Email, model.RestaurantRole)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "Failed to generate token",
-		})
+		response.Error(c, response.CodeInternal, "Failed to generate token")
 		return
 	}
 
 	// Success
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "Login is successful",
-		"data": gin.H{
-			"name":                existingRestaurant.Name,
-			"email":               existingRestaurant.Email,
-			"description":         existingRestaurant.Description,
-			"address":             existingRestaurant.Address,
-			"phone_number":        existingRestaurant.PhoneNumber,
-			"image_url":           existingRestaurant.ImageURL,
-			"certificate_url":     existingRestaurant.CertificateURL,
-			"verification_status": existingRestaurant.VerificationStatus,
-		},
-		"token": token,
+	response.OK(c, "Login is successful", gin.H{
+		"name":                existingRestaurant.Name,
+		"email":               existingRestaurant.Email,
+		"description":         existingRestaurant.Description,
+		"address":             existingRestaurant.Address,
+		"phone_number":        existingRestaurant.PhoneNumber,
+		"image_url":           existingRestaurant.ImageURL,
+		"certificate_url":     existingRestaurant.CertificateURL,
+		"verification_status": existingRestaurant.VerificationStatus,
+		"token":               token,
 	})
 }
 
-// public
+// public - verified restaurants that are not blocked
 func GetRestaurants(c *gin.Context) {
+	listRestaurants(c, database.DB.Scopes(repository.ListedRestaurants))
+}
+
+// admin - every restaurant whatever its verification
+func GetAllRestaurants(c *gin.Context) {
+	listRestaurants(c, database.DB)
+}
+
+func listRestaurants(c *gin.Context, tx *gorm.DB) {
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
 	var restaurants []model.Restaurant
-	// Search db and get all
-	if err := database.DB.Select("*").Find(&restaurants).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to retrieve data from the database",
-		})
+	// Search db and get the page
+	if err := Page.Keyset(tx, "id").Find(&restaurants).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve data from the database")
 		return
 	}
+	restaurants, NextCursor := pagination.Trim(Page, restaurants, func(r model.Restaurant) uint { return r.ID })
+
 	var simplifiedRestaurants []gin.H
 
 	for _, r := range restaurants {
@@ -270,14 +225,21 @@ func GetRestaurants(c *gin.Context) {
 			"image_url":           r.ImageURL,
 			"certificate_url":     r.CertificateURL,
 			"verification_status": r.VerificationStatus,
+			"average_rating":      r.AverageRating,
+			"rating_count":        r.RatingCount,
+			"food_rating":         r.FoodRating,
+			"packaging_rating":    r.PackagingRating,
+			"delivery_rating":     r.DeliveryRating,
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "restaurants retrieved successfully",
-		"data":    gin.H{"restaurantslist": simplifiedRestaurants},
-	})
+	Response, err := Page.Project(simplifiedRestaurants)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	response.Page(c, "restaurants retrieved successfully", gin.H{"restaurantslist": Response}, NextCursor)
 }
 
 // restaurant
@@ -286,65 +248,44 @@ func EditRestaurant(c *gin.Context) {
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
 	// Bind JSON
 	var Request model.RestaurantProfileUpdate
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
 		return
 	}
 
 	if err := utils.Validate(Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
 	RestaurantID, ok := RestIDfromEmail(email)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve restaurant information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
 		return
 	}
 
 	// Check if present and update it with the new data
 	var existingRestaurant model.Restaurant
 	if err := database.DB.First(&existingRestaurant, RestaurantID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "restaurant doesn't exist",
-		})
+		response.Error(c, response.CodeNotFound, "restaurant doesn't exist")
 		return
 	}
 
 	// Edit the restaurant
 	if err := database.DB.Model(&existingRestaurant).Updates(Request).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to edit the restaurant",
-		})
+		response.Error(c, response.CodeInternal, "failed to edit the restaurant")
 		return
 	}
 
-	fmt.Println("done")
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully edited the restaurant",
-		"data":    Request,
-	})
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+
+	response.OK(c, "successfully edited the restaurant", Request)
 }
 
 // admin
@@ -353,10 +294,7 @@ func DeleteRestaurant(c *gin.Context) {
 	//check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -364,47 +302,35 @@ func DeleteRestaurant(c *gin.Context) {
 	restaurantIDStr := c.Param("restaurantid")
 	restaurantID, err := strconv.Atoi(restaurantIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid restaurant ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid restaurant ID")
 		return
 	}
 
 	// Check if it's already present
 	var existingRestaurant model.Restaurant
 	if err := database.DB.First(&existingRestaurant, restaurantID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "restaurant doesn't exist",
-		})
+		response.Error(c, response.CodeNotFound, "restaurant doesn't exist")
 		return
 	}
 
 	// Delete it
 	if err := database.DB.Delete(&existingRestaurant).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to delete the restaurant",
-		})
+		response.Error(c, response.CodeInternal, "failed to delete the restaurant")
 		return
 	}
+	audit.Record(c, audit.RestaurantDelete, audit.EntityRestaurant, existingRestaurant.ID, existingRestaurant, nil)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully deleted the restaurant",
-	})
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+
+	response.OK(c, "successfully deleted the restaurant", nil)
 }
 
-// admin
+// admin - reason, note and duration_hours in the body, the restaurant is mailed a notice to appeal with
 func BlockRestaurant(c *gin.Context) {
 	//check admin api authentication
-	_, role, err := utils.GetJWTClaim(c)
+	email, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -412,73 +338,60 @@ func BlockRestaurant(c *gin.Context) {
 	restaurantIDStr := c.Query("restaurantid")
 	restaurantID, err := strconv.Atoi(restaurantIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":     false,
-			"message":    "invalid restaurant ID",
-			"error_code": http.StatusBadRequest,
-		})
+		response.Error(c, response.CodeBadRequest, "invalid restaurant ID")
+		return
+	}
+	var Request model.BlockAccountRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
 		return
 	}
 
 	// Check restaurant by id
 	var restaurant model.Restaurant
 	if err := database.DB.First(&restaurant, restaurantID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":     false,
-			"message":    "restaurant not found",
-			"error_code": http.StatusNotFound,
-		})
+		response.Error(c, response.CodeNotFound, "restaurant not found")
 		return
 	}
 
 	if restaurant.Blocked {
-		c.JSON(http.StatusAlreadyReported, gin.H{
-			"status":     false,
-			"message":    "restaurant is already blocked",
-			"error_code": http.StatusConflict,
-			"data": gin.H{
-				"name":        restaurant.Name,
-				"email":       restaurant.Email,
-				"address":     restaurant.Address,
-				"blockstatus": restaurant.Blocked,
-			},
-		})
+		response.Error(c, response.CodeInvalidState, "restaurant is already blocked")
 		return
 	}
 
 	// Set blocked as true
+	before := restaurant
 	restaurant.Blocked = true
 
-	if err := database.DB.Save(&restaurant).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":     false,
-			"message":    "failed to change the block status",
-			"error_code": http.StatusInternalServerError,
-		})
+	Block, Token, err := BlockAccount(database.DB, model.RestaurantRole, restaurant.ID, Request, email)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to change the block status")
 		return
 	}
+	audit.Record(c, audit.RestaurantBlock, audit.EntityRestaurant, restaurant.ID, before, restaurant)
+	sendBlockNotice(c, restaurant.Email, restaurant.Name, Block, Token)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "restaurant is blocked",
-		"data": gin.H{
-			"name":        restaurant.Name,
-			"email":       restaurant.Email,
-			"address":     restaurant.Address,
-			"blockstatus": restaurant.Blocked,
-		},
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+
+	response.OK(c, "restaurant is blocked", gin.H{
+		"name":        restaurant.Name,
+		"email":       restaurant.Email,
+		"address":     restaurant.Address,
+		"blockstatus": restaurant.Blocked,
+		"block":       Block,
 	})
 }
 
 // admin
 func UnblockRestaurant(c *gin.Context) {
 	//check admin api authentication
-	_, role, err := utils.GetJWTClaim(c)
+	email, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -486,57 +399,39 @@ func UnblockRestaurant(c *gin.Context) {
 	restaurantIDStr := c.Query("restaurantid")
 	restaurantID, err := strconv.Atoi(restaurantIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid restaurant ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid restaurant ID")
 		return
 	}
 
 	// Check restaurant by id
 	var restaurant model.Restaurant
 	if err := database.DB.First(&restaurant, restaurantID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "restaurant not found",
-		})
+		response.Error(c, response.CodeNotFound, "restaurant not found")
 		return
 	}
 
 	if !restaurant.Blocked {
-		c.JSON(http.StatusConflict, gin.H{
-			"status":  false,
-			"message": "restaurant is already unblocked",
-			"data": gin.H{
-				"name":         restaurant.Name,
-				"email":        restaurant.Email,
-				"address":      restaurant.Address,
-				"block_status": restaurant.Blocked,
-			},
-		})
+		response.Error(c, response.CodeInvalidState, "restaurant is already unblocked")
 		return
 	}
 
 	// Set blocked as false
+	before := restaurant
 	restaurant.Blocked = false
 
-	if err := database.DB.Save(&restaurant).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to change the block status",
-		})
+	if err := LiftBlock(database.DB, model.RestaurantRole, restaurant.ID, email); err != nil {
+		response.Error(c, response.CodeInternal, "failed to change the block status")
 		return
 	}
+	audit.Record(c, audit.RestaurantUnblock, audit.EntityRestaurant, restaurant.ID, before, restaurant)
+
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "restaurant is unblocked",
-		"data": gin.H{
-			"name":         restaurant.Name,
-			"email":        restaurant.Email,
-			"address":      restaurant.Address,
-			"block_status": restaurant.Blocked,
-		},
+	response.OK(c, "restaurant is unblocked", gin.H{
+		"name":         restaurant.Name,
+		"email":        restaurant.Email,
+		"address":      restaurant.Address,
+		"block_status": restaurant.Blocked,
 	})
 }
 
@@ -544,10 +439,7 @@ func VerifyRestaurant(c *gin.Context) {
 	//check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -555,39 +447,46 @@ func VerifyRestaurant(c *gin.Context) {
 	restaurantIDStr := c.Query("restaurantid")
 	restaurantID, err := strconv.Atoi(restaurantIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid restaurant ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid restaurant ID")
 		return
 	}
 	var Restaurant model.Restaurant
 	if err := database.DB.Where("id = ?", restaurantID).First(&Restaurant).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": err.Error()})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
 
+	before := Restaurant
 	if Restaurant.VerificationStatus == model.VerificationStatusVerified {
-		c.JSON(http.StatusAlreadyReported, gin.H{"status": true, "message": "restaurant is already verified"})
+		response.OK(c, "restaurant is already verified", nil)
 		return
-	} else {
-		Restaurant.VerificationStatus = model.VerificationStatusVerified
 	}
+	//verification goes through the onboarding review, this only restores it once every document is approved
+	Pending, err := UnapprovedDocuments(database.DB, Restaurant.ID)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the documents")
+		return
+	}
+	if len(Pending) > 0 {
+		response.Error(c, response.CodeInvalidState, "every onboarding document should be approved before the restaurant is verified")
+		return
+	}
+	Restaurant.VerificationStatus = model.VerificationStatusVerified
 
 	if err := database.DB.Updates(&Restaurant).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": err.Error()})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "restaurant status changed to status - verified"})
+	audit.Record(c, audit.RestaurantVerify, audit.EntityRestaurant, Restaurant.ID, before, Restaurant)
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+
+	response.OK(c, "restaurant status changed to status - verified", nil)
 }
 func RemoveVerifyStatusRestaurant(c *gin.Context) {
 	//check admin api authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -595,29 +494,31 @@ func RemoveVerifyStatusRestaurant(c *gin.Context) {
 	restaurantIDStr := c.Query("restaurantid")
 	restaurantID, err := strconv.Atoi(restaurantIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid restaurant ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid restaurant ID")
 		return
 	}
 	var Restaurant model.Restaurant
 	if err := database.DB.Where("id = ?", restaurantID).First(&Restaurant).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": err.Error()})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
 
+	before := Restaurant
 	if Restaurant.VerificationStatus == model.VerificationStatusPending {
-		c.JSON(http.StatusAlreadyReported, gin.H{"status": true, "message": "restaurant is already unverified"})
+		response.OK(c, "restaurant is already unverified", nil)
+		return
 	} else {
 		Restaurant.VerificationStatus = model.VerificationStatusPending
 	}
 
 	if err := database.DB.Updates(&Restaurant).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": err.Error()})
+		response.Error(c, response.CodeNotFound, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "restaurant status changed to status - pending"})
+	audit.Record(c, audit.RestaurantUnverify, audit.EntityRestaurant, Restaurant.ID, before, Restaurant)
+	cache.Invalidate(cache.TagRestaurants, cache.TagProducts)
+
+	response.OK(c, "restaurant status changed to status - pending", nil)
 }
 
 // get restaurantid from rest email
@@ -637,42 +538,37 @@ func RestaurantWalletBalance(email string) (float64, bool) {
 	return Restaurant.WalletAmount, true
 }
 
-func GetRestaurantWalletData(c *gin.Context) {
+func (h *Handler) GetRestaurantWalletData(c *gin.Context) {
 	//check restaurant api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
-	RestID, _ := RestIDfromEmail(email)
-	WalletBalance, _ := RestaurantWalletBalance(email)
+	Restaurant, err := h.Restaurants.ByEmail(email)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
 
-	var Result []model.RestaurantWalletHistory
-	if err := database.DB.Where("restaurant_id = ?", RestID).Find(&Result).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get restaurant wallet history"})
+	Result, err := h.Wallets.RestaurantHistory(Restaurant.ID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get restaurant wallet history")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data": gin.H{
-			"wallet_balance": WalletBalance,
-			"history":        Result,
-		},
+	response.OK(c, "successfully retrieved wallet", gin.H{
+		"wallet_balance": Restaurant.WalletAmount,
+		"history":        Result,
 	})
 }
 
+
 func OrderItemsCSVFileForRestaurant(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -681,13 +577,13 @@ func OrderItemsCSVFileForRestaurant(c *gin.Context) {
 	var OrderInformation []model.OrderItem
 	err = database.DB.Where("restaurant_id =?", RestID).Find(&OrderInformation).Error
 	if err != nil {
-		log.Printf("Failed to retrieve order information: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve order information"})
+		logging.From(c).Error("failed to retrieve order information", "error", err)
+		response.Error(c, response.CodeInternal, "Failed to retrieve order information")
 		return
 	}
 
 	if len(OrderInformation) == 0 {
-		c.JSON(http.StatusOK, gin.H{"status": true, "message": "No orders found for this restaurant."})
+		response.OK(c, "No orders found for this restaurant.", nil)
 		return
 	}
 
@@ -711,15 +607,15 @@ func OrderItemsCSVFileForRestaurant(c *gin.Context) {
 
 	tmpfile, err := os.CreateTemp("", "orders_*.csv")
 	if err != nil {
-		log.Printf("Failed to create temp file: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create CSV file"})
+		logging.From(c).Error("failed to create temp file", "error", err)
+		response.Error(c, response.CodeInternal, "Failed to create CSV file")
 		return
 	}
 	defer os.Remove(tmpfile.Name())
 
 	if err := gocsv.MarshalFile(data, tmpfile); err != nil {
-		log.Printf("Failed to marshal CSV data: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write CSV data"})
+		logging.From(c).Error("failed to marshal csv data", "error", err)
+		response.Error(c, response.CodeInternal, "Failed to write CSV data")
 		return
 	}
 	c.Writer.Header().Set("Content-Type", "text/csv")
@@ -733,10 +629,7 @@ func OrderPaymentInformationsExcelFile(c *gin.Context) {
 func ListOrderItemsForRestaurants(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.RestaurantRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -745,13 +638,13 @@ func ListOrderItemsForRestaurants(c *gin.Context) {
 	var OrderInformation []model.OrderItem
 	err = database.DB.Where("restaurant_id =?", RestID).Find(&OrderInformation).Error
 	if err != nil {
-		log.Printf("Failed to retrieve order information: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve order information"})
+		logging.From(c).Error("failed to retrieve order information", "error", err)
+		response.Error(c, response.CodeInternal, "Failed to retrieve order information")
 		return
 	}
 
 	if len(OrderInformation) == 0 {
-		c.JSON(http.StatusOK, gin.H{"status": true, "message": "No orders found for this restaurant."})
+		response.OK(c, "No orders found for this restaurant.", nil)
 		return
 	}
 
@@ -773,35 +666,33 @@ func ListOrderItemsForRestaurants(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status": true,
-		"data":   data,
-	})
+	response.OK(c, "successfully retrieved order items", data)
 }
 
 func GetRestaurantProfile(c *gin.Context){
 	RestaurantID := c.Query("id")
     if RestaurantID == "0" || RestaurantID == ""{
-		c.JSON(http.StatusBadRequest,gin.H{"status":false,"message":"id is not provided in the query params"});return
+		response.Error(c, response.CodeBadRequest, "id is not provided in the query params");return
 	}
 
     var Restaurant model.Restaurant
 	if err:=database.DB.Where("id = ?",RestaurantID).First(&Restaurant).Error;err!=nil{
-		c.JSON(http.StatusBadRequest,gin.H{"status":false,"message":"restaurant profile not found,confirm id of the restaurant"});return
+		response.Error(c, response.CodeBadRequest, "restaurant profile not found,confirm id of the restaurant");return
 	}	
 
-	c.JSON(http.StatusOK,
-		gin.H{
-			"status":true,
-			"data":gin.H{
-				"restaurant_id":RestaurantID,
-				"name":Restaurant.Name,
-				"description":Restaurant.Description,
-				"address":Restaurant.Address,
-				"phone_number":Restaurant.PhoneNumber,
-				"image_url":Restaurant.ImageURL,
-				"certificate_url":Restaurant.CertificateURL,
-				"blocked":Restaurant.Blocked,
-			},
-		})
+	response.OK(c, "successfully retrieved restaurant profile", gin.H{
+		"restaurant_id":RestaurantID,
+		"name":Restaurant.Name,
+		"description":Restaurant.Description,
+		"address":Restaurant.Address,
+		"phone_number":Restaurant.PhoneNumber,
+		"image_url":Restaurant.ImageURL,
+		"certificate_url":Restaurant.CertificateURL,
+		"blocked":Restaurant.Blocked,
+		"average_rating":Restaurant.AverageRating,
+		"rating_count":Restaurant.RatingCount,
+		"food_rating":Restaurant.FoodRating,
+		"packaging_rating":Restaurant.PackagingRating,
+		"delivery_rating":Restaurant.DeliveryRating,
+	})
 }
@@ -0,0 +1,570 @@
+package controllers
+
+import (
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/moderation"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// orders of the public review list, recent is the default
+var reviewSorts = map[string]string{
+	"recent":  "id DESC",
+	"helpful": "helpful_count DESC, id DESC",
+	"highest": "rating DESC, id DESC",
+	"lowest":  "rating ASC, id DESC",
+}
+
+// recompute the rating aggregates of the restaurant from its published reviews
+func RecomputeRestaurantRating(tx *gorm.DB, RestaurantID uint) error {
+	var Aggregate struct {
+		Count     uint
+		Average   float64
+		Food      float64
+		Packaging float64
+		Delivery  float64
+	}
+	err := tx.Model(&model.RestaurantReview{}).
+		Select("COUNT(*) AS count, COALESCE(AVG(rating), 0) AS average, COALESCE(AVG(food_rating), 0) AS food, "+
+			"COALESCE(AVG(packaging_rating), 0) AS packaging, COALESCE(AVG(delivery_rating), 0) AS delivery").
+		Where("restaurant_id = ? AND status = ?", RestaurantID, model.ReviewStatusPublished).
+		Scan(&Aggregate).Error
+	if err != nil {
+		return err
+	}
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return tx.Model(&model.Restaurant{}).Where("id = ?", RestaurantID).Updates(map[string]interface{}{
+		"average_rating":   round(Aggregate.Average),
+		"rating_count":     Aggregate.Count,
+		"food_rating":      round(Aggregate.Food),
+		"packaging_rating": round(Aggregate.Packaging),
+		"delivery_rating":  round(Aggregate.Delivery),
+	}).Error
+}
+
+// review of the reviewid query param, the error response is sent when it fails
+func reviewFromQuery(c *gin.Context) (model.RestaurantReview, bool) {
+	var Review model.RestaurantReview
+	ReviewID, err := strconv.Atoi(c.Query("reviewid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid review ID")
+		return Review, false
+	}
+	if err := database.DB.First(&Review, ReviewID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "review not found")
+		return Review, false
+	}
+	return Review, true
+}
+
+// user - review the restaurant of one of their delivered orders, once per order
+func AddRestaurantReview(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, ok := UserIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve user information")
+		return
+	}
+
+	var Request model.RestaurantReviewRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var Order model.Order
+	if err := database.DB.Where("order_id = ? AND user_id = ?", Request.OrderID, UserID).First(&Order).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "order not found")
+		return
+	}
+	var Delivered int64
+	if err := database.DB.Model(&model.OrderItem{}).Where("order_id = ? AND order_status = ?", Order.OrderID, model.OrderStatusDelivered).Count(&Delivered).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the order items")
+		return
+	}
+	if Delivered == 0 {
+		response.Error(c, response.CodeInvalidState, "reviews can only be added after the order is delivered")
+		return
+	}
+	var Existing int64
+	if err := database.DB.Model(&model.RestaurantReview{}).Where("order_id = ?", Order.OrderID).Count(&Existing).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the reviews")
+		return
+	}
+	if Existing > 0 {
+		response.Error(c, response.CodeConflict, "the order is already reviewed")
+		return
+	}
+
+	//profane words are masked and the review goes to the moderation queue
+	Text, Profane := moderation.Clean(strings.TrimSpace(Request.Text))
+	Review := model.RestaurantReview{
+		RestaurantID:    Order.RestaurantID,
+		UserID:          UserID,
+		OrderID:         Order.OrderID,
+		FoodRating:      Request.FoodRating,
+		PackagingRating: Request.PackagingRating,
+		DeliveryRating:  Request.DeliveryRating,
+		Rating:          math.Round(float64(Request.FoodRating+Request.PackagingRating+Request.DeliveryRating)/3*100) / 100,
+		Text:            Text,
+		Status:          model.ReviewStatusPublished,
+		Flagged:         Profane,
+	}
+	if Profane {
+		Review.ModerationNote = "profanity masked automatically"
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Review).Error; err != nil {
+			return err
+		}
+		return RecomputeRestaurantRating(tx, Review.RestaurantID)
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to add the review")
+		return
+	}
+
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "review added", Review)
+}
+
+// public - id of the restaurant in the query param, published reviews with the rating summary,
+// sort is recent, helpful, highest or lowest
+func GetRestaurantReviews(c *gin.Context) {
+	RestaurantID, err := strconv.Atoi(c.Query("id"))
+	if err != nil || RestaurantID == 0 {
+		response.Error(c, response.CodeBadRequest, "id is not provided in the query params")
+		return
+	}
+	Order, ok := reviewSorts[c.DefaultQuery("sort", "recent")]
+	if !ok {
+		response.Error(c, response.CodeBadRequest, "sort should be recent, helpful, highest or lowest")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	var Restaurant model.Restaurant
+	if err := database.DB.First(&Restaurant, RestaurantID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "restaurant not found")
+		return
+	}
+
+	var Reviews []model.RestaurantReview
+	tx := database.DB.Where("restaurant_id = ? AND status = ?", RestaurantID, model.ReviewStatusPublished)
+	if err := Page.Offset(tx, Order).Find(&Reviews).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the reviews")
+		return
+	}
+	Reviews, NextCursor := pagination.Trim(Page, Reviews, nil)
+
+	UserIDs := make([]uint, 0, len(Reviews))
+	for _, Review := range Reviews {
+		UserIDs = append(UserIDs, Review.UserID)
+	}
+	var Users []model.User
+	if err := database.DB.Where("id IN ?", UserIDs).Find(&Users).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the reviewers")
+		return
+	}
+	Names := make(map[uint]string, len(Users))
+	for _, User := range Users {
+		Names[User.ID] = User.Name
+	}
+
+	var ReviewList []gin.H
+	for _, r := range Reviews {
+		ReviewList = append(ReviewList, gin.H{
+			"review_id":        r.ID,
+			"reviewer":         Names[r.UserID],
+			"rating":           r.Rating,
+			"food_rating":      r.FoodRating,
+			"packaging_rating": r.PackagingRating,
+			"delivery_rating":  r.DeliveryRating,
+			"text":             r.Text,
+			"helpful_count":    r.HelpfulCount,
+			"reply":            r.Reply,
+			"replied_at":       r.RepliedAt,
+			"created_at":       r.CreatedAt,
+		})
+	}
+	Projected, err := Page.Project(ReviewList)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	response.Page(c, "reviews retrieved", gin.H{
+		"summary": gin.H{
+			"average_rating":   Restaurant.AverageRating,
+			"rating_count":     Restaurant.RatingCount,
+			"food_rating":      Restaurant.FoodRating,
+			"packaging_rating": Restaurant.PackagingRating,
+			"delivery_rating":  Restaurant.DeliveryRating,
+		},
+		"reviews": Projected,
+	}, NextCursor)
+}
+
+// user - reviewid in the query param, one vote per user and never on their own review
+func MarkReviewHelpful(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, ok := UserIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve user information")
+		return
+	}
+	Review, ok := reviewFromQuery(c)
+	if !ok {
+		return
+	}
+	if Review.Status != model.ReviewStatusPublished {
+		response.Error(c, response.CodeNotFound, "review not found")
+		return
+	}
+	if Review.UserID == UserID {
+		response.Error(c, response.CodeForbidden, "you can't vote on your own review")
+		return
+	}
+
+	Voted := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var Votes int64
+		if err := tx.Model(&model.ReviewVote{}).Where("review_id = ? AND user_id = ?", Review.ID, UserID).Count(&Votes).Error; err != nil {
+			return err
+		}
+		if Votes > 0 {
+			Voted = true
+			return nil
+		}
+		if err := tx.Create(&model.ReviewVote{ReviewID: Review.ID, UserID: UserID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Review).UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the vote")
+		return
+	}
+	if Voted {
+		response.Error(c, response.CodeConflict, "you already marked the review as helpful")
+		return
+	}
+
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "review marked as helpful", gin.H{"helpful_count": Review.HelpfulCount + 1})
+}
+
+// user - reviewid in the query param
+func UnmarkReviewHelpful(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, ok := UserIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve user information")
+		return
+	}
+	Review, ok := reviewFromQuery(c)
+	if !ok {
+		return
+	}
+
+	var Removed int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		Result := tx.Where("review_id = ? AND user_id = ?", Review.ID, UserID).Delete(&model.ReviewVote{})
+		if Result.Error != nil {
+			return Result.Error
+		}
+		Removed = Result.RowsAffected
+		if Removed == 0 {
+			return nil
+		}
+		return tx.Model(&Review).UpdateColumn("helpful_count", gorm.Expr("helpful_count - 1")).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to remove the vote")
+		return
+	}
+	if Removed == 0 {
+		response.Error(c, response.CodeNotFound, "you haven't marked the review as helpful")
+		return
+	}
+
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "vote removed", gin.H{"helpful_count": Review.HelpfulCount - 1})
+}
+
+// user and restaurant - reviewid in the query param, a restaurant can only flag its own reviews,
+// flagged reviews stay published until an admin hides them
+func FlagRestaurantReview(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	var ReporterID uint
+	var ok bool
+	switch role {
+	case model.UserRole:
+		ReporterID, ok = UserIDfromEmail(email)
+	case model.RestaurantRole:
+		ReporterID, ok = RestIDfromEmail(email)
+	default:
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve account information")
+		return
+	}
+
+	var Request model.ReviewFlagRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	Review, ok := reviewFromQuery(c)
+	if !ok {
+		return
+	}
+	Visible := Review.Status == model.ReviewStatusPublished
+	if role == model.RestaurantRole {
+		Visible = Review.RestaurantID == ReporterID
+	}
+	if !Visible {
+		response.Error(c, response.CodeNotFound, "review not found")
+		return
+	}
+	if role == model.UserRole && Review.UserID == ReporterID {
+		response.Error(c, response.CodeForbidden, "you can't flag your own review")
+		return
+	}
+
+	Flagged := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var Flags int64
+		if err := tx.Model(&model.ReviewFlag{}).Where("review_id = ? AND reporter_role = ? AND reporter_id = ?", Review.ID, role, ReporterID).Count(&Flags).Error; err != nil {
+			return err
+		}
+		if Flags > 0 {
+			Flagged = true
+			return nil
+		}
+		Flag := model.ReviewFlag{ReviewID: Review.ID, ReporterRole: role, ReporterID: ReporterID, Reason: Request.Reason}
+		if err := tx.Create(&Flag).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Review).UpdateColumns(map[string]interface{}{
+			"flag_count": gorm.Expr("flag_count + 1"),
+			"flagged":    true,
+		}).Error
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to flag the review")
+		return
+	}
+	if Flagged {
+		response.Error(c, response.CodeConflict, "you already flagged the review")
+		return
+	}
+
+	response.OK(c, "review flagged for moderation", nil)
+}
+
+// restaurant - reviews of the restaurant including hidden ones, newest first, filtered with ?status
+func ListOwnRestaurantReviews(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+	listReviews(c, database.DB.Where("restaurant_id = ?", RestaurantID))
+}
+
+// admin - the moderation queue, newest first, filtered with ?status, ?restaurant_id and ?flagged=true
+func ListReviewsForModeration(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	tx := database.DB.Model(&model.RestaurantReview{})
+	if RestaurantID := c.Query("restaurant_id"); RestaurantID != "" {
+		tx = tx.Where("restaurant_id = ?", RestaurantID)
+	}
+	if c.Query("flagged") == "true" {
+		tx = tx.Where("flagged = ?", true)
+	}
+	listReviews(c, tx)
+}
+
+func listReviews(c *gin.Context, tx *gorm.DB) {
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+	if Status := strings.ToUpper(c.Query("status")); Status != "" {
+		tx = tx.Where("status = ?", Status)
+	}
+
+	var Reviews []model.RestaurantReview
+	if err := Page.Offset(tx, "id DESC").Find(&Reviews).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the reviews")
+		return
+	}
+	Reviews, NextCursor := pagination.Trim(Page, Reviews, nil)
+
+	response.Page(c, "reviews retrieved", gin.H{"reviews": Reviews}, NextCursor)
+}
+
+// restaurant - reviewid in the query param, a new reply replaces the previous one
+func ReplyToRestaurantReview(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.RestaurantRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	RestaurantID, ok := RestIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+		return
+	}
+	var Request model.ReviewReplyRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	Review, ok := reviewFromQuery(c)
+	if !ok {
+		return
+	}
+	if Review.RestaurantID != RestaurantID {
+		response.Error(c, response.CodeNotFound, "review not found")
+		return
+	}
+
+	Now := time.Now()
+	Review.Reply, _ = moderation.Clean(strings.TrimSpace(Request.Reply))
+	Review.RepliedAt = &Now
+	if err := database.DB.Model(&Review).Updates(map[string]interface{}{"reply": Review.Reply, "replied_at": Now}).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the reply")
+		return
+	}
+
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "reply saved", Review)
+}
+
+// admin - reviewid in the query param, hidden reviews leave the public list and the rating aggregates
+func ModerateRestaurantReview(c *gin.Context) {
+	_, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	var Request model.ModerateReviewRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	Review, ok := reviewFromQuery(c)
+	if !ok {
+		return
+	}
+
+	Before := Review
+	switch Request.Action {
+	case model.ReviewActionHide:
+		if Review.Status == model.ReviewStatusHidden {
+			response.Error(c, response.CodeInvalidState, "review is already hidden")
+			return
+		}
+		Review.Status = model.ReviewStatusHidden
+		Review.Flagged = false
+	case model.ReviewActionUnhide:
+		if Review.Status != model.ReviewStatusHidden {
+			response.Error(c, response.CodeInvalidState, "review isn't hidden")
+			return
+		}
+		Review.Status = model.ReviewStatusPublished
+	case model.ReviewActionClearFlags:
+		if !Review.Flagged {
+			response.Error(c, response.CodeInvalidState, "review isn't flagged")
+			return
+		}
+		Review.Flagged = false
+	}
+	if Request.Note != "" {
+		Review.ModerationNote = Request.Note
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Review).Select("status", "flagged", "moderation_note").Updates(&Review).Error; err != nil {
+			return err
+		}
+		if Review.Status == Before.Status {
+			return nil
+		}
+		return RecomputeRestaurantRating(tx, Review.RestaurantID)
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to moderate the review")
+		return
+	}
+	audit.Record(c, audit.ReviewModerate, audit.EntityReview, Review.ID, Before, Review)
+
+	cache.Invalidate(cache.TagRestaurants)
+
+	response.OK(c, "review moderated", Review)
+}
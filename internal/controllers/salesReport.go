@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"foodbuddy/internal/database"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
-	"net/http"
-	"os"
 	"strconv"
 	"time"
 
@@ -18,15 +18,11 @@ import (
 func ProductReport(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Query("productid"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid ProductID",
-		})
+		response.Error(c, response.CodeBadRequest, "Invalid ProductID")
 		return
 	}
 	report := IndividualProductReport(strconv.Itoa(productID))
-	c.JSON(http.StatusOK, gin.H{
-		"data": report,
-	})
+	response.OK(c, "successfully retrieved product report", report)
 }
 
 func IndividualProductReport(ProductID string) model.ProductSales {
@@ -57,7 +53,7 @@ func GeneratePDFInvoice(order model.Order, orderItems []model.OrderItem, address
 	pdf.SetFont("Arial", "B", 12)
 
 	// Inserting an image
-	pdf.Image(os.Getenv(model.ProjectRoot)+"/assets/FoodBuddy-Logo.png", 10, 10, 50, 0, false, "", 0, "")
+	pdf.Image(appConfig.ProjectRoot+"/assets/FoodBuddy-Logo.png", 10, 10, 50, 0, false, "", 0, "")
 	pdf.Ln(20)
 	// Title
 	pdf.Cell(40, 10, "Order Invoice")
@@ -151,48 +147,30 @@ func GetOrderInfoByOrderIDAndGeneratePDF(c *gin.Context) {
 
 	var Order model.Order
 	if err := database.DB.Where("order_id = ?", OrderID).First(&Order).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to fetch order information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch order information")
 		return
 	}
 
 	var OrderItems []model.OrderItem
 	if err := database.DB.Where("order_id = ?", OrderID).Find(&OrderItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to fetch order information",
-		})
+		response.Error(c, response.CodeInternal, "failed to fetch order information")
 		return
 	}
 	var User model.User
 	if err := database.DB.Where("id = ?", Order.UserID).First(&User).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user information",
-			"error":   err.Error(),
-		})
+		response.Error(c, response.CodeNotFound, "failed to get user information")
 		return
 	}
 
 	var Address model.Address
 	if err := database.DB.Where("address_id = ?", Order.AddressID).First(&Address).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get address",
-			"error":   err.Error(),
-		})
+		response.Error(c, response.CodeNotFound, "failed to get address")
 		return
 	}
 
 	pdfBytes, err := GeneratePDFInvoice(Order, OrderItems, Address, User)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to generate PDF",
-			"error":   err.Error(),
-		})
+		response.Internal(c, "failed to generate PDF", err)
 		return
 	}
 
@@ -207,52 +185,31 @@ func BestSellingProducts(c *gin.Context) {
 
 	var BestProduct []model.BestProduct
 
-	tx := database.DB.Table("order_items").
-		Select("product_id, COUNT(product_id) AS TotalSales").
-		Group("product_id").
-		Order("TotalSales desc").
-		Find(&BestProduct)
-
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to get best selling product"})
-		return
-	}
-
-	if indexNum > len(BestProduct) || indexNum == 0 {
-		indexNum = len(BestProduct)
+	//product and category details are joined in, instead of a lookup per product
+	tx := database.DB.Table("order_items AS oi").
+		Select("oi.product_id, p.name, c.name AS category_name, p.description, p.image_url, p.price, p.average_rating AS rating, COUNT(oi.product_id) AS total_sales").
+		Joins("JOIN products AS p ON p.id = oi.product_id AND p.deleted_at IS NULL").
+		Joins("JOIN categories AS c ON c.id = p.category_id").
+		Group("oi.product_id, p.name, c.name, p.description, p.image_url, p.price, p.average_rating").
+		Order("total_sales desc")
+	if indexNum > 0 {
+		tx = tx.Limit(indexNum)
 	}
 
-	for i, product := range BestProduct {
-		var dbProduct model.Product
-		if err := database.DB.Where("id =?", product.ProductID).First(&dbProduct).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to find product information"})
-			return
-		}
-
-		var dbCategory model.Category
-		if err := database.DB.Where("id =?", dbProduct.CategoryID).First(&dbCategory).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to find category information"})
-			return
-		}
-
-		BestProduct[i].Name = dbProduct.Name
-		BestProduct[i].CategoryName = dbCategory.Name
-		BestProduct[i].Description = dbProduct.Description
-		BestProduct[i].ImageURL = dbProduct.ImageURL
-		BestProduct[i].Price = float64(dbProduct.Price)
-		BestProduct[i].Rating = dbProduct.AverageRating
+	if err := tx.Scan(&BestProduct).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to get best selling product")
+		return
 	}
 
-	data := BestProduct[:indexNum]
-	c.JSON(http.StatusOK, gin.H{"status": true, "data": data})
+	response.OK(c, "successfully retrieved best selling products", BestProduct)
 }
 
 func PriceLowToHigh(c *gin.Context) {
 	var Products []model.Product
 
-	tx := database.DB.Table("products").Select("*").Order("price ASC").Find(&Products)
+	tx := database.DB.Table("products").Select("*").Scopes(repository.ListedProducts).Order("price ASC").Find(&Products)
 	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get product information"})
+		response.Error(c, response.CodeNotFound, "failed to get product information")
 		return
 	}
 
@@ -262,12 +219,12 @@ func PriceLowToHigh(c *gin.Context) {
 		var dbRestaurant model.Restaurant
 
 		if err := database.DB.Where("id = ?", product.CategoryID).First(&dbCategory).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get category information"})
+			response.Error(c, response.CodeNotFound, "failed to get category information")
 			return
 		}
 
 		if err := database.DB.Where("id = ?", product.RestaurantID).First(&dbRestaurant).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get restaurant information"})
+			response.Error(c, response.CodeNotFound, "failed to get restaurant information")
 			return
 		}
 
@@ -285,15 +242,15 @@ func PriceLowToHigh(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "data": Response})
+	response.OK(c, "successfully retrieved products", Response)
 }
 
 func PriceHighToLow(c *gin.Context) {
 	var Products []model.Product
 
-	tx := database.DB.Table("products").Select("*").Order("price DESC").Find(&Products)
+	tx := database.DB.Table("products").Select("*").Scopes(repository.ListedProducts).Order("price DESC").Find(&Products)
 	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get product information"})
+		response.Error(c, response.CodeNotFound, "failed to get product information")
 		return
 	}
 
@@ -303,12 +260,12 @@ func PriceHighToLow(c *gin.Context) {
 		var dbRestaurant model.Restaurant
 
 		if err := database.DB.Where("id = ?", product.CategoryID).First(&dbCategory).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get category information"})
+			response.Error(c, response.CodeNotFound, "failed to get category information")
 			return
 		}
 
 		if err := database.DB.Where("id = ?", product.RestaurantID).First(&dbRestaurant).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get restaurant information"})
+			response.Error(c, response.CodeNotFound, "failed to get restaurant information")
 			return
 		}
 
@@ -326,41 +283,38 @@ func PriceHighToLow(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "data": Response})
+	response.OK(c, "successfully retrieved products", Response)
 }
 
 func NewArrivals(c *gin.Context) {
 	var products []model.Product
 	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
 
-	tx := database.DB.Where("created_at >= ?", sevenDaysAgo).
+	tx := database.DB.Scopes(repository.ListedProducts).Where("created_at >= ?", sevenDaysAgo).
 		Order("price ASC").
 		Find(&products)
 
 	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get product information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to get product information")
 		return
 	}
 
-	var response []model.ProductResponse
+	var productList []model.ProductResponse
 	for _, product := range products {
 		var dbCategory model.Category
 		var dbRestaurant model.Restaurant
 
 		if err := database.DB.Where("id = ?", product.CategoryID).First(&dbCategory).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get category information"})
+			response.Error(c, response.CodeNotFound, "failed to get category information")
 			return
 		}
 
 		if err := database.DB.Where("id = ?", product.RestaurantID).First(&dbRestaurant).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to get restaurant information"})
+			response.Error(c, response.CodeNotFound, "failed to get restaurant information")
 			return
 		}
 
-		response = append(response, model.ProductResponse{
+		productList = append(productList, model.ProductResponse{
 			ID:             product.ID,
 			RestaurantName: dbRestaurant.Name,
 			CategoryName:   dbCategory.Name,
@@ -374,19 +328,19 @@ func NewArrivals(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "data": response})
+	response.OK(c, "successfully retrieved new arrivals", productList)
 }
 
 func PlatformOverallSalesReport(c *gin.Context) {
 	var input model.PlatformSalesReportInput
 
 	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
 	if input.StartDate == "" && input.EndDate == "" && input.Limit == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "please provide start date and end date, or specify the limit as day, week, month, year"})
+		response.Error(c, response.CodeBadRequest, "please provide start date and end date, or specify the limit as day, week, month, year")
 		return
 	}
 
@@ -401,7 +355,7 @@ func PlatformOverallSalesReport(c *gin.Context) {
 			}
 		}
 		if !found {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit specified, valid options are: day, week, month, year"})
+			response.Error(c, response.CodeBadRequest, "invalid limit specified, valid options are: day, week, month, year")
 			return
 		}
 
@@ -426,43 +380,34 @@ func PlatformOverallSalesReport(c *gin.Context) {
 			startDate = time.Now().AddDate(-1, 0, 0).Format("2006-01-02")
 		}
 
-		fmt.Println(startDate)
-		fmt.Println(endDate)
 		result, amount, err := TotalOrders(startDate, endDate, input.PaymentStatus, 0)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error processing orders"})
+			response.Error(c, response.CodeInternal, "error processing orders")
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"status":  true,
-			"message": "successfully created sales report",
-			"result":  result,
-			"amount":  amount,
+		response.OK(c, "successfully created sales report", gin.H{
+			"result": result,
+			"amount": amount,
 		})
 		return
 	}
 
 	result, amount, err := TotalOrders(input.StartDate, input.EndDate, input.PaymentStatus, 0)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error processing orders"})
+		response.Error(c, response.CodeInternal, "error processing orders")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully created sales report",
-		"result":  result,
-		"amount":  amount,
+	response.OK(c, "successfully created sales report", gin.H{
+		"result": result,
+		"amount": amount,
 	})
 }
 
 func RestaurantOverallSalesReport(c *gin.Context) {
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 	
@@ -471,12 +416,12 @@ func RestaurantOverallSalesReport(c *gin.Context) {
 	var input model.RestaurantOverallSalesReport
 
 	if err := c.BindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
 	if input.StartDate == "" && input.EndDate == "" && input.Limit == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "please provide start date and end date, or specify the limit as day, week, month, year"})
+		response.Error(c, response.CodeBadRequest, "please provide start date and end date, or specify the limit as day, week, month, year")
 		return
 	}
 
@@ -491,7 +436,7 @@ func RestaurantOverallSalesReport(c *gin.Context) {
 			}
 		}
 		if !found {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit specified, valid options are: day, week, month, year"})
+			response.Error(c, response.CodeBadRequest, "invalid limit specified, valid options are: day, week, month, year")
 			return
 		}
 
@@ -516,32 +461,26 @@ func RestaurantOverallSalesReport(c *gin.Context) {
 			startDate = time.Now().AddDate(-1, 0, 0).Format("2006-01-02")
 		}
 
-		fmt.Println(startDate)
-		fmt.Println(endDate)
 		result, amount, err := TotalOrders(startDate, endDate, input.PaymentStatus, RestaurantID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error processing orders"})
+			response.Error(c, response.CodeInternal, "error processing orders")
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{
-			"status":  true,
-			"message": "successfully created sales report",
-			"result":  result,
-			"amount":  amount,
+		response.OK(c, "successfully created sales report", gin.H{
+			"result": result,
+			"amount": amount,
 		})
 		return
 	}
 
 	result, amount, err := TotalOrders(input.StartDate, input.EndDate, input.PaymentStatus, 0)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error processing orders"})
+		response.Error(c, response.CodeInternal, "error processing orders")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully created sales report",
-		"result":  result,
-		"amount":  amount,
+	response.OK(c, "successfully created sales report", gin.H{
+		"result": result,
+		"amount": amount,
 	})
 }
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const SearchRestaurantsLimit = 5
+
+// boolean mode query for mysql fulltext, every term is matched as a prefix
+func fulltextBooleanQuery(Query string) string {
+	var terms []string
+	for _, word := range strings.Fields(Query) {
+		term := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if term != "" {
+			terms = append(terms, term+"*")
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// relevance of the text query on a name and description column pair, name matches weigh more
+// mysql uses the fulltext indexes, other dialects fall back to LIKE matching
+func textRelevance(Query string, NameColumn string, DescriptionColumn string, Weight int) (string, []interface{}) {
+	if database.DB.Dialector.Name() == "mysql" {
+		booleanQuery := fulltextBooleanQuery(Query)
+		if booleanQuery == "" {
+			return "", nil
+		}
+		return "MATCH(" + NameColumn + ", " + DescriptionColumn + ") AGAINST (? IN BOOLEAN MODE) * " + strconv.Itoa(Weight),
+			[]interface{}{booleanQuery}
+	}
+
+	like := "%" + strings.ToLower(strings.TrimSpace(Query)) + "%"
+	return "(CASE WHEN LOWER(" + NameColumn + ") LIKE ? THEN " + strconv.Itoa(Weight*2) + " ELSE 0 END + " +
+			"CASE WHEN LOWER(" + DescriptionColumn + ") LIKE ? THEN " + strconv.Itoa(Weight) + " ELSE 0 END)",
+		[]interface{}{like, like}
+}
+
+// relevance over product and restaurant names and descriptions, product matches rank first
+func searchRelevance(Query string) (string, []interface{}) {
+	if strings.TrimSpace(Query) == "" {
+		return "", nil
+	}
+	productExpr, productArgs := textRelevance(Query, "p.name", "p.description", 2)
+	restaurantExpr, restaurantArgs := textRelevance(Query, "r.name", "r.description", 1)
+	if productExpr == "" {
+		return "", nil
+	}
+	return "(" + productExpr + " + " + restaurantExpr + ")", append(productArgs, restaurantArgs...)
+}
+
+// products matching the text query and every filter of the request
+func searchProductsQuery(Request model.SearchRequest) *gorm.DB {
+	tx := database.DB.Table("products AS p").
+		Joins("JOIN restaurants AS r ON r.id = p.restaurant_id AND r.deleted_at IS NULL").
+		Joins("JOIN categories AS c ON c.id = p.category_id AND c.deleted_at IS NULL").
+		Where("p.deleted_at IS NULL AND r.blocked = ? AND r.verification_status = ?", false, model.VerificationStatusVerified)
+
+	if expr, args := searchRelevance(Request.Query); expr != "" {
+		tx = tx.Where(expr+" > 0", args...)
+	}
+	if Request.Veg != "" {
+		tx = tx.Where("p.veg = ?", Request.Veg)
+	}
+	if Request.CategoryID != 0 {
+		tx = tx.Where("p.category_id = ?", Request.CategoryID)
+	}
+	if Request.RestaurantID != 0 {
+		tx = tx.Where("p.restaurant_id = ?", Request.RestaurantID)
+	}
+	//price range is applied on the price after the product offer
+	if Request.MinPrice != 0 {
+		tx = tx.Where("(p.price - p.offer_amount) >= ?", Request.MinPrice)
+	}
+	if Request.MaxPrice != 0 {
+		tx = tx.Where("(p.price - p.offer_amount) <= ?", Request.MaxPrice)
+	}
+	if Request.MinRating != 0 {
+		tx = tx.Where("p.average_rating >= ?", Request.MinRating)
+	}
+	if Request.OffersOnly {
+		tx = tx.Where("p.offer_amount > 0")
+	}
+	return tx
+}
+
+func searchOrder(Request model.SearchRequest) string {
+	Sort := Request.Sort
+	if Sort == "" {
+		Sort = "newest"
+		if Request.Query != "" {
+			Sort = "relevance"
+		}
+	}
+
+	switch Sort {
+	case "price_asc":
+		return "selling_price ASC, p.id ASC"
+	case "price_desc":
+		return "selling_price DESC, p.id DESC"
+	case "rating":
+		return "p.average_rating DESC, p.rating_count DESC, p.id DESC"
+	case "relevance":
+		if Request.Query != "" {
+			return "relevance DESC, p.id DESC"
+		}
+	}
+	return "p.created_at DESC, p.id DESC"
+}
+
+// facet counts of the filtered result set
+func searchFacets(Request model.SearchRequest) (gin.H, error) {
+	var Categories, Restaurants, Veg []model.SearchFacet
+	var Offers int64
+
+	if err := searchProductsQuery(Request).Select("c.id AS id, c.name AS name, COUNT(*) AS count").
+		Group("c.id, c.name").Order("count DESC").Scan(&Categories).Error; err != nil {
+		return nil, err
+	}
+	if err := searchProductsQuery(Request).Select("r.id AS id, r.name AS name, COUNT(*) AS count").
+		Group("r.id, r.name").Order("count DESC").Scan(&Restaurants).Error; err != nil {
+		return nil, err
+	}
+	if err := searchProductsQuery(Request).Select("p.veg AS name, COUNT(*) AS count").
+		Group("p.veg").Scan(&Veg).Error; err != nil {
+		return nil, err
+	}
+	if err := searchProductsQuery(Request).Where("p.offer_amount > 0").Count(&Offers).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"categories":  Categories,
+		"restaurants": Restaurants,
+		"veg":         Veg,
+		"offers":      Offers,
+	}, nil
+}
+
+// public - search products and restaurants with combinable filters
+// query params: q, veg, category_id, restaurant_id, min_price, max_price, min_rating, offers_only, sort, limit, cursor, fields
+func Search(c *gin.Context) {
+	var Request model.SearchRequest
+	if err := c.ShouldBindQuery(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid search parameters")
+		return
+	}
+
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	if Request.MaxPrice != 0 && Request.MinPrice > Request.MaxPrice {
+		response.Error(c, response.CodeBadRequest, "min_price should not be more than max_price")
+		return
+	}
+
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	selectColumns := "p.id, p.restaurant_id, r.name AS restaurant_name, p.category_id, c.name AS category_name, p.name, p.description, " +
+		"p.image_url, p.price, p.offer_amount, (p.price - p.offer_amount) AS selling_price, p.stock_left, p.average_rating, p.veg"
+	var selectArgs []interface{}
+	if expr, args := searchRelevance(Request.Query); expr != "" {
+		selectColumns += ", " + expr + " AS relevance"
+		selectArgs = args
+	} else {
+		selectColumns += ", 0 AS relevance"
+	}
+
+	var Products []model.SearchProductResult
+	tx := searchProductsQuery(Request).Select(selectColumns, selectArgs...)
+	if err := Page.Offset(tx, searchOrder(Request)).Scan(&Products).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to search products")
+		return
+	}
+	Products, NextCursor := pagination.Trim(Page, Products, nil)
+
+	Response, err := Page.Project(Products)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	var Total int64
+	if err := searchProductsQuery(Request).Count(&Total).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to count search results")
+		return
+	}
+
+	Facets, err := searchFacets(Request)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to get search facets")
+		return
+	}
+
+	//matching restaurants are listed on the first page only
+	var Restaurants []model.SearchRestaurantResult
+	if Request.Query != "" && Page.Cursor.Offset == 0 {
+		if expr, args := textRelevance(Request.Query, "r.name", "r.description", 1); expr != "" {
+			if err := database.DB.Table("restaurants AS r").Select("r.id, r.name, r.description, r.image_url").
+				Where("r.deleted_at IS NULL AND r.blocked = ? AND r.verification_status = ?", false, model.VerificationStatusVerified).Where(expr+" > 0", args...).
+				Order(clause.OrderBy{Expression: clause.Expr{SQL: expr + " DESC, r.id DESC", Vars: args, WithoutParentheses: true}}).Limit(SearchRestaurantsLimit).Scan(&Restaurants).Error; err != nil {
+				response.Error(c, response.CodeInternal, "failed to search restaurants")
+				return
+			}
+		}
+	}
+
+	response.Page(c, "successfully retrieved search results", gin.H{
+		"products":    Response,
+		"restaurants": Restaurants,
+		"facets":      Facets,
+		"total":       Total,
+	}, NextCursor)
+}
@@ -0,0 +1,482 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/metrics"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"foodbuddy/internal/utils"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// the restaurant or an admin should answer within the first, an admin should resolve within the second
+const (
+	SupportFirstResponseSLA = 4 * time.Hour
+	SupportResolutionSLA    = 48 * time.Hour
+)
+
+var errTicketResolved = errors.New("ticket is already resolved")
+
+type ticketDetail struct {
+	model.SupportTicket
+	ProductIDs []uint                 `json:"product_ids"`
+	Messages   []model.SupportMessage `json:"messages"`
+}
+
+// ticket of the ticketid query param when the caller may see it: the user who raised it,
+// the restaurant of the order or an admin. the error response is sent when it fails
+func ticketForCaller(c *gin.Context) (model.SupportTicket, string, string, bool) {
+	var Ticket model.SupportTicket
+	email, role, err := utils.GetJWTClaim(c)
+	if err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return Ticket, "", "", false
+	}
+	TicketID, err := strconv.Atoi(c.Query("ticketid"))
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, "invalid ticket ID")
+		return Ticket, "", "", false
+	}
+	if err := database.DB.First(&Ticket, TicketID).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "ticket not found")
+		return Ticket, "", "", false
+	}
+
+	if !ticketVisible(Ticket, role, email) {
+		//tickets of others look like they don't exist
+		response.Error(c, response.CodeNotFound, "ticket not found")
+		return Ticket, "", "", false
+	}
+	return Ticket, role, email, true
+}
+
+func ticketVisible(Ticket model.SupportTicket, role string, email string) bool {
+	switch role {
+	case model.AdminRole:
+		return true
+	case model.UserRole:
+		UserID, ok := UserIDfromEmail(email)
+		return ok && UserID == Ticket.UserID
+	case model.RestaurantRole:
+		RestaurantID, ok := RestIDfromEmail(email)
+		return ok && RestaurantID == Ticket.RestaurantID
+	}
+	return false
+}
+
+func ticketWithThread(Ticket model.SupportTicket) (ticketDetail, error) {
+	Detail := ticketDetail{SupportTicket: Ticket, ProductIDs: []uint{}, Messages: []model.SupportMessage{}}
+	if err := database.DB.Model(&model.SupportTicketItem{}).Where("ticket_id = ?", Ticket.ID).Order("product_id").Pluck("product_id", &Detail.ProductIDs).Error; err != nil {
+		return Detail, err
+	}
+	err := database.DB.Where("ticket_id = ?", Ticket.ID).Order("id").Find(&Detail.Messages).Error
+	return Detail, err
+}
+
+// adds the message to the thread, the first one from the restaurant or an admin stops the first response timer
+func addTicketMessage(Ticket *model.SupportTicket, Role string, Email string, Body string, AttachmentURL string) (model.SupportMessage, error) {
+	Message := model.SupportMessage{
+		TicketID:      Ticket.ID,
+		AuthorRole:    Role,
+		AuthorEmail:   Email,
+		Body:          Body,
+		AttachmentURL: AttachmentURL,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Message).Error; err != nil {
+			return err
+		}
+		if Role == model.UserRole || Ticket.FirstRespondedAt != nil {
+			return nil
+		}
+		Ticket.FirstRespondedAt = &Message.CreatedAt
+		return tx.Model(Ticket).UpdateColumn("first_responded_at", Message.CreatedAt).Error
+	})
+	return Message, err
+}
+
+// user - a ticket about delivered items of one of their orders, one open ticket per order
+func RaiseSupportTicket(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.UserRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	UserID, ok := UserIDfromEmail(email)
+	if !ok {
+		response.Error(c, response.CodeNotFound, "failed to retrieve user information")
+		return
+	}
+
+	var Request model.RaiseTicketRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+
+	var Order model.Order
+	if err := database.DB.Where("order_id = ? AND user_id = ?", Request.OrderID, UserID).First(&Order).Error; err != nil {
+		response.Error(c, response.CodeNotFound, "order not found")
+		return
+	}
+	var Items []model.OrderItem
+	if err := database.DB.Where("order_id = ? AND product_id IN ?", Order.OrderID, Request.ProductIDs).Find(&Items).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the order items")
+		return
+	}
+	ProductIDs := map[uint]bool{}
+	for _, ProductID := range Request.ProductIDs {
+		ProductIDs[ProductID] = true
+	}
+	if len(Items) != len(ProductIDs) {
+		response.Error(c, response.CodeBadRequest, "every product should be an item of the order")
+		return
+	}
+	//items before delivery are cancelled and refunded in full instead, a ticket refund on them could be paid twice
+	for _, Item := range Items {
+		if Item.OrderStatus != model.OrderStatusDelivered {
+			response.Error(c, response.CodeInvalidState, "tickets can only be raised on delivered items, cancel undelivered items instead")
+			return
+		}
+	}
+	var Open int64
+	if err := database.DB.Model(&model.SupportTicket{}).Where("order_id = ? AND status = ?", Order.OrderID, model.TicketStatusOpen).Count(&Open).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the tickets")
+		return
+	}
+	if Open > 0 {
+		response.Error(c, response.CodeConflict, "the order already has an open ticket, add to its conversation instead")
+		return
+	}
+
+	Now := time.Now()
+	Ticket := model.SupportTicket{
+		UserID:             UserID,
+		OrderID:            Order.OrderID,
+		RestaurantID:       Order.RestaurantID,
+		Category:           Request.Category,
+		Description:        Request.Description,
+		Status:             model.TicketStatusOpen,
+		FirstResponseDueAt: Now.Add(SupportFirstResponseSLA),
+		ResolutionDueAt:    Now.Add(SupportResolutionSLA),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&Ticket).Error; err != nil {
+			return err
+		}
+		for ProductID := range ProductIDs {
+			if err := tx.Create(&model.SupportTicketItem{TicketID: Ticket.ID, ProductID: ProductID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to raise the ticket")
+		return
+	}
+
+	Detail, err := ticketWithThread(Ticket)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the ticket")
+		return
+	}
+	response.OK(c, "ticket raised", Detail)
+}
+
+// user, restaurant and admin - the tickets the caller may see, oldest first,
+// filtered with ?status and ?breached=true
+func ListSupportTickets(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	tx := database.DB.Model(&model.SupportTicket{})
+	switch role {
+	case model.UserRole:
+		UserID, ok := UserIDfromEmail(email)
+		if !ok {
+			response.Error(c, response.CodeNotFound, "failed to retrieve user information")
+			return
+		}
+		tx = tx.Where("user_id = ?", UserID)
+	case model.RestaurantRole:
+		RestaurantID, ok := RestIDfromEmail(email)
+		if !ok {
+			response.Error(c, response.CodeNotFound, "failed to retrieve restaurant information")
+			return
+		}
+		tx = tx.Where("restaurant_id = ?", RestaurantID)
+	case model.AdminRole:
+	default:
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	if Status := strings.ToUpper(c.Query("status")); Status != "" {
+		tx = tx.Where("status = ?", Status)
+	}
+	if c.Query("breached") == "true" {
+		tx = tx.Where("first_response_breached = ? OR resolution_breached = ?", true, true)
+	}
+
+	var Tickets []model.SupportTicket
+	if err := Page.Keyset(tx, "id").Find(&Tickets).Error; err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve the tickets")
+		return
+	}
+	Tickets, NextCursor := pagination.Trim(Page, Tickets, func(t model.SupportTicket) uint { return t.ID })
+
+	response.Page(c, "tickets retrieved", gin.H{"tickets": Tickets}, NextCursor)
+}
+
+// user, restaurant and admin - ticketid in the query param, with its items and conversation
+func GetSupportTicket(c *gin.Context) {
+	Ticket, _, _, ok := ticketForCaller(c)
+	if !ok {
+		return
+	}
+	Detail, err := ticketWithThread(Ticket)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the ticket")
+		return
+	}
+	response.OK(c, "ticket retrieved", Detail)
+}
+
+// user, restaurant and admin - ticketid in the query param
+func PostTicketMessage(c *gin.Context) {
+	Ticket, role, email, ok := ticketForCaller(c)
+	if !ok {
+		return
+	}
+	var Request model.TicketMessageRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if Ticket.Status != model.TicketStatusOpen {
+		response.Error(c, response.CodeInvalidState, "ticket is already resolved")
+		return
+	}
+
+	Message, err := addTicketMessage(&Ticket, role, email, Request.Message, "")
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the message")
+		return
+	}
+	response.OK(c, "message added", Message)
+}
+
+// user, restaurant and admin - ticketid in the query param, the file with an optional message field
+func UploadTicketAttachment(c *gin.Context) {
+	Ticket, role, email, ok := ticketForCaller(c)
+	if !ok {
+		return
+	}
+	if Ticket.Status != model.TicketStatusOpen {
+		response.Error(c, response.CodeInvalidState, "ticket is already resolved")
+		return
+	}
+
+	//the message field is read before the file, so the body is capped here already
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, storage.TicketAttachment.MaxBytes+1<<20)
+	Body := strings.TrimSpace(c.PostForm("message"))
+	if len(Body) > 2000 {
+		response.Error(c, response.CodeBadRequest, "message should be at most 2000 characters")
+		return
+	}
+	stored, ok := uploadFormFile(c, storage.TicketAttachment)
+	if !ok {
+		return
+	}
+
+	Message, err := addTicketMessage(&Ticket, role, email, Body, stored.URL)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to save the message")
+		return
+	}
+	response.OK(c, "attachment added", Message)
+}
+
+// admin - ticketid in the query param, a refund is credited to the user wallet through the order refund path
+func ResolveSupportTicket(c *gin.Context) {
+	email, role, err := utils.GetJWTClaim(c)
+	if role != model.AdminRole || err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
+		return
+	}
+	Ticket, _, _, ok := ticketForCaller(c)
+	if !ok {
+		return
+	}
+	var Request model.ResolveTicketRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
+		return
+	}
+	if Ticket.Status != model.TicketStatusOpen {
+		response.Error(c, response.CodeInvalidState, "ticket is already resolved")
+		return
+	}
+
+	Refund := math.Round(Request.RefundAmount*100) / 100
+	if Request.Resolution == model.TicketResolutionNoRefund {
+		Refund = 0
+	}
+	var Items []model.OrderItem
+	if Refund > 0 {
+		var Order model.Order
+		if err := database.DB.Where("order_id = ?", Ticket.OrderID).First(&Order).Error; err != nil {
+			response.Error(c, response.CodeNotFound, "order not found")
+			return
+		}
+		if Order.PaymentStatus != model.OnlinePaymentConfirmed && Order.PaymentStatus != model.CODStatusConfirmed {
+			response.Error(c, response.CodeInvalidState, "the order isn't paid, nothing can be refunded")
+			return
+		}
+		if err := database.DB.Where("order_id = ? AND product_id IN (?)", Ticket.OrderID,
+			database.DB.Model(&model.SupportTicketItem{}).Select("product_id").Where("ticket_id = ?", Ticket.ID)).
+			Find(&Items).Error; err != nil {
+			response.Error(c, response.CodeInternal, "failed to fetch the ticket items")
+			return
+		}
+		//every item is capped by what it paid less what was refunded on it before, by any ticket or cancellation
+		var Refundable float64
+		for _, Item := range Items {
+			Refundable += Item.AfterDeduction - Item.RefundedAmount
+		}
+		if Refundable = math.Round(Refundable*100) / 100; Refund > Refundable {
+			response.Error(c, response.CodeBadRequest, fmt.Sprintf("at most %.2f can be refunded for the ticket items", math.Max(Refundable, 0)))
+			return
+		}
+	}
+
+	//the ticket is claimed in the same transaction as the refund, a second resolution finds it resolved
+	//and a failed refund leaves the ticket open with no wallet touched
+	Now := time.Now()
+	Before := Ticket
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		Claim := tx.Model(&model.SupportTicket{}).Where("id = ? AND status = ?", Ticket.ID, model.TicketStatusOpen).Updates(map[string]interface{}{
+			"status":        model.TicketStatusResolved,
+			"resolution":    Request.Resolution,
+			"refund_amount": Refund,
+			"resolved_by":   email,
+			"resolved_at":   Now,
+		})
+		if Claim.Error != nil {
+			return Claim.Error
+		}
+		if Claim.RowsAffected == 0 {
+			return errTicketResolved
+		}
+		if Refund == 0 {
+			return nil
+		}
+		return ProvidePartialWalletRefund(tx, Ticket.UserID, Items, Refund, model.WalletTxTypeSupportRefund)
+	})
+	switch {
+	case errors.Is(err, errTicketResolved):
+		response.Error(c, response.CodeInvalidState, "ticket is already resolved")
+		return
+	case errors.Is(err, ErrRefundExceedsPaid):
+		response.Error(c, response.CodeBadRequest, "the refund is more than what is left to refund on the ticket items")
+		return
+	case err != nil:
+		logging.From(c).Error("failed to resolve the ticket", "ticket_id", Ticket.ID, "error", err)
+		response.Error(c, response.CodeInternal, "failed to refund to the wallet")
+		return
+	}
+	Ticket.Status = model.TicketStatusResolved
+	Ticket.Resolution = Request.Resolution
+	Ticket.RefundAmount = Refund
+	Ticket.ResolvedBy = email
+	Ticket.ResolvedAt = &Now
+	if _, err := addTicketMessage(&Ticket, model.AdminRole, email, Request.Note, ""); err != nil {
+		logging.From(c).Error("failed to add the resolution note", "ticket_id", Ticket.ID, "error", err)
+	}
+	audit.Record(c, audit.TicketResolve, audit.EntityTicket, Ticket.ID, Before, Ticket)
+
+	var User model.User
+	if err := database.DB.First(&User, Ticket.UserID).Error; err == nil {
+		Outcome := "No refund was issued."
+		if Refund > 0 {
+			Outcome = fmt.Sprintf("%.2f is refunded to your FoodBuddy wallet.", Refund)
+		}
+		msg := []byte("MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\r\n" +
+			"Subject: FoodBuddy Support Ticket #" + strconv.Itoa(int(Ticket.ID)) + " Resolved\r\n\r\n" +
+			"Hi " + User.Name + ",\n\nYour ticket about order " + Ticket.OrderID + " is resolved. " + Outcome + "\n\n" + Request.Note + "\n")
+		if err := gateway.Get().Mail.Send([]string{User.Email}, msg); err != nil {
+			logging.From(c).Warn("failed to send the ticket resolution", "ticket_id", Ticket.ID, "error", err)
+		}
+	}
+
+	Detail, err := ticketWithThread(Ticket)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to fetch the ticket")
+		return
+	}
+	response.OK(c, "ticket resolved", Detail)
+}
+
+// worker - flags open tickets that missed their first response or resolution due time
+func FlagSupportSLABreaches(ctx context.Context) error {
+	Now := time.Now()
+	for _, check := range []struct{ sla, column, query string }{
+		{"first_response", "first_response_breached", "first_responded_at IS NULL AND first_response_due_at <= ?"},
+		{"resolution", "resolution_breached", "resolution_due_at <= ?"},
+	} {
+		var IDs []uint
+		if err := database.DB.WithContext(ctx).Model(&model.SupportTicket{}).
+			Where("status = ? AND "+check.column+" = ?", model.TicketStatusOpen, false).
+			Where(check.query, Now).Pluck("id", &IDs).Error; err != nil {
+			return err
+		}
+		if len(IDs) == 0 {
+			continue
+		}
+		if err := database.DB.WithContext(ctx).Model(&model.SupportTicket{}).Where("id IN ?", IDs).
+			UpdateColumn(check.column, true).Error; err != nil {
+			return fmt.Errorf("failed to flag the %v breaches: %w", check.sla, err)
+		}
+		for range IDs {
+			metrics.SupportSLABreached(check.sla)
+		}
+		slog.Warn("support tickets missed their sla", "sla", check.sla, "ticket_ids", IDs)
+	}
+	return nil
+}
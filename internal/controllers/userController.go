@@ -3,19 +3,20 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"foodbuddy/internal/audit"
 	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
 	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+	"foodbuddy/internal/response"
 	"foodbuddy/internal/utils"
 	"net/http"
-	"net/smtp"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	passwordvalidator "github.com/wagslane/go-password-validator"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func UserIDfromEmail(Email string) (ID uint, ok bool) {
@@ -26,132 +27,121 @@ func UserIDfromEmail(Email string) (ID uint, ok bool) {
 	return User.ID, true
 }
 
-func GetUserProfile(c *gin.Context) {
+func (h *Handler) GetUserProfile(c *gin.Context) {
 
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
 
 	//check user info and save it on struct
-	var UserProfile model.User
-	if err := database.DB.Where("id = ?", UserID).First(&UserProfile).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve data from the database, or the data doesn't exists",
-		})
+	UserProfile, err := h.Users.ByEmail(email)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve data from the database, or the data doesn't exists")
 		return
 	}
 
 	//return
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully fetched user profile",
-		"data": gin.H{
-			"id":            UserProfile.ID,
-			"name":          UserProfile.Name,
-			"email":         UserProfile.Email,
-			"phone_number":  UserProfile.PhoneNumber,
-			"picture":       UserProfile.Picture,
-			"login_method":  UserProfile.LoginMethod,
-			"blocked":       UserProfile.Blocked,
-			"wallet_amount": UserProfile.WalletAmount,
-		},
+	response.OK(c, "successfully fetched user profile", gin.H{
+		"id":            UserProfile.ID,
+		"name":          UserProfile.Name,
+		"email":         UserProfile.Email,
+		"phone_number":  UserProfile.PhoneNumber,
+		"picture":       UserProfile.Picture,
+		"login_method":  UserProfile.LoginMethod,
+		"blocked":       UserProfile.Blocked,
+		"wallet_amount": UserProfile.WalletAmount,
 	})
 }
 
-func GetUserList(c *gin.Context) {
-	// Define a struct for the response that excludes sensitive and auto-generated fields
-	type UserResponse struct {
-		ID           uint    `json:"id"`
-		Name         string  `json:"name"`
-		Email        string  `json:"email"`
-		PhoneNumber  uint    `json:"phone_number"`
-		Picture      string  `json:"picture"`
-		ReferralCode string  `json:"referral_code"`
-		WalletAmount float64 `json:"wallet_amount"`
-		LoginMethod  string  `json:"login_method"`
-		Blocked      bool    `json:"blocked"`
-	}
 
-	var users []UserResponse
+// user information for admins, excludes sensitive and auto-generated fields
+func userListResponse(User model.User) model.BlockedUserResponse {
+	PhoneNumber, _ := strconv.ParseUint(User.PhoneNumber, 10, 64)
+	return model.BlockedUserResponse{
+		ID:           User.ID,
+		Name:         User.Name,
+		Email:        User.Email,
+		PhoneNumber:  uint(PhoneNumber),
+		Picture:      User.Picture,
+		ReferralCode: User.ReferralCode,
+		WalletAmount: User.WalletAmount,
+		LoginMethod:  User.LoginMethod,
+		Blocked:      User.Blocked,
+	}
+}
 
+func (h *Handler) GetUserList(c *gin.Context) {
 	// Check admin API authentication
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
-	// Specify fields to retrieve, excluding sensitive and auto-generated ones
-	tx := database.DB.Model(&model.User{}).Select("id, name, email, phone_number, picture, referral_code, wallet_amount, login_method, blocked").Find(&users)
-	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve data from the database, or the data doesn't exist",
-		})
+	Page, err := pagination.FromContext(c)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully retrieved user informations",
-		"data": gin.H{
-			"users": users,
-		},
-	})
+	Users, err := h.Users.List(Page)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve data from the database, or the data doesn't exist")
+		return
+	}
+
+	Users, NextCursor := pagination.Trim(Page, Users, func(u model.User) uint { return u.ID })
+	users := make([]model.BlockedUserResponse, 0, len(Users))
+	for _, User := range Users {
+		users = append(users, userListResponse(User))
+	}
+
+	Response, err := Page.Project(users)
+	if err != nil {
+		response.Error(c, response.CodeBadRequest, err.Error())
+		return
+	}
+
+	response.Page(c, "successfully retrieved user informations", gin.H{
+		"users": Response,
+	}, NextCursor)
 }
 
-func GetBlockedUserList(c *gin.Context) {
-	var blockedUsers []model.BlockedUserResponse
 
+func (h *Handler) GetBlockedUserList(c *gin.Context) {
 	_, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
-	tx := database.DB.Model(&model.User{}).Select("id, name, email, phone_number, picture, referral_code, wallet_amount, login_method, blocked").Where("deleted_at IS NULL AND blocked = ?", true).Scan(&blockedUsers)
-	if tx.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to retrieve blocked user data from the database, or the data doesn't exists",
-		})
+	Users, err := h.Users.ListBlocked()
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to retrieve blocked user data from the database, or the data doesn't exists")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully retrieved blocked user's data",
-		"data": gin.H{
-			"blocked_users": blockedUsers,
-		},
+	var blockedUsers []model.BlockedUserResponse
+	for _, User := range Users {
+		blockedUsers = append(blockedUsers, userListResponse(User))
+	}
+
+	response.OK(c, "successfully retrieved blocked user's data", gin.H{
+		"blocked_users": blockedUsers,
 	})
 }
 
-func BlockUser(c *gin.Context) {
 
-	var user model.User
+// reason, note and duration_hours in the body, the user is mailed a notice to appeal with
+func (h *Handler) BlockUser(c *gin.Context) {
 
 	//check admin api authentication
-	_, role, err := utils.GetJWTClaim(c)
+	email, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -159,57 +149,51 @@ func BlockUser(c *gin.Context) {
 
 	userId, err := strconv.Atoi(userIdStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid user ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid user ID")
+		return
+	}
+
+	var Request model.BlockAccountRequest
+	if err := c.BindJSON(&Request); err != nil {
+		response.Error(c, response.CodeBadRequest, "failed to bind request")
+		return
+	}
+	if err := utils.Validate(Request); err != nil {
+		response.Invalid(c, err)
 		return
 	}
 
-	if err := database.DB.First(&user, userId).Error; err != nil {
+	user, err := h.Users.ByID(uint(userId))
+	if err != nil {
 
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to fetch user information",
-		})
+		response.Error(c, response.CodeNotFound, "failed to fetch user information")
 		return
 	}
 
 	if user.Blocked {
-		c.JSON(http.StatusAlreadyReported, gin.H{
-			"status":  false,
-			"message": "user is already blocked",
-		})
+		response.Error(c, response.CodeInvalidState, "user is already blocked")
 		return
 	}
 
-	user.Blocked = true
-	fmt.Println(user)
-	tx := database.DB.Updates(&user)
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to change the block status ",
-		})
+	Block, Token, err := BlockAccount(database.DB, model.UserRole, user.ID, Request, email)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to change the block status ")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  false,
-		"message": "successfully blocked the user",
-	})
+	blocked := user
+	blocked.Blocked = true
+	audit.Record(c, audit.UserBlock, audit.EntityUser, user.ID, user, blocked)
+	sendBlockNotice(c, user.Email, user.Name, Block, Token)
+	response.OK(c, "successfully blocked the user", Block)
 }
 
-func UnblockUser(c *gin.Context) {
 
-	var user model.User
+func (h *Handler) UnblockUser(c *gin.Context) {
 
 	//check admin api authentication
-	_, role, err := utils.GetJWTClaim(c)
+	email, role, err := utils.GetJWTClaim(c)
 	if role != model.AdminRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
 
@@ -217,65 +201,46 @@ func UnblockUser(c *gin.Context) {
 
 	userId, err := strconv.Atoi(userIdStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "invalid user ID",
-		})
+		response.Error(c, response.CodeBadRequest, "invalid user ID")
 		return
 	}
 
-	if err := database.DB.First(&user, userId).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "User not found",
-		})
+	user, err := h.Users.ByID(uint(userId))
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "User not found")
 		return
 	}
 
 	if !user.Blocked {
-		c.JSON(http.StatusAlreadyReported, gin.H{
-			"status":  false,
-			"message": "user is already unblocked",
-		})
+		response.Error(c, response.CodeInvalidState, "user is already unblocked")
 		return
 	}
 
-	user.Blocked = false
-	fmt.Println(user)
-	tx := database.DB.Model(&user).UpdateColumn("blocked", false)
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to change the unblock status",
-		})
+	if err := LiftBlock(database.DB, model.UserRole, user.ID, email); err != nil {
+		response.Error(c, response.CodeInternal, "failed to change the unblock status")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "user is succefully blocked",
-	})
+	unblocked := user
+	unblocked.Blocked = false
+	audit.Record(c, audit.UserUnblock, audit.EntityUser, user.ID, user, unblocked)
+	response.OK(c, "user is succefully unblocked", nil)
 }
 
-func AddUserAddress(c *gin.Context) {
+
+func (h *Handler) AddUserAddress(c *gin.Context) {
 
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 
 	var UserAddress model.Address
 	//bind the json to the struct
 	if err := c.BindJSON(&UserAddress); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind the incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the incoming request")
 		return
 	}
 	UserAddress.UserID = UserID
@@ -284,132 +249,90 @@ func AddUserAddress(c *gin.Context) {
 
 	//validating the useraddress for required,number tag etc....
 	if errs := utils.Validate(UserAddress); errs != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": errs.Error(),
-		})
+		response.Invalid(c, errs)
 		return
 	}
 
 	//check if the user exists...
-	var userinfo model.User
-	if err := database.DB.First(&userinfo, UserAddress.UserID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "User not found",
-		})
+	if _, err := h.Users.ByID(UserAddress.UserID); err != nil {
+		response.Error(c, response.CodeNotFound, "User not found")
 		return
 	}
 
 	//check if there is 3 addresses, if >= 3 return address limit reached
-	var UserAddresses []model.Address
-	if err := database.DB.Where("user_id = ?", UserAddress.UserID).Find(&UserAddresses).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to retrieve the existing user addresses from the database",
-		})
+	UserAddresses, err := h.Users.Addresses(UserAddress.UserID)
+	if err != nil {
+		response.Error(c, response.CodeInternal, "failed to retrieve the existing user addresses from the database")
 		return
 	}
 
 	//check if the user already has 3 address...3 is the limit
 	if len(UserAddresses) >= 3 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "user already have three addresses, please delete or edit the existing addresses",
-		})
+		response.Error(c, response.CodeInvalidState, "user already have three addresses, please delete or edit the existing addresses")
 		return
 	}
 
 	//create the address, provide address_id similar to serial numbers...no 1,2,3 for addresses based on user_id
 	UserAddress.UserID = UserID
-	if err := database.DB.Create(&UserAddress).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to create the address on the database",
-		})
+	if err := h.Users.CreateAddress(&UserAddress); err != nil {
+		response.Error(c, response.CodeInternal, "failed to create the address on the database")
 		return
 	}
 
 	//return the addresses of the particular user
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully added new address",
-		"data": gin.H{
-			"address": UserAddress,
-		},
+	response.OK(c, "successfully added new address", gin.H{
+		"address": UserAddress,
 	})
 }
 
-func GetUserAddress(c *gin.Context) {
+func (h *Handler) GetUserAddress(c *gin.Context) {
 
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 	//get the addresses where user_id == UserID
-	var UserAddresses []model.Address
-	if err := database.DB.Where("user_id = ?", UserID).Find(&UserAddresses).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get informations from the database",
-		})
+	UserAddresses, err := h.Users.Addresses(UserID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get informations from the database")
 		return
 	}
 
 	//checking the user sending is performing in his/her account..
-	email, ok := EmailFromUserID(uint(UserID))
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user email from the database",
-		})
+	User, err := h.Users.ByID(UserID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get user email from the database")
 		return
 	}
-	if err := VerifyJWT(c, model.UserRole, email); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized user",
-		})
+	if err := VerifyJWT(c, model.UserRole, User.Email); err != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized user")
 		return
 	}
 
 	//check if there's any address related to the user
 	if len(UserAddresses) == 0 {
 
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "no addresses related to the userid",
-		})
+		response.Error(c, response.CodeNotFound, "no addresses related to the userid")
 		return
 	}
 
 	//return the addresses
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully retrieved user address",
-		"data": gin.H{
-			"addresses": UserAddresses,
-		},
+	response.OK(c, "successfully retrieved user address", gin.H{
+		"addresses": UserAddresses,
 	})
 }
 
-func EditUserAddress(c *gin.Context) {
+func (h *Handler) EditUserAddress(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 
 	var updateUserAddress model.EditUserAddress
 	updateUserAddress.UserID = UserID
@@ -417,41 +340,27 @@ func EditUserAddress(c *gin.Context) {
 	// Bind the incoming JSON to the updateUserAddress struct
 	if err := c.BindJSON(&updateUserAddress); err != nil {
 
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "failed to bind the incoming request",
-		})
+		response.Error(c, response.CodeBadRequest, "failed to bind the incoming request")
 		return
 	}
 
-	fmt.Println(updateUserAddress)
-
 	// Retrieve the existing UserAddress record
-	var existingUserAddress model.Address
-	if err := database.DB.Where("user_id =? AND address_id =?", updateUserAddress.UserID, updateUserAddress.AddressID).First(&existingUserAddress).Error; err != nil {
+	existingUserAddress, err := h.Users.Address(updateUserAddress.UserID, updateUserAddress.AddressID)
+	if err != nil {
 
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "address not found",
-		})
+		response.Error(c, response.CodeNotFound, "address not found")
 		return
 	}
 
 	//check if the user is not impersonating other users through jwt email and users email match..
-	email, ok := EmailFromUserID(existingUserAddress.UserID)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user email from the database",
-		})
+	User, err := h.Users.ByID(existingUserAddress.UserID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get user email from the database")
 		return
 	}
-	if err := VerifyJWT(c, model.UserRole, email); err != nil {
+	if err := VerifyJWT(c, model.UserRole, User.Email); err != nil {
 
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized user",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized user")
 		return
 	}
 
@@ -465,39 +374,27 @@ func EditUserAddress(c *gin.Context) {
 	existingUserAddress.PostalCode = updateUserAddress.PostalCode
 
 	// Save the updated record back to the database
-	if err := database.DB.Updates(&existingUserAddress).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update the address",
-		})
+	if err := h.Users.UpdateAddress(&existingUserAddress); err != nil {
+		response.Error(c, response.CodeInternal, "failed to update the address")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "address updated successfully",
-	})
+	response.OK(c, "address updated successfully", nil)
 }
 
-func DeleteUserAddress(c *gin.Context) {
+func (h *Handler) DeleteUserAddress(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 
 	// Bind the incoming JSON to the updateUserAddress struct
 	var addressidstr string
 	if addressidstr = c.Query("addressid"); addressidstr == "" { //use query ?addressid = 1
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "provide the addressid to delete the address",
-		})
+		response.Error(c, response.CodeBadRequest, "provide the addressid to delete the address")
 		return
 	}
 	addressid, _ := strconv.Atoi(addressidstr)
@@ -506,104 +403,70 @@ func DeleteUserAddress(c *gin.Context) {
 	AddressInfo.UserID = UserID
 	AddressInfo.AddressID = uint(addressid)
 	//check the userid and addressid
-	var existingUserAddress model.Address
-	if err := database.DB.Where("user_id =? AND address_id =?", AddressInfo.UserID, AddressInfo.AddressID).First(&existingUserAddress).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "address not found",
-		})
+	existingUserAddress, err := h.Users.Address(AddressInfo.UserID, AddressInfo.AddressID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "address not found")
 		return
 	}
 
 	//check if the user is not impersonating other users ,
 	//using jwt email and users email fo a match
-	email, ok := EmailFromUserID(existingUserAddress.UserID)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{
-			"status":  false,
-			"message": "failed to get user email from the database",
-		})
+	User, err := h.Users.ByID(existingUserAddress.UserID)
+	if err != nil {
+		response.Error(c, response.CodeNotFound, "failed to get user email from the database")
 		return
 	}
-	if errs := VerifyJWT(c, model.UserRole, email); errs != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized user",
-		})
+	if errs := VerifyJWT(c, model.UserRole, User.Email); errs != nil {
+		response.Error(c, response.CodeUnauthorized, "unauthorized user")
 
 		return
 	}
 
 	//delete the user address
-	if err := database.DB.Delete(&existingUserAddress).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to delete the address",
-		})
+	if err := h.Users.DeleteAddress(existingUserAddress); err != nil {
+		response.Error(c, response.CodeInternal, "failed to delete the address")
 		return
 	}
 
 	//return the response
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "address deleted successfully",
-	})
+	response.OK(c, "address deleted successfully", nil)
 }
 
-func UpdateUserInformation(c *gin.Context) {
+func (h *Handler) UpdateUserInformation(c *gin.Context) {
 	//check user api authentication
 	email, role, err := utils.GetJWTClaim(c)
 	if role != model.UserRole || err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"status":  false,
-			"message": "unauthorized request",
-		})
+		response.Error(c, response.CodeUnauthorized, "unauthorized request")
 		return
 	}
-	UserID, _ := UserIDfromEmail(email)
+	UserID, _ := h.userIDfromEmail(email)
 	//bind json
 	var Request model.UpdateUserInformation
 
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
 	//validate
 	if err := utils.Validate(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Invalid(c, err)
 		return
 	}
 
-	if ok := CheckUser(UserID); !ok {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": "user doesnt exist",
-		})
+	if _, err := h.Users.ByID(UserID); err != nil {
+		response.Error(c, response.CodeBadRequest, "user doesnt exist")
 		return
 	}
 
 	//update the user information
-	if err := database.DB.Model(&model.User{}).Where("id = ?", UserID).Updates(&Request).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status":  false,
-			"message": "failed to update user profile",
-		})
+	if err := h.Users.UpdateProfile(UserID, Request); err != nil {
+		response.Error(c, response.CodeInternal, "failed to update user profile")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  true,
-		"message": "successfully updated user profile",
-		"data": gin.H{
-			"user": Request,
-		},
+	response.OK(c, "successfully updated user profile", gin.H{
+		"user": Request,
 	})
 }
 
@@ -613,24 +476,24 @@ func Step1PasswordReset(c *gin.Context) {
 	//receive email
 	var Request model.Step1PasswordReset
 	if err := c.BindJSON(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "failed to bind the request"})
+		response.Error(c, response.CodeBadRequest, "failed to bind the request")
 		return
 	}
 
 	if Request.Role != model.UserRole && Request.Role != model.RestaurantRole {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "role should be either user or restaurant"})
+		response.Error(c, response.CodeBadRequest, "role should be either user or restaurant")
 		return
 	}
 
 	switch Request.Role {
 	case model.UserRole:
 		if !VerifyUserPasswordReset(Request.Email) {
-			c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "unauthorized request"})
+			response.Error(c, response.CodeUnauthorized, "unauthorized request")
 			return
 		}
 	case model.RestaurantRole:
 		if !VerifyRestaurantPasswordReset(Request.Email) {
-			c.JSON(http.StatusUnauthorized, gin.H{"status": false, "message": "unauthorized request"})
+			response.Error(c, response.CodeUnauthorized, "unauthorized request")
 			return
 		}
 	}
@@ -640,16 +503,13 @@ func Step1PasswordReset(c *gin.Context) {
 	ExpiryTime := time.Now().Unix() + 1*60
 
 	//sent email  use smtp with token as que
-	from := "foodbuddycode@gmail.com"
-	appPassword := os.Getenv("SMTPAPP")
-	auth := smtp.PlainAuth("", from, appPassword, "smtp.gmail.com")
-	url := fmt.Sprintf("https://%v/api/v1/auth/passwordreset?email=%v&token=%v&role=%v", utils.GetEnvVariables().ServerIP, Request.Email, ResetToken, Request.Role)
+	url := fmt.Sprintf("https://%v/api/v1/auth/passwordreset?email=%v&token=%v&role=%v", appConfig.Server.PublicHost, Request.Email, ResetToken, Request.Role)
 	mail := fmt.Sprintf("FoodBuddy Password Reset \n Click here to reset your password %v", url)
 
 	//send the otp to the specified email
-	err := smtp.SendMail("smtp.gmail.com:587", auth, from, []string{Request.Email}, []byte(mail))
+	err := gateway.Get().Mail.Send([]string{Request.Email}, []byte(mail))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to sent the password reset mail"})
+		response.Error(c, response.CodeInternal, "failed to sent the password reset mail")
 		return
 	}
 
@@ -665,18 +525,18 @@ func Step1PasswordReset(c *gin.Context) {
 	if err := database.DB.Where("email = ? AND role = ?", Request.Email, Request.Role).First(&CheckEntity).Error; err != nil {
 		//email row doesnt exist create new entry
 		if err := database.DB.Create(&PasswordReset).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to save password reset information, try again"})
+			response.Error(c, response.CodeInternal, "failed to save password reset information, try again")
 			return
 		}
 	} else {
 		//update the mail if it exists
 		if err := database.DB.Where("email = ? AND role = ?", Request.Email, Request.Role).Updates(&PasswordReset).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": "failed to save password reset information, try again"})
+			response.Error(c, response.CodeInternal, "failed to save password reset information, try again")
 			return
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "Successfully sent the password reset email"})
+	response.OK(c, "Successfully sent the password reset email", nil)
 }
 
 func LoadPasswordReset(c *gin.Context) {
@@ -686,17 +546,15 @@ func LoadPasswordReset(c *gin.Context) {
 
 	var PasswordReset model.PasswordReset
 	if err := database.DB.Where("email = ? AND role =?", email, role).First(&PasswordReset).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch password reset information"})
+		response.Error(c, response.CodeNotFound, "failed to fetch password reset information")
 		return
 	}
 
 	if PasswordReset.Active == model.NO {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "link is expired"})
+		response.Error(c, response.CodeBadRequest, "link is expired")
 		return
 	}
 
-	fmt.Println(email, ": ", token)
-
 	c.HTML(http.StatusOK, "passwordreset.html", gin.H{
 		"email": email,
 		"role":  role,
@@ -708,54 +566,51 @@ func Step2PasswordReset(c *gin.Context) {
 	//user clicks the url with token,
 	var Request model.Step2PasswordReset
 	if err := c.ShouldBind(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "failed to bind the request"})
+		response.Error(c, response.CodeBadRequest, "failed to bind the request")
 		return
 	}
 
 	if err := utils.Validate(&Request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": err.Error()})
+		response.Invalid(c, err)
 		return
 	}
 	// this function recieves email, token, passwords(check for password match)
 	//check email
 	var PasswordReset model.PasswordReset
 	if err := database.DB.Where("email = ? AND role =?", Request.Email, Request.Role).First(&PasswordReset).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"status": false, "message": "failed to fetch password reset information"})
+		response.Error(c, response.CodeNotFound, "failed to fetch password reset information")
 		return
 	}
 
 	if PasswordReset.Active == model.NO {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "link is expired"})
+		response.Error(c, response.CodeBadRequest, "link is expired")
 		return
 	}
 
 	if Request.ConfirmPassword != Request.Password {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "password doesnt match"})
+		response.Error(c, response.CodeBadRequest, "password doesnt match")
 		return
 	}
 
 	if Request.Token != PasswordReset.ResetToken {
-		c.JSON(http.StatusBadRequest, gin.H{"status": false, "message": "password token doesnt match"})
+		response.Error(c, response.CodeBadRequest, "password token doesnt match")
 		return
 	}
 
 	err := passwordvalidator.Validate(Request.Password, model.PasswordEntropy)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  false,
-			"message": err.Error(),
-		})
+		response.Error(c, response.CodeBadRequest, err.Error())
 		return
 	}
 
 	//call step3
 	ok, err := Step3PasswordReset(Request)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"status": false, "message": err.Error()})
+		response.Error(c, response.CodeInternal, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": true, "message": "Successfully Reseted password"})
+	response.OK(c, "Successfully Reseted password", nil)
 }
 
 func Step3PasswordReset(Request model.Step2PasswordReset) (bool, error) {
@@ -764,12 +619,8 @@ func Step3PasswordReset(Request model.Step2PasswordReset) (bool, error) {
 		return false, errors.New("please ensure both the passwords are same")
 	}
 
-	// generate salt
-	salt := utils.GenerateRandomString(7)
-
-	// combine salt and password and hash it
-	saltedPassword := salt + Request.Password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(saltedPassword), bcrypt.DefaultCost)
+	// hash the password with a fresh salt
+	salt, hashedPassword, err := HashPassword(Request.Password)
 	if err != nil {
 		return false, errors.New("failed to hash the password")
 	}
@@ -779,7 +630,7 @@ func Step3PasswordReset(Request model.Step2PasswordReset) (bool, error) {
 		user := model.User{
 			Email:          Request.Email,
 			Salt:           salt,
-			HashedPassword: string(hashedPassword),
+			HashedPassword: hashedPassword,
 		}
 		if err := database.DB.Model(&user).Where("email = ?", user.Email).Updates(user).Error; err != nil {
 			return false, errors.New("failed to update the password")
@@ -788,7 +639,7 @@ func Step3PasswordReset(Request model.Step2PasswordReset) (bool, error) {
 		restaurant := model.Restaurant{
 			Email:          Request.Email,
 			Salt:           salt,
-			HashedPassword: string(hashedPassword),
+			HashedPassword: hashedPassword,
 		}
 		if err := database.DB.Model(&restaurant).Where("email = ?", restaurant.Email).Updates(restaurant).Error; err != nil {
 			return false, errors.New("failed to update the password")
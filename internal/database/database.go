@@ -2,10 +2,11 @@ package database
 
 import (
 	"fmt"
+	"foodbuddy/internal/config"
+	"foodbuddy/internal/logging"
 	"foodbuddy/internal/model"
-	"foodbuddy/internal/utils"
-	"log"
-	"os"
+	"log/slog"
+	"regexp"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -13,71 +14,69 @@ import (
 
 var DB *gorm.DB
 
-func ConnectToDB() {
+func ConnectToDB(cfg config.Database) {
 	var err error
-	databaseCredentials := utils.GetEnvVariables()
 
-	dsn := fmt.Sprintf("%s:%s@tcp(mysql.foodbuddy:3306)/?parseTime=true", databaseCredentials.DBUser, databaseCredentials.DBPassword)
-	log.Println("Connecting to MySQL server with DSN:", dsn)
+	slog.Info("connecting to MySQL server", "host", cfg.Host, "port", cfg.Port)
 
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	server, err := gorm.Open(mysql.Open(cfg.DSN("")), &gorm.Config{Logger: logging.NewGormLogger()})
 	if err != nil {
-		log.Fatalf("unable to connect to MySQL server: %v", err)
+		logging.Fatal("unable to connect to MySQL server", "error", err)
 	}
 
-	if !databaseExists(databaseCredentials.DBName) {
-		if err := createDatabase(databaseCredentials.DBName); err != nil {
-			log.Printf("Warning: %v", err)
+	if !databaseExists(server, cfg.Name) {
+		if err := createDatabase(server, cfg.Name); err != nil {
+			slog.Warn("failed to create database", "error", err)
 		}
 	}
+	if sqlDB, err := server.DB(); err == nil {
+		sqlDB.Close()
+	}
 
-	dsn = fmt.Sprintf("%s:%s@tcp(mysql.foodbuddy:3306)/%s?parseTime=true", databaseCredentials.DBUser, databaseCredentials.DBPassword, databaseCredentials.DBName)
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(mysql.Open(cfg.DSN(cfg.Name)), &gorm.Config{Logger: logging.NewGormLogger()})
 	if err != nil {
-		log.Fatalf("unable to connect to database: %v", err)
+		logging.Fatal("unable to connect to database", "database", cfg.Name, "error", err)
 	} else {
-		log.Println("Connection to database: OK")
+		slog.Info("connected to database", "database", cfg.Name)
 	}
-
-	AutoMigrate()
 }
 
-func databaseExists(dbName string) bool {
-	var exists int
-	dsn := fmt.Sprintf("%s:%s@tcp(mysql.foodbuddy:3306)/?parseTime=true", os.Getenv("DBUSER"), os.Getenv("DBPASSWORD"))
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		log.Fatalf("unable to connect to MySQL server: %v", err)
-	}
+// database names are used as identifiers in the create statement, so only plain names are allowed
+var databaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
 
+func databaseExists(db *gorm.DB, dbName string) bool {
+	var exists int
 	if err := db.Raw("SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name = ?", dbName).Scan(&exists).Error; err != nil {
-		log.Printf("Failed to check database existence: %v", err)
+		slog.Error("failed to check database existence", "error", err)
 		return false
 	}
 	return exists > 0
 }
 
-func createDatabase(dbName string) error {
-	dsn := fmt.Sprintf("%s:%s@tcp(mysql.foodbuddy:3306)/?parseTime=true", os.Getenv("DBUSER"), os.Getenv("DBPASSWORD"))
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return fmt.Errorf("unable to connect to MySQL server: %w", err)
+func createDatabase(db *gorm.DB, dbName string) error {
+	if !databaseNamePattern.MatchString(dbName) {
+		return fmt.Errorf("invalid database name %q, only letters, digits and underscores are allowed", dbName)
 	}
 
-	if err := db.Exec("CREATE DATABASE " + dbName).Error; err != nil {
+	if err := db.Exec("CREATE DATABASE `" + dbName + "`").Error; err != nil {
 		return fmt.Errorf("failed to create database: %w", err)
 	}
-	log.Printf("Database %s created successfully!", dbName)
+	slog.Info("database created", "database", dbName)
 	return nil
 }
 
-func AutoMigrate() {
-	err := DB.AutoMigrate(
+// create or update the tables of every model on the given connection,
+// used for sqlite databases, mysql is migrated with the versioned migrations
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
 		&model.User{},
 		&model.Restaurant{},
+		&model.RestaurantDocument{},
 		&model.Category{},
 		&model.Product{},
 		&model.FavouriteProduct{},
+		&model.Bundle{},
+		&model.BundleItem{},
 		&model.Address{},
 		&model.Admin{},
 		&model.VerificationTable{},
@@ -87,14 +86,25 @@ func AutoMigrate() {
 		&model.Payment{},
 		&model.PasswordReset{},
 		&model.CouponInventory{},
+		&model.CouponTarget{},
+		&model.CouponCampaign{},
+		&model.CouponRedemption{},
 		&model.CouponUsage{},
 		&model.UserWalletHistory{},
 		&model.RestaurantWalletHistory{},
 		&model.UserReferralHistory{},
 		&model.DeliveryVerification{},
+		&model.RateLimitBucket{},
+		&model.AuditLog{},
+		&model.AccountBlock{},
+		&model.BlockAppeal{},
+		&model.SupportTicket{},
+		&model.SupportTicketItem{},
+		&model.SupportMessage{},
+		&model.RestaurantReview{},
+		&model.ReviewFlag{},
+		&model.ReviewVote{},
+		&model.ReviewPhoto{},
+		&model.ReviewRevision{},
 	)
-
-	if err != nil {
-		log.Fatal("failed to automigrate models:", err)
-	}
 }
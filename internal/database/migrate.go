@@ -0,0 +1,300 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	migrationLockName    = "foodbuddy_schema_migrations"
+	migrationLockTimeout = 60 //seconds
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// one versioned schema change, files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// row of the schema version table, one per applied migration
+type SchemaMigration struct {
+	Version   uint      `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;type:varchar(255)"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// embedded migrations ordered by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("unexpected migration file %s", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionStr, migrationName, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseUint(versionStr, 10, 32)
+		if !ok || err != nil || version == 0 {
+			return nil, fmt.Errorf("migration file %s should be named <version>_<name>.%s.sql", name, direction)
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: migrationName}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != migrationName {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// version the binary expects the database to be at
+func LatestVersion() (uint, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// highest applied version, 0 on a database that was never migrated
+func SchemaVersion(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+	var version uint
+	err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// refuse databases that are behind or ahead of the embedded migrations
+func CheckSchema(db *gorm.DB) error {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("database schema is at version %d, expected %d, run `foodbuddy migrate up`", current, latest)
+	}
+	if current > latest {
+		return fmt.Errorf("database schema is at version %d, newer than the expected %d of this build", current, latest)
+	}
+	return nil
+}
+
+// apply every pending migration in order and return the applied ones
+func MigrateUp(db *gorm.DB) (applied []Migration, err error) {
+	err = withMigrationLock(db, func(db *gorm.DB) error {
+		applied, err = migrateUp(db)
+		return err
+	})
+	return applied, err
+}
+
+func migrateUp(db *gorm.DB) ([]Migration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema version table: %w", err)
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		if err := execStatements(db, migration.Up); err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if err := db.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error; err != nil {
+			return applied, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// revert the last applied migrations, newest first
+func MigrateDown(db *gorm.DB, steps int) (reverted []Migration, err error) {
+	if steps < 1 {
+		return nil, errors.New("steps should be a positive number")
+	}
+	err = withMigrationLock(db, func(db *gorm.DB) error {
+		reverted, err = migrateDown(db, steps)
+		return err
+	})
+	return reverted, err
+}
+
+func migrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := migrations[i]
+		if migration.Version > current {
+			continue
+		}
+		if err := execStatements(db, migration.Down); err != nil {
+			return reverted, fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if err := db.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error; err != nil {
+			return reverted, fmt.Errorf("failed to remove migration %d: %w", migration.Version, err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// every embedded migration with its applied state
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []SchemaMigration
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Find(&applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	appliedAt := make(map[uint]time.Time, len(applied))
+	for _, row := range applied {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		at, ok := appliedAt[migration.Version]
+		status = append(status, MigrationStatus{Migration: migration, Applied: ok, AppliedAt: at})
+	}
+	return status, nil
+}
+
+// replicas starting together run the migrations one at a time,
+// mysql named locks belong to a connection so the whole run is pinned to one
+func withMigrationLock(db *gorm.DB, fn func(db *gorm.DB) error) error {
+	if db.Dialector.Name() != "mysql" {
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		var locked int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+		if locked != 1 {
+			return errors.New("timed out waiting for the migration lock")
+		}
+		defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+		return fn(conn)
+	})
+}
+
+// mysql runs one statement per call and commits ddl implicitly, so statements run one by one
+func execStatements(db *gorm.DB, script string) error {
+	for _, statement := range splitStatements(script) {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// split a script on the semicolons ending a line, comment lines are dropped
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMigrationsAreSequential(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != uint(i+1) {
+			t.Fatalf("migration %d_%s should be version %d", migration.Version, migration.Name, i+1)
+		}
+	}
+}
+
+// the baseline only recreates the tables that predate the migrations, changes to them belong
+// in later migrations or databases built by automigrate never get them
+func TestBaselineHasNoLaterChanges(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, later := range []string{"bundle", "FULLTEXT"} {
+		if strings.Contains(migrations[0].Up, later) {
+			t.Errorf("0001_%s mentions %s", migrations[0].Name, later)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("-- comment\nCREATE TABLE `a` (\n  `id` int\n);\n\nDROP TABLE `b`;\nSELECT 1")
+	want := []string{"CREATE TABLE `a` (\n  `id` int\n)", "DROP TABLE `b`", "SELECT 1"}
+	if len(statements) != len(want) {
+		t.Fatalf("statements = %q", statements)
+	}
+	for i := range want {
+		if statements[i] != want[i] {
+			t.Errorf("statement %d = %q, want %q", i, statements[i], want[i])
+		}
+	}
+}
+
+// tables the migrations alter, as automigrate built them before the migrations existed
+type baselineRestaurant struct {
+	gorm.Model
+	Name               string
+	Description        string
+	Address            string
+	Email              string
+	PhoneNumber        uint
+	WalletAmount       float64
+	ImageURL           string
+	CertificateURL     string
+	VerificationStatus string
+	Blocked            bool
+	Salt               string
+	HashedPassword     string
+}
+
+func (baselineRestaurant) TableName() string { return "restaurants" }
+
+type baselineProduct struct {
+	gorm.Model
+	RestaurantID    uint
+	CategoryID      uint
+	Name            string
+	Description     string
+	ImageURL        string
+	Price           float64
+	PreparationTime float64
+	MaxStock        uint
+	OfferAmount     float64
+	StockLeft       uint
+	RatingSum       float64
+	RatingCount     uint
+	AverageRating   float64
+	Veg             string
+}
+
+func (baselineProduct) TableName() string { return "products" }
+
+type baselineCartItem struct {
+	UserID         uint
+	ProductID      uint
+	RestaurantID   uint
+	Quantity       uint
+	CookingRequest string
+}
+
+func (baselineCartItem) TableName() string { return "cart_items" }
+
+type baselineOrderItem struct {
+	OrderID            string
+	UserID             uint
+	RestaurantID       uint
+	ProductID          uint
+	Quantity           uint
+	Amount             float64
+	ProductOfferAmount float64
+	AfterDeduction     float64
+	CookingRequest     string
+	OrderStatus        string
+	OrderReview        string
+	OrderRating        float64
+}
+
+func (baselineOrderItem) TableName() string { return "order_items" }
+
+type baselineCoupon struct {
+	CouponCode    string `gorm:"primary_key"`
+	Expiry        uint
+	Percentage    uint
+	MaximumUsage  uint
+	MinimumAmount float64
+}
+
+func (baselineCoupon) TableName() string { return "coupon_inventories" }
+
+// FOODBUDDY_TEST_MYSQL_DSN points at an empty scratch database, the test drops every table it creates
+func TestMigrateUpOnAutoMigratedDatabase(t *testing.T) {
+	dsn := os.Getenv("FOODBUDDY_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("FOODBUDDY_TEST_MYSQL_DSN is not set")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&baselineRestaurant{}, &baselineProduct{}, &baselineCartItem{}, &baselineOrderItem{}, &baselineCoupon{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO cart_items (user_id, product_id, restaurant_id, quantity) VALUES (1, 1, 1, 1)").Error; err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := MigrateDown(db, len(migrations)); err != nil {
+			t.Errorf("migrate down: %v", err)
+		}
+		db.Migrator().DropTable(&SchemaMigration{})
+	})
+
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSchema(db); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator := db.Migrator()
+	for _, column := range []struct{ Table, Column string }{
+		{"cart_items", "bundle_id"},
+		{"restaurants", "average_rating"},
+		{"order_items", "delivered_at"},
+		{"coupon_inventories", "campaign_id"},
+		{"order_items", "refunded_amount"},
+		{"support_tickets", "resolution_breached"},
+	} {
+		if !migrator.HasColumn(column.Table, column.Column) {
+			t.Errorf("%s has no %s column", column.Table, column.Column)
+		}
+	}
+	for _, index := range []struct{ Table, Name string }{
+		{"products", "ft_products_search"},
+		{"restaurants", "ft_restaurants_search"},
+	} {
+		if !migrator.HasIndex(index.Table, index.Name) {
+			t.Errorf("%s has no %s index", index.Table, index.Name)
+		}
+	}
+	if !migrator.HasConstraint("bundle_items", "fk_bundles_items") {
+		t.Error("bundle_items has no fk_bundles_items constraint")
+	}
+
+	// rows from before the migration are product rows
+	var Existing int64
+	if err := db.Table("cart_items").Where("bundle_id = 0").Count(&Existing).Error; err != nil || Existing != 1 {
+		t.Errorf("existing cart rows with bundle_id 0: %d, %v", Existing, err)
+	}
+	// the search query runs against the new indexes
+	var Matches int64
+	if err := db.Table("products").Where("MATCH(name, description) AGAINST (? IN BOOLEAN MODE)", "burger*").Count(&Matches).Error; err != nil {
+		t.Errorf("fulltext search failed: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS `delivery_verifications`;
+DROP TABLE IF EXISTS `user_referral_histories`;
+DROP TABLE IF EXISTS `restaurant_wallet_histories`;
+DROP TABLE IF EXISTS `user_wallet_histories`;
+DROP TABLE IF EXISTS `coupon_usages`;
+DROP TABLE IF EXISTS `coupon_inventories`;
+DROP TABLE IF EXISTS `password_resets`;
+DROP TABLE IF EXISTS `payments`;
+DROP TABLE IF EXISTS `order_items`;
+DROP TABLE IF EXISTS `orders`;
+DROP TABLE IF EXISTS `cart_items`;
+DROP TABLE IF EXISTS `verification_tables`;
+DROP TABLE IF EXISTS `admins`;
+DROP TABLE IF EXISTS `addresses`;
+DROP TABLE IF EXISTS `favourite_products`;
+DROP TABLE IF EXISTS `products`;
+DROP TABLE IF EXISTS `categories`;
+DROP TABLE IF EXISTS `restaurants`;
+DROP TABLE IF EXISTS `users`;
@@ -0,0 +1,234 @@
+-- tables as they were created by gorm automigrate, existing databases keep their tables
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `name` varchar(255),
+  `email` varchar(255),
+  `phone_number` varchar(255),
+  `picture` text,
+  `referral_code` longtext,
+  `wallet_amount` double,
+  `login_method` varchar(255),
+  `blocked` boolean,
+  `salt` varchar(255),
+  `hashed_password` varchar(255),
+  PRIMARY KEY (`id`),
+  INDEX `idx_users_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `restaurants` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `name` longtext,
+  `description` longtext,
+  `address` longtext,
+  `email` longtext,
+  `phone_number` bigint unsigned,
+  `wallet_amount` double,
+  `image_url` longtext,
+  `certificate_url` longtext,
+  `verification_status` longtext,
+  `blocked` boolean,
+  `salt` longtext,
+  `hashed_password` longtext,
+  PRIMARY KEY (`id`),
+  INDEX `idx_restaurants_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `categories` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `name` longtext,
+  `description` longtext,
+  `image_url` longtext,
+  `offer_percentage` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_categories_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `products` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `restaurant_id` bigint unsigned,
+  `category_id` bigint unsigned,
+  `name` longtext,
+  `description` longtext,
+  `image_url` longtext,
+  `price` double,
+  `preparation_time` double,
+  `max_stock` bigint unsigned,
+  `offer_amount` double,
+  `stock_left` bigint unsigned,
+  `rating_sum` double,
+  `rating_count` bigint unsigned,
+  `average_rating` double,
+  `veg` longtext,
+  PRIMARY KEY (`id`),
+  INDEX `idx_products_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_categories_products` FOREIGN KEY (`category_id`) REFERENCES `categories` (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `favourite_products` (
+  `user_id` bigint unsigned,
+  `product_id` bigint unsigned
+);
+
+CREATE TABLE IF NOT EXISTS `addresses` (
+  `user_id` bigint unsigned,
+  `address_id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `phone_number` bigint unsigned,
+  `address_type` longtext,
+  `street_name` longtext,
+  `street_number` longtext,
+  `city` longtext,
+  `state` longtext,
+  `postal_code` longtext,
+  PRIMARY KEY (`address_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `admins` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `email` longtext,
+  PRIMARY KEY (`id`),
+  INDEX `idx_admins_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `verification_tables` (
+  `email` varchar(255),
+  `role` longtext,
+  `otp` bigint unsigned,
+  `otp_expiry` bigint unsigned,
+  `verification_status` varchar(255)
+);
+
+CREATE TABLE IF NOT EXISTS `cart_items` (
+  `user_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  `restaurant_id` bigint unsigned,
+  `quantity` bigint unsigned,
+  `cooking_request` longtext
+);
+
+CREATE TABLE IF NOT EXISTS `orders` (
+  `order_id` longtext,
+  `user_id` bigint unsigned,
+  `restaurant_id` bigint unsigned,
+  `address_id` bigint unsigned,
+  `item_count` bigint unsigned,
+  `coupon_code` longtext,
+  `coupon_discount_amount` double,
+  `product_offer_amount` double,
+  `total_amount` double,
+  `final_amount` double,
+  `payment_method` longtext,
+  `payment_status` longtext,
+  `ordered_at` datetime(3) NULL
+);
+
+CREATE TABLE IF NOT EXISTS `order_items` (
+  `order_id` longtext,
+  `user_id` bigint unsigned,
+  `restaurant_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  `quantity` bigint unsigned,
+  `amount` double,
+  `product_offer_amount` double,
+  `after_deduction` double,
+  `cooking_request` longtext,
+  `order_status` longtext,
+  `order_review` longtext,
+  `order_rating` double
+);
+
+CREATE TABLE IF NOT EXISTS `payments` (
+  `order_id` longtext,
+  `wallet_payment_id` longtext,
+  `stripe_session_id` longtext,
+  `stripe_payment_id` longtext,
+  `razorpay_order_id` longtext,
+  `razorpay_payment_id` longtext,
+  `razorpay_signature` longtext,
+  `payment_gateway` longtext,
+  `payment_status` longtext
+);
+
+CREATE TABLE IF NOT EXISTS `password_resets` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `email` longtext,
+  `role` longtext,
+  `reset_token` longtext,
+  `active` longtext,
+  `expiry_time` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_password_resets_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `coupon_inventories` (
+  `coupon_code` varchar(191) NOT NULL,
+  `expiry` bigint unsigned,
+  `percentage` bigint unsigned,
+  `maximum_usage` bigint unsigned,
+  `minimum_amount` double,
+  PRIMARY KEY (`coupon_code`)
+);
+
+CREATE TABLE IF NOT EXISTS `coupon_usages` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `user_id` bigint unsigned,
+  `coupon_code` longtext,
+  `usage_count` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_coupon_usages_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `user_wallet_histories` (
+  `transaction_time` datetime(3) NULL,
+  `wallet_payment_id` longtext,
+  `user_id` bigint unsigned,
+  `type` longtext,
+  `order_id` longtext,
+  `amount` double,
+  `current_balance` double,
+  `reason` longtext
+);
+
+CREATE TABLE IF NOT EXISTS `restaurant_wallet_histories` (
+  `transaction_time` datetime(3) NULL,
+  `type` longtext,
+  `order_id` longtext,
+  `restaurant_id` bigint unsigned,
+  `amount` double,
+  `current_balance` double,
+  `reason` longtext
+);
+
+CREATE TABLE IF NOT EXISTS `user_referral_histories` (
+  `user_id` bigint unsigned,
+  `referral_code` longtext,
+  `referred_by` longtext,
+  `refer_claimed` boolean
+);
+
+CREATE TABLE IF NOT EXISTS `delivery_verifications` (
+  `order_id` longtext,
+  `user_id` bigint unsigned,
+  `otp` bigint unsigned,
+  `last_sent_at` bigint unsigned
+);
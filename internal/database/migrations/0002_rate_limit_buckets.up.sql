@@ -0,0 +1,8 @@
+-- token buckets shared by every replica when RATELIMIT_STORE=database
+CREATE TABLE IF NOT EXISTS `rate_limit_buckets` (
+  `bucket_key` varchar(191) NOT NULL,
+  `tokens` double,
+  `last_seen` datetime(3) NULL,
+  PRIMARY KEY (`bucket_key`),
+  INDEX `idx_rate_limit_buckets_last_seen` (`last_seen`)
+);
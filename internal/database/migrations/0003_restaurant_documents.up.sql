@@ -0,0 +1,16 @@
+-- onboarding documents of restaurants and their review
+CREATE TABLE IF NOT EXISTS `restaurant_documents` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `restaurant_id` bigint unsigned,
+  `type` varchar(32),
+  `reference` longtext,
+  `ifsc` longtext,
+  `file_url` longtext,
+  `status` varchar(32),
+  `comment` longtext,
+  `reviewed_by` longtext,
+  `reviewed_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_restaurant_documents_restaurant_id` (`restaurant_id`)
+);
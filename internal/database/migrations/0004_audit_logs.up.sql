@@ -0,0 +1,24 @@
+-- privileged changes, the triggers keep the table append only
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `actor_email` varchar(191),
+  `actor_role` varchar(32),
+  `action` varchar(64),
+  `entity_type` varchar(64),
+  `entity_id` varchar(191),
+  `changes` text,
+  `ip` varchar(64),
+  `request_id` varchar(64),
+  PRIMARY KEY (`id`),
+  INDEX `idx_audit_logs_created_at` (`created_at`),
+  INDEX `idx_audit_logs_actor_email` (`actor_email`),
+  INDEX `idx_audit_logs_action` (`action`),
+  INDEX `idx_audit_logs_entity` (`entity_type`, `entity_id`)
+);
+
+CREATE TRIGGER `audit_logs_no_update` BEFORE UPDATE ON `audit_logs` FOR EACH ROW
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append only';
+
+CREATE TRIGGER `audit_logs_no_delete` BEFORE DELETE ON `audit_logs` FOR EACH ROW
+  SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append only';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `block_appeals`;
+DROP TABLE IF EXISTS `account_blocks`;
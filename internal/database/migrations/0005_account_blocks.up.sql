@@ -0,0 +1,34 @@
+-- why and until when accounts are blocked, and the appeals against the blocks
+CREATE TABLE IF NOT EXISTS `account_blocks` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `account_role` varchar(32),
+  `account_id` bigint unsigned,
+  `reason` varchar(32),
+  `note` text,
+  `blocked_by` longtext,
+  `expires_at` datetime(3) NULL,
+  `lifted_at` datetime(3) NULL,
+  `lifted_by` longtext,
+  `appeal_token_hash` varchar(64),
+  PRIMARY KEY (`id`),
+  INDEX `idx_account_blocks_account` (`account_role`, `account_id`),
+  INDEX `idx_account_blocks_expires_at` (`expires_at`),
+  INDEX `idx_account_blocks_appeal_token_hash` (`appeal_token_hash`)
+);
+
+CREATE TABLE IF NOT EXISTS `block_appeals` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `block_id` bigint unsigned,
+  `account_role` varchar(32),
+  `account_id` bigint unsigned,
+  `message` text,
+  `status` varchar(32),
+  `response` text,
+  `reviewed_by` longtext,
+  `reviewed_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_block_appeals_block_id` (`block_id`),
+  INDEX `idx_block_appeals_status` (`status`)
+);
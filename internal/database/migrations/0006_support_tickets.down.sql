@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `support_messages`;
+DROP TABLE IF EXISTS `support_ticket_items`;
+DROP TABLE IF EXISTS `support_tickets`;
@@ -0,0 +1,44 @@
+-- support tickets about order items and their conversation
+CREATE TABLE IF NOT EXISTS `support_tickets` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `user_id` bigint unsigned,
+  `order_id` varchar(64),
+  `restaurant_id` bigint unsigned,
+  `category` varchar(32),
+  `description` text,
+  `status` varchar(32),
+  `first_response_due_at` datetime(3) NULL,
+  `resolution_due_at` datetime(3) NULL,
+  `first_responded_at` datetime(3) NULL,
+  `sla_breached` boolean,
+  `resolution` varchar(32),
+  `refund_amount` double,
+  `resolved_by` longtext,
+  `resolved_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_support_tickets_user_id` (`user_id`),
+  INDEX `idx_support_tickets_order_id` (`order_id`),
+  INDEX `idx_support_tickets_restaurant_id` (`restaurant_id`),
+  INDEX `idx_support_tickets_status` (`status`),
+  INDEX `idx_support_tickets_sla_breached` (`sla_breached`)
+);
+
+CREATE TABLE IF NOT EXISTS `support_ticket_items` (
+  `ticket_id` bigint unsigned NOT NULL,
+  `product_id` bigint unsigned NOT NULL,
+  PRIMARY KEY (`ticket_id`, `product_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `support_messages` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `ticket_id` bigint unsigned,
+  `author_role` varchar(32),
+  `author_email` longtext,
+  `body` text,
+  `attachment_url` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_support_messages_ticket_id` (`ticket_id`)
+);
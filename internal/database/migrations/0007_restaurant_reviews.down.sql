@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS `review_votes`;
+DROP TABLE IF EXISTS `review_flags`;
+DROP TABLE IF EXISTS `restaurant_reviews`;
+ALTER TABLE `restaurants`
+  DROP COLUMN `average_rating`,
+  DROP COLUMN `rating_count`,
+  DROP COLUMN `food_rating`,
+  DROP COLUMN `packaging_rating`,
+  DROP COLUMN `delivery_rating`;
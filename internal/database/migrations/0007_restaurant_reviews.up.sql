@@ -0,0 +1,51 @@
+-- restaurant reviews with owner replies, flags and helpful votes
+ALTER TABLE `restaurants`
+  ADD COLUMN `average_rating` double,
+  ADD COLUMN `rating_count` bigint unsigned,
+  ADD COLUMN `food_rating` double,
+  ADD COLUMN `packaging_rating` double,
+  ADD COLUMN `delivery_rating` double;
+
+CREATE TABLE IF NOT EXISTS `restaurant_reviews` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `restaurant_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `order_id` varchar(64),
+  `rating` double,
+  `food_rating` bigint unsigned,
+  `packaging_rating` bigint unsigned,
+  `delivery_rating` bigint unsigned,
+  `text` text,
+  `status` varchar(32),
+  `flagged` boolean,
+  `flag_count` bigint unsigned,
+  `helpful_count` bigint unsigned,
+  `reply` text,
+  `replied_at` datetime(3) NULL,
+  `moderation_note` longtext,
+  PRIMARY KEY (`id`),
+  INDEX `idx_restaurant_reviews_restaurant_id` (`restaurant_id`),
+  INDEX `idx_restaurant_reviews_user_id` (`user_id`),
+  UNIQUE INDEX `idx_restaurant_reviews_order_id` (`order_id`),
+  INDEX `idx_restaurant_reviews_status` (`status`),
+  INDEX `idx_restaurant_reviews_flagged` (`flagged`)
+);
+
+CREATE TABLE IF NOT EXISTS `review_flags` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `review_id` bigint unsigned,
+  `reporter_role` varchar(32),
+  `reporter_id` bigint unsigned,
+  `reason` longtext,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_review_flags_reporter` (`review_id`, `reporter_role`, `reporter_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `review_votes` (
+  `review_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  PRIMARY KEY (`review_id`, `user_id`)
+);
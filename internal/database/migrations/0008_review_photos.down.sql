@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `review_revisions`;
+DROP TABLE IF EXISTS `review_photos`;
+ALTER TABLE `order_items` DROP COLUMN `delivered_at`;
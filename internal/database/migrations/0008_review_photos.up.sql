@@ -0,0 +1,31 @@
+-- delivery time of order items for the review window, photos and edit history of item reviews
+ALTER TABLE `order_items` ADD COLUMN `delivered_at` datetime(3) NULL;
+
+-- items delivered before the column existed count from the order time
+UPDATE `order_items` JOIN `orders` ON `orders`.`order_id` = `order_items`.`order_id`
+SET `order_items`.`delivered_at` = `orders`.`ordered_at`
+WHERE `order_items`.`order_status` = 'DELIVERED';
+
+CREATE TABLE IF NOT EXISTS `review_photos` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `order_id` varchar(64),
+  `product_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `url` text,
+  `thumb_url` text,
+  PRIMARY KEY (`id`),
+  INDEX `idx_review_photos_item` (`order_id`, `product_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `review_revisions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `order_id` varchar(64),
+  `product_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `review` text,
+  `rating` double,
+  PRIMARY KEY (`id`),
+  INDEX `idx_review_revisions_item` (`order_id`, `product_id`)
+);
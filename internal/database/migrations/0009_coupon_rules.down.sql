@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS `coupon_targets`;
+ALTER TABLE `coupon_inventories`
+  DROP INDEX `idx_coupon_inventories_restaurant_id`,
+  DROP COLUMN `restaurant_id`,
+  DROP COLUMN `discount_type`,
+  DROP COLUMN `flat_amount`,
+  DROP COLUMN `max_discount`,
+  DROP COLUMN `starts_at`,
+  DROP COLUMN `total_usage_limit`,
+  DROP COLUMN `redeemed_count`,
+  DROP COLUMN `first_order_only`,
+  DROP COLUMN `new_user_days`;
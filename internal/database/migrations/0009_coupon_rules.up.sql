@@ -0,0 +1,19 @@
+-- restaurant coupons, flat discounts, caps, start dates, audience and item restrictions
+ALTER TABLE `coupon_inventories`
+  ADD COLUMN `restaurant_id` bigint unsigned NOT NULL DEFAULT 0,
+  ADD COLUMN `discount_type` varchar(32) NOT NULL DEFAULT 'PERCENTAGE',
+  ADD COLUMN `flat_amount` double NOT NULL DEFAULT 0,
+  ADD COLUMN `max_discount` double NOT NULL DEFAULT 0,
+  ADD COLUMN `starts_at` bigint unsigned NOT NULL DEFAULT 0,
+  ADD COLUMN `total_usage_limit` bigint unsigned NOT NULL DEFAULT 0,
+  ADD COLUMN `redeemed_count` bigint unsigned NOT NULL DEFAULT 0,
+  ADD COLUMN `first_order_only` boolean NOT NULL DEFAULT false,
+  ADD COLUMN `new_user_days` bigint unsigned NOT NULL DEFAULT 0,
+  ADD INDEX `idx_coupon_inventories_restaurant_id` (`restaurant_id`);
+
+CREATE TABLE IF NOT EXISTS `coupon_targets` (
+  `coupon_code` varchar(191) NOT NULL,
+  `target_type` varchar(32) NOT NULL,
+  `target_id` bigint unsigned NOT NULL,
+  PRIMARY KEY (`coupon_code`, `target_type`, `target_id`)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `coupon_redemptions`;
+DROP TABLE IF EXISTS `coupon_campaigns`;
+ALTER TABLE `coupon_inventories`
+  DROP INDEX `idx_coupon_inventories_campaign_id`,
+  DROP COLUMN `campaign_id`;
@@ -0,0 +1,29 @@
+-- bulk single-use codes of a campaign and the redemption of every coupon
+ALTER TABLE `coupon_inventories`
+  ADD COLUMN `campaign_id` bigint unsigned NOT NULL DEFAULT 0,
+  ADD INDEX `idx_coupon_inventories_campaign_id` (`campaign_id`);
+
+CREATE TABLE IF NOT EXISTS `coupon_campaigns` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `name` varchar(128),
+  `description` longtext,
+  `code_prefix` varchar(32),
+  `code_count` bigint unsigned,
+  `created_by` longtext,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `coupon_redemptions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `coupon_code` varchar(191),
+  `campaign_id` bigint unsigned,
+  `order_id` varchar(191),
+  `user_id` bigint unsigned,
+  `discount` double,
+  PRIMARY KEY (`id`),
+  INDEX `idx_coupon_redemptions_coupon_code` (`coupon_code`),
+  INDEX `idx_coupon_redemptions_campaign_id` (`campaign_id`),
+  INDEX `idx_coupon_redemptions_order_id` (`order_id`)
+);
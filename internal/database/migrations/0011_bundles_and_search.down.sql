@@ -0,0 +1,5 @@
+DROP INDEX `ft_restaurants_search` ON `restaurants`;
+DROP INDEX `ft_products_search` ON `products`;
+ALTER TABLE `cart_items` DROP COLUMN `bundle_id`;
+DROP TABLE IF EXISTS `bundle_items`;
+DROP TABLE IF EXISTS `bundles`;
@@ -0,0 +1,27 @@
+-- combo bundles and the fulltext indexes of the public search, added to databases baselined by 0001
+CREATE TABLE IF NOT EXISTS `bundles` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `restaurant_id` bigint unsigned,
+  `name` longtext,
+  `description` longtext,
+  `image_url` longtext,
+  `bundle_price` double,
+  PRIMARY KEY (`id`),
+  INDEX `idx_bundles_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `bundle_items` (
+  `bundle_id` bigint unsigned,
+  `product_id` bigint unsigned,
+  `quantity` bigint unsigned,
+  CONSTRAINT `fk_bundles_items` FOREIGN KEY (`bundle_id`) REFERENCES `bundles` (`id`)
+);
+
+-- product rows of the cart keep bundle_id 0
+ALTER TABLE `cart_items` ADD COLUMN `bundle_id` bigint unsigned NOT NULL DEFAULT 0 AFTER `product_id`;
+
+CREATE FULLTEXT INDEX `ft_products_search` ON `products` (`name`, `description`);
+CREATE FULLTEXT INDEX `ft_restaurants_search` ON `restaurants` (`name`, `description`);
@@ -0,0 +1,7 @@
+-- what was refunded on each order item, refunds are capped by what the item paid
+ALTER TABLE `order_items` ADD COLUMN `refunded_amount` double NOT NULL DEFAULT 0 AFTER `after_deduction`;
+
+-- cancelled items of paid online orders were refunded in full
+UPDATE `order_items` JOIN `orders` ON `orders`.`order_id` = `order_items`.`order_id`
+SET `order_items`.`refunded_amount` = `order_items`.`after_deduction`
+WHERE `order_items`.`order_status` = 'CANCELLED' AND `orders`.`payment_method` = 'ONLINE';
@@ -0,0 +1,9 @@
+ALTER TABLE `support_tickets` ADD COLUMN `sla_breached` boolean AFTER `first_responded_at`, ADD INDEX `idx_support_tickets_sla_breached` (`sla_breached`);
+
+UPDATE `support_tickets` SET `sla_breached` = (`first_response_breached` OR `resolution_breached`);
+
+ALTER TABLE `support_tickets`
+  DROP INDEX `idx_support_tickets_first_response_breached`,
+  DROP INDEX `idx_support_tickets_resolution_breached`,
+  DROP COLUMN `first_response_breached`,
+  DROP COLUMN `resolution_breached`;
@@ -0,0 +1,14 @@
+-- first response and resolution breaches are flagged separately, one no longer hides the other
+ALTER TABLE `support_tickets`
+  ADD COLUMN `first_response_breached` boolean NOT NULL DEFAULT false AFTER `first_responded_at`,
+  ADD COLUMN `resolution_breached` boolean NOT NULL DEFAULT false AFTER `first_response_breached`,
+  ADD INDEX `idx_support_tickets_first_response_breached` (`first_response_breached`),
+  ADD INDEX `idx_support_tickets_resolution_breached` (`resolution_breached`);
+
+-- flagged tickets are split by the due times they missed
+UPDATE `support_tickets`
+SET `first_response_breached` = (`first_responded_at` IS NULL OR `first_responded_at` > `first_response_due_at`),
+    `resolution_breached` = (`resolution_due_at` <= COALESCE(`resolved_at`, NOW(3)))
+WHERE `sla_breached` = true;
+
+ALTER TABLE `support_tickets` DROP INDEX `idx_support_tickets_sla_breached`, DROP COLUMN `sla_breached`;
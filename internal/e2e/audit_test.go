@@ -0,0 +1,88 @@
+package e2e
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	user := h.user("Anu", 0)
+	restaurant := h.restaurant("Paragon")
+
+	blockRequest := `{"reason":"POLICY_VIOLATION","note":"fake reviews"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/block/?userid="+itoa(user.ID), strings.NewReader(blockRequest))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(logging.RequestIDHeader, "block-anu")
+	admin.send(req).ok(t, nil)
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/block?restaurantid="+itoa(restaurant.ID), model.BlockAccountRequest{Reason: model.BlockReasonFoodSafety}).ok(t, nil)
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "WELCOME", Expiry: uint(time.Now().Add(72 * time.Hour).Unix()), Percentage: 10, MaximumUsage: 3, MinimumAmount: 100,
+	}).ok(t, nil)
+	admin.do(http.MethodPatch, "/api/v1/admin/coupon/update", model.CouponInventoryRequest{
+		CouponCode: "WELCOME", Expiry: uint(time.Now().Add(72 * time.Hour).Unix()), Percentage: 20, MaximumUsage: 3, MinimumAmount: 100,
+	}).ok(t, nil)
+	//rejected requests change nothing and leave no entry
+	admin.do(http.MethodPut, "/api/v1/admin/users/block/?userid="+itoa(user.ID), model.BlockAccountRequest{Reason: model.BlockReasonAbuse}).fails(t, string(response.CodeInvalidState))
+
+	var page struct {
+		Entries []model.AuditLog `json:"entries"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/audit", nil).ok(t, &page)
+	if len(page.Entries) != 4 {
+		t.Fatalf("entries = %+v", page.Entries)
+	}
+	blocked := page.Entries[0]
+	if blocked.Action != audit.UserBlock || blocked.ActorEmail != "admin@foodbuddy.test" || blocked.EntityID != itoa(user.ID) || blocked.RequestID != "block-anu" || blocked.IP == "" {
+		t.Errorf("block entry = %+v", blocked)
+	}
+	var changes map[string]audit.Change
+	if err := json.Unmarshal([]byte(blocked.Changes), &changes); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes["blocked"].Before != false || changes["blocked"].After != true {
+		t.Errorf("block changes = %v", blocked.Changes)
+	}
+
+	admin.do(http.MethodGet, "/api/v1/admin/audit?entity_type=coupon&action=coupon.update", nil).ok(t, &page)
+	if len(page.Entries) != 1 || !strings.Contains(page.Entries[0].Changes, `"percentage":{"before":10,"after":20}`) {
+		t.Errorf("coupon updates = %+v", page.Entries)
+	}
+	tomorrow := time.Now().Add(24 * time.Hour).Format(time.DateOnly)
+	admin.do(http.MethodGet, "/api/v1/admin/audit?from="+tomorrow, nil).ok(t, &page)
+	if len(page.Entries) != 0 {
+		t.Errorf("entries from tomorrow = %+v", page.Entries)
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/audit?from=yesterday", nil).fails(t, string(response.CodeBadRequest))
+	user.do(http.MethodGet, "/api/v1/admin/audit", nil).fails(t, string(response.CodeUnauthorized))
+
+	res := admin.do(http.MethodGet, "/api/v1/admin/audit/export?actor=admin@foodbuddy.test", nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", res.Code, res.Raw)
+	}
+	rows, err := csv.NewReader(strings.NewReader(res.Raw)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || rows[0][0] != "ID" || rows[1][4] != audit.UserBlock {
+		t.Errorf("csv = %v", rows)
+	}
+
+	//entries can't be changed or removed
+	if err := h.db.Model(&blocked).Update("action", "user.unblock").Error; err != model.ErrAuditAppendOnly {
+		t.Errorf("updating an entry: %v", err)
+	}
+	if err := h.db.Delete(&blocked).Error; err != model.ErrAuditAppendOnly {
+		t.Errorf("deleting an entry: %v", err)
+	}
+}
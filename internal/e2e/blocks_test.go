@@ -0,0 +1,139 @@
+package e2e
+
+import (
+	"context"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var appealToken = regexp.MustCompile(`use this token: ([0-9a-f]+)`)
+
+func TestBlockExpiryAndAppeal(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+
+	user := h.anonymous()
+	user.do(http.MethodPost, "/api/v1/auth/user/email/signup", model.EmailSignupRequest{
+		Name: "Ravi", Email: "ravi@foodbuddy.test", PhoneNumber: 9876543210, Password: password, ConfirmPassword: password,
+	}).ok(t, nil)
+	link, ok := h.fakes.Mail.Find("ravi@foodbuddy.test", verifyLink)
+	if !ok {
+		t.Fatalf("no verification link was mailed")
+	}
+	user.do(http.MethodGet, "/api/v1/auth/verifyemail/"+link, nil).ok(t, nil)
+	var ravi model.User
+	h.reload(&ravi, "email = ?", "ravi@foodbuddy.test")
+	login := model.EmailLoginRequest{Email: ravi.Email, Password: password}
+
+	block := "/api/v1/admin/users/block/?userid=" + itoa(ravi.ID)
+	admin.do(http.MethodPut, block, nil).fails(t, string(response.CodeBadRequest))
+	admin.do(http.MethodPut, block, model.BlockAccountRequest{Reason: model.BlockReasonOther}).fails(t, string(response.CodeValidation))
+	var suspension model.AccountBlock
+	admin.do(http.MethodPut, block, model.BlockAccountRequest{Reason: model.BlockReasonAbuse, Note: "abusive chat with a rider", DurationHours: 24}).ok(t, &suspension)
+	if suspension.ExpiresAt == nil || suspension.BlockedBy != "admin@foodbuddy.test" {
+		t.Fatalf("suspension = %+v", suspension)
+	}
+	mail, ok := h.fakes.Mail.Last(ravi.Email)
+	if !ok || !strings.Contains(mail.Body, "abusive chat with a rider") {
+		t.Fatalf("no block notice was mailed")
+	}
+
+	//the reason is only told once the password matched
+	user.do(http.MethodPost, "/api/v1/auth/user/email/login", model.EmailLoginRequest{Email: ravi.Email, Password: "wrong-Horse-battery-staple-42"}).
+		fails(t, string(response.CodeBadRequest))
+	res := user.do(http.MethodPost, "/api/v1/auth/user/email/login", login)
+	res.fails(t, string(response.CodeAccountBlocked))
+	if !strings.Contains(res.Message, "abuse until") {
+		t.Errorf("login message = %q", res.Message)
+	}
+
+	//nothing happens before the expiry, the worker lifts it after
+	if err := controllers.ExpireBlocks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h.reload(&ravi, "id = ?", ravi.ID)
+	if !ravi.Blocked {
+		t.Fatalf("block was lifted before its expiry")
+	}
+	h.db.Model(&model.AccountBlock{}).Where("id = ?", suspension.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := controllers.ExpireBlocks(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h.reload(&suspension, "id = ?", suspension.ID)
+	if suspension.LiftedAt == nil || suspension.LiftedBy != controllers.BlockExpiryActor {
+		t.Errorf("expired block = %+v", suspension)
+	}
+	user.do(http.MethodPost, "/api/v1/auth/user/email/login", login).ok(t, nil)
+	var lifted model.AuditLog
+	h.reload(&lifted, "action = ? AND actor_email = ?", audit.UserUnblock, audit.SystemActor)
+
+	//an indefinite block is appealed with the token of the notice
+	admin.do(http.MethodPut, block, model.BlockAccountRequest{Reason: model.BlockReasonFraud, Note: "chargebacks"}).ok(t, nil)
+	mail, _ = h.fakes.Mail.Last(ravi.Email)
+	first := appealToken.FindStringSubmatch(mail.Body)
+	if first == nil {
+		t.Fatalf("notice has no appeal token: %s", mail.Body)
+	}
+	user.do(http.MethodPost, "/api/v1/auth/block/notice", model.BlockNoticeRequest{Email: "nobody@foodbuddy.test", Role: model.UserRole}).ok(t, nil)
+	user.do(http.MethodPost, "/api/v1/auth/block/notice", model.BlockNoticeRequest{Email: ravi.Email, Role: model.UserRole}).ok(t, nil)
+	mail, _ = h.fakes.Mail.Last(ravi.Email)
+	token := appealToken.FindStringSubmatch(mail.Body)[1]
+	if token == first[1] {
+		t.Errorf("the resent notice has the same token")
+	}
+	user.do(http.MethodGet, "/api/v1/auth/block/status?token="+first[1], nil).fails(t, string(response.CodeNotFound))
+
+	var status model.BlockStatusResponse
+	res = user.do(http.MethodGet, "/api/v1/auth/block/status?token="+token, nil)
+	res.ok(t, &status)
+	if !status.Active || status.Reason != model.BlockReasonFraud || status.Note != "chargebacks" || status.ExpiresAt != nil || len(status.Appeals) != 0 {
+		t.Errorf("status = %+v", status)
+	}
+	//the token holder doesn't learn which admins handled the block
+	if strings.Contains(res.Raw, "admin@foodbuddy.test") {
+		t.Errorf("status names an admin: %s", res.Raw)
+	}
+
+	appeal := model.BlockAppealRequest{Token: token, Message: "the chargebacks were my bank's mistake"}
+	var filed model.BlockAppeal
+	user.do(http.MethodPost, "/api/v1/auth/block/appeal", appeal).ok(t, &filed)
+	user.do(http.MethodPost, "/api/v1/auth/block/appeal", appeal).fails(t, string(response.CodeConflict))
+
+	var queue struct {
+		Appeals []struct {
+			ID    uint               `json:"id"`
+			Block model.AccountBlock `json:"block"`
+		} `json:"appeals"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/appeals", nil).ok(t, &queue)
+	if len(queue.Appeals) != 1 || queue.Appeals[0].ID != filed.ID || queue.Appeals[0].Block.Note != "chargebacks" {
+		t.Fatalf("queue = %+v", queue)
+	}
+
+	review := "/api/v1/admin/appeals/review?appealid=" + itoa(filed.ID)
+	admin.do(http.MethodPut, review, model.ReviewBlockAppeal{Status: model.AppealStatusAccepted, Response: "the bank confirmed it"}).ok(t, nil)
+	admin.do(http.MethodPut, review, model.ReviewBlockAppeal{Status: model.AppealStatusRejected, Response: "again"}).fails(t, string(response.CodeInvalidState))
+	if mail, _ := h.fakes.Mail.Last(ravi.Email); !strings.Contains(mail.Body, "the bank confirmed it") {
+		t.Errorf("appeal decision wasn't mailed")
+	}
+	user.do(http.MethodPost, "/api/v1/auth/user/email/login", login).ok(t, nil)
+	user.do(http.MethodPost, "/api/v1/auth/block/appeal", appeal).fails(t, string(response.CodeInvalidState))
+	res = user.do(http.MethodGet, "/api/v1/auth/block/status?token="+token, nil)
+	res.ok(t, &status)
+	if status.Active || len(status.Appeals) != 1 || status.Appeals[0].Status != model.AppealStatusAccepted || status.Appeals[0].Response != "the bank confirmed it" {
+		t.Errorf("status after the appeal = %+v", status)
+	}
+	if strings.Contains(res.Raw, "admin@foodbuddy.test") {
+		t.Errorf("status after the appeal names an admin: %s", res.Raw)
+	}
+
+	var reviewed model.AuditLog
+	h.reload(&reviewed, "action = ? AND entity_id = ?", audit.AppealReview, itoa(filed.ID))
+}
@@ -0,0 +1,109 @@
+package e2e
+
+import (
+	"testing"
+
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/model"
+)
+
+// burger, fries and cola at 100 each sold together for 250
+func (h *harness) comboFixture() (restaurantFixture, model.Bundle, []model.Product) {
+	restaurant := h.restaurant("Combo Corner")
+	category := h.category("Combos")
+	products := []model.Product{
+		h.product(restaurant.ID, category.ID, "Burger", 100, 10),
+		h.product(restaurant.ID, category.ID, "Fries", 100, 10),
+		h.product(restaurant.ID, category.ID, "Cola", 100, 10),
+	}
+	bundle := model.Bundle{RestaurantID: restaurant.ID, Name: "Meal", BundlePrice: 250}
+	for _, product := range products {
+		bundle.Items = append(bundle.Items, model.BundleItem{ProductID: product.ID, Quantity: 1})
+	}
+	h.create(&bundle)
+	return restaurant, bundle, products
+}
+
+// the bundle saving counts as offer, split over the components with the rounding left on the last one
+func TestBundlePricing(t *testing.T) {
+	h := newHarness(t)
+	restaurant, bundle, products := h.comboFixture()
+
+	amount, offer, err := controllers.CartItemPrice(model.CartItems{BundleID: bundle.ID, RestaurantID: restaurant.ID, Quantity: 2})
+	if err != nil || amount != 600 || offer != 100 {
+		t.Fatalf("bundle price = %v, %v, %v, want 600 with 100 off", amount, offer, err)
+	}
+	h.db.Model(&model.Product{}).Where("id = ?", products[0].ID).Update("offer_amount", 10)
+	amount, offer, err = controllers.CartItemPrice(model.CartItems{ProductID: products[0].ID, RestaurantID: restaurant.ID, Quantity: 3})
+	if err != nil || amount != 300 || offer != 30 {
+		t.Fatalf("product price = %v, %v, %v, want 300 with 30 off", amount, offer, err)
+	}
+	if _, _, err := controllers.CartItemPrice(model.CartItems{BundleID: bundle.ID + 100, Quantity: 1}); err == nil {
+		t.Fatal("price of a missing bundle")
+	}
+
+	lines, err := controllers.BundleOrderLines(bundle.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOffers := []float64{33.34, 33.34, 33.32}
+	var totalOffer float64
+	for i, line := range lines {
+		if line.ProductID != products[i].ID || line.Quantity != 2 || line.Amount != 200 || line.RestaurantID != restaurant.ID {
+			t.Fatalf("line %d = %+v", i, line)
+		}
+		if diff := line.ProductOfferAmount - wantOffers[i]; diff > 0.001 || diff < -0.001 {
+			t.Errorf("line %d offer = %v, want %v", i, line.ProductOfferAmount, wantOffers[i])
+		}
+		totalOffer += line.ProductOfferAmount
+	}
+	if diff := totalOffer - 100; diff > 0.001 || diff < -0.001 {
+		t.Fatalf("bundle offer allocated %v, want 100", totalOffer)
+	}
+}
+
+// bundles and the same product ordered on its own draw from one stock
+func TestBundleStock(t *testing.T) {
+	h := newHarness(t)
+	restaurant, bundle, products := h.comboFixture()
+	user := h.user("Tara", 0)
+	fries := products[1]
+
+	h.create(&model.CartItems{UserID: user.ID, BundleID: bundle.ID, RestaurantID: restaurant.ID, Quantity: 2})
+	h.cart(user.ID, fries, 1)
+	h.db.Model(&model.Product{}).Where("id = ?", fries.ID).Update("stock_left", 3)
+	if count, ok := controllers.CheckStock(user.ID); !ok || count != 7 {
+		t.Fatalf("check stock = %d, %v, want 7 items in stock", count, ok)
+	}
+	h.db.Model(&model.Product{}).Where("id = ?", fries.ID).Update("stock_left", 2)
+	if _, ok := controllers.CheckStock(user.ID); ok {
+		t.Fatal("fries counted in stock although the bundles and the side need 3")
+	}
+
+	// a component out of stock leaves the earlier ones untouched
+	for _, product := range products {
+		h.create(&model.OrderItem{OrderID: "order-stock", UserID: user.ID, RestaurantID: restaurant.ID, ProductID: product.ID, Quantity: 3, OrderStatus: model.OrderStatusInitiated})
+	}
+	if controllers.DecrementStock("order-stock") {
+		t.Fatal("stock decremented past zero")
+	}
+	stockLeft := func() []uint {
+		var left []uint
+		for _, product := range products {
+			h.reload(&product, "id = ?", product.ID)
+			left = append(left, product.StockLeft)
+		}
+		return left
+	}
+	if left := stockLeft(); left[0] != 10 || left[1] != 2 || left[2] != 10 {
+		t.Fatalf("stock left %v after a failed decrement, want it unchanged", left)
+	}
+
+	h.db.Model(&model.Product{}).Where("id = ?", fries.ID).Update("stock_left", 3)
+	if !controllers.DecrementStock("order-stock") {
+		t.Fatal("stock not decremented")
+	}
+	if left := stockLeft(); left[0] != 7 || left[1] != 0 || left[2] != 7 {
+		t.Fatalf("stock left %v, want 7, 0 and 7", left)
+	}
+}
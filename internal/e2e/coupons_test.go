@@ -0,0 +1,385 @@
+package e2e
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+)
+
+// restaurant coupons stay on their menu, category coupons only discount the matching items
+func TestRestaurantAndCategoryCoupons(t *testing.T) {
+	h := newHarness(t)
+	burgers := h.category("Burgers")
+	drinks := h.category("Drinks")
+	restaurant := h.restaurant("Burger Bar")
+	other := h.restaurant("Pizza Point")
+	burger := h.product(restaurant.ID, burgers.ID, "Classic", 200, 20)
+	cola := h.product(restaurant.ID, drinks.ID, "Cola", 50, 20)
+	pizza := h.product(other.ID, h.category("Pizza").ID, "Margherita", 300, 20)
+	user := h.user("Meera", 0)
+	expiry := uint(time.Now().Add(48 * time.Hour).Unix())
+
+	// products of another restaurant can't be targeted
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "BAD", Expiry: expiry, Percentage: 10, MaximumUsage: 1, MinimumAmount: 100, ProductIDs: []uint{pizza.ID},
+	}).fails(t, string(response.CodeBadRequest))
+
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "BURGER50", Expiry: expiry, DiscountType: model.CouponDiscountFlat, FlatAmount: 50,
+		MaximumUsage: 2, MinimumAmount: 200, CategoryIDs: []uint{burgers.ID},
+	}).ok(t, nil)
+	var coupons []model.CouponInventory
+	restaurant.do(http.MethodGet, "/api/v1/restaurants/coupon/all", nil).ok(t, &coupons)
+	if len(coupons) != 1 || coupons[0].RestaurantID != restaurant.ID || len(coupons[0].Targets) != 1 {
+		t.Fatalf("unexpected restaurant coupons %+v", coupons)
+	}
+	other.do(http.MethodPatch, "/api/v1/restaurants/coupon/update", model.CouponInventoryRequest{
+		CouponCode: "BURGER50", Expiry: expiry, Percentage: 90, MaximumUsage: 9, MinimumAmount: 100,
+	}).fails(t, string(response.CodeNotFound))
+
+	h.cart(user.ID, pizza, 1)
+	user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode=BURGER50&restaurant_id="+itoa(other.ID), nil).
+		fails(t, string(response.CodeBadRequest))
+
+	// only the cola in the cart, nothing the coupon applies to
+	h.cart(user.ID, cola, 4)
+	user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode=BURGER50&restaurant_id="+itoa(restaurant.ID), nil).
+		fails(t, string(response.CodeBadRequest))
+
+	h.cart(user.ID, burger, 1)
+	var preview struct {
+		CouponDiscount float64 `json:"coupon_discount"`
+		FinalAmount    float64 `json:"final_amount"`
+	}
+	user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode=BURGER50&restaurant_id="+itoa(restaurant.ID), nil).ok(t, &preview)
+	if preview.CouponDiscount != 50 || preview.FinalAmount != 350 {
+		t.Fatalf("unexpected coupon preview %+v", preview)
+	}
+
+	order := h.placeOrder(user, restaurant.ID, model.CashOnDelivery, "BURGER50")
+	if order.CouponDiscountAmount != 50 || order.FinalAmount != 350 {
+		t.Fatalf("coupon not applied to the order %+v", order)
+	}
+	var burgerItem, colaItem model.OrderItem
+	h.reload(&burgerItem, "order_id = ? AND product_id = ?", order.OrderID, burger.ID)
+	h.reload(&colaItem, "order_id = ? AND product_id = ?", order.OrderID, cola.ID)
+	if burgerItem.AfterDeduction != 150 || colaItem.AfterDeduction != 200 {
+		t.Fatalf("coupon split over the wrong items: burger %v cola %v", burgerItem.AfterDeduction, colaItem.AfterDeduction)
+	}
+
+	var listed []model.CouponInventory
+	h.anonymous().do(http.MethodGet, "/api/v1/public/coupon/all?restaurant_id="+itoa(other.ID), nil).ok(t, &listed)
+	if len(listed) != 0 {
+		t.Fatalf("coupon of another restaurant listed %+v", listed)
+	}
+}
+
+// a flat coupon is split by what each item costs, so cancelling a cheap item refunds what was paid for it
+func TestCouponSplitByPayable(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Annapoorna")
+	meals := h.category("Meals")
+	tea := h.product(restaurant.ID, meals.ID, "Tea", 20, 20)
+	thali := h.product(restaurant.ID, meals.ID, "Thali", 500, 20)
+	user := h.user("Devi", 1000)
+
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "FLAT100", Expiry: uint(time.Now().Add(48 * time.Hour).Unix()), DiscountType: model.CouponDiscountFlat, FlatAmount: 100,
+		MaximumUsage: 1, MinimumAmount: 100,
+	}).ok(t, nil)
+	h.cart(user.ID, tea, 1)
+	h.cart(user.ID, thali, 1)
+	order := h.placeOrder(user, restaurant.ID, model.OnlinePayment, "FLAT100")
+	if order.CouponDiscountAmount != 100 || order.FinalAmount != 420 {
+		t.Fatalf("coupon not applied to the order %+v", order)
+	}
+	user.do(http.MethodPost, "/api/v1/user/order/step2/initiatepayment", model.InitiatePayment{OrderID: order.OrderID, PaymentGateway: model.Wallet}).ok(t, nil)
+
+	var teaItem, thaliItem model.OrderItem
+	h.reload(&teaItem, "order_id = ? AND product_id = ?", order.OrderID, tea.ID)
+	h.reload(&thaliItem, "order_id = ? AND product_id = ?", order.OrderID, thali.ID)
+	if teaItem.AfterDeduction != 16.15 || thaliItem.AfterDeduction != 403.85 {
+		t.Fatalf("coupon split: tea %v thali %v, want 16.15 and 403.85", teaItem.AfterDeduction, thaliItem.AfterDeduction)
+	}
+
+	user.do(http.MethodPost, "/api/v1/user/order/cancel/online", model.CancelOrderedProduct{OrderID: order.OrderID, ProductId: tea.ID}).ok(t, nil)
+	var refund model.UserWalletHistory
+	h.reload(&refund, "user_id = ? AND reason = ?", user.ID, model.WalletTxTypeOrderRefund)
+	if refund.Amount != 16.15 {
+		t.Fatalf("refund %v for the tea, want 16.15", refund.Amount)
+	}
+}
+
+// max discount caps, first order and new user coupons, start dates and the global cap
+func TestCouponRules(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	restaurant := h.restaurant("Burger Bar")
+	burger := h.product(restaurant.ID, h.category("Burgers").ID, "Classic", 200, 50)
+	first := h.user("Ravi", 0)
+	second := h.user("Sara", 0)
+	expiry := uint(time.Now().Add(48 * time.Hour).Unix())
+
+	create := func(request model.CouponInventoryRequest) {
+		request.Expiry = expiry
+		request.MinimumAmount = 100
+		if request.MaximumUsage == 0 {
+			request.MaximumUsage = 5
+		}
+		admin.do(http.MethodPost, "/api/v1/admin/coupon/create", request).ok(t, nil)
+	}
+	preview := func(user userFixture, code string) float64 {
+		var out struct {
+			CouponDiscount float64 `json:"coupon_discount"`
+		}
+		user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode="+code+"&restaurant_id="+itoa(restaurant.ID), nil).ok(t, &out)
+		return out.CouponDiscount
+	}
+	rejected := func(user userFixture, code string) {
+		t.Helper()
+		user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode="+code+"&restaurant_id="+itoa(restaurant.ID), nil).
+			fails(t, string(response.CodeBadRequest))
+	}
+
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "BIG", Expiry: expiry, Percentage: 90, MaximumUsage: 1, MinimumAmount: 100,
+	}).fails(t, string(response.CodeBadRequest))
+
+	create(model.CouponInventoryRequest{CouponCode: "HALF", Percentage: 50, MaxDiscount: 120})
+	create(model.CouponInventoryRequest{CouponCode: "FIRST", Percentage: 10, FirstOrderOnly: true})
+	create(model.CouponInventoryRequest{CouponCode: "NEWBIE", Percentage: 10, NewUserDays: 7})
+	create(model.CouponInventoryRequest{CouponCode: "LATER", Percentage: 10, StartsAt: uint(time.Now().Add(24 * time.Hour).Unix())})
+	create(model.CouponInventoryRequest{CouponCode: "ONLYONE", Percentage: 10, TotalUsageLimit: 1})
+
+	h.cart(first.ID, burger, 2)
+	h.cart(second.ID, burger, 2)
+	if discount := preview(first, "HALF"); discount != 120 {
+		t.Fatalf("max discount not applied: %v", discount)
+	}
+	if discount := preview(first, "FIRST"); discount != 40 {
+		t.Fatalf("unexpected first order discount %v", discount)
+	}
+	rejected(first, "LATER")
+
+	h.db.Model(&model.User{}).Where("id = ?", second.ID).Update("created_at", time.Now().Add(-30*24*time.Hour))
+	rejected(second, "NEWBIE")
+	if discount := preview(first, "NEWBIE"); discount != 40 {
+		t.Fatalf("unexpected new user discount %v", discount)
+	}
+
+	order := h.placeOrder(first, restaurant.ID, model.CashOnDelivery, "ONLYONE")
+	if order.CouponDiscountAmount != 40 {
+		t.Fatalf("coupon not applied to the order %+v", order)
+	}
+	var coupon model.CouponInventory
+	h.reload(&coupon, "coupon_code = ?", "ONLYONE")
+	if coupon.RedeemedCount != 1 {
+		t.Fatalf("redemption not counted %+v", coupon)
+	}
+	rejected(second, "ONLYONE")
+
+	// the cod order counts as the first order now
+	h.cart(first.ID, burger, 2)
+	rejected(first, "FIRST")
+	if discount := preview(second, "FIRST"); discount != 40 {
+		t.Fatalf("unexpected first order discount %v", discount)
+	}
+}
+
+// the cart is checked against every coupon, the best one can be applied when placing the order
+func TestBestCoupon(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	restaurant := h.restaurant("Burger Bar")
+	other := h.restaurant("Pizza Point")
+	burger := h.product(restaurant.ID, h.category("Burgers").ID, "Classic", 200, 20)
+	user := h.user("Kiran", 0)
+	expiry := uint(time.Now().Add(48 * time.Hour).Unix())
+
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "TEN", Expiry: expiry, Percentage: 10, MaximumUsage: 1, MinimumAmount: 100,
+	}).ok(t, nil)
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "BIGSPEND", Expiry: expiry, Percentage: 30, MaximumUsage: 1, MinimumAmount: 1000,
+	}).ok(t, nil)
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "FLAT60", Expiry: expiry, DiscountType: model.CouponDiscountFlat, FlatAmount: 60, MaximumUsage: 1, MinimumAmount: 300,
+	}).ok(t, nil)
+	other.do(http.MethodPost, "/api/v1/restaurants/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "PIZZA", Expiry: expiry, Percentage: 20, MaximumUsage: 1, MinimumAmount: 100,
+	}).ok(t, nil)
+	// expired since it was created
+	h.db.Model(&model.CouponInventory{}).Where("coupon_code = ?", "TEN").Update("expiry", time.Now().Add(-time.Hour).Unix())
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "FIVE", Expiry: expiry, Percentage: 5, MaximumUsage: 1, MinimumAmount: 100,
+	}).ok(t, nil)
+
+	h.cart(user.ID, burger, 2)
+	var ranked struct {
+		Best       string                       `json:"best_coupon"`
+		Eligible   []model.CouponRecommendation `json:"eligible"`
+		Ineligible []model.CouponRecommendation `json:"ineligible"`
+	}
+	user.do(http.MethodGet, "/api/v1/user/coupon/best?restaurant_id="+itoa(restaurant.ID), nil).ok(t, &ranked)
+	if ranked.Best != "FLAT60" || len(ranked.Eligible) != 2 || ranked.Eligible[1].CouponCode != "FIVE" ||
+		ranked.Eligible[0].Discount != 60 || ranked.Eligible[0].FinalAmount != 340 {
+		t.Fatalf("unexpected ranking %+v", ranked)
+	}
+	reasons := map[string]string{}
+	for _, coupon := range ranked.Ineligible {
+		reasons[coupon.CouponCode] = coupon.Reason
+	}
+	if len(reasons) != 2 || reasons["TEN"] != "coupon has expired" || reasons["BIGSPEND"] != "minimum of 1000 is needed for using this coupon" {
+		t.Fatalf("unexpected ineligible coupons %+v", ranked.Ineligible)
+	}
+
+	var placed struct {
+		Order model.Order `json:"order_details"`
+	}
+	user.do(http.MethodPost, "/api/v1/user/order/step1/placeorder", model.PlaceOrder{
+		AddressID: user.AddressID, RestaurantID: restaurant.ID, PaymentMethod: model.CashOnDelivery, AutoApplyCoupon: true,
+	}).ok(t, &placed)
+	if placed.Order.CouponCode != "FLAT60" || placed.Order.FinalAmount != 340 {
+		t.Fatalf("best coupon not applied %+v", placed.Order)
+	}
+
+	// both used up, the order goes through without a coupon
+	h.db.Model(&model.CouponInventory{}).Where("coupon_code = ?", "FIVE").Update("expiry", time.Now().Add(-time.Hour).Unix())
+	h.cart(user.ID, burger, 2)
+	user.do(http.MethodGet, "/api/v1/user/coupon/best?restaurant_id="+itoa(restaurant.ID), nil).ok(t, &ranked)
+	if ranked.Best != "" || len(ranked.Eligible) != 0 {
+		t.Fatalf("unexpected ranking %+v", ranked)
+	}
+	user.do(http.MethodPost, "/api/v1/user/order/step1/placeorder", model.PlaceOrder{
+		AddressID: user.AddressID, RestaurantID: restaurant.ID, PaymentMethod: model.CashOnDelivery, AutoApplyCoupon: true,
+	}).ok(t, &placed)
+	if placed.Order.CouponCode != "" || placed.Order.FinalAmount != 400 {
+		t.Fatalf("unexpected coupon on the order %+v", placed.Order)
+	}
+}
+
+// campaign codes are unique and single use, and the report counts what they brought in
+func TestCouponCampaign(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	restaurant := h.restaurant("Burger Bar")
+	burger := h.product(restaurant.ID, h.category("Burgers").ID, "Classic", 200, 20)
+	first := h.user("Asha", 0)
+	second := h.user("Vinod", 0)
+	rules := model.CouponInventoryRequest{Expiry: uint(time.Now().Add(48 * time.Hour).Unix()), Percentage: 25, MinimumAmount: 100}
+
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/campaign/create", model.CouponCampaignRequest{
+		Name: "Too many", CodePrefix: "MANY", CodeCount: model.CampaignCodeLimit + 1, CouponInventoryRequest: rules,
+	}).fails(t, string(response.CodeValidation))
+
+	var campaign model.CouponCampaign
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/campaign/create", model.CouponCampaignRequest{
+		Name: "Diwali flyers", CodePrefix: "diwali", CodeCount: 600, CouponInventoryRequest: rules,
+	}).ok(t, &campaign)
+
+	res := admin.do(http.MethodGet, "/api/v1/admin/coupon/campaign/codes?campaignid="+itoa(campaign.ID), nil)
+	if res.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", res.Code, res.Raw)
+	}
+	rows, err := csv.NewReader(strings.NewReader(res.Raw)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %v", err)
+	}
+	codes := map[string]bool{}
+	for _, row := range rows[1:] {
+		if !strings.HasPrefix(row[0], "DIWALI-") || len(row[0]) != len("DIWALI-")+model.CampaignCodeLength {
+			t.Fatalf("unexpected code %v", row)
+		}
+		codes[row[0]] = true
+	}
+	if len(rows) != 601 || len(codes) != 600 {
+		t.Fatalf("expected 600 unique codes, got %d rows and %d codes", len(rows)-1, len(codes))
+	}
+	code := rows[1][0]
+
+	// an edited code stays in the campaign and single-use
+	edited := rules
+	edited.CouponCode = rows[2][0]
+	edited.Percentage = 20
+	edited.MaximumUsage = 5
+	admin.do(http.MethodPatch, "/api/v1/admin/coupon/update", edited).ok(t, nil)
+	var editedCoupon model.CouponInventory
+	h.reload(&editedCoupon, "coupon_code = ?", edited.CouponCode)
+	if editedCoupon.CampaignID != campaign.ID || editedCoupon.Percentage != 20 || editedCoupon.MaximumUsage != 1 || editedCoupon.TotalUsageLimit != 1 {
+		t.Fatalf("edited campaign code %+v", editedCoupon)
+	}
+
+	var listed []model.CouponInventory
+	h.anonymous().do(http.MethodGet, "/api/v1/public/coupon/all", nil).ok(t, &listed)
+	if len(listed) != 0 {
+		t.Fatalf("campaign codes listed publicly: %d", len(listed))
+	}
+
+	h.cart(first.ID, burger, 2)
+	order := h.placeOrder(first, restaurant.ID, model.CashOnDelivery, code)
+	if order.CouponDiscountAmount != 100 || order.FinalAmount != 300 {
+		t.Fatalf("campaign code not applied %+v", order)
+	}
+	h.cart(second.ID, burger, 2)
+	second.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode="+code+"&restaurant_id="+itoa(restaurant.ID), nil).
+		fails(t, string(response.CodeBadRequest))
+
+	var redemptions struct {
+		Redemptions []model.CouponRedemption `json:"redemptions"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/coupon/campaign/redemptions?campaignid="+itoa(campaign.ID)+"&coupon_code="+code, nil).ok(t, &redemptions)
+	if len(redemptions.Redemptions) != 1 || redemptions.Redemptions[0].OrderID != order.OrderID || redemptions.Redemptions[0].Discount != 100 {
+		t.Fatalf("unexpected redemptions %+v", redemptions)
+	}
+
+	var report struct {
+		Campaign model.CouponCampaign `json:"campaign"`
+		Report   model.CampaignReport `json:"report"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/coupon/campaign?campaignid="+itoa(campaign.ID), nil).ok(t, &report)
+	want := model.CampaignReport{Codes: 600, RedeemedCodes: 1, Redemptions: 1, Orders: 1, Revenue: 300, Discount: 100, RevenuePerDiscount: 3}
+	if report.Report != want || report.Campaign.CodePrefix != "DIWALI" {
+		t.Fatalf("unexpected campaign report %+v", report)
+	}
+}
+
+// an order discarded after its coupon was applied gives the redemption back
+func TestCouponReleasedWithDiscardedOrder(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	restaurant := h.restaurant("Burger Bar")
+	burger := h.product(restaurant.ID, h.category("Burgers").ID, "Classic", 200, 50)
+	user := h.user("Ravi", 0)
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "ONLYONE", Expiry: uint(time.Now().Add(48 * time.Hour).Unix()), Percentage: 10, MaximumUsage: 1, MinimumAmount: 100, TotalUsageLimit: 1,
+	}).ok(t, nil)
+	h.cart(user.ID, burger, 2)
+
+	order := model.Order{OrderID: "order-discarded", UserID: user.ID, RestaurantID: restaurant.ID, TotalAmount: 400, FinalAmount: 400}
+	h.create(&order)
+	if ok, msg, _ := controllers.ApplyCouponToOrder(order, user.ID, "ONLYONE"); !ok {
+		t.Fatalf("coupon not applied: %v", msg)
+	}
+	if err := controllers.ReleaseCouponClaim(order.OrderID); err != nil {
+		t.Fatal(err)
+	}
+	var coupon model.CouponInventory
+	h.reload(&coupon, "coupon_code = ?", "ONLYONE")
+	var redemptions, used int64
+	h.db.Model(&model.CouponRedemption{}).Where("coupon_code = ?", "ONLYONE").Count(&redemptions)
+	h.db.Model(&model.CouponUsage{}).Where("coupon_code = ? AND usage_count > 0", "ONLYONE").Count(&used)
+	if coupon.RedeemedCount != 0 || redemptions != 0 || used != 0 {
+		t.Fatalf("after the release: redeemed %v, %v redemptions, %v users", coupon.RedeemedCount, redemptions, used)
+	}
+
+	placed := h.placeOrder(user, restaurant.ID, model.CashOnDelivery, "ONLYONE")
+	if placed.CouponDiscountAmount != 40 {
+		t.Fatalf("released coupon not applied to the next order %+v", placed)
+	}
+}
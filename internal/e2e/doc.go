@@ -0,0 +1,5 @@
+// Package e2e drives the api over http against a temporary SQLite database with the
+// payment, mail and image gateways replaced by the fakes of gatewaytest.
+//
+// Run it with `go test ./internal/e2e`, it needs neither MySQL nor network access.
+package e2e
@@ -0,0 +1,359 @@
+package e2e
+
+import (
+	"errors"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/gateway/gatewaytest"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var verifyLink = regexp.MustCompile(`/api/v1/auth/verifyemail/([^"\s]+)`)
+
+const password = "correct-Horse-battery-staple-42"
+
+// a user and a restaurant sign up over the api, verify their email with the mailed link,
+// and the order goes from the cart through razorpay to the delivery confirmation
+func TestSignupToDelivery(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+
+	user := h.anonymous()
+	user.do(http.MethodPost, "/api/v1/auth/user/email/signup", model.EmailSignupRequest{
+		Name: "Asha", Email: "asha@foodbuddy.test", PhoneNumber: 9876543210, Password: password, ConfirmPassword: password,
+	}).ok(t, nil)
+	link, ok := h.fakes.Mail.Find("asha@foodbuddy.test", verifyLink)
+	if !ok {
+		t.Fatalf("no verification link was mailed")
+	}
+	user.do(http.MethodGet, "/api/v1/auth/verifyemail/"+link, nil).ok(t, nil)
+	user.do(http.MethodPost, "/api/v1/auth/user/email/login", model.EmailLoginRequest{Email: "asha@foodbuddy.test", Password: password}).ok(t, nil)
+
+	kitchen := h.anonymous()
+	kitchen.do(http.MethodPost, "/api/v1/auth/restaurant/signup", model.RestaurantSignupRequest{
+		Name: "Spice Route", Description: "kerala meals", Address: "Kochi", Email: "spice@restaurant.test",
+		Password: password, ConfirmPassword: password, PhoneNumber: 9876543211,
+		ImageURL: "https://images.test/spice.jpg", CertificateURL: "https://images.test/spice.pdf",
+	}).ok(t, nil)
+	kitchen.do(http.MethodPost, "/api/v1/auth/restaurant/login", model.RestaurantLoginRequest{Email: "spice@restaurant.test", Password: password}).
+		fails(t, string(response.CodeEmailNotVerified))
+	link, ok = h.fakes.Mail.Find("spice@restaurant.test", verifyLink)
+	if !ok {
+		t.Fatalf("no verification link was mailed to the restaurant")
+	}
+	kitchen.do(http.MethodGet, "/api/v1/auth/verifyemail/"+link, nil).ok(t, nil)
+
+	var restaurant model.Restaurant
+	h.reload(&restaurant, "email = ?", "spice@restaurant.test")
+	//the documents were reviewed already
+	for _, Type := range controllers.RequiredDocuments {
+		h.create(&model.RestaurantDocument{RestaurantID: restaurant.ID, Type: Type, Reference: "REVIEWED", Status: model.DocumentStatusApproved})
+	}
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/verify/success?restaurantid="+itoa(restaurant.ID), nil).ok(t, nil)
+	admin.do(http.MethodPost, "/api/v1/admin/categories/add", model.AddCategoryRequest{
+		Name: "Meals", Description: "full plates of rice with curries, pickles and papad served on a banana leaf", ImageURL: "https://images.test/meals.jpg",
+	}).ok(t, nil)
+	var category model.Category
+	h.reload(&category, "name = ?", "Meals")
+
+	var added struct {
+		ID uint `json:"id"`
+	}
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/products/add", model.AddProductRequest{
+		CategoryID: category.ID, Name: "Sadhya", Description: "feast", ImageURL: "https://images.test/sadhya.jpg",
+		Price: 250, MaxStock: 10, StockLeft: 10, Veg: model.YES,
+	}).ok(t, &added)
+
+	user.do(http.MethodPost, "/api/v1/user/address/add", model.Address{
+		PhoneNumber: 9876543210, AddressType: "home", StreetName: "MG Road", StreetNumber: "1", City: "Kochi", State: "Kerala", PostalCode: "682001",
+	}).ok(t, nil)
+	var address model.Address
+	var asha model.User
+	h.reload(&asha, "email = ?", "asha@foodbuddy.test")
+	h.reload(&address, "user_id = ?", asha.ID)
+	user.do(http.MethodPost, "/api/v1/user/cart/add", model.AddToCartReq{ProductID: added.ID, Quantity: 2}).ok(t, nil)
+
+	buyer := userFixture{User: asha, client: user, AddressID: address.AddressID}
+	order := h.placeOrder(buyer, restaurant.ID, model.OnlinePayment, "")
+	if order.FinalAmount != 500 || order.RestaurantID != restaurant.ID {
+		t.Fatalf("unexpected order %+v", order)
+	}
+	h.payWithRazorpay(buyer, order.OrderID)
+
+	h.reload(&order, "order_id = ?", order.OrderID)
+	if order.PaymentStatus != model.OnlinePaymentConfirmed {
+		t.Fatalf("payment status %s after the razorpay callback", order.PaymentStatus)
+	}
+	var product model.Product
+	h.reload(&product, "id = ?", added.ID)
+	if product.StockLeft != 8 {
+		t.Fatalf("stock left %d, want 8", product.StockLeft)
+	}
+
+	h.deliver(buyer, restaurantFixture{Restaurant: restaurant, client: kitchen}, order.OrderID)
+
+	var item model.OrderItem
+	h.reload(&item, "order_id = ?", order.OrderID)
+	if item.OrderStatus != model.OrderStatusDelivered {
+		t.Fatalf("order item status %s after delivery", item.OrderStatus)
+	}
+	h.reload(&restaurant, "id = ?", restaurant.ID)
+	if restaurant.WalletAmount != 500 {
+		t.Fatalf("restaurant wallet %v, want 500", restaurant.WalletAmount)
+	}
+}
+
+func TestStripeCheckout(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Dosa Hut")
+	dosa := h.product(restaurant.ID, h.category("Tiffin").ID, "Masala Dosa", 120, 5)
+	user := h.user("Ravi", 0)
+	h.cart(user.ID, dosa, 1)
+
+	order := h.placeOrder(user, restaurant.ID, model.OnlinePayment, "")
+	var checkout struct {
+		URL string `json:"url"`
+	}
+	user.do(http.MethodPost, "/api/v1/user/order/step2/initiatepayment", model.InitiatePayment{OrderID: order.OrderID, PaymentGateway: model.Stripe}).ok(t, &checkout)
+
+	var payment model.Payment
+	h.reload(&payment, "order_id = ?", order.OrderID)
+	if !strings.HasSuffix(checkout.URL, payment.StripeSessionID) {
+		t.Fatalf("checkout url %s does not match session %s", checkout.URL, payment.StripeSessionID)
+	}
+	if err := h.fakes.Stripe.Pay(payment.StripeSessionID); err != nil {
+		t.Fatal(err)
+	}
+	user.do(http.MethodGet, "/api/v1/user/order/step3/stripecallback?session_id="+payment.StripeSessionID, nil).ok(t, nil)
+
+	h.reload(&order, "order_id = ?", order.OrderID)
+	if order.PaymentStatus != model.OnlinePaymentConfirmed {
+		t.Fatalf("payment status %s after the stripe callback", order.PaymentStatus)
+	}
+	h.reload(&dosa, "id = ?", dosa.ID)
+	if dosa.StockLeft != 4 {
+		t.Fatalf("stock left %d, want 4", dosa.StockLeft)
+	}
+	h.reload(&restaurant.Restaurant, "id = ?", restaurant.ID)
+	earned := restaurant.WalletAmount
+
+	//reloading the success page confirms nothing twice
+	user.do(http.MethodGet, "/api/v1/user/order/step3/stripecallback?session_id="+payment.StripeSessionID, nil).ok(t, nil)
+	h.reload(&dosa, "id = ?", dosa.ID)
+	h.reload(&restaurant.Restaurant, "id = ?", restaurant.ID)
+	if dosa.StockLeft != 4 || restaurant.WalletAmount != earned {
+		t.Fatalf("after a reload: stock left %d, restaurant wallet %v (was %v)", dosa.StockLeft, restaurant.WalletAmount, earned)
+	}
+}
+
+// a replayed razorpay verification leaves the stock, the restaurant wallet and the item progress alone
+func TestRazorpayCallbackReplay(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Biryani House")
+	biryani := h.product(restaurant.ID, h.category("Meals").ID, "Biryani", 240, 5)
+	user := h.user("Nila", 0)
+	h.cart(user.ID, biryani, 2)
+	order := h.placeOrder(user, restaurant.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(user, order.OrderID)
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/order/nextstatus", model.UpdateOrderStatusForRestaurant{OrderID: order.OrderID, ProductID: biryani.ID}).ok(t, nil)
+
+	h.reload(&restaurant.Restaurant, "id = ?", restaurant.ID)
+	earned := restaurant.WalletAmount
+	var item model.OrderItem
+	h.reload(&item, "order_id = ?", order.OrderID)
+	progress := item.OrderStatus
+
+	var payment model.Payment
+	h.reload(&payment, "order_id = ?", order.OrderID)
+	paymentID := "pay_" + payment.RazorpayOrderID
+	user.form("/api/v1/user/order/step3/razorpaycallback/"+order.OrderID, url.Values{
+		"razorpay_order_id":   {payment.RazorpayOrderID},
+		"razorpay_payment_id": {paymentID},
+		"razorpay_signature":  {gatewaytest.RazorpaySignature(payment.RazorpayOrderID, paymentID, razorpaySecret)},
+	}).ok(t, nil)
+
+	h.reload(&biryani, "id = ?", biryani.ID)
+	h.reload(&restaurant.Restaurant, "id = ?", restaurant.ID)
+	h.reload(&item, "order_id = ?", order.OrderID)
+	if biryani.StockLeft != 3 || restaurant.WalletAmount != earned || item.OrderStatus != progress {
+		t.Fatalf("after a replay: stock left %d, restaurant wallet %v (was %v), item %v (was %v)", biryani.StockLeft, restaurant.WalletAmount, earned, item.OrderStatus, progress)
+	}
+}
+
+func TestGatewayFailureMarksPaymentFailed(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Biryani House")
+	biryani := h.product(restaurant.ID, h.category("Rice").ID, "Biryani", 300, 5)
+	user := h.user("Meera", 0)
+	h.cart(user.ID, biryani, 1)
+	order := h.placeOrder(user, restaurant.ID, model.OnlinePayment, "")
+
+	h.fakes.Razorpay.Err = errors.New("razorpay is down")
+	user.do(http.MethodPost, "/api/v1/user/order/step2/initiatepayment", model.InitiatePayment{OrderID: order.OrderID, PaymentGateway: model.Razorpay}).
+		fails(t, string(response.CodeUpstream))
+
+	h.reload(&order, "order_id = ?", order.OrderID)
+	if order.PaymentStatus != model.OnlinePaymentFailed {
+		t.Fatalf("payment status %s after a gateway failure", order.PaymentStatus)
+	}
+}
+
+func TestCODOrderConfirmedByRestaurant(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Tea Stall")
+	tea := h.product(restaurant.ID, h.category("Drinks").ID, "Chai", 20, 10)
+	user := h.user("Nikhil", 0)
+	h.cart(user.ID, tea, 3)
+
+	//the cached menu follows the stock
+	listedStock := func() uint {
+		var menu struct {
+			Products []model.Product `json:"products"`
+		}
+		h.anonymous().do(http.MethodGet, "/api/v1/public/restaurants/products/?restaurantid="+itoa(restaurant.ID), nil).ok(t, &menu)
+		if len(menu.Products) != 1 {
+			t.Fatalf("menu = %+v", menu.Products)
+		}
+		return menu.Products[0].StockLeft
+	}
+	if got := listedStock(); got != 10 {
+		t.Fatalf("listed stock %d before the order", got)
+	}
+
+	order := h.placeOrder(user, restaurant.ID, model.CashOnDelivery, "")
+	if order.PaymentStatus != model.CODStatusPending {
+		t.Fatalf("payment status %s for a cod order", order.PaymentStatus)
+	}
+	h.reload(&tea, "id = ?", tea.ID)
+	if tea.StockLeft != 7 {
+		t.Fatalf("stock left %d, want 7 right after a cod order", tea.StockLeft)
+	}
+	if got := listedStock(); got != 7 {
+		t.Fatalf("listed stock %d after the order, want 7", got)
+	}
+
+	other := h.restaurant("Other Stall")
+	other.do(http.MethodPost, "/api/v1/restaurants/order/confirmcod", model.ConfirmCODPayment{OrderID: order.OrderID}).
+		fails(t, string(response.CodeUnauthorized))
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/order/confirmcod", model.ConfirmCODPayment{OrderID: order.OrderID}).ok(t, nil)
+	h.deliver(user, restaurant, order.OrderID)
+}
+
+// cancelling an item of a paid order refunds it to the wallet, takes it back from the restaurant and restocks it
+func TestCancellationRefundsWallet(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Pizza Place")
+	category := h.category("Pizza")
+	margherita := h.product(restaurant.ID, category.ID, "Margherita", 200, 10)
+	farmhouse := h.product(restaurant.ID, category.ID, "Farmhouse", 300, 10)
+	user := h.user("Kiran", 1000)
+	h.cart(user.ID, margherita, 1)
+	h.cart(user.ID, farmhouse, 1)
+
+	order := h.placeOrder(user, restaurant.ID, model.OnlinePayment, "")
+	user.do(http.MethodPost, "/api/v1/user/order/step2/initiatepayment", model.InitiatePayment{OrderID: order.OrderID, PaymentGateway: model.Wallet}).ok(t, nil)
+
+	var kiran model.User
+	h.reload(&kiran, "id = ?", user.ID)
+	if kiran.WalletAmount != 500 {
+		t.Fatalf("wallet %v after paying 500 from 1000", kiran.WalletAmount)
+	}
+
+	user.do(http.MethodPost, "/api/v1/user/order/cancel/online", model.CancelOrderedProduct{OrderID: order.OrderID, ProductId: farmhouse.ID}).ok(t, nil)
+
+	h.reload(&kiran, "id = ?", user.ID)
+	if kiran.WalletAmount != 800 {
+		t.Fatalf("wallet %v after refunding 300", kiran.WalletAmount)
+	}
+	var refund model.UserWalletHistory
+	h.reload(&refund, "user_id = ? AND reason = ?", user.ID, model.WalletTxTypeOrderRefund)
+	if refund.Amount != 300 || refund.OrderID != order.OrderID {
+		t.Fatalf("unexpected refund %+v", refund)
+	}
+	var pizzaPlace model.Restaurant
+	h.reload(&pizzaPlace, "id = ?", restaurant.ID)
+	if pizzaPlace.WalletAmount != 200 {
+		t.Fatalf("restaurant wallet %v, want 200 after the refund", pizzaPlace.WalletAmount)
+	}
+	h.reload(&farmhouse, "id = ?", farmhouse.ID)
+	if farmhouse.StockLeft != 10 {
+		t.Fatalf("stock left %d, want the cancelled item back", farmhouse.StockLeft)
+	}
+
+	user.do(http.MethodPost, "/api/v1/user/order/cancel/online", model.CancelOrderedProduct{OrderID: order.OrderID, ProductId: farmhouse.ID}).
+		fails(t, string(response.CodeConflict))
+}
+
+func TestCouponOnCartAndOrder(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	restaurant := h.restaurant("Burger Bar")
+	burger := h.product(restaurant.ID, h.category("Burgers").ID, "Classic", 150, 20)
+	user := h.user("Divya", 0)
+
+	admin.do(http.MethodPost, "/api/v1/admin/coupon/create", model.CouponInventoryRequest{
+		CouponCode: "WELCOME20", Expiry: uint(time.Now().Add(48 * time.Hour).Unix()), Percentage: 20, MaximumUsage: 1, MinimumAmount: 200,
+	}).ok(t, nil)
+
+	h.cart(user.ID, burger, 1)
+	user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode=WELCOME20&restaurant_id="+itoa(restaurant.ID), nil).
+		fails(t, string(response.CodeBadRequest))
+
+	user.do(http.MethodPut, "/api/v1/user/cart/update/", model.UpdateQuantityCart{ProductID: burger.ID, Quantity: 2}).ok(t, nil)
+	var preview struct {
+		CouponDiscount float64 `json:"coupon_discount"`
+		FinalAmount    float64 `json:"final_amount"`
+	}
+	user.do(http.MethodGet, "/api/v1/user/coupon/cart/?couponcode=WELCOME20&restaurant_id="+itoa(restaurant.ID), nil).ok(t, &preview)
+	if preview.CouponDiscount != 60 || preview.FinalAmount != 240 {
+		t.Fatalf("unexpected coupon preview %+v", preview)
+	}
+
+	order := h.placeOrder(user, restaurant.ID, model.CashOnDelivery, "WELCOME20")
+	if order.CouponCode != "WELCOME20" || order.CouponDiscountAmount != 60 || order.FinalAmount != 240 {
+		t.Fatalf("coupon not applied to the order %+v", order)
+	}
+
+	h.cart(user.ID, burger, 2)
+	user.do(http.MethodPost, "/api/v1/user/order/step1/placeorder", model.PlaceOrder{
+		AddressID: user.AddressID, RestaurantID: restaurant.ID, PaymentMethod: model.CashOnDelivery, CouponCode: "WELCOME20",
+	}).fails(t, string(response.CodeBadRequest))
+}
+
+// the referrer can claim a reward once a referred user has an order delivered
+func TestReferralClaim(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Juice Corner")
+	juice := h.product(restaurant.ID, h.category("Juices").ID, "Mango", 80, 10)
+	referrer := h.user("Anil", 0)
+	friend := h.user("Bina", 0)
+
+	friend.do(http.MethodPatch, "/api/v1/user/referral/activate?referralcode="+referrer.ReferralCode, nil).ok(t, nil)
+	referrer.do(http.MethodGet, "/api/v1/user/referral/claim", nil).fails(t, string(response.CodeInvalidState))
+
+	h.cart(friend.ID, juice, 1)
+	order := h.placeOrder(friend, restaurant.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(friend, order.OrderID)
+	h.deliver(friend, restaurant, order.OrderID)
+
+	var claim struct {
+		Eligible int64 `json:"eligible_referrals"`
+		Amount   int64 `json:"claim_refund"`
+	}
+	referrer.do(http.MethodGet, "/api/v1/user/referral/claim", nil).ok(t, &claim)
+	if claim.Eligible != 1 || claim.Amount != model.ReferralClaimAmount {
+		t.Fatalf("unexpected claim %+v", claim)
+	}
+	var anil model.User
+	h.reload(&anil, "id = ?", referrer.ID)
+	if anil.WalletAmount != model.ReferralClaimAmount {
+		t.Fatalf("wallet %v after claiming the referral reward", anil.WalletAmount)
+	}
+	referrer.do(http.MethodGet, "/api/v1/user/referral/claim", nil).fails(t, string(response.CodeInvalidState))
+}
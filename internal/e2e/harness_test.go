@@ -0,0 +1,388 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"foodbuddy/internal/api"
+	"foodbuddy/internal/cache"
+	"foodbuddy/internal/config"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/gateway"
+	"foodbuddy/internal/gateway/gatewaytest"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/ratelimit"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/repository/sqlite"
+	"foodbuddy/internal/utils"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const razorpaySecret = "rzp_test_secret"
+
+// one api instance on its own database, the package globals are swapped for the test and restored after
+type harness struct {
+	t      *testing.T
+	db     *gorm.DB
+	cfg    *config.Config
+	router *gin.Engine
+	fakes  *gatewaytest.Fakes
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "foodbuddy.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	cfg, _, err := config.Load([]string{"-env", config.Test})
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	cfg.Server.PublicHost = "foodbuddy.test"
+	cfg.Razorpay = config.Razorpay{KeyID: "rzp_test_key", KeySecret: razorpaySecret}
+
+	previousDB, previousGateways := database.DB, gateway.Get()
+	fakes := gatewaytest.New()
+	database.DB = db
+	utils.Configure(cfg)
+	controllers.Configure(cfg)
+	gateway.Set(fakes.Gateways())
+	ratelimit.SetStore(nil)
+	cache.SetStore(cache.NewLRU(100))
+	t.Cleanup(func() {
+		database.DB = previousDB
+		gateway.Set(previousGateways)
+		ratelimit.SetStore(ratelimit.NewMemoryStore())
+		cache.SetStore(cache.NewLRU(1000))
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	router := gin.New()
+	router.SetTrustedProxies(nil)
+	router.Use(logging.Middleware(), gin.Recovery())
+	router.LoadHTMLGlob("../../templates/*")
+	h := controllers.NewHandler(repository.New(db))
+	api.ServerHealth(router, db, "")
+	api.PublicRoutes(router, h)
+	api.AuthenticationRoutes(router)
+	api.AdminRoutes(router, h)
+	api.UserRoutes(router, h)
+	api.RestaurantRoutes(router, h)
+	api.AdditionalRoutes(router)
+	api.OpenAPIRoutes(router)
+
+	return &harness{t: t, db: db, cfg: cfg, router: router, fakes: fakes}
+}
+
+// client keeps the jwt cookie the api sets, like a browser would
+type client struct {
+	h     *harness
+	token string
+}
+
+func (h *harness) anonymous() *client {
+	return &client{h: h}
+}
+
+// response envelope with the payload left raw so each test decodes what it expects
+type result struct {
+	Code    int
+	Status  bool            `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code string `json:"code"`
+	} `json:"error"`
+	Raw string
+}
+
+func (c *client) do(method string, path string, body any) *result {
+	c.h.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			c.h.t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(content)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req)
+}
+
+func (c *client) form(path string, values url.Values) *result {
+	c.h.t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(req)
+}
+
+// multipart upload of content as the "file" field
+func (c *client) upload(path string, filename string, content []byte) *result {
+	c.h.t.Helper()
+	return c.uploadWith(path, nil, filename, content)
+}
+
+// multipart upload with form fields ahead of the file
+func (c *client) uploadWith(path string, fields url.Values, filename string, content []byte) *result {
+	c.h.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, values := range fields {
+		for _, value := range values {
+			w.WriteField(name, value)
+		}
+	}
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		c.h.t.Fatalf("failed to build upload: %v", err)
+	}
+	part.Write(content)
+	w.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return c.send(req)
+}
+
+func (c *client) send(req *http.Request) *result {
+	c.h.t.Helper()
+	if c.token != "" {
+		req.AddCookie(&http.Cookie{Name: "Authorization", Value: c.token})
+	}
+	w := httptest.NewRecorder()
+	c.h.router.ServeHTTP(w, req)
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "Authorization" {
+			c.token = cookie.Value
+		}
+	}
+
+	res := &result{Code: w.Code, Raw: w.Body.String()}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+			c.h.t.Fatalf("%s %s: invalid json response: %v", req.Method, req.URL, err)
+		}
+	}
+	return res
+}
+
+// fail unless the request succeeded, decoding data into out when given
+func (r *result) ok(t *testing.T, out any) *result {
+	t.Helper()
+	if r.Code != http.StatusOK || !r.Status {
+		t.Fatalf("expected success, got %d: %s", r.Code, r.Raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(r.Data, out); err != nil {
+			t.Fatalf("failed to decode data: %v: %s", err, r.Raw)
+		}
+	}
+	return r
+}
+
+// fail unless the request was rejected with the error code
+func (r *result) fails(t *testing.T, code string) {
+	t.Helper()
+	if r.Status || r.Error == nil || r.Error.Code != code {
+		t.Fatalf("expected error %s, got %d: %s", code, r.Code, r.Raw)
+	}
+}
+
+// signed in client without going through the login flow, the token matches controllers.GenerateJWT
+func (h *harness) login(email string, role string) *client {
+	h.t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email": email,
+		"role":  role,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(h.cfg.JWT.Secret))
+	if err != nil {
+		h.t.Fatalf("failed to sign token: %v", err)
+	}
+	return &client{h: h, token: token}
+}
+
+func (h *harness) create(value any) {
+	h.t.Helper()
+	if err := h.db.Create(value).Error; err != nil {
+		h.t.Fatalf("failed to create fixture %T: %v", value, err)
+	}
+}
+
+func (h *harness) reload(value any, query string, args ...any) {
+	h.t.Helper()
+	if err := h.db.Where(query, args...).First(value).Error; err != nil {
+		h.t.Fatalf("failed to load %T: %v", value, err)
+	}
+}
+
+// fixture builders write straight to the database for the parts a test is not about
+
+func (h *harness) admin(email string) *client {
+	h.create(&model.Admin{Email: email})
+	h.create(&model.VerificationTable{Email: email, Role: model.AdminRole, VerificationStatus: model.VerificationStatusVerified})
+	return h.login(email, model.AdminRole)
+}
+
+type userFixture struct {
+	model.User
+	*client
+	AddressID uint
+}
+
+// verified email user with an address and a referral code
+func (h *harness) user(name string, wallet float64) userFixture {
+	h.t.Helper()
+	email := strings.ToLower(name) + "@foodbuddy.test"
+	user := model.User{
+		Name:         name,
+		Email:        email,
+		PhoneNumber:  "9876543210",
+		LoginMethod:  model.EmailLoginMethod,
+		ReferralCode: strings.ToUpper(name),
+		WalletAmount: wallet,
+	}
+	h.create(&user)
+	h.create(&model.VerificationTable{Email: email, Role: model.UserRole, VerificationStatus: model.VerificationStatusVerified})
+	h.create(&model.UserReferralHistory{UserID: user.ID, ReferralCode: user.ReferralCode})
+	address := model.Address{UserID: user.ID, PhoneNumber: 9876543210, AddressType: "home", StreetName: "MG Road", StreetNumber: "1", City: "Kochi", State: "Kerala", PostalCode: "682001"}
+	h.create(&address)
+	return userFixture{User: user, client: h.login(email, model.UserRole), AddressID: address.AddressID}
+}
+
+type restaurantFixture struct {
+	model.Restaurant
+	*client
+}
+
+// verified restaurant
+func (h *harness) restaurant(name string) restaurantFixture {
+	h.t.Helper()
+	email := strings.ToLower(name) + "@restaurant.test"
+	restaurant := model.Restaurant{
+		Name:               name,
+		Email:              email,
+		Description:        "test kitchen",
+		Address:            "Kochi",
+		PhoneNumber:        9876543210,
+		ImageURL:           "https://images.test/restaurant.jpg",
+		CertificateURL:     "https://images.test/certificate.pdf",
+		VerificationStatus: model.VerificationStatusVerified,
+	}
+	h.create(&restaurant)
+	h.create(&model.VerificationTable{Email: email, Role: model.RestaurantRole, VerificationStatus: model.VerificationStatusVerified})
+	return restaurantFixture{Restaurant: restaurant, client: h.login(email, model.RestaurantRole)}
+}
+
+func (h *harness) category(name string) model.Category {
+	category := model.Category{Name: name, Description: name + " dishes", ImageURL: "https://images.test/category.jpg"}
+	h.create(&category)
+	return category
+}
+
+func (h *harness) product(restaurantID uint, categoryID uint, name string, price float64, stock uint) model.Product {
+	product := model.Product{
+		RestaurantID: restaurantID,
+		CategoryID:   categoryID,
+		Name:         name,
+		Description:  name,
+		ImageURL:     "https://images.test/product.jpg",
+		Price:        price,
+		MaxStock:     stock,
+		StockLeft:    stock,
+		Veg:          model.YES,
+	}
+	h.create(&product)
+	return product
+}
+
+func (h *harness) cart(userID uint, product model.Product, quantity uint) {
+	h.create(&model.CartItems{UserID: userID, ProductID: product.ID, RestaurantID: product.RestaurantID, Quantity: quantity})
+}
+
+// place an order of the cart of the restaurant through the api
+func (h *harness) placeOrder(user userFixture, restaurantID uint, method string, coupon string) model.Order {
+	h.t.Helper()
+	var placed struct {
+		Order model.Order `json:"order_details"`
+	}
+	user.do(http.MethodPost, "/api/v1/user/order/step1/placeorder", model.PlaceOrder{
+		AddressID:     user.AddressID,
+		RestaurantID:  restaurantID,
+		PaymentMethod: method,
+		CouponCode:    coupon,
+	}).ok(h.t, &placed)
+	return placed.Order
+}
+
+// pay an online order through the fake razorpay checkout and its signed callback
+func (h *harness) payWithRazorpay(user userFixture, orderID string) {
+	h.t.Helper()
+	res := user.do(http.MethodPost, "/api/v1/user/order/step2/initiatepayment", model.InitiatePayment{OrderID: orderID, PaymentGateway: model.Razorpay})
+	if res.Code != http.StatusOK {
+		h.t.Fatalf("failed to initiate razorpay payment: %d %s", res.Code, res.Raw)
+	}
+
+	var payment model.Payment
+	h.reload(&payment, "order_id = ?", orderID)
+	paymentID := "pay_" + payment.RazorpayOrderID
+	user.form("/api/v1/user/order/step3/razorpaycallback/"+orderID, url.Values{
+		"razorpay_order_id":   {payment.RazorpayOrderID},
+		"razorpay_payment_id": {paymentID},
+		"razorpay_signature":  {gatewaytest.RazorpaySignature(payment.RazorpayOrderID, paymentID, razorpaySecret)},
+	}).ok(h.t, nil)
+}
+
+// move every item of the order out for delivery and confirm it with the mailed code
+func (h *harness) deliver(user userFixture, restaurant restaurantFixture, orderID string) {
+	h.t.Helper()
+	var items []model.OrderItem
+	if err := h.db.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		h.t.Fatalf("failed to load order items: %v", err)
+	}
+	for _, item := range items {
+		for {
+			var current model.OrderItem
+			h.reload(&current, "order_id = ? AND product_id = ?", orderID, item.ProductID)
+			if current.OrderStatus == model.OrderStatusOntheway {
+				break
+			}
+			restaurant.do(http.MethodPost, "/api/v1/restaurants/order/nextstatus", model.UpdateOrderStatusForRestaurant{OrderID: orderID, ProductID: item.ProductID}).ok(h.t, nil)
+		}
+	}
+
+	var code struct {
+		OTP uint `json:"otp"`
+	}
+	user.do(http.MethodGet, "/api/v1/user/order/deliverycode?order_id="+orderID, nil).ok(h.t, &code)
+	if mail, ok := h.fakes.Mail.Last(user.Email); !ok || !strings.Contains(mail.Body, fmt.Sprint(code.OTP)) {
+		h.t.Fatalf("delivery code %d was not mailed to %s", code.OTP, user.Email)
+	}
+	restaurant.do(http.MethodPost, "/api/v1/restaurants/order/confirmdelivery", model.ConfirmDelivery{OrderID: orderID, DeliveryOTP: code.OTP}).ok(h.t, nil)
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
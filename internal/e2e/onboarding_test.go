@@ -0,0 +1,185 @@
+package e2e
+
+import (
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var fakePDF = []byte("%PDF-1.7\n%fake document\n")
+
+func TestRestaurantOnboarding(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	kitchen := h.restaurant("Malabar")
+	h.db.Model(&model.Restaurant{}).Where("id = ?", kitchen.ID).Update("verification_status", model.VerificationStatusPending)
+	category := h.category("Meals")
+	addProduct := model.AddProductRequest{
+		CategoryID: category.ID, Name: "Sadhya", Description: "feast", ImageURL: "https://images.test/sadhya.jpg",
+		Price: 250, MaxStock: 10, StockLeft: 10, Veg: model.YES,
+	}
+
+	//nothing is listed or sold before the onboarding is approved
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/products/add", addProduct).fails(t, string(response.CodeForbidden))
+	listed := h.product(kitchen.ID, category.ID, "Biryani", 200, 10)
+	user := h.user("Meera", 0)
+	user.do(http.MethodPost, "/api/v1/user/cart/add", model.AddToCartReq{ProductID: listed.ID, Quantity: 1}).fails(t, string(response.CodeForbidden))
+	//products, search results and restaurant lists of the public catalogue
+	catalogue := func() int {
+		var products, search struct {
+			Products    []struct{} `json:"products"`
+			Restaurants []struct{} `json:"restaurants"`
+		}
+		h.anonymous().do(http.MethodGet, "/api/v1/public/products", nil).ok(t, &products)
+		h.anonymous().do(http.MethodGet, "/api/v1/public/search?q=Malabar", nil).ok(t, &search)
+		var restaurants struct {
+			List []struct{} `json:"restaurantslist"`
+		}
+		h.anonymous().do(http.MethodGet, "/api/v1/public/restaurants", nil).ok(t, &restaurants)
+		return len(products.Products) + len(search.Products) + len(search.Restaurants) + len(restaurants.List)
+	}
+	if got := catalogue(); got != 0 {
+		t.Fatalf("unverified restaurant listed %v times in the public catalogue", got)
+	}
+	var all struct {
+		List []struct{} `json:"restaurantslist"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/restaurants", nil).ok(t, &all)
+	if len(all.List) != 1 {
+		t.Fatalf("admins see %v restaurants, want the unverified one", len(all.List))
+	}
+	h.cart(user.ID, listed, 1)
+	user.do(http.MethodPost, "/api/v1/user/order/step1/placeorder", model.PlaceOrder{
+		AddressID: user.AddressID, RestaurantID: kitchen.ID, PaymentMethod: model.CashOnDelivery,
+	}).fails(t, string(response.CodeForbidden))
+
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/onboarding/submit", nil).fails(t, string(response.CodeInvalidState))
+
+	documents := map[string]url.Values{
+		model.DocumentFSSAI: {"reference": {"12345678901234"}},
+		model.DocumentGST:   {"reference": {"32ABCDE1234F1Z5"}},
+		model.DocumentBank:  {"reference": {"123456789012"}, "ifsc": {"SBIN0001234"}},
+	}
+	kitchen.uploadWith("/api/v1/restaurants/onboarding/documents?type=FSSAI", url.Values{"reference": {"1234"}}, "fssai.pdf", fakePDF).
+		fails(t, string(response.CodeBadRequest))
+	kitchen.uploadWith("/api/v1/restaurants/onboarding/documents?type=BANK", url.Values{"reference": {"123456789012"}}, "bank.pdf", fakePDF).
+		fails(t, string(response.CodeBadRequest))
+	uploaded := map[string]model.RestaurantDocument{}
+	for _, Type := range []string{model.DocumentFSSAI, model.DocumentGST, model.DocumentBank} {
+		var document model.RestaurantDocument
+		kitchen.uploadWith("/api/v1/restaurants/onboarding/documents?type="+Type, documents[Type], "document.pdf", fakePDF).ok(t, &document)
+		if document.Status != model.DocumentStatusPending || document.FileURL == "" {
+			t.Fatalf("uploaded %v = %+v", Type, document)
+		}
+		uploaded[Type] = document
+	}
+
+	//documents are private, only the restaurant and admins can fetch them
+	if len(h.fakes.Images.Objects) != 0 {
+		t.Fatalf("documents stored with the public images: %v", h.fakes.Images.Objects)
+	}
+	bank := uploaded[model.DocumentBank].FileURL
+	if !strings.HasPrefix(bank, storage.PrivatePath+"/documents/") {
+		t.Fatalf("bank proof linked at %v", bank)
+	}
+	for _, viewer := range []*client{kitchen.client, admin} {
+		if res := viewer.do(http.MethodGet, bank, nil); res.Code != http.StatusOK || res.Raw != string(fakePDF) {
+			t.Fatalf("document: %d %s", res.Code, res.Raw)
+		}
+	}
+	h.restaurant("Rival").do(http.MethodGet, bank, nil).fails(t, string(response.CodeNotFound))
+	user.do(http.MethodGet, bank, nil).fails(t, string(response.CodeNotFound))
+	admin.do(http.MethodGet, storage.PrivatePath+"/documents/missing.pdf", nil).fails(t, string(response.CodeNotFound))
+	admin.do(http.MethodGet, storage.PrivatePath+"/../uploads/x.pdf", nil).fails(t, string(response.CodeNotFound))
+
+	//verification goes through the review of the documents
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/verify/success?restaurantid="+itoa(kitchen.ID), nil).fails(t, string(response.CodeInvalidState))
+
+	var status struct {
+		VerificationStatus string                     `json:"verification_status"`
+		Documents          []model.RestaurantDocument `json:"documents"`
+		Missing            []string                   `json:"missing"`
+	}
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/onboarding/submit", nil).ok(t, &status)
+	if status.VerificationStatus != model.VerificationStatusInReview || len(status.Documents) != 3 {
+		t.Fatalf("submitted = %+v", status)
+	}
+	if mail, ok := h.fakes.Mail.Last(kitchen.Email); !ok || !strings.Contains(mail.Body, "Onboarding Submitted") {
+		t.Errorf("no submission mail was sent")
+	}
+	kitchen.uploadWith("/api/v1/restaurants/onboarding/documents?type=GST", documents[model.DocumentGST], "gst.pdf", fakePDF).
+		fails(t, string(response.CodeInvalidState))
+
+	var queue struct {
+		Restaurants []struct {
+			RestaurantID uint                       `json:"restaurant_id"`
+			Documents    []model.RestaurantDocument `json:"documents"`
+		} `json:"restaurants"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/restaurants/onboarding", nil).ok(t, &queue)
+	if len(queue.Restaurants) != 1 || queue.Restaurants[0].RestaurantID != kitchen.ID || len(queue.Restaurants[0].Documents) != 3 {
+		t.Fatalf("queue = %+v", queue)
+	}
+
+	review := func(document model.RestaurantDocument, decision model.ReviewRestaurantDocument) string {
+		t.Helper()
+		var reviewed struct {
+			VerificationStatus string `json:"verification_status"`
+		}
+		admin.do(http.MethodPut, "/api/v1/admin/restaurants/onboarding/review?documentid="+itoa(document.ID), decision).ok(t, &reviewed)
+		return reviewed.VerificationStatus
+	}
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/onboarding/review?documentid="+itoa(uploaded[model.DocumentGST].ID),
+		model.ReviewRestaurantDocument{Status: model.DocumentStatusRejected}).fails(t, string(response.CodeValidation))
+
+	//the restaurant is rejected once the last document is reviewed with one of them rejected
+	review(uploaded[model.DocumentFSSAI], model.ReviewRestaurantDocument{Status: model.DocumentStatusApproved})
+	review(uploaded[model.DocumentGST], model.ReviewRestaurantDocument{Status: model.DocumentStatusRejected, Comment: "gstin doesn't match the certificate"})
+	if got := review(uploaded[model.DocumentBank], model.ReviewRestaurantDocument{Status: model.DocumentStatusApproved}); got != model.VerificationStatusRejected {
+		t.Fatalf("status after a rejection = %v", got)
+	}
+	if mail, ok := h.fakes.Mail.Last(kitchen.Email); !ok || !strings.Contains(mail.Body, "gstin doesn't match the certificate") {
+		t.Errorf("rejection mail doesn't carry the reason")
+	}
+
+	//approved documents stay, only the rejected one is uploaded again
+	kitchen.uploadWith("/api/v1/restaurants/onboarding/documents?type=FSSAI", documents[model.DocumentFSSAI], "fssai.pdf", fakePDF).
+		fails(t, string(response.CodeInvalidState))
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/onboarding/submit", nil).fails(t, string(response.CodeInvalidState))
+	var resubmitted model.RestaurantDocument
+	kitchen.uploadWith("/api/v1/restaurants/onboarding/documents?type=GST", url.Values{"reference": {"32ABCDE1234F2Z5"}}, "gst.pdf", fakePDF).ok(t, &resubmitted)
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/onboarding/submit", nil).ok(t, nil)
+
+	var superseded model.RestaurantDocument
+	h.reload(&superseded, "id = ?", uploaded[model.DocumentGST].ID)
+	if superseded.Status != model.DocumentStatusSuperseded || superseded.Comment == "" {
+		t.Errorf("rejected document after resubmission = %+v", superseded)
+	}
+
+	if got := review(resubmitted, model.ReviewRestaurantDocument{Status: model.DocumentStatusApproved}); got != model.VerificationStatusVerified {
+		t.Fatalf("status after every approval = %v", got)
+	}
+	h.reload(&kitchen.Restaurant, "id = ?", kitchen.ID)
+	if kitchen.CertificateURL != uploaded[model.DocumentFSSAI].FileURL {
+		t.Errorf("certificate = %v, want the fssai document", kitchen.CertificateURL)
+	}
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/onboarding/review?documentid="+itoa(resubmitted.ID),
+		model.ReviewRestaurantDocument{Status: model.DocumentStatusRejected, Comment: "again"}).fails(t, string(response.CodeInvalidState))
+
+	//with every document approved a removed verification can be restored
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/verify/failed?restaurantid="+itoa(kitchen.ID), nil).ok(t, nil)
+	admin.do(http.MethodPut, "/api/v1/admin/restaurants/verify/success?restaurantid="+itoa(kitchen.ID), nil).ok(t, nil)
+
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/products/add", addProduct).ok(t, nil)
+	//both products, the search hit on each and the restaurant in search, listed next to Rival
+	if got := catalogue(); got != 7 {
+		t.Errorf("verified restaurant listed %v times in the public catalogue, want 7", got)
+	}
+	user.do(http.MethodPost, "/api/v1/user/order/step1/placeorder", model.PlaceOrder{
+		AddressID: user.AddressID, RestaurantID: kitchen.ID, PaymentMethod: model.CashOnDelivery,
+	}).ok(t, nil)
+}
@@ -0,0 +1,257 @@
+package e2e
+
+import (
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type publicReviews struct {
+	Summary struct {
+		AverageRating float64 `json:"average_rating"`
+		RatingCount   uint    `json:"rating_count"`
+		FoodRating    float64 `json:"food_rating"`
+	} `json:"summary"`
+	Reviews []struct {
+		ReviewID     uint   `json:"review_id"`
+		Reviewer     string `json:"reviewer"`
+		Text         string `json:"text"`
+		HelpfulCount uint   `json:"helpful_count"`
+		Reply        string `json:"reply"`
+	} `json:"reviews"`
+}
+
+func TestRestaurantReviews(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	nila := h.user("Nila", 0)
+	arjun := h.user("Arjun", 0)
+	kitchen := h.restaurant("Rahmath")
+	rival := h.restaurant("Paragon")
+	category := h.category("Meals")
+	biryani := h.product(kitchen.ID, category.ID, "Biryani", 240, 10)
+
+	//orders of both users, only the first one is delivered before reviewing
+	h.cart(nila.ID, biryani, 1)
+	first := h.placeOrder(nila, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(nila, first.OrderID)
+	h.cart(arjun.ID, biryani, 1)
+	second := h.placeOrder(arjun, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(arjun, second.OrderID)
+
+	review := model.RestaurantReviewRequest{OrderID: second.OrderID, FoodRating: 2, PackagingRating: 2, DeliveryRating: 2, Text: "cold and soggy shit"}
+	arjun.do(http.MethodPost, "/api/v1/user/reviews", review).fails(t, string(response.CodeInvalidState))
+	nila.do(http.MethodPost, "/api/v1/user/reviews", review).fails(t, string(response.CodeNotFound))
+	h.deliver(nila, kitchen, first.OrderID)
+	h.deliver(arjun, kitchen, second.OrderID)
+
+	var praise model.RestaurantReview
+	nila.do(http.MethodPost, "/api/v1/user/reviews", model.RestaurantReviewRequest{
+		OrderID: first.OrderID, FoodRating: 5, PackagingRating: 4, DeliveryRating: 3, Text: "best biryani in town",
+	}).ok(t, &praise)
+	if praise.Rating != 4 || praise.RestaurantID != kitchen.ID || praise.Flagged {
+		t.Fatalf("review = %+v", praise)
+	}
+	nila.do(http.MethodPost, "/api/v1/user/reviews", model.RestaurantReviewRequest{
+		OrderID: first.OrderID, FoodRating: 1, PackagingRating: 1, DeliveryRating: 1,
+	}).fails(t, string(response.CodeConflict))
+
+	//profanity is masked and queued for moderation
+	var rant model.RestaurantReview
+	arjun.do(http.MethodPost, "/api/v1/user/reviews", review).ok(t, &rant)
+	if rant.Text != "cold and soggy s***" || !rant.Flagged {
+		t.Fatalf("profane review = %+v", rant)
+	}
+
+	var public publicReviews
+	reviews := "/api/v1/public/restaurant/reviews?id=" + itoa(kitchen.ID)
+	h.anonymous().do(http.MethodGet, reviews, nil).ok(t, &public)
+	if public.Summary.RatingCount != 2 || public.Summary.AverageRating != 3 || len(public.Reviews) != 2 || public.Reviews[0].ReviewID != rant.ID {
+		t.Fatalf("public reviews = %+v", public)
+	}
+
+	//votes, never on your own review and once per user
+	helpful := "/api/v1/user/reviews/helpful?reviewid=" + itoa(praise.ID)
+	nila.do(http.MethodPost, helpful, nil).fails(t, string(response.CodeForbidden))
+	arjun.do(http.MethodPost, helpful, nil).ok(t, nil)
+	arjun.do(http.MethodPost, helpful, nil).fails(t, string(response.CodeConflict))
+	h.anonymous().do(http.MethodGet, reviews+"&sort=helpful", nil).ok(t, &public)
+	if public.Reviews[0].ReviewID != praise.ID || public.Reviews[0].HelpfulCount != 1 || public.Reviews[0].Reviewer != "Nila" {
+		t.Fatalf("helpful first = %+v", public.Reviews)
+	}
+	arjun.do(http.MethodDelete, helpful, nil).ok(t, nil)
+	arjun.do(http.MethodDelete, helpful, nil).fails(t, string(response.CodeNotFound))
+
+	//replies only on the restaurant's own reviews
+	reply := model.ReviewReplyRequest{Reply: "thank you, see you again"}
+	rival.do(http.MethodPut, "/api/v1/restaurants/reviews/reply?reviewid="+itoa(praise.ID), reply).fails(t, string(response.CodeNotFound))
+	kitchen.do(http.MethodPut, "/api/v1/restaurants/reviews/reply?reviewid="+itoa(praise.ID), reply).ok(t, nil)
+	h.anonymous().do(http.MethodGet, reviews+"&sort=highest", nil).ok(t, &public)
+	if public.Reviews[0].Reply != reply.Reply {
+		t.Errorf("reply isn't public: %+v", public.Reviews[0])
+	}
+	h.anonymous().do(http.MethodGet, reviews+"&sort=best", nil).fails(t, string(response.CodeBadRequest))
+
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/reviews/flag?reviewid="+itoa(rant.ID), model.ReviewFlagRequest{Reason: "abusive"}).ok(t, nil)
+	kitchen.do(http.MethodPost, "/api/v1/restaurants/reviews/flag?reviewid="+itoa(rant.ID), model.ReviewFlagRequest{Reason: "abusive"}).
+		fails(t, string(response.CodeConflict))
+
+	var queue struct {
+		Reviews []model.RestaurantReview `json:"reviews"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/reviews?flagged=true", nil).ok(t, &queue)
+	if len(queue.Reviews) != 1 || queue.Reviews[0].ID != rant.ID || queue.Reviews[0].FlagCount != 1 {
+		t.Fatalf("moderation queue = %+v", queue.Reviews)
+	}
+
+	//hiding takes the review out of the list and the aggregates
+	moderate := "/api/v1/admin/reviews/moderate?reviewid=" + itoa(rant.ID)
+	admin.do(http.MethodPut, moderate, model.ModerateReviewRequest{Action: model.ReviewActionHide}).fails(t, string(response.CodeValidation))
+	admin.do(http.MethodPut, moderate, model.ModerateReviewRequest{Action: model.ReviewActionHide, Note: "abusive language"}).ok(t, nil)
+	admin.do(http.MethodPut, moderate, model.ModerateReviewRequest{Action: model.ReviewActionHide, Note: "again"}).fails(t, string(response.CodeInvalidState))
+	h.anonymous().do(http.MethodGet, reviews, nil).ok(t, &public)
+	if public.Summary.RatingCount != 1 || public.Summary.AverageRating != 4 || len(public.Reviews) != 1 {
+		t.Fatalf("public reviews after hiding = %+v", public)
+	}
+	var entry model.AuditLog
+	h.reload(&entry, "action = ? AND entity_id = ?", audit.ReviewModerate, itoa(rant.ID))
+
+	var restaurants struct {
+		List []struct {
+			ID            uint    `json:"restaurant_id"`
+			AverageRating float64 `json:"average_rating"`
+			RatingCount   uint    `json:"rating_count"`
+		} `json:"restaurantslist"`
+	}
+	h.anonymous().do(http.MethodGet, "/api/v1/public/restaurants", nil).ok(t, &restaurants)
+	for _, r := range restaurants.List {
+		if r.ID == kitchen.ID && (r.AverageRating != 4 || r.RatingCount != 1) {
+			t.Errorf("listed rating = %+v", r)
+		}
+	}
+	var profile struct {
+		FoodRating float64 `json:"food_rating"`
+	}
+	h.anonymous().do(http.MethodGet, "/api/v1/public/restaurant/profile?id="+itoa(kitchen.ID), nil).ok(t, &profile)
+	if profile.FoodRating != 5 {
+		t.Errorf("profile food rating = %v", profile.FoodRating)
+	}
+
+	var own struct {
+		Reviews []model.RestaurantReview `json:"reviews"`
+	}
+	kitchen.do(http.MethodGet, "/api/v1/restaurants/reviews?status=hidden", nil).ok(t, &own)
+	if len(own.Reviews) != 1 || own.Reviews[0].ModerationNote != "abusive language" {
+		t.Errorf("hidden reviews = %+v", own.Reviews)
+	}
+}
+
+func TestProductReviewEdits(t *testing.T) {
+	h := newHarness(t)
+	nila := h.user("Nila", 0)
+	arjun := h.user("Arjun", 0)
+	kitchen := h.restaurant("Rahmath")
+	category := h.category("Meals")
+	biryani := h.product(kitchen.ID, category.ID, "Biryani", 240, 10)
+
+	h.cart(nila.ID, biryani, 1)
+	mine := h.placeOrder(nila, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(nila, mine.OrderID)
+	h.cart(arjun.ID, biryani, 1)
+	theirs := h.placeOrder(arjun, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(arjun, theirs.OrderID)
+
+	rate := func(user userFixture, orderID string, rating float64) *result {
+		return user.do(http.MethodPost, "/api/v1/user/order/rating", model.UserRatingOrderItem{OrderID: orderID, ProductID: biryani.ID, UserRating: rating})
+	}
+	rate(nila, mine.OrderID, 4).fails(t, string(response.CodeInvalidState))
+	h.deliver(nila, kitchen, mine.OrderID)
+	h.deliver(arjun, kitchen, theirs.OrderID)
+	rate(nila, mine.OrderID, 6).fails(t, string(response.CodeValidation))
+	rate(arjun, mine.OrderID, 4).fails(t, string(response.CodeNotFound))
+
+	//a changed rating replaces the old one instead of being counted again
+	rate(nila, mine.OrderID, 4).ok(t, nil)
+	rate(arjun, theirs.OrderID, 2).ok(t, nil)
+	rate(nila, mine.OrderID, 5).ok(t, nil)
+	h.reload(&biryani, "id = ?", biryani.ID)
+	if biryani.RatingCount != 2 || biryani.AverageRating != 3.5 {
+		t.Fatalf("product rating = %v of %v, want 3.5 of 2", biryani.AverageRating, biryani.RatingCount)
+	}
+
+	review := func(text string) {
+		nila.do(http.MethodPost, "/api/v1/user/order/review", model.UserReviewonOrderItem{OrderID: mine.OrderID, ProductID: biryani.ID, ReviewText: text}).ok(t, nil)
+	}
+	review("tasty")
+	review("tasty, a bit too spicy")
+
+	item := "?order_id=" + mine.OrderID + "&product_id=" + itoa(biryani.ID)
+	for i := 0; i < model.ReviewPhotoLimit; i++ {
+		nila.upload("/api/v1/user/order/review/photo"+item, "plate.png", testPNG(t, 400, 300)).ok(t, nil)
+	}
+	nila.upload("/api/v1/user/order/review/photo"+item, "plate.png", testPNG(t, 400, 300)).fails(t, string(response.CodeConflict))
+	arjun.upload("/api/v1/user/order/review/photo"+item, "plate.png", testPNG(t, 400, 300)).fails(t, string(response.CodeNotFound))
+
+	var history struct {
+		Review    string                 `json:"review"`
+		Rating    float64                `json:"rating"`
+		Photos    []model.ReviewPhoto    `json:"photos"`
+		Revisions []model.ReviewRevision `json:"revisions"`
+	}
+	nila.do(http.MethodGet, "/api/v1/user/order/review/history"+item, nil).ok(t, &history)
+	if history.Review != "tasty, a bit too spicy" || history.Rating != 5 || len(history.Photos) != model.ReviewPhotoLimit || history.Photos[0].ThumbURL == "" {
+		t.Fatalf("history = %+v", history)
+	}
+	//newest first: the first review, the rating before the review, the first rating
+	if len(history.Revisions) != 3 || history.Revisions[0].Review != "tasty" || history.Revisions[2].Rating != 4 {
+		t.Fatalf("revisions = %+v", history.Revisions)
+	}
+	arjun.do(http.MethodDelete, "/api/v1/user/order/review/photo?photoid="+itoa(history.Photos[0].ID), nil).fails(t, string(response.CodeNotFound))
+	nila.do(http.MethodDelete, "/api/v1/user/order/review/photo?photoid="+itoa(history.Photos[0].ID), nil).ok(t, nil)
+	//the removed photo takes every variant with it
+	removed := storage.Keys(storage.ReviewPhoto, history.Photos[0].URL)
+	if len(removed) != len(storage.ReviewPhoto.Variants) {
+		t.Fatalf("keys of %v = %v", history.Photos[0].URL, removed)
+	}
+	for _, key := range removed {
+		if _, ok := h.fakes.Images.Objects[key]; ok {
+			t.Errorf("%v left after the photo was removed", key)
+		}
+	}
+	if stored := len(h.fakes.Images.Objects); stored != (model.ReviewPhotoLimit-1)*len(storage.ReviewPhoto.Variants) {
+		t.Fatalf("%v stored images, want the variants of the other photos", stored)
+	}
+
+	var public []struct {
+		UserID uint     `json:"userid"`
+		Photos []string `json:"photos"`
+		Edited bool     `json:"edited"`
+	}
+	h.anonymous().do(http.MethodGet, "/api/v1/public/product/reviewandrating?product_id="+itoa(biryani.ID), nil).ok(t, &public)
+	for _, r := range public {
+		if r.UserID == nila.ID && (len(r.Photos) != model.ReviewPhotoLimit-1 || !r.Edited) {
+			t.Errorf("public review = %+v", r)
+		}
+	}
+
+	//outside the window nothing changes anymore
+	h.db.Model(&model.OrderItem{}).Where("order_id = ?", theirs.OrderID).Update("delivered_at", time.Now().AddDate(0, 0, -model.ReviewWindowDays-1))
+	rate(arjun, theirs.OrderID, 5).fails(t, string(response.CodeInvalidState))
+
+	//deleting takes the rating out of the product and the photos with it
+	nila.do(http.MethodDelete, "/api/v1/user/order/review"+item, nil).ok(t, nil)
+	nila.do(http.MethodDelete, "/api/v1/user/order/review"+item, nil).fails(t, string(response.CodeNotFound))
+	h.reload(&biryani, "id = ?", biryani.ID)
+	if biryani.RatingCount != 1 || biryani.AverageRating != 2 {
+		t.Errorf("product rating after delete = %v of %v, want 2 of 1", biryani.AverageRating, biryani.RatingCount)
+	}
+	var photos int64
+	h.db.Model(&model.ReviewPhoto{}).Where("order_id = ?", mine.OrderID).Count(&photos)
+	if photos != 0 || len(h.fakes.Images.Objects) != 0 {
+		t.Errorf("%v photos and %v images left after the delete", photos, len(h.fakes.Images.Objects))
+	}
+}
@@ -0,0 +1,109 @@
+package e2e
+
+import (
+	"encoding/json"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+type searchResult struct {
+	Products    []model.SearchProductResult    `json:"products"`
+	Restaurants []model.SearchRestaurantResult `json:"restaurants"`
+	Facets      struct {
+		Restaurants []model.SearchFacet `json:"restaurants"`
+		Offers      int64               `json:"offers"`
+	} `json:"facets"`
+	Total int64 `json:"total"`
+}
+
+func (h *harness) search(query url.Values) (searchResult, string) {
+	h.t.Helper()
+	var found searchResult
+	res := h.anonymous().do(http.MethodGet, "/api/v1/public/search?"+query.Encode(), nil)
+	res.ok(h.t, &found)
+	var page struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	json.Unmarshal([]byte(res.Raw), &page)
+	return found, page.NextCursor
+}
+
+func names(products []model.SearchProductResult) []string {
+	var names []string
+	for _, product := range products {
+		names = append(names, product.Name)
+	}
+	return names
+}
+
+func same(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// product names weigh more than product descriptions, restaurant names and restaurant descriptions
+func TestSearchRelevance(t *testing.T) {
+	h := newHarness(t)
+	kayees := h.restaurant("Kayees")
+	corner := h.restaurant("Biryani Corner")
+	category := h.category("Meals")
+	h.product(kayees.ID, category.ID, "Mutton Biryani", 320, 10)
+	rice := h.product(kayees.ID, category.ID, "Ghee Rice", 120, 10)
+	h.db.Model(&rice).Update("description", "goes well with a biryani")
+	chicken := h.product(corner.ID, category.ID, "Chicken 65", 180, 10)
+	h.db.Model(&chicken).Updates(map[string]any{"veg": model.NO, "offer_amount": 30})
+	h.product(corner.ID, category.ID, "Biryani Special", 400, 10)
+	h.product(kayees.ID, category.ID, "Lime Juice", 60, 10)
+
+	//name and restaurant match, name match, then the description and restaurant matches by newest
+	found, next := h.search(url.Values{"q": {"BIRYANI"}})
+	if !same(names(found.Products), "Biryani Special", "Mutton Biryani", "Chicken 65", "Ghee Rice") || found.Total != 4 || next != "" {
+		t.Fatalf("search = %v, total %v, next %q", names(found.Products), found.Total, next)
+	}
+	if found.Products[0].Relevance <= found.Products[1].Relevance || found.Products[2].Relevance != found.Products[3].Relevance {
+		t.Errorf("relevance = %+v", found.Products)
+	}
+	if len(found.Restaurants) != 1 || found.Restaurants[0].ID != corner.ID {
+		t.Errorf("restaurants = %+v", found.Restaurants)
+	}
+	if len(found.Facets.Restaurants) != 2 || found.Facets.Restaurants[0].Count != 2 || found.Facets.Offers != 1 {
+		t.Errorf("facets = %+v", found.Facets)
+	}
+
+	//filters narrow the matches, the price filter is on the price after the offer
+	found, _ = h.search(url.Values{"q": {"biryani"}, "veg": {model.NO}, "max_price": {"150"}})
+	if !same(names(found.Products), "Chicken 65") || found.Products[0].SellingPrice != 150 {
+		t.Errorf("non veg up to 150 = %+v", found.Products)
+	}
+	found, _ = h.search(url.Values{"q": {"biryani"}, "restaurant_id": {itoa(kayees.ID)}, "sort": {"price_asc"}})
+	if !same(names(found.Products), "Ghee Rice", "Mutton Biryani") {
+		t.Errorf("kayees by price = %v", names(found.Products))
+	}
+
+	//pages continue in relevance order, restaurants come on the first page only
+	found, next = h.search(url.Values{"q": {"biryani"}, "limit": {"3"}})
+	if !same(names(found.Products), "Biryani Special", "Mutton Biryani", "Chicken 65") || next == "" {
+		t.Fatalf("first page = %v, next %q", names(found.Products), next)
+	}
+	found, next = h.search(url.Values{"q": {"biryani"}, "limit": {"3"}, "cursor": {next}})
+	if !same(names(found.Products), "Ghee Rice") || len(found.Restaurants) != 0 || next != "" {
+		t.Errorf("second page = %v with %v restaurants, next %q", names(found.Products), len(found.Restaurants), next)
+	}
+
+	//without a query every listed product comes, newest first
+	found, _ = h.search(url.Values{})
+	if found.Total != 5 || found.Products[0].Name != "Lime Juice" {
+		t.Errorf("everything = %v", names(found.Products))
+	}
+	h.anonymous().do(http.MethodGet, "/api/v1/public/search?min_price=200&max_price=100", nil).fails(t, string(response.CodeBadRequest))
+}
@@ -0,0 +1,276 @@
+package e2e
+
+import (
+	"context"
+	"foodbuddy/internal/audit"
+	"foodbuddy/internal/controllers"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ticketResult struct {
+	model.SupportTicket
+	ProductIDs []uint                 `json:"product_ids"`
+	Messages   []model.SupportMessage `json:"messages"`
+}
+
+func TestSupportTicketRefund(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	user := h.user("Nila", 0)
+	other := h.user("Arjun", 0)
+	kitchen := h.restaurant("Rahmath")
+	category := h.category("Meals")
+	biryani := h.product(kitchen.ID, category.ID, "Biryani", 240, 10)
+	juice := h.product(kitchen.ID, category.ID, "Lime Juice", 60, 10)
+	h.cart(user.ID, biryani, 1)
+	h.cart(user.ID, juice, 1)
+	order := h.placeOrder(user, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(user, order.OrderID)
+	h.deliver(user, kitchen, order.OrderID)
+	h.reload(&kitchen.Restaurant, "id = ?", kitchen.ID)
+	earned := kitchen.WalletAmount
+
+	raise := model.RaiseTicketRequest{OrderID: order.OrderID, ProductIDs: []uint{biryani.ID}, Category: model.TicketCategoryWrongItem, Description: "got chicken instead of mutton biryani"}
+	other.do(http.MethodPost, "/api/v1/user/support/tickets", raise).fails(t, string(response.CodeNotFound))
+	user.do(http.MethodPost, "/api/v1/user/support/tickets", model.RaiseTicketRequest{
+		OrderID: order.OrderID, ProductIDs: []uint{biryani.ID, 9999}, Category: model.TicketCategoryMissingItem, Description: "half the order is missing",
+	}).fails(t, string(response.CodeBadRequest))
+	var ticket ticketResult
+	user.do(http.MethodPost, "/api/v1/user/support/tickets", raise).ok(t, &ticket)
+	if ticket.Status != model.TicketStatusOpen || ticket.RestaurantID != kitchen.ID || len(ticket.ProductIDs) != 1 || ticket.FirstResponseDueAt.IsZero() {
+		t.Fatalf("ticket = %+v", ticket)
+	}
+	user.do(http.MethodPost, "/api/v1/user/support/tickets", raise).fails(t, string(response.CodeConflict))
+
+	//ticket routes of the group for the current ticket
+	as := func(group string, action string) string {
+		return "/api/v1/" + group + "/support/ticket" + action + "?ticketid=" + itoa(ticket.ID)
+	}
+
+	other.do(http.MethodGet, as("user", ""), nil).fails(t, string(response.CodeNotFound))
+	user.uploadWith(as("user", "/attachment"), url.Values{"message": {"photo of what came"}}, "receipt.pdf", fakePDF).ok(t, nil)
+	kitchen.do(http.MethodPost, as("restaurants", "/message"), model.TicketMessageRequest{Message: "sorry, the orders got swapped"}).ok(t, nil)
+	admin.do(http.MethodPost, as("admin", "/message"), model.TicketMessageRequest{Message: "looking into it"}).ok(t, nil)
+
+	var thread ticketResult
+	user.do(http.MethodGet, as("user", ""), nil).ok(t, &thread)
+	if len(thread.Messages) != 3 || thread.Messages[0].AttachmentURL == "" || thread.Messages[1].AuthorRole != model.RestaurantRole {
+		t.Fatalf("thread = %+v", thread.Messages)
+	}
+	if thread.FirstRespondedAt == nil || !thread.FirstRespondedAt.Equal(thread.Messages[1].CreatedAt) {
+		t.Errorf("first response = %v, want the restaurant's answer", thread.FirstRespondedAt)
+	}
+
+	//attachments are private, served to whoever can see the ticket
+	attachment := thread.Messages[0].AttachmentURL
+	if !strings.HasPrefix(attachment, storage.PrivatePath+"/tickets/") || len(h.fakes.Images.Objects) != 0 {
+		t.Fatalf("attachment stored at %v with public images %v", attachment, h.fakes.Images.Objects)
+	}
+	for _, viewer := range []*client{user.client, kitchen.client, admin} {
+		if res := viewer.do(http.MethodGet, attachment, nil); res.Code != http.StatusOK || res.Raw != string(fakePDF) {
+			t.Fatalf("attachment: %d %s", res.Code, res.Raw)
+		}
+	}
+	other.do(http.MethodGet, attachment, nil).fails(t, string(response.CodeNotFound))
+	h.anonymous().do(http.MethodGet, attachment, nil).fails(t, string(response.CodeUnauthorized))
+
+	//answered tickets only breach the resolution due time
+	h.db.Model(&model.SupportTicket{}).Where("id = ?", ticket.ID).Update("first_response_due_at", time.Now().Add(-time.Hour))
+	if err := controllers.FlagSupportSLABreaches(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var tickets struct {
+		Tickets []model.SupportTicket `json:"tickets"`
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/support/tickets?breached=true", nil).ok(t, &tickets)
+	if len(tickets.Tickets) != 0 {
+		t.Fatalf("breached tickets = %+v", tickets.Tickets)
+	}
+	h.db.Model(&model.SupportTicket{}).Where("id = ?", ticket.ID).Update("resolution_due_at", time.Now().Add(-time.Minute))
+	if err := controllers.FlagSupportSLABreaches(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	admin.do(http.MethodGet, "/api/v1/admin/support/tickets?breached=true", nil).ok(t, &tickets)
+	if len(tickets.Tickets) != 1 || tickets.Tickets[0].FirstResponseBreached || !tickets.Tickets[0].ResolutionBreached {
+		t.Fatalf("breached tickets = %+v", tickets.Tickets)
+	}
+	kitchen.do(http.MethodGet, "/api/v1/restaurants/support/tickets", nil).ok(t, &tickets)
+	if len(tickets.Tickets) != 1 || tickets.Tickets[0].ID != ticket.ID {
+		t.Errorf("restaurant tickets = %+v", tickets.Tickets)
+	}
+
+	var item model.OrderItem
+	h.reload(&item, "order_id = ? AND product_id = ?", order.OrderID, biryani.ID)
+	resolve := as("admin", "/resolve")
+	admin.do(http.MethodPut, resolve, model.ResolveTicketRequest{Resolution: model.TicketResolutionRefund, RefundAmount: item.AfterDeduction + 1, Note: "full refund"}).
+		fails(t, string(response.CodeBadRequest))
+	kitchen.do(http.MethodPut, resolve, model.ResolveTicketRequest{Resolution: model.TicketResolutionNoRefund, Note: "no"}).
+		fails(t, string(response.CodeUnauthorized))
+
+	var resolved ticketResult
+	admin.do(http.MethodPut, resolve, model.ResolveTicketRequest{Resolution: model.TicketResolutionRefund, RefundAmount: 100, Note: "refunding the price difference"}).ok(t, &resolved)
+	if resolved.Status != model.TicketStatusResolved || resolved.RefundAmount != 100 || resolved.Messages[len(resolved.Messages)-1].Body != "refunding the price difference" {
+		t.Fatalf("resolved = %+v", resolved)
+	}
+	admin.do(http.MethodPut, resolve, model.ResolveTicketRequest{Resolution: model.TicketResolutionRefund, RefundAmount: 10, Note: "again"}).
+		fails(t, string(response.CodeInvalidState))
+	user.do(http.MethodPost, as("user", "/message"), model.TicketMessageRequest{Message: "thanks"}).fails(t, string(response.CodeInvalidState))
+
+	h.reload(&user.User, "id = ?", user.ID)
+	h.reload(&kitchen.Restaurant, "id = ?", kitchen.ID)
+	if user.WalletAmount != 100 || math.Abs(earned-kitchen.WalletAmount-100) > 0.001 {
+		t.Errorf("wallets after the refund: user %v, restaurant %v (was %v)", user.WalletAmount, kitchen.WalletAmount, earned)
+	}
+	var history model.UserWalletHistory
+	h.reload(&history, "user_id = ? AND reason = ?", user.ID, model.WalletTxTypeSupportRefund)
+	if mail, ok := h.fakes.Mail.Last(user.Email); !ok || !strings.Contains(mail.Body, "100.00 is refunded") {
+		t.Errorf("no resolution mail was sent")
+	}
+	var entry model.AuditLog
+	h.reload(&entry, "action = ? AND entity_id = ?", audit.TicketResolve, itoa(ticket.ID))
+
+	//a second ticket on the order can only refund what is left
+	user.do(http.MethodPost, "/api/v1/user/support/tickets", raise).ok(t, &ticket)
+	admin.do(http.MethodPut, as("admin", "/resolve"), model.ResolveTicketRequest{Resolution: model.TicketResolutionRefund, RefundAmount: item.AfterDeduction - 99, Note: "too much"}).
+		fails(t, string(response.CodeBadRequest))
+	admin.do(http.MethodPut, as("admin", "/resolve"), model.ResolveTicketRequest{Resolution: model.TicketResolutionNoRefund, Note: "already refunded"}).ok(t, nil)
+}
+
+// undelivered items are cancelled, not ticketed, so a support refund and a cancellation refund never add up
+// each due time is flagged on its own, a missed first response doesn't hide a missed resolution
+func TestSupportSLABreaches(t *testing.T) {
+	h := newHarness(t)
+	ticket := model.SupportTicket{
+		UserID: 1, OrderID: "order-1", RestaurantID: 1, Status: model.TicketStatusOpen,
+		FirstResponseDueAt: time.Now().Add(-time.Minute), ResolutionDueAt: time.Now().Add(time.Hour),
+	}
+	h.create(&ticket)
+	flag := func() {
+		if err := controllers.FlagSupportSLABreaches(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		h.reload(&ticket, "id = ?", ticket.ID)
+	}
+
+	flag()
+	if !ticket.FirstResponseBreached || ticket.ResolutionBreached {
+		t.Fatalf("after the first response due time: %+v", ticket)
+	}
+	h.db.Model(&model.SupportTicket{}).Where("id = ?", ticket.ID).Update("resolution_due_at", time.Now().Add(-time.Second))
+	flag()
+	if !ticket.FirstResponseBreached || !ticket.ResolutionBreached {
+		t.Fatalf("after the resolution due time: %+v", ticket)
+	}
+}
+
+func TestSupportTicketThenCancel(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	user := h.user("Nila", 0)
+	kitchen := h.restaurant("Rahmath")
+	biryani := h.product(kitchen.ID, h.category("Meals").ID, "Biryani", 240, 10)
+	raise := func(orderID string) model.RaiseTicketRequest {
+		return model.RaiseTicketRequest{OrderID: orderID, ProductIDs: []uint{biryani.ID}, Category: model.TicketCategoryLate, Description: "still waiting for the biryani"}
+	}
+	cancel := func(orderID string) *result {
+		return user.do(http.MethodPost, "/api/v1/user/order/cancel/online", model.CancelOrderedProduct{OrderID: orderID, ProductId: biryani.ID})
+	}
+
+	h.cart(user.ID, biryani, 1)
+	pending := h.placeOrder(user, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(user, pending.OrderID)
+	user.do(http.MethodPost, "/api/v1/user/support/tickets", raise(pending.OrderID)).fails(t, string(response.CodeInvalidState))
+	cancel(pending.OrderID).ok(t, nil)
+	h.reload(&user.User, "id = ?", user.ID)
+	if user.WalletAmount != 240 {
+		t.Fatalf("wallet after cancelling = %v, want 240", user.WalletAmount)
+	}
+
+	h.cart(user.ID, biryani, 1)
+	delivered := h.placeOrder(user, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(user, delivered.OrderID)
+	h.deliver(user, kitchen, delivered.OrderID)
+	var ticket ticketResult
+	user.do(http.MethodPost, "/api/v1/user/support/tickets", raise(delivered.OrderID)).ok(t, &ticket)
+	admin.do(http.MethodPut, "/api/v1/admin/support/ticket/resolve?ticketid="+itoa(ticket.ID),
+		model.ResolveTicketRequest{Resolution: model.TicketResolutionRefund, RefundAmount: 240, Note: "sorry for the wait"}).ok(t, nil)
+	cancel(delivered.OrderID).fails(t, string(response.CodeConflict))
+
+	h.reload(&user.User, "id = ?", user.ID)
+	if user.WalletAmount != 480 {
+		t.Errorf("wallet = %v, want the cancellation and the ticket refund once each", user.WalletAmount)
+	}
+}
+
+// refunds are capped per item, one item's refund never eats into another and never goes past what it paid
+func TestSupportRefundPerItem(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin("admin@foodbuddy.test")
+	user := h.user("Nila", 0)
+	kitchen := h.restaurant("Rahmath")
+	category := h.category("Meals")
+	biryani := h.product(kitchen.ID, category.ID, "Biryani", 240, 10)
+	juice := h.product(kitchen.ID, category.ID, "Lime Juice", 60, 10)
+	h.cart(user.ID, biryani, 1)
+	h.cart(user.ID, juice, 1)
+	order := h.placeOrder(user, kitchen.ID, model.OnlinePayment, "")
+	h.payWithRazorpay(user, order.OrderID)
+	h.deliver(user, kitchen, order.OrderID)
+	h.reload(&kitchen.Restaurant, "id = ?", kitchen.ID)
+	earned := kitchen.WalletAmount
+
+	raise := func(products ...uint) uint {
+		var ticket ticketResult
+		user.do(http.MethodPost, "/api/v1/user/support/tickets", model.RaiseTicketRequest{
+			OrderID: order.OrderID, ProductIDs: products, Category: model.TicketCategoryWrongItem, Description: "not what was ordered",
+		}).ok(t, &ticket)
+		return ticket.ID
+	}
+	resolve := func(ticketID uint, refund float64) *result {
+		return admin.do(http.MethodPut, "/api/v1/admin/support/ticket/resolve?ticketid="+itoa(ticketID),
+			model.ResolveTicketRequest{Resolution: model.TicketResolutionRefund, RefundAmount: refund, Note: "refund"})
+	}
+	refunded := func(product model.Product) float64 {
+		var item model.OrderItem
+		h.reload(&item, "order_id = ? AND product_id = ?", order.OrderID, product.ID)
+		return item.RefundedAmount
+	}
+
+	//a failed refund leaves the ticket open and every wallet as it was
+	ticketID := raise(biryani.ID)
+	h.db.Delete(&user.User)
+	resolve(ticketID, 200).fails(t, string(response.CodeInternal))
+	var ticket model.SupportTicket
+	h.reload(&ticket, "id = ?", ticketID)
+	h.reload(&kitchen.Restaurant, "id = ?", kitchen.ID)
+	if ticket.Status != model.TicketStatusOpen || kitchen.WalletAmount != earned || refunded(biryani) != 0 {
+		t.Fatalf("after a failed refund: ticket %v, restaurant %v (was %v), refunded %v", ticket.Status, kitchen.WalletAmount, earned, refunded(biryani))
+	}
+	h.db.Unscoped().Model(&user.User).Update("deleted_at", nil)
+	resolve(ticketID, 200).ok(t, nil)
+
+	//the juice keeps its whole allowance after the biryani refund
+	resolve(raise(juice.ID), 30).ok(t, nil)
+	//the rest of both items, split by what is left on each
+	ticketID = raise(biryani.ID, juice.ID)
+	resolve(ticketID, 70.01).fails(t, string(response.CodeBadRequest))
+	resolve(ticketID, 70).ok(t, nil)
+	if refunded(biryani) != 240 || refunded(juice) != 60 {
+		t.Fatalf("refunded biryani %v and juice %v, want 240 and 60", refunded(biryani), refunded(juice))
+	}
+	resolve(raise(juice.ID), 1).fails(t, string(response.CodeBadRequest))
+
+	h.reload(&user.User, "id = ?", user.ID)
+	h.reload(&kitchen.Restaurant, "id = ?", kitchen.ID)
+	if user.WalletAmount != 300 || math.Abs(earned-kitchen.WalletAmount-300) > 0.001 {
+		t.Errorf("wallets after the refunds: user %v, restaurant %v (was %v)", user.WalletAmount, kitchen.WalletAmount, earned)
+	}
+}
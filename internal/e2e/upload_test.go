@@ -0,0 +1,85 @@
+package e2e
+
+import (
+	"bytes"
+	"foodbuddy/internal/gateway/gatewaytest"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/storage"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageUploads(t *testing.T) {
+	h := newHarness(t)
+	restaurant := h.restaurant("Paragon")
+	other := h.restaurant("Rahmath")
+	category := h.category("Biryani")
+	product := h.product(restaurant.ID, category.ID, "Chicken Biryani", 200, 10)
+
+	//the extension is ignored, the content decides
+	var stored storage.Stored
+	restaurant.upload("/api/v1/restaurants/products/image?productid="+itoa(product.ID), "biryani.txt", testPNG(t, 640, 480)).ok(t, &stored)
+	if len(stored.Variants) != 3 || stored.URL != stored.Variants["card"] {
+		t.Fatalf("stored = %+v", stored)
+	}
+	h.reload(&product, "id = ?", product.ID)
+	if product.ImageURL != stored.URL {
+		t.Errorf("product image = %v, want %v", product.ImageURL, stored.URL)
+	}
+	for name, url := range stored.Variants {
+		key := strings.TrimPrefix(url, gatewaytest.ImageURL(""))
+		content, ok := h.fakes.Images.Objects[key]
+		if !ok {
+			t.Fatalf("variant %v was not stored", name)
+		}
+		config, err := png.DecodeConfig(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("variant %v: %v", name, err)
+		}
+		want := map[string][2]int{"thumb": {150, 150}, "card": {300, 300}, "large": {640, 480}}[name]
+		if config.Width != want[0] || config.Height != want[1] {
+			t.Errorf("variant %v is %vx%v, want %vx%v", name, config.Width, config.Height, want[0], want[1])
+		}
+	}
+
+	other.upload("/api/v1/restaurants/products/image?productid="+itoa(product.ID), "biryani.png", testPNG(t, 10, 10)).
+		fails(t, string(response.CodeUnauthorized))
+	restaurant.upload("/api/v1/restaurants/products/image?productid="+itoa(product.ID), "biryani.png", []byte("not an image at all")).
+		fails(t, string(response.CodeUnsupportedType))
+
+	//certificates may be pdfs, they are stored as uploaded
+	var certificate storage.Stored
+	restaurant.upload("/api/v1/restaurants/certificate", "fssai.pdf", []byte("%PDF-1.7\n%fake certificate\n")).ok(t, &certificate)
+	h.reload(&restaurant.Restaurant, "id = ?", restaurant.ID)
+	if restaurant.CertificateURL != certificate.URL || !strings.HasSuffix(certificate.URL, ".pdf") {
+		t.Errorf("certificate = %v, stored = %v", restaurant.CertificateURL, certificate.URL)
+	}
+	big := append([]byte("%PDF-1.7\n"), make([]byte, storage.Certificate.MaxBytes)...)
+	restaurant.upload("/api/v1/restaurants/certificate", "fssai.pdf", big).fails(t, string(response.CodeTooLarge))
+
+	admin := h.admin("admin@foodbuddy.test")
+	var categoryImage storage.Stored
+	admin.upload("/api/v1/admin/categories/image?categoryid="+itoa(category.ID), "biryani.png", testPNG(t, 100, 200)).ok(t, &categoryImage)
+	var updated model.Category
+	h.reload(&updated, "id = ?", category.ID)
+	if updated.ImageURL != categoryImage.URL || len(categoryImage.Variants) != 2 {
+		t.Errorf("category image = %v, stored = %+v", updated.ImageURL, categoryImage)
+	}
+}
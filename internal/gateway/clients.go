@@ -0,0 +1,51 @@
+package gateway
+
+import (
+	"foodbuddy/internal/config"
+	"net/smtp"
+
+	"github.com/razorpay/razorpay-go"
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/checkout/session"
+)
+
+type razorpayClient struct {
+	client *razorpay.Client
+}
+
+func NewRazorpay(cfg config.Razorpay) Razorpay {
+	return &razorpayClient{client: razorpay.NewClient(cfg.KeyID, cfg.KeySecret)}
+}
+
+func (r *razorpayClient) CreateOrder(data map[string]interface{}) (map[string]interface{}, error) {
+	return r.client.Order.Create(data, nil)
+}
+
+type stripeClient struct {
+	sessions session.Client
+}
+
+func NewStripe(cfg config.Stripe) Stripe {
+	return &stripeClient{sessions: session.Client{B: stripe.GetBackend(stripe.APIBackend), Key: cfg.SecretKey}}
+}
+
+func (s *stripeClient) NewCheckoutSession(params *stripe.CheckoutSessionParams) (*stripe.CheckoutSession, error) {
+	return s.sessions.New(params)
+}
+
+func (s *stripeClient) GetCheckoutSession(id string) (*stripe.CheckoutSession, error) {
+	return s.sessions.Get(id, nil)
+}
+
+type smtpMailer struct {
+	cfg config.SMTP
+}
+
+func NewSMTP(cfg config.SMTP) Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+func (m *smtpMailer) Send(to []string, msg []byte) error {
+	auth := smtp.PlainAuth("", m.cfg.From, m.cfg.Password, m.cfg.Host)
+	return smtp.SendMail(m.cfg.Addr(), auth, m.cfg.From, to, msg)
+}
@@ -0,0 +1,65 @@
+// Package gateway wraps the external services the api calls, so tests and local setups
+// can swap them for fakes, see the gatewaytest package.
+package gateway
+
+import (
+	"foodbuddy/internal/config"
+	"foodbuddy/internal/storage"
+	"sync"
+
+	"github.com/stripe/stripe-go/v78"
+)
+
+type Razorpay interface {
+	// create an order, data follows the razorpay orders api and the created order is returned as is
+	CreateOrder(data map[string]interface{}) (map[string]interface{}, error)
+}
+
+type Stripe interface {
+	NewCheckoutSession(params *stripe.CheckoutSessionParams) (*stripe.CheckoutSession, error)
+	GetCheckoutSession(id string) (*stripe.CheckoutSession, error)
+}
+
+type Mailer interface {
+	// send a message with its headers already written to the recipients
+	Send(to []string, msg []byte) error
+}
+
+type Gateways struct {
+	Razorpay Razorpay
+	Stripe   Stripe
+	Mail     Mailer
+	// uploaded images and certificates, see the storage package
+	Images storage.Blob
+	// restaurant documents and ticket attachments, only served through the api
+	Documents storage.Private
+}
+
+// clients of the real services built from the configuration
+func New(cfg *config.Config) Gateways {
+	return Gateways{
+		Razorpay:  NewRazorpay(cfg.Razorpay),
+		Stripe:    NewStripe(cfg.Stripe),
+		Mail:      NewSMTP(cfg.SMTP),
+		Images:    storage.New(cfg.Storage, cfg.Cloudinary),
+		Documents: storage.NewPrivate(cfg.Storage, cfg.Cloudinary),
+	}
+}
+
+var (
+	mu      sync.RWMutex
+	current = New(&config.Config{})
+)
+
+// replace the gateways used by the handlers
+func Set(g Gateways) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = g
+}
+
+func Get() Gateways {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
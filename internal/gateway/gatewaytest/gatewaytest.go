@@ -0,0 +1,232 @@
+// Package gatewaytest provides in memory fakes of the external services for tests.
+package gatewaytest
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"foodbuddy/internal/gateway"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-go/v78"
+)
+
+// every fake, install them with gateway.Set(f.Gateways())
+type Fakes struct {
+	Razorpay *Razorpay
+	Stripe   *Stripe
+	Mail     *Mailer
+	Images   *Images
+	// private uploads, kept apart from the images
+	Documents *Images
+}
+
+func New() *Fakes {
+	return &Fakes{
+		Razorpay:  &Razorpay{},
+		Stripe:    &Stripe{sessions: map[string]*stripe.CheckoutSession{}},
+		Mail:      &Mailer{},
+		Images:    &Images{},
+		Documents: &Images{},
+	}
+}
+
+func (f *Fakes) Gateways() gateway.Gateways {
+	return gateway.Gateways{Razorpay: f.Razorpay, Stripe: f.Stripe, Mail: f.Mail, Images: f.Images, Documents: f.Documents}
+}
+
+// Razorpay records created orders, set Err to make the next calls fail
+type Razorpay struct {
+	mu     sync.Mutex
+	Orders []map[string]interface{}
+	Err    error
+}
+
+func (r *Razorpay) CreateOrder(data map[string]interface{}) (map[string]interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	order := map[string]interface{}{
+		"id":       fmt.Sprintf("order_fake%d", len(r.Orders)+1),
+		"entity":   "order",
+		"amount":   data["amount"],
+		"currency": data["currency"],
+		"receipt":  data["receipt"],
+		"status":   "created",
+	}
+	r.Orders = append(r.Orders, order)
+	return order, nil
+}
+
+// signature razorpay sends to the callback for a captured payment
+func RazorpaySignature(orderID string, paymentID string, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(orderID + "|" + paymentID))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Stripe keeps checkout sessions in memory, they stay unpaid until Pay is called
+type Stripe struct {
+	mu       sync.Mutex
+	sessions map[string]*stripe.CheckoutSession
+	Err      error
+}
+
+func (s *Stripe) NewCheckoutSession(params *stripe.CheckoutSessionParams) (*stripe.CheckoutSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	var amount int64
+	for _, item := range params.LineItems {
+		amount += *item.PriceData.UnitAmount * *item.Quantity
+	}
+	id := fmt.Sprintf("cs_test_%d", len(s.sessions)+1)
+	session := &stripe.CheckoutSession{
+		ID:             id,
+		URL:            "https://checkout.stripe.test/" + id,
+		AmountSubtotal: amount,
+		AmountTotal:    amount,
+		Currency:       stripe.CurrencyINR,
+		Metadata:       params.Metadata,
+		Mode:           stripe.CheckoutSessionModePayment,
+		PaymentStatus:  stripe.CheckoutSessionPaymentStatusUnpaid,
+		PaymentIntent:  &stripe.PaymentIntent{ID: "pi_" + id},
+		Created:        time.Now().Unix(),
+		ExpiresAt:      time.Now().Add(24 * time.Hour).Unix(),
+	}
+	s.sessions[id] = session
+	return session, nil
+}
+
+func (s *Stripe) GetCheckoutSession(id string) (*stripe.CheckoutSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, errors.New("no such checkout session: " + id)
+	}
+	copied := *session
+	return &copied, nil
+}
+
+// mark the session as paid, like a customer completing the checkout
+func (s *Stripe) Pay(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return errors.New("no such checkout session: " + id)
+	}
+	session.PaymentStatus = stripe.CheckoutSessionPaymentStatusPaid
+	session.Status = stripe.CheckoutSessionStatusComplete
+	return nil
+}
+
+type Mail struct {
+	To   []string
+	Body string
+}
+
+// Mailer keeps every sent message
+type Mailer struct {
+	mu   sync.Mutex
+	Sent []Mail
+	Err  error
+}
+
+func (m *Mailer) Send(to []string, msg []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Sent = append(m.Sent, Mail{To: to, Body: string(msg)})
+	return nil
+}
+
+// last message sent to the address
+func (m *Mailer) Last(to string) (Mail, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.Sent) - 1; i >= 0; i-- {
+		for _, address := range m.Sent[i].To {
+			if address == to {
+				return m.Sent[i], true
+			}
+		}
+	}
+	return Mail{}, false
+}
+
+// first submatch of pattern in the last message sent to the address, like an otp or a link
+func (m *Mailer) Find(to string, pattern *regexp.Regexp) (string, bool) {
+	mail, ok := m.Last(to)
+	if !ok {
+		return "", false
+	}
+	match := pattern.FindStringSubmatch(mail.Body)
+	if len(match) < 2 {
+		return "", false
+	}
+	return match[1], true
+}
+
+// Images keeps stored objects in memory by key and hands out fake urls
+type Images struct {
+	mu      sync.Mutex
+	Objects map[string][]byte
+	Err     error
+}
+
+func (i *Images) Put(ctx context.Context, key string, content io.Reader, contentType string) (string, error) {
+	if i.Err != nil {
+		return "", i.Err
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.Objects == nil {
+		i.Objects = map[string][]byte{}
+	}
+	i.Objects[key] = data
+	return ImageURL(key), nil
+}
+
+func (i *Images) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	data, ok := i.Objects[key]
+	if !ok {
+		return nil, fmt.Errorf("no object %v", key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (i *Images) Delete(ctx context.Context, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.Objects, key)
+	return nil
+}
+
+// url the fake hands out for key
+func ImageURL(key string) string {
+	return "https://images.test/foodbuddy/" + key
+}
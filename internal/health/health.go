@@ -0,0 +1,162 @@
+package health
+
+import (
+	"context"
+	"foodbuddy/internal/database"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// a worker is unhealthy when it has not finished a run in this many intervals
+const missedRuns = 3
+
+var (
+	draining atomic.Bool
+
+	mutex   sync.Mutex
+	workers = map[string]*worker{}
+	running sync.WaitGroup
+)
+
+type worker struct {
+	interval time.Duration
+	lastRun  time.Time
+	lastErr  error
+	stopped  bool
+}
+
+type WorkerStatus struct {
+	Name    string    `json:"name"`
+	Healthy bool      `json:"healthy"`
+	LastRun time.Time `json:"last_run"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// readiness fails from now on so the load balancer stops sending traffic before the server shuts down
+func SetDraining() {
+	draining.Store(true)
+}
+
+// run fn every interval until ctx is cancelled, the outcome of each run is reported by /readyz
+func RunWorker(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	mutex.Lock()
+	state := &worker{interval: interval, lastRun: time.Now()}
+	workers[name] = state
+	mutex.Unlock()
+
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				mutex.Lock()
+				state.stopped = true
+				mutex.Unlock()
+				return
+			case <-ticker.C:
+				err := fn(ctx)
+				mutex.Lock()
+				state.lastRun = time.Now()
+				state.lastErr = err
+				mutex.Unlock()
+			}
+		}
+	}()
+}
+
+// wait for the workers to return after their context is cancelled
+func WaitWorkers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func Workers() []WorkerStatus {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	status := make([]WorkerStatus, 0, len(workers))
+	for name, w := range workers {
+		s := WorkerStatus{
+			Name:    name,
+			Healthy: !w.stopped && w.lastErr == nil && time.Since(w.lastRun) < missedRuns*w.interval,
+			LastRun: w.lastRun,
+		}
+		if w.lastErr != nil {
+			s.Error = w.lastErr.Error()
+		}
+		status = append(status, s)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
+
+// liveness, the process is up and serving requests
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  true,
+		"message": "alive",
+	})
+}
+
+// readiness, the database answers, the schema is at the expected version and every worker is healthy
+func Readiness(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ready := !draining.Load()
+		checks := gin.H{"draining": draining.Load()}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		checks["database"] = "ok"
+		if sqlDB, err := db.DB(); err != nil {
+			ready = false
+			checks["database"] = err.Error()
+		} else if err := sqlDB.PingContext(ctx); err != nil {
+			ready = false
+			checks["database"] = err.Error()
+		}
+
+		checks["schema"] = "ok"
+		if err := database.CheckSchema(db.WithContext(ctx)); err != nil {
+			ready = false
+			checks["schema"] = err.Error()
+		}
+
+		workerStatus := Workers()
+		for _, w := range workerStatus {
+			if !w.Healthy {
+				ready = false
+			}
+		}
+		checks["workers"] = workerStatus
+
+		status := http.StatusOK
+		message := "ready"
+		if !ready {
+			status = http.StatusServiceUnavailable
+			message = "not ready"
+		}
+		c.JSON(status, gin.H{
+			"status":  ready,
+			"message": message,
+			"data":    checks,
+		})
+	}
+}
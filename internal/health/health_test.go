@@ -0,0 +1,166 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/repository/sqlite"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// status of the named worker once it finished a run after since
+func waitRun(t *testing.T, name string, since time.Time) WorkerStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, status := range Workers() {
+			if status.Name == name && status.LastRun.After(since) {
+				return status
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("worker %v never ran", name)
+	return WorkerStatus{}
+}
+
+func workerStatus(name string) (WorkerStatus, bool) {
+	for _, status := range Workers() {
+		if status.Name == name {
+			return status, true
+		}
+	}
+	return WorkerStatus{}, false
+}
+
+type readiness struct {
+	Status bool `json:"status"`
+	Data   struct {
+		Draining bool           `json:"draining"`
+		Database string         `json:"database"`
+		Schema   string         `json:"schema"`
+		Workers  []WorkerStatus `json:"workers"`
+	} `json:"data"`
+}
+
+func probe(t *testing.T, db *gorm.DB) (int, readiness) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", Readiness(db))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body readiness
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("readyz body %s: %v", w.Body, err)
+	}
+	return w.Code, body
+}
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", Liveness)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz = %d %s", w.Code, w.Body)
+	}
+}
+
+// the workers and the draining flag are process wide, they are reset once the test is done
+func reset(t *testing.T) {
+	t.Cleanup(func() {
+		mutex.Lock()
+		workers = map[string]*worker{}
+		mutex.Unlock()
+		draining.Store(false)
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	reset(t)
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//auto migrated tables don't record a schema version
+	if code, body := probe(t, db); code != http.StatusServiceUnavailable || body.Data.Database != "ok" || body.Data.Schema == "ok" {
+		t.Fatalf("unversioned schema = %d %+v", code, body)
+	}
+	latest, err := database.LatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&database.SchemaMigration{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&database.SchemaMigration{Version: latest, Name: "latest", AppliedAt: time.Now()})
+	if code, body := probe(t, db); code != http.StatusOK || !body.Status || body.Data.Schema != "ok" {
+		t.Fatalf("migrated database = %d %+v", code, body)
+	}
+
+	//a failing run makes the worker and the instance unhealthy until a run succeeds
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan error)
+	RunWorker(ctx, "test-flaky", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case err := <-results:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	status, ok := workerStatus("test-flaky")
+	if !ok || !status.Healthy {
+		t.Fatalf("registered worker = %+v", status)
+	}
+	results <- errors.New("upstream is down")
+	status = waitRun(t, "test-flaky", status.LastRun)
+	if status.Healthy || status.Error != "upstream is down" {
+		t.Fatalf("after a failed run = %+v", status)
+	}
+	if code, body := probe(t, db); code != http.StatusServiceUnavailable || body.Status {
+		t.Errorf("with a failing worker = %d %+v", code, body)
+	}
+	results <- nil
+	status = waitRun(t, "test-flaky", status.LastRun)
+	if !status.Healthy || status.Error != "" {
+		t.Fatalf("after a good run = %+v", status)
+	}
+	if code, _ := probe(t, db); code != http.StatusOK {
+		t.Errorf("with the worker recovered = %d", code)
+	}
+
+	//stopped workers are waited for and reported unhealthy
+	cancel()
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer waitCancel()
+	if err := WaitWorkers(waitCtx); err != nil {
+		t.Fatal(err)
+	}
+	if status, ok := workerStatus("test-flaky"); !ok || status.Healthy {
+		t.Errorf("stopped worker = %+v", status)
+	}
+
+	//a closed database fails the ping
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+	if code, body := probe(t, db); code != http.StatusServiceUnavailable || body.Data.Database == "ok" {
+		t.Errorf("closed database = %d %+v", code, body)
+	}
+
+	SetDraining()
+	if _, body := probe(t, db); !body.Data.Draining || body.Status {
+		t.Errorf("draining = %+v", body)
+	}
+}
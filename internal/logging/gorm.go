@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// queries slower than this are logged as warnings
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// gorm logger writing through slog with the request logger of the query context,
+// query parameters are never logged since they carry passwords and personal data
+type GormLogger struct {
+	level gormlogger.LogLevel
+}
+
+func NewGormLogger() *GormLogger {
+	return &GormLogger{level: gormlogger.Warn}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return &GormLogger{level: level}
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		FromContext(ctx).ErrorContext(ctx, "query failed", slog.String("sql", sql), slog.Int64("rows", rows),
+			slog.Duration("elapsed", elapsed), slog.String("error", err.Error()))
+	case elapsed > SlowQueryThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		FromContext(ctx).WarnContext(ctx, "slow query", slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed))
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		FromContext(ctx).DebugContext(ctx, "query", slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed))
+	}
+}
+
+// keep the placeholders in logged sql instead of the values
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
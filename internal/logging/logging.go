@@ -0,0 +1,173 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+const redacted = "[REDACTED]"
+
+// keys whose values never reach the logs, matched case-insensitively on the whole key or as a suffix
+var secretKeys = []string{
+	"password", "secret", "token", "authorization", "cookie", "signature", "otp", "dsn", "salt", "key",
+}
+
+// personal data, masked but kept recognisable for debugging
+var piiKeys = []string{"email", "phone", "phone_number", "phonenumber"}
+
+// configure the default slog logger, level is debug, info, warn or error and format json or text
+func Setup(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	options := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, use json or text", format)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// log and exit, for startup failures
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type contextKey struct{}
+
+// context carrying the request logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// logger of the request, the default logger outside of requests
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey || a.Key == slog.SourceKey {
+		return a
+	}
+
+	if isSecret(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	if isPII(a.Key) {
+		return slog.String(a.Key, mask(a.Value.Resolve().String()))
+	}
+
+	// structs and maps are walked so fields like hashed_password inside a model are redacted too
+	if a.Value.Kind() == slog.KindAny {
+		value := a.Value.Any()
+		if _, isErr := value.(error); isErr {
+			return a
+		}
+		kind := reflect.Indirect(reflect.ValueOf(value)).Kind()
+		if kind == reflect.Struct || kind == reflect.Map || kind == reflect.Slice {
+			return slog.Any(a.Key, Redact(value))
+		}
+	}
+	return a
+}
+
+// copy of the value with secret and personal fields redacted, as produced by its json encoding
+func Redact(value any) any {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return redacted
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return redacted
+	}
+	return redactDecoded(decoded)
+}
+
+func redactDecoded(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			switch {
+			case isSecret(key):
+				v[key] = redacted
+			case isPII(key):
+				v[key] = mask(fmt.Sprint(field))
+			default:
+				v[key] = redactDecoded(field)
+			}
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = redactDecoded(v[i])
+		}
+		return v
+	}
+	return value
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if key == secret || strings.HasSuffix(key, "_"+secret) {
+			return true
+		}
+		// struct fields without json tags, like HashedPassword
+		if secret != "key" && strings.HasSuffix(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPII(key string) bool {
+	key = strings.ToLower(key)
+	for _, pii := range piiKeys {
+		if key == pii || strings.HasSuffix(key, "_"+pii) {
+			return true
+		}
+	}
+	return false
+}
+
+// keep the first character and the email domain, j***@example.com
+func mask(value string) string {
+	if value == "" {
+		return value
+	}
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		return value[:1] + "***" + value[at:]
+	}
+	if len(value) > 4 {
+		return "***" + value[len(value)-2:]
+	}
+	return "***"
+}
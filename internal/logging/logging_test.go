@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// json logger writing to the returned buffer, the previous default comes back after the test
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	var buf bytes.Buffer
+	if _, err := Setup(&buf, "debug", FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSetup(t *testing.T) {
+	capture(t)
+	if _, err := Setup(&bytes.Buffer{}, "verbose", FormatJSON); err == nil {
+		t.Error("unknown level was accepted")
+	}
+	if _, err := Setup(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("unknown format was accepted")
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	buf := capture(t)
+	type user struct {
+		Name           string
+		Email          string `json:"email"`
+		HashedPassword string
+		Addresses      []map[string]string `json:"addresses"`
+	}
+	slog.Info("signup",
+		"password", "hunter2",
+		"razorpay_signature", "abc123",
+		"Authorization", "Bearer eyJ",
+		"email", "anu@example.com",
+		"phone_number", 9876543210,
+		"order_id", "order_42",
+		"user", user{
+			Name: "Anu", Email: "anu@example.com", HashedPassword: "$2a$10$hash",
+			Addresses: []map[string]string{{"city": "Kochi", "api_key": "k-1"}},
+		},
+		"error", errors.New("token expired"),
+	)
+
+	record := lines(t, buf)[0]
+	for key, want := range map[string]any{
+		"password":           redacted,
+		"razorpay_signature": redacted,
+		"Authorization":      redacted,
+		"email":              "a***@example.com",
+		"phone_number":       "***10",
+		"order_id":           "order_42",
+		"error":              "token expired",
+	} {
+		if record[key] != want {
+			t.Errorf("%v = %v, want %v", key, record[key], want)
+		}
+	}
+	logged := record["user"].(map[string]any)
+	if logged["Name"] != "Anu" || logged["email"] != "a***@example.com" || logged["HashedPassword"] != redacted {
+		t.Errorf("user = %v", logged)
+	}
+	if address := logged["addresses"].([]any)[0].(map[string]any); address["city"] != "Kochi" || address["api_key"] != redacted {
+		t.Errorf("address = %v", address)
+	}
+	for _, secret := range []string{"hunter2", "abc123", "eyJ", "$2a$10$hash", "k-1", "anu@"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("%q reached the log: %s", secret, buf)
+		}
+	}
+}
+
+func TestSecretKeys(t *testing.T) {
+	for key, secret := range map[string]bool{
+		"password": true, "new_password": true, "ConfirmPassword": true, "jwt_token": true, "salt": true,
+		"key": true, "stripe_key": true, "monkey": false, "keyword": false, "order_id": false, "tokens_left": false,
+	} {
+		if isSecret(key) != secret {
+			t.Errorf("isSecret(%q) = %v", key, !secret)
+		}
+	}
+	for value, want := range map[string]string{"": "", "abc": "***", "9876543210": "***10", "j@x.in": "j***@x.in"} {
+		if got := mask(value); got != want {
+			t.Errorf("mask(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	buf := capture(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/orders/:id", func(c *gin.Context) {
+		From(c).Info("handled", "email", "anu@example.com")
+		c.String(http.StatusNotFound, RequestID(c))
+	})
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/orders/7?token=secret", nil)
+		if id != "" {
+			r.Header.Set(RequestIDHeader, id)
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("lb-1234.abc")
+	if w.Header().Get(RequestIDHeader) != "lb-1234.abc" || w.Body.String() != "lb-1234.abc" {
+		t.Errorf("kept id = %q, body %q", w.Header().Get(RequestIDHeader), w.Body)
+	}
+	records := lines(t, buf)
+	handled, access := records[0], records[1]
+	if handled["request_id"] != "lb-1234.abc" || handled["email"] != "a***@example.com" {
+		t.Errorf("handler log = %v", handled)
+	}
+	//the route is logged without the path values or the query
+	if access["msg"] != "request" || access["route"] != "/orders/:id" || access["status"] != float64(404) || access["level"] != "WARN" || access["request_id"] != "lb-1234.abc" {
+		t.Errorf("access log = %v", access)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("the query reached the log: %s", buf)
+	}
+
+	//ids that aren't short and plain are replaced
+	for _, id := range []string{"", "has space", strings.Repeat("x", 65), "<script>"} {
+		if got := serve(id).Header().Get(RequestIDHeader); got == id || len(got) != 36 {
+			t.Errorf("request id %q was answered with %q", id, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	loggerKey       = "logger"
+	requestIDKey    = "request_id"
+)
+
+// ids sent by clients or proxies are kept when they are short and plain
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// tag every request with an id, attach a logger carrying it and write one access log line per request
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		logger := slog.Default().With(slog.String(requestIDKey, requestID))
+		c.Set(requestIDKey, requestID)
+		c.Set(loggerKey, logger)
+		c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", max(c.Writer.Size(), 0)),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// logger of the request, tagged with its request id
+func From(c *gin.Context) *slog.Logger {
+	if value, ok := c.Get(loggerKey); ok {
+		if logger, ok := value.(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "foodbuddy"
+
+// gateways and wallet owners used as label values
+const (
+	GatewayRazorpay = "razorpay"
+	GatewayStripe   = "stripe"
+	GatewayWallet   = "wallet"
+
+	OwnerUser       = "user"
+	OwnerRestaurant = "restaurant"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route template.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	ordersPlaced = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_placed_total",
+		Help:      "Orders placed by payment method.",
+	}, []string{"payment_method"})
+
+	payments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payments_total",
+		Help:      "Completed payment attempts by gateway and outcome.",
+	}, []string{"gateway", "outcome"})
+
+	gatewayDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "payment_gateway_request_duration_seconds",
+		Help:      "Latency of payment gateway API calls by gateway and operation.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10},
+	}, []string{"gateway", "operation"})
+
+	gatewayFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_gateway_failures_total",
+		Help:      "Failed payment gateway API calls by gateway and operation.",
+	}, []string{"gateway", "operation"})
+
+	walletTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wallet_transactions_total",
+		Help:      "Wallet transactions by owner, direction and reason.",
+	}, []string{"owner", "direction", "reason"})
+
+	walletAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "wallet_amount_total",
+		Help:      "Amount moved through wallets by owner and direction, in rupees.",
+	}, []string{"owner", "direction"})
+
+	supportSLABreaches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "support_sla_breaches_total",
+		Help:      "Support tickets that missed a due time, by sla.",
+	}, []string{"sla"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by the rate limiter by policy group.",
+	}, []string{"group"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		ordersPlaced, payments, gatewayDuration, gatewayFailures,
+		walletTransactions, walletAmount, rateLimited, supportSLABreaches,
+	)
+}
+
+// connection pool stats of the database, registered once the connection is open
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// count and time every request by its route template, raw paths would explode the label set
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// /metrics in the prometheus text format, a bearer token is required when one is configured
+func Handler(token string) gin.HandlerFunc {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		if token != "" {
+			given := c.GetHeader("Authorization")
+			if subtle.ConstantTimeCompare([]byte(given), []byte("Bearer "+token)) != 1 {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+func OrderPlaced(paymentMethod string) {
+	ordersPlaced.WithLabelValues(paymentMethod).Inc()
+}
+
+// outcome of a payment, success or failed
+func Payment(gateway string, success bool) {
+	outcome := "success"
+	if !success {
+		outcome = "failed"
+	}
+	payments.WithLabelValues(gateway, outcome).Inc()
+}
+
+// record the latency and outcome of a payment gateway call started at start
+func GatewayCall(gateway string, operation string, start time.Time, err error) {
+	gatewayDuration.WithLabelValues(gateway, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		gatewayFailures.WithLabelValues(gateway, operation).Inc()
+	}
+}
+
+// credit for incoming and debit for outgoing wallet history entries
+func WalletTransaction(owner string, credit bool, reason string, amount float64) {
+	direction := "debit"
+	if credit {
+		direction = "credit"
+	}
+	walletTransactions.WithLabelValues(owner, direction, reason).Inc()
+	walletAmount.WithLabelValues(owner, direction).Add(amount)
+}
+
+// sla is first_response or resolution
+func SupportSLABreached(sla string) {
+	supportSLABreaches.WithLabelValues(sla).Inc()
+}
+
+func RateLimited(group string) {
+	rateLimited.WithLabelValues(group).Inc()
+}
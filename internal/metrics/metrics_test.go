@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddlewareRouteLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/orders/:id", "204"))
+	for _, path := range []string{"/orders/1", "/orders/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	//requests are labelled with the route template, not the raw path
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/orders/:id", "204")) - before; got != 2 {
+		t.Errorf("templated route counted %v times, want 2", got)
+	}
+	if testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "unmatched", "404")) < 1 {
+		t.Error("unmatched requests were not counted")
+	}
+	if testutil.ToFloat64(httpInFlight) != 0 {
+		t.Error("in flight gauge wasn't decremented")
+	}
+}
+
+func TestRecorders(t *testing.T) {
+	Payment(GatewayStripe, false)
+	if testutil.ToFloat64(payments.WithLabelValues(GatewayStripe, "failed")) < 1 {
+		t.Error("failed payment not counted")
+	}
+	WalletTransaction(OwnerUser, true, "refund", 150)
+	WalletTransaction(OwnerUser, false, "order", 40)
+	if testutil.ToFloat64(walletAmount.WithLabelValues(OwnerUser, "credit")) < 150 || testutil.ToFloat64(walletTransactions.WithLabelValues(OwnerUser, "debit", "order")) < 1 {
+		t.Error("wallet transactions not counted by direction")
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/metrics", Handler("scrape-token"))
+
+	scrape := func(auth string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		router.ServeHTTP(w, r)
+		return w
+	}
+	for _, auth := range []string{"", "scrape-token", "Bearer wrong"} {
+		if w := scrape(auth); w.Code != http.StatusUnauthorized {
+			t.Errorf("%q = %d", auth, w.Code)
+		}
+	}
+	if w := scrape("Bearer scrape-token"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "foodbuddy_http_requests_in_flight") {
+		t.Errorf("authorized scrape = %d", w.Code)
+	}
+}
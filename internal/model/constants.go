@@ -2,11 +2,12 @@ package model
 
 const (
 	LocalHost                  = "localhost"
-	ProjectRoot                = "PROJECTROOT"
 	EmailLoginMethod           = "email"
 	GoogleSSOMethod            = "googlesso"
 	VerificationStatusVerified = "verified"
 	VerificationStatusPending  = "pending"
+	VerificationStatusInReview = "in_review"
+	VerificationStatusRejected = "rejected"
 	UserRole                   = "user"
 	AdminRole                  = "admin"
 	RestaurantRole             = "restaurant"
@@ -31,6 +32,8 @@ const (
 	WalletTxTypeOrderRefund    = "ORDERREFUND"
 	WalletTxTypeReferralReward = "REFERRALREWARD"
 	WalletTxTypeOrderPayment   = "ORDERPAYMENT"
+	WalletTxTypeAdjustment     = "ADJUSTMENT"
+	WalletTxTypeSupportRefund  = "SUPPORTREFUND"
 
 	OnlinePaymentPending   = "ONLINE_PENDING"
 	OnlinePaymentConfirmed = "ONLINE_CONFIRMED"
@@ -50,6 +53,58 @@ const (
 
 	CouponDiscountPercentageLimit = 50
 
+	CouponDiscountPercentage = "PERCENTAGE"
+	CouponDiscountFlat       = "FLAT"
+
+	CouponTargetProduct  = "PRODUCT"
+	CouponTargetCategory = "CATEGORY"
+
+	CampaignCodeLimit  = 10000
+	CampaignCodeLength = 8 //random characters after the prefix
+
+	TicketCategoryWrongItem   = "WRONG_ITEM"
+	TicketCategoryMissingItem = "MISSING_ITEM"
+	TicketCategoryDamaged     = "DAMAGED"
+	TicketCategoryQuality     = "QUALITY"
+	TicketCategoryLate        = "LATE_DELIVERY"
+	TicketCategoryOther       = "OTHER"
+
+	TicketStatusOpen     = "OPEN"
+	TicketStatusResolved = "RESOLVED"
+
+	TicketResolutionRefund   = "REFUND"
+	TicketResolutionNoRefund = "NO_REFUND"
+
+	ReviewStatusPublished = "PUBLISHED"
+	ReviewStatusHidden    = "HIDDEN"
+
+	ReviewActionHide       = "HIDE"
+	ReviewActionUnhide     = "UNHIDE"
+	ReviewActionClearFlags = "CLEAR_FLAGS"
+
+	BlockReasonFraud      = "FRAUD"
+	BlockReasonAbuse      = "ABUSE"
+	BlockReasonPolicy     = "POLICY_VIOLATION"
+	BlockReasonPayment    = "PAYMENT_DEFAULT"
+	BlockReasonFoodSafety = "FOOD_SAFETY"
+	BlockReasonOther      = "OTHER"
+
+	AppealStatusPending  = "PENDING"
+	AppealStatusAccepted = "ACCEPTED"
+	AppealStatusRejected = "REJECTED"
+
+	DocumentFSSAI = "FSSAI"
+	DocumentGST   = "GST"
+	DocumentBank  = "BANK"
+
+	DocumentStatusPending    = "PENDING"
+	DocumentStatusApproved   = "APPROVED"
+	DocumentStatusRejected   = "REJECTED"
+	DocumentStatusSuperseded = "SUPERSEDED"
+
+	ReviewWindowDays = 30
+	ReviewPhotoLimit = 5
+
 	ReferralClaimAmount = 30
 	ReferralClaimLimit  = 1
 )
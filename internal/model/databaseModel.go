@@ -1,23 +1,13 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-type EnvVariables struct {
-	ServerIP            string
-	ClientID            string
-	ClientSecret        string
-	DBUser              string
-	DBPassword          string
-	DBName              string
-	JWTSecret           string
-	CloudinaryCloudName string
-	CloudinaryAccessKey string
-	CloudinarySecretKey string
-}
+var ErrAuditAppendOnly = errors.New("audit log entries can't be changed")
 
 type Admin struct {
 	gorm.Model
@@ -83,6 +73,24 @@ type Product struct {
 	Veg             string  `validate:"required" json:"veg" gorm:"column:veg"`
 }
 
+// combo of component products sold together at a single bundle price
+type Bundle struct {
+	gorm.Model
+	ID           uint
+	RestaurantID uint         `gorm:"column:restaurant_id" json:"restaurant_id"`
+	Name         string       `json:"name"`
+	Description  string       `gorm:"column:description" json:"description"`
+	ImageURL     string       `gorm:"column:image_url" json:"image_url"`
+	BundlePrice  float64      `gorm:"column:bundle_price" json:"bundle_price"`
+	Items        []BundleItem `gorm:"foreignKey:BundleID" json:"items"`
+}
+
+type BundleItem struct {
+	BundleID  uint `gorm:"column:bundle_id" json:"bundle_id"`
+	ProductID uint `gorm:"column:product_id" json:"product_id"`
+	Quantity  uint `gorm:"column:quantity" json:"quantity"`
+}
+
 type Restaurant struct {
 	gorm.Model
 	ID                 uint
@@ -98,6 +106,27 @@ type Restaurant struct {
 	Blocked            bool
 	Salt               string
 	HashedPassword     string `gorm:"column:hashed_password"`
+	//aggregates of the published restaurant reviews, recomputed when one changes
+	AverageRating   float64 `gorm:"column:average_rating" json:"average_rating"`
+	RatingCount     uint    `gorm:"column:rating_count" json:"rating_count"`
+	FoodRating      float64 `gorm:"column:food_rating" json:"food_rating"`
+	PackagingRating float64 `gorm:"column:packaging_rating" json:"packaging_rating"`
+	DeliveryRating  float64 `gorm:"column:delivery_rating" json:"delivery_rating"`
+}
+
+// document a restaurant submits for onboarding, a resubmission supersedes the previous one of its type
+type RestaurantDocument struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RestaurantID uint       `gorm:"column:restaurant_id;index" json:"restaurant_id"`
+	Type         string     `gorm:"column:type;size:32" json:"type"`
+	Reference    string     `gorm:"column:reference" json:"reference"` //licence number, gstin or bank account number
+	IFSC         string     `gorm:"column:ifsc" json:"ifsc,omitempty"`
+	FileURL      string     `gorm:"column:file_url" json:"file_url"`
+	Status       string     `gorm:"column:status;size:32" json:"status"`
+	Comment      string     `gorm:"column:comment" json:"comment,omitempty"` //reason of the reviewer, required for rejections
+	ReviewedBy   string     `gorm:"column:reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
 }
 
 type FavouriteProduct struct {
@@ -120,6 +149,7 @@ type Address struct {
 type CartItems struct {
 	UserID         uint   `gorm:"column:user_id" validate:"required,number" json:"user_id"`
 	ProductID      uint   `validate:"required,number" json:"product_id"`
+	BundleID       uint   `gorm:"column:bundle_id" json:"bundle_id"` //set for bundle rows, product_id is 0 then
 	RestaurantID   uint   `gorm:"column:restaurant_id"  json:"restaurant_id"`
 	Quantity       uint   ` validate:"required,number" json:"quantity"`
 	CookingRequest string `json:"cooking_request"` // similar to zomato,, requesting restaurant to add or remove specific ingredients etc
@@ -149,10 +179,13 @@ type OrderItem struct {
 	Amount             float64 `validate:"required,number" json:"amount" csv:"Amount"`
 	ProductOfferAmount float64 `json:"product_offer_amount" csv:"ProductOfferAmount"`
 	AfterDeduction     float64 `gorm:"column:after_deduction" json:"after_deduction" csv:"AfterDeduction"`
+	RefundedAmount     float64 `gorm:"column:refunded_amount" json:"refunded_amount" csv:"-"` //by cancellation or support, never more than AfterDeduction
 	CookingRequest     string  `csv:"CookingRequest" json:"cooking_request"`
 	OrderStatus        string  `json:"order_status" gorm:"column:order_status" csv:"OrderStatus"`
 	OrderReview        string  `csv:"OrderReview" json:"order_review"`
 	OrderRating        float64 `csv:"OrderRating" json:"order_rating"`
+	//reviews and ratings are accepted for ReviewWindowDays after this
+	DeliveredAt *time.Time `gorm:"column:delivered_at" csv:"-" json:"delivered_at,omitempty"`
 }
 
 type Payment struct {
@@ -182,6 +215,47 @@ type CouponInventory struct {
 	Percentage    uint    `validate:"required" json:"percentage"`
 	MaximumUsage  uint    `validate:"required" json:"maximum_usage"`
 	MinimumAmount float64 `validate:"required" json:"minimum_amount"`
+	//restaurant whose menu the coupon is limited to, 0 for platform coupons
+	RestaurantID    uint           `gorm:"column:restaurant_id;index" json:"restaurant_id"`
+	DiscountType    string         `gorm:"column:discount_type;size:32" json:"discount_type"`
+	FlatAmount      float64        `gorm:"column:flat_amount" json:"flat_amount"`
+	MaxDiscount     float64        `gorm:"column:max_discount" json:"max_discount"`           //0 when the discount isn't capped
+	StartsAt        uint           `gorm:"column:starts_at" json:"starts_at"`                 //unix time like expiry
+	TotalUsageLimit uint           `gorm:"column:total_usage_limit" json:"total_usage_limit"` //redemptions across all users, 0 for no limit
+	RedeemedCount   uint           `gorm:"column:redeemed_count" json:"redeemed_count"`
+	FirstOrderOnly  bool           `gorm:"column:first_order_only" json:"first_order_only"`
+	NewUserDays     uint           `gorm:"column:new_user_days" json:"new_user_days"`             //only accounts younger than this, 0 for everyone
+	CampaignID      uint           `gorm:"column:campaign_id;index" json:"campaign_id,omitempty"` //campaign that generated the code, 0 otherwise
+	Targets         []CouponTarget `gorm:"foreignKey:CouponCode;references:CouponCode" json:"targets,omitempty"`
+}
+
+// product or category a coupon is restricted to, the discount only counts the matching items
+type CouponTarget struct {
+	CouponCode string `gorm:"column:coupon_code;primaryKey;size:191" json:"-"`
+	TargetType string `gorm:"column:target_type;primaryKey;size:32" json:"target_type"`
+	TargetID   uint   `gorm:"column:target_id;primaryKey;autoIncrement:false" json:"target_id"`
+}
+
+// batch of single-use codes generated with one rule set, the codes are coupon_inventories rows
+type CouponCampaign struct {
+	ID          uint      `gorm:"primaryKey" json:"campaign_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Name        string    `gorm:"size:128" json:"name"`
+	Description string    `json:"description"`
+	CodePrefix  string    `gorm:"size:32" json:"code_prefix"`
+	CodeCount   uint      `json:"code_count"`
+	CreatedBy   string    `json:"created_by"` //email of the admin
+}
+
+// coupon claimed by an order, CampaignID is 0 for coupons outside campaigns
+type CouponRedemption struct {
+	ID         uint      `gorm:"primaryKey" json:"redemption_id"`
+	CreatedAt  time.Time `json:"redeemed_at"`
+	CouponCode string    `gorm:"size:191;index" json:"coupon_code"`
+	CampaignID uint      `gorm:"index" json:"campaign_id"`
+	OrderID    string    `gorm:"size:191;index" json:"order_id"`
+	UserID     uint      `json:"user_id"`
+	Discount   float64   `json:"discount"`
 }
 
 type CouponUsage struct {
@@ -218,3 +292,160 @@ type DeliveryVerification struct {
 	OTP        uint   `gorm:"column:otp" json:"otp"`
 	LastSentAT uint   `gorm:"column:last_sent_at" json:"last_sent_at"`
 }
+
+// token bucket of the shared rate limiter store
+type RateLimitBucket struct {
+	Key      string    `gorm:"column:bucket_key;primaryKey;size:191" json:"bucket_key"`
+	Tokens   float64   `gorm:"column:tokens" json:"tokens"`
+	LastSeen time.Time `gorm:"column:last_seen;index" json:"last_seen"`
+}
+
+// support ticket a user raises about items of an order, the restaurant and the admins answer in its messages
+type SupportTicket struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	UserID             uint       `gorm:"column:user_id;index" json:"user_id"`
+	OrderID            string     `gorm:"column:order_id;size:64;index" json:"order_id"`
+	RestaurantID       uint       `gorm:"column:restaurant_id;index" json:"restaurant_id"`
+	Category           string     `gorm:"column:category;size:32" json:"category"`
+	Description        string     `gorm:"column:description;type:text" json:"description"`
+	Status             string     `gorm:"column:status;size:32;index" json:"status"`
+	FirstResponseDueAt time.Time  `gorm:"column:first_response_due_at" json:"first_response_due_at"`
+	ResolutionDueAt    time.Time  `gorm:"column:resolution_due_at" json:"resolution_due_at"`
+	FirstRespondedAt   *time.Time `gorm:"column:first_responded_at" json:"first_responded_at"`
+	//set by the worker once the due time passed, a ticket can breach both
+	FirstResponseBreached bool       `gorm:"column:first_response_breached;index" json:"first_response_breached"`
+	ResolutionBreached    bool       `gorm:"column:resolution_breached;index" json:"resolution_breached"`
+	Resolution            string     `gorm:"column:resolution;size:32" json:"resolution,omitempty"`
+	RefundAmount          float64    `gorm:"column:refund_amount" json:"refund_amount"`
+	ResolvedBy            string     `gorm:"column:resolved_by" json:"resolved_by,omitempty"`
+	ResolvedAt            *time.Time `gorm:"column:resolved_at" json:"resolved_at,omitempty"`
+}
+
+// order items a ticket is about
+type SupportTicketItem struct {
+	TicketID  uint `gorm:"column:ticket_id;primaryKey;autoIncrement:false" json:"ticket_id"`
+	ProductID uint `gorm:"column:product_id;primaryKey;autoIncrement:false" json:"product_id"`
+}
+
+type SupportMessage struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	TicketID      uint      `gorm:"column:ticket_id;index" json:"ticket_id"`
+	AuthorRole    string    `gorm:"column:author_role;size:32" json:"author_role"`
+	AuthorEmail   string    `gorm:"column:author_email" json:"author_email"`
+	Body          string    `gorm:"column:body;type:text" json:"body"`
+	AttachmentURL string    `gorm:"column:attachment_url;type:text" json:"attachment_url,omitempty"`
+}
+
+// photo attached to the review of an order item
+type ReviewPhoto struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	OrderID   string    `gorm:"column:order_id;size:64;index:idx_review_photos_item" json:"order_id"`
+	ProductID uint      `gorm:"column:product_id;index:idx_review_photos_item" json:"product_id"`
+	UserID    uint      `gorm:"column:user_id" json:"user_id"`
+	URL       string    `gorm:"column:url;type:text" json:"url"`
+	ThumbURL  string    `gorm:"column:thumb_url;type:text" json:"thumb_url"`
+}
+
+// review and rating of an order item before an edit or delete
+type ReviewRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	OrderID   string    `gorm:"column:order_id;size:64;index:idx_review_revisions_item" json:"order_id"`
+	ProductID uint      `gorm:"column:product_id;index:idx_review_revisions_item" json:"product_id"`
+	UserID    uint      `gorm:"column:user_id" json:"user_id"`
+	Review    string    `gorm:"column:review;type:text" json:"review"`
+	Rating    float64   `gorm:"column:rating" json:"rating"`
+}
+
+// review of a restaurant for one delivered order, rating is the mean of the three aspects
+type RestaurantReview struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	RestaurantID    uint       `gorm:"column:restaurant_id;index" json:"restaurant_id"`
+	UserID          uint       `gorm:"column:user_id;index" json:"user_id"`
+	OrderID         string     `gorm:"column:order_id;size:64;uniqueIndex" json:"order_id"`
+	Rating          float64    `gorm:"column:rating" json:"rating"`
+	FoodRating      uint       `gorm:"column:food_rating" json:"food_rating"`
+	PackagingRating uint       `gorm:"column:packaging_rating" json:"packaging_rating"`
+	DeliveryRating  uint       `gorm:"column:delivery_rating" json:"delivery_rating"`
+	Text            string     `gorm:"column:text;type:text" json:"text"`
+	Status          string     `gorm:"column:status;size:32;index" json:"status"`
+	Flagged         bool       `gorm:"column:flagged;index" json:"flagged"`
+	FlagCount       uint       `gorm:"column:flag_count" json:"flag_count"`
+	HelpfulCount    uint       `gorm:"column:helpful_count" json:"helpful_count"`
+	Reply           string     `gorm:"column:reply;type:text" json:"reply,omitempty"`
+	RepliedAt       *time.Time `gorm:"column:replied_at" json:"replied_at,omitempty"`
+	ModerationNote  string     `gorm:"column:moderation_note" json:"moderation_note,omitempty"`
+}
+
+// report of a review by a user or the reviewed restaurant, one per reporter
+type ReviewFlag struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	ReviewID     uint      `gorm:"column:review_id;uniqueIndex:idx_review_flags_reporter" json:"review_id"`
+	ReporterRole string    `gorm:"column:reporter_role;size:32;uniqueIndex:idx_review_flags_reporter" json:"reporter_role"`
+	ReporterID   uint      `gorm:"column:reporter_id;uniqueIndex:idx_review_flags_reporter" json:"reporter_id"`
+	Reason       string    `gorm:"column:reason" json:"reason"`
+}
+
+// a user marking a review as helpful
+type ReviewVote struct {
+	ReviewID uint `gorm:"column:review_id;primaryKey;autoIncrement:false" json:"review_id"`
+	UserID   uint `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"user_id"`
+}
+
+// block of a user or restaurant account, lifted by an admin, an accepted appeal or its expiry
+type AccountBlock struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	AccountRole     string     `gorm:"column:account_role;size:32;index:idx_account_blocks_account" json:"account_role"` //user or restaurant
+	AccountID       uint       `gorm:"column:account_id;index:idx_account_blocks_account" json:"account_id"`
+	Reason          string     `gorm:"column:reason;size:32" json:"reason"`
+	Note            string     `gorm:"column:note;type:text" json:"note"`
+	BlockedBy       string     `gorm:"column:blocked_by" json:"blocked_by"`
+	ExpiresAt       *time.Time `gorm:"column:expires_at;index" json:"expires_at"` //nil until lifted by an admin
+	LiftedAt        *time.Time `gorm:"column:lifted_at" json:"lifted_at"`
+	LiftedBy        string     `gorm:"column:lifted_by" json:"lifted_by,omitempty"`
+	AppealTokenHash string     `gorm:"column:appeal_token_hash;size:64;index" json:"-"` //sha256 of the token mailed with the notice
+}
+
+type BlockAppeal struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	BlockID     uint       `gorm:"column:block_id;index" json:"block_id"`
+	AccountRole string     `gorm:"column:account_role;size:32" json:"account_role"`
+	AccountID   uint       `gorm:"column:account_id" json:"account_id"`
+	Message     string     `gorm:"column:message;type:text" json:"message"`
+	Status      string     `gorm:"column:status;size:32;index" json:"status"`
+	Response    string     `gorm:"column:response;type:text" json:"response,omitempty"`
+	ReviewedBy  string     `gorm:"column:reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+}
+
+// append only record of a privileged change, changes holds the json of the changed fields
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id" csv:"ID"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at" csv:"CreatedAt"`
+	ActorEmail string    `gorm:"column:actor_email;size:191;index" json:"actor_email" csv:"ActorEmail"`
+	ActorRole  string    `gorm:"column:actor_role;size:32" json:"actor_role" csv:"ActorRole"`
+	Action     string    `gorm:"column:action;size:64;index" json:"action" csv:"Action"`
+	EntityType string    `gorm:"column:entity_type;size:64;index:idx_audit_logs_entity" json:"entity_type" csv:"EntityType"`
+	EntityID   string    `gorm:"column:entity_id;size:191;index:idx_audit_logs_entity" json:"entity_id" csv:"EntityID"`
+	Changes    string    `gorm:"column:changes;type:text" json:"changes" csv:"Changes"`
+	IP         string    `gorm:"column:ip;size:64" json:"ip" csv:"IP"`
+	RequestID  string    `gorm:"column:request_id;size:64" json:"request_id" csv:"RequestID"`
+}
+
+// entries are never changed once written
+func (AuditLog) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+func (AuditLog) BeforeDelete(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
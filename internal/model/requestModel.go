@@ -28,7 +28,7 @@ type RestaurantSignupRequest struct {
 	Password        string `gorm:"column:password" validate:"required" json:"password"`
 	PhoneNumber     uint   `gorm:"column:phone_number" validate:"required,number,min=1000000000,max=9999999999" json:"phone_number"`
 	ImageURL        string `gorm:"column:image_url" validate:"required,url" json:"image_url"`
-	CertificateURL  string `gorm:"column:certificate_url" validate:"required,url" json:"certificate_url"`
+	CertificateURL  string `gorm:"column:certificate_url" validate:"omitempty,url" json:"certificate_url"` //documents are uploaded during onboarding
 }
 
 type UpdateQuantityCart struct {
@@ -61,6 +61,8 @@ type PlaceOrder struct {
 	RestaurantID  uint   `validate:"required,number" gorm:"column:restaurant_id" json:"restaurant_id"`
 	PaymentMethod string `validate:"required" json:"payment_method"`
 	CouponCode    string `json:"coupon_code"`
+	//apply the coupon saving the most when no coupon_code is given
+	AutoApplyCoupon bool `json:"auto_apply_coupon"`
 }
 
 type InitiatePayment struct {
@@ -115,24 +117,44 @@ type Step2PasswordReset struct {
 	ConfirmPassword string `form:"password2" binding:"required" json:"password2"`
 }
 
+// a second review of the item replaces the first, which is kept in its history
 type UserReviewonOrderItem struct {
 	OrderID    string `validate:"required" json:"order_id"`
 	ProductID  uint   `validate:"required" json:"product_id"`
-	ReviewText string `validate:"required" json:"user_review"`
+	ReviewText string `validate:"required,max=2000" json:"user_review"`
 }
 
 type UserRatingOrderItem struct {
 	OrderID    string  `validate:"required" json:"order_id"`
 	ProductID  uint    `validate:"required" json:"product_id"`
-	UserRating float64 `validate:"required" json:"user_rating"`
+	UserRating float64 `validate:"required,min=1,max=5" json:"user_rating"`
 }
 
+// discount_type is PERCENTAGE when empty, product_ids and category_ids restrict the coupon to those items
 type CouponInventoryRequest struct {
-	CouponCode    string `validate:"required" json:"coupon_code"`
-	Expiry        uint   `validate:"required" json:"expiry"`
-	Percentage    uint   `validate:"required" json:"percentage"`
-	MaximumUsage  uint   `validate:"required" json:"maximum_usage"`
-	MinimumAmount uint   `validate:"required" json:"minimum_amount"`
+	CouponCode      string  `validate:"required" json:"coupon_code"`
+	Expiry          uint    `validate:"required" json:"expiry"`
+	StartsAt        uint    `json:"starts_at"`
+	DiscountType    string  `validate:"omitempty,oneof=PERCENTAGE FLAT" json:"discount_type"`
+	Percentage      uint    `validate:"required_unless=DiscountType FLAT" json:"percentage"`
+	FlatAmount      float64 `validate:"required_if=DiscountType FLAT,gte=0" json:"flat_amount"`
+	MaxDiscount     float64 `validate:"gte=0" json:"max_discount"`
+	MaximumUsage    uint    `validate:"required" json:"maximum_usage"`
+	TotalUsageLimit uint    `json:"total_usage_limit"`
+	MinimumAmount   uint    `validate:"required" json:"minimum_amount"`
+	FirstOrderOnly  bool    `json:"first_order_only"`
+	NewUserDays     uint    `validate:"max=365" json:"new_user_days"`
+	ProductIDs      []uint  `validate:"dive,required" json:"product_ids"`
+	CategoryIDs     []uint  `validate:"dive,required" json:"category_ids"`
+}
+
+// the rules every generated code gets, codes are always single use
+type CouponCampaignRequest struct {
+	Name        string `validate:"required,max=128" json:"name"`
+	Description string `validate:"max=1000" json:"description"`
+	CodePrefix  string `validate:"required,alphanum,max=12" json:"code_prefix"`
+	CodeCount   uint   `validate:"required,min=1,max=10000" json:"code_count"`
+	CouponInventoryRequest
 }
 
 type ApplyCouponOnOrderRequest struct {
@@ -231,3 +253,114 @@ type ConfirmDelivery struct {
 	OrderID     string `json:"order_id"`
 	DeliveryOTP uint   `json:"delivery_otp"`
 }
+
+type BundleItemRequest struct {
+	ProductID uint `validate:"required,number" json:"product_id"`
+	Quantity  uint `validate:"required,number" json:"quantity"`
+}
+
+type AddBundleRequest struct {
+	Name        string              `validate:"required" json:"name"`
+	Description string              `validate:"required" json:"description"`
+	ImageURL    string              `validate:"required" json:"image_url"`
+	BundlePrice float64             `validate:"required,number" json:"bundle_price"`
+	Items       []BundleItemRequest `validate:"required,min=2,dive" json:"items"`
+}
+
+type EditBundleRequest struct {
+	BundleID    uint                `validate:"required,number" json:"bundle_id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	ImageURL    string              `json:"image_url"`
+	BundlePrice float64             `json:"bundle_price"`
+	Items       []BundleItemRequest `validate:"omitempty,min=2,dive" json:"items"`
+}
+
+type AddBundleToCartReq struct {
+	BundleID uint `validate:"required,number" json:"bundle_id"`
+	Quantity uint `validate:"required,number" json:"quantity"`
+}
+
+type RemoveBundle struct {
+	BundleID uint `validate:"required,number" json:"bundle_id"`
+}
+
+type SearchRequest struct {
+	Query        string  `form:"q" json:"q"`
+	Veg          string  `form:"veg" validate:"omitempty,oneof=YES NO" json:"veg"`
+	CategoryID   uint    `form:"category_id" json:"category_id"`
+	RestaurantID uint    `form:"restaurant_id" json:"restaurant_id"`
+	MinPrice     float64 `form:"min_price" validate:"omitempty,min=0" json:"min_price"`
+	MaxPrice     float64 `form:"max_price" validate:"omitempty,min=0" json:"max_price"`
+	MinRating    float64 `form:"min_rating" validate:"omitempty,min=0,max=5" json:"min_rating"`
+	OffersOnly   bool    `form:"offers_only" json:"offers_only"`
+	Sort         string  `form:"sort" validate:"omitempty,oneof=relevance price_asc price_desc rating newest" json:"sort"`
+}
+
+// admin decision on one onboarding document
+type ReviewRestaurantDocument struct {
+	Status  string `validate:"required,oneof=APPROVED REJECTED" json:"status"`
+	Comment string `validate:"required_if=Status REJECTED" json:"comment"`
+}
+
+// reason is one of the BlockReason constants, the block lasts until lifted when duration_hours is 0
+type BlockAccountRequest struct {
+	Reason        string `validate:"required,oneof=FRAUD ABUSE POLICY_VIOLATION PAYMENT_DEFAULT FOOD_SAFETY OTHER" json:"reason"`
+	Note          string `validate:"required_if=Reason OTHER,max=1000" json:"note"`
+	DurationHours uint   `validate:"max=8760" json:"duration_hours"`
+}
+
+type BlockNoticeRequest struct {
+	Email string `validate:"required,email" json:"email"`
+	Role  string `validate:"required,oneof=user restaurant" json:"role"`
+}
+
+type BlockAppealRequest struct {
+	Token   string `validate:"required" json:"token"`
+	Message string `validate:"required,min=10,max=2000" json:"message"`
+}
+
+type ReviewBlockAppeal struct {
+	Status   string `validate:"required,oneof=ACCEPTED REJECTED" json:"status"`
+	Response string `validate:"required,max=2000" json:"response"`
+}
+
+type RaiseTicketRequest struct {
+	OrderID     string `validate:"required" json:"order_id"`
+	ProductIDs  []uint `validate:"required,min=1,dive,required" json:"product_ids"`
+	Category    string `validate:"required,oneof=WRONG_ITEM MISSING_ITEM DAMAGED QUALITY LATE_DELIVERY OTHER" json:"category"`
+	Description string `validate:"required,min=10,max=2000" json:"description"`
+}
+
+type TicketMessageRequest struct {
+	Message string `validate:"required,max=2000" json:"message"`
+}
+
+// refund_amount goes to the user wallet, at most what the ticket items paid less earlier refunds of the order
+type ResolveTicketRequest struct {
+	Resolution   string  `validate:"required,oneof=REFUND NO_REFUND" json:"resolution"`
+	RefundAmount float64 `validate:"required_if=Resolution REFUND,gte=0" json:"refund_amount"`
+	Note         string  `validate:"required,max=2000" json:"note"`
+}
+
+// each aspect is rated 1 to 5, the order should be delivered
+type RestaurantReviewRequest struct {
+	OrderID         string `validate:"required" json:"order_id"`
+	FoodRating      uint   `validate:"required,min=1,max=5" json:"food_rating"`
+	PackagingRating uint   `validate:"required,min=1,max=5" json:"packaging_rating"`
+	DeliveryRating  uint   `validate:"required,min=1,max=5" json:"delivery_rating"`
+	Text            string `validate:"max=2000" json:"text"`
+}
+
+type ReviewReplyRequest struct {
+	Reply string `validate:"required,max=1000" json:"reply"`
+}
+
+type ReviewFlagRequest struct {
+	Reason string `validate:"required,max=500" json:"reason"`
+}
+
+type ModerateReviewRequest struct {
+	Action string `validate:"required,oneof=HIDE UNHIDE CLEAR_FLAGS" json:"action"`
+	Note   string `validate:"required_if=Action HIDE,max=1000" json:"note"`
+}
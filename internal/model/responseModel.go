@@ -13,16 +13,6 @@ type GoogleResponse struct {
 	Locale        string `json:"locale"`
 }
 
-type SuccessResponse struct {
-	Status  bool   `json:"status"`
-	Message string `json:"message"`
-}
-
-type ErrorResponse struct {
-	Status  bool   `json:"status" example:"false"`
-	Message string `json:"message" example:"Error message"`
-}
-
 type ProductSales struct {
 	TotalAmount uint    `json:"total_product_sales_amount"`
 	TotalOrders uint    `json:"total_orders"`
@@ -110,3 +100,76 @@ type BlockedUserResponse struct {
 	LoginMethod  string  `json:"login_method"`
 	Blocked      bool    `json:"blocked"`
 }
+
+// block shown to the holder of the notice token, the admins who blocked, lifted or reviewed are left out
+type BlockStatusResponse struct {
+	Reason    string              `json:"reason"`
+	Note      string              `json:"note"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+	Active    bool                `json:"active"`
+	Appeals   []BlockAppealStatus `json:"appeals"`
+}
+
+type BlockAppealStatus struct {
+	Status   string `json:"status"`
+	Response string `json:"response,omitempty"`
+}
+
+type SearchProductResult struct {
+	ID             uint    `json:"product_id"`
+	RestaurantID   uint    `json:"restaurant_id"`
+	RestaurantName string  `json:"restaurant_name"`
+	CategoryID     uint    `json:"category_id"`
+	CategoryName   string  `json:"category_name"`
+	Name           string  `json:"product_name"`
+	Description    string  `json:"description"`
+	ImageURL       string  `json:"image_url"`
+	Price          float64 `json:"price"`
+	OfferAmount    float64 `json:"offer_amount"`
+	SellingPrice   float64 `json:"selling_price"`
+	StockLeft      uint    `json:"stock_left"`
+	AverageRating  float64 `json:"average_rating"`
+	Veg            string  `json:"veg"`
+	Relevance      float64 `json:"relevance"`
+}
+
+type SearchRestaurantResult struct {
+	ID          uint   `json:"restaurant_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+}
+
+type SearchFacet struct {
+	ID    uint   `json:"id,omitempty"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type CouponRecommendation struct {
+	CouponCode   string  `json:"coupon_code"`
+	RestaurantID uint    `json:"restaurant_id"`
+	DiscountType string  `json:"discount_type"`
+	Expiry       uint    `json:"expiry"`
+	Discount     float64 `json:"discount"`
+	FinalAmount  float64 `json:"final_amount"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// code of a campaign as exported to csv
+type CampaignCode struct {
+	CouponCode    string `csv:"CouponCode" json:"coupon_code"`
+	StartsAt      uint   `csv:"StartsAt" json:"starts_at"`
+	Expiry        uint   `csv:"Expiry" json:"expiry"`
+	RedeemedCount uint   `csv:"RedeemedCount" json:"redeemed_count"`
+}
+
+type CampaignReport struct {
+	Codes              int64   `json:"codes"`
+	RedeemedCodes      int64   `json:"redeemed_codes"`
+	Redemptions        int64   `json:"redemptions"`
+	Orders             int64   `json:"orders"`               //redemptions whose order got paid
+	Revenue            float64 `json:"revenue"`              //final amount of those orders
+	Discount           float64 `json:"discount"`             //coupon discount on those orders
+	RevenuePerDiscount float64 `json:"revenue_per_discount"` //0 when nothing was discounted
+}
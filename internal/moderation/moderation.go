@@ -0,0 +1,38 @@
+// Package moderation checks user written text before it is published.
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// stems match any word they start, so fucking and shitty are caught too
+var stems = []string{"fuck", "shit", "bitch", "bastard", "asshole", "cunt", "wanker", "motherf"}
+
+// words only match on their own, ass or dick would flag class or dickens as a stem
+var words = []string{"ass", "dick", "crap", "piss", "slut", "whore", "bollocks"}
+
+var profanity = buildPattern()
+
+func buildPattern() *regexp.Regexp {
+	var alternatives []string
+	for _, stem := range stems {
+		alternatives = append(alternatives, regexp.QuoteMeta(stem)+`\w*`)
+	}
+	for _, word := range words {
+		alternatives = append(alternatives, regexp.QuoteMeta(word)+`e?s?`)
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(alternatives, "|") + `)\b`)
+}
+
+// Clean masks every profane word but its first letter and tells whether any was found
+func Clean(text string) (string, bool) {
+	found := false
+	cleaned := profanity.ReplaceAllStringFunc(text, func(word string) string {
+		found = true
+		_, size := utf8.DecodeRuneInString(word)
+		return word[:size] + strings.Repeat("*", utf8.RuneCountInString(word)-1)
+	})
+	return cleaned, found
+}
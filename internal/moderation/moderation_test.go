@@ -0,0 +1,24 @@
+package moderation
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	cases := []struct {
+		text  string
+		want  string
+		found bool
+	}{
+		{"Great biryani, will order again", "Great biryani, will order again", false},
+		{"the class was a classic", "the class was a classic", false},
+		{"Shitty packaging", "S***** packaging", true},
+		{"what the FUCK, cold food", "what the F***, cold food", true},
+		{"rider was an ass.", "rider was an a**.", true},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		got, found := Clean(tc.text)
+		if got != tc.want || found != tc.found {
+			t.Errorf("Clean(%q) = %q, %v; want %q, %v", tc.text, got, found, tc.want, tc.found)
+		}
+	}
+}
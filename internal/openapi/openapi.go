@@ -0,0 +1,262 @@
+package openapi
+
+import (
+	"foodbuddy/internal/response"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents one route of the router, the spec only lists routes that have an operation
+type Operation struct {
+	Method  string
+	Path    string // gin path, :name segments become path parameters
+	Summary string
+	Tag     string
+	// role whose jwt cookie is required, empty for public routes
+	Auth  string
+	Query []Param
+	// struct whose form tags are the query parameters
+	QueryStruct any
+	// limit, cursor and fields of the pagination package
+	Paginated bool
+	// json request body
+	Body any
+	// multipart form fields
+	Form []Param
+	// payload of the data field of a successful response
+	Data any
+	// content type of responses that are not json, like pdf invoices or html pages
+	Produces string
+}
+
+type Param struct {
+	Name string
+	// string, integer, number, boolean or file, string when empty
+	Type        string
+	Required    bool
+	Description string
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	OperationID string                `json:"operationId"`
+	Description string                `json:"description,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]respObject `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type respObject struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+const cookieAuth = "cookieAuth"
+
+// route key used to match operations with the router, like "GET /api/v1/user/profile"
+func Key(method string, path string) string {
+	return method + " " + path
+}
+
+// Build documents the registered routes with their operations.
+// Routes without an operation and operations without a route are returned so tests can fail on drift.
+func Build(info Info, routes gin.RoutesInfo, operations []Operation) (*Document, []string, []string) {
+	byKey := make(map[string]Operation, len(operations))
+	for _, op := range operations {
+		byKey[Key(op.Method, op.Path)] = op
+	}
+
+	gen := newGenerator()
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]map[string]*operation{},
+		Components: components{
+			Schemas: gen.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				cookieAuth: {Type: "apiKey", In: "cookie", Name: "Authorization", Description: "jwt set by the login endpoints"},
+			},
+		},
+	}
+	envelope := gen.schema(reflect.TypeOf(response.Body{}))
+
+	var undocumented []string
+	registered := map[string]bool{}
+	for _, route := range routes {
+		key := Key(route.Method, route.Path)
+		registered[key] = true
+		op, ok := byKey[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			continue
+		}
+		path, pathParams := convertPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*operation{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = gen.operation(op, pathParams, envelope)
+	}
+
+	var unregistered []string
+	for key := range byKey {
+		if !registered[key] {
+			unregistered = append(unregistered, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unregistered)
+	return doc, undocumented, unregistered
+}
+
+// gin :name segments to openapi {name} templates
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func (g *generator) operation(op Operation, pathParams []string, envelope *Schema) *operation {
+	out := &operation{
+		Summary:     op.Summary,
+		OperationID: operationID(op.Method, op.Path),
+		Responses:   map[string]respObject{},
+	}
+	if op.Tag != "" {
+		out.Tags = []string{op.Tag}
+	}
+	if op.Auth != "" {
+		out.Security = []map[string][]string{{cookieAuth: {}}}
+		out.Description = "requires the jwt cookie of a " + op.Auth
+	}
+
+	for _, name := range pathParams {
+		out.Parameters = append(out.Parameters, parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, p := range op.Query {
+		out.Parameters = append(out.Parameters, parameter{Name: p.Name, In: "query", Required: p.Required, Description: p.Description, Schema: paramSchema(p.Type)})
+	}
+	if op.QueryStruct != nil {
+		out.Parameters = append(out.Parameters, g.queryParams(reflect.TypeOf(op.QueryStruct))...)
+	}
+	if op.Paginated {
+		out.Parameters = append(out.Parameters,
+			parameter{Name: "limit", In: "query", Description: "page size, 20 by default and at most 100", Schema: &Schema{Type: "integer"}},
+			parameter{Name: "cursor", In: "query", Description: "next_cursor of the previous page", Schema: &Schema{Type: "string"}},
+			parameter{Name: "fields", In: "query", Description: "comma separated fields to return", Schema: &Schema{Type: "string"}},
+		)
+	}
+
+	if op.Body != nil {
+		out.RequestBody = &requestBody{Required: true, Content: map[string]mediaType{
+			"application/json": {Schema: g.schema(reflect.TypeOf(op.Body))},
+		}}
+	}
+	if len(op.Form) > 0 {
+		form := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		for _, p := range op.Form {
+			form.Properties[p.Name] = paramSchema(p.Type)
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+			}
+		}
+		out.RequestBody = &requestBody{Required: true, Content: map[string]mediaType{"multipart/form-data": {Schema: form}}}
+	}
+
+	switch {
+	case op.Produces != "":
+		out.Responses["200"] = respObject{Description: "success", Content: map[string]mediaType{op.Produces: {Schema: &Schema{Type: "string", Format: "binary"}}}}
+	case op.Data != nil:
+		success := &Schema{AllOf: []*Schema{envelope, {
+			Type:       "object",
+			Properties: map[string]*Schema{"data": g.schema(reflect.TypeOf(op.Data))},
+		}}}
+		out.Responses["200"] = respObject{Description: "success", Content: map[string]mediaType{"application/json": {Schema: success}}}
+	default:
+		out.Responses["200"] = respObject{Description: "success", Content: map[string]mediaType{"application/json": {Schema: envelope}}}
+	}
+	out.Responses["default"] = respObject{Description: "error with a machine readable error.code", Content: map[string]mediaType{"application/json": {Schema: envelope}}}
+	return out
+}
+
+func paramSchema(kind string) *Schema {
+	switch kind {
+	case "", "string":
+		return &Schema{Type: "string"}
+	case "file":
+		return &Schema{Type: "string", Format: "binary"}
+	default:
+		return &Schema{Type: kind}
+	}
+}
+
+// unique id from the method and path, like get_api_v1_user_profile
+func operationID(method string, path string) string {
+	return strings.ToLower(method) + strings.NewReplacer("/", "_", ":", "", "*", "").Replace(strings.TrimSuffix(path, "/"))
+}
+
+// serve the document as json, built on the first request once every route is registered
+func Handler(build func() *Document) gin.HandlerFunc {
+	var (
+		once sync.Once
+		doc  *Document
+	)
+	return func(c *gin.Context) {
+		once.Do(func() { doc = build() })
+		c.JSON(http.StatusOK, doc)
+	}
+}
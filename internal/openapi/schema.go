@@ -0,0 +1,176 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+// builds schemas from go types following encoding/json, named structs become shared components
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: map[string]*Schema{}}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			//reserve the name first so recursive types end in a reference
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		//interfaces and anything else accept any json value
+		return &Schema{}
+	}
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	out := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(t, out)
+	if len(out.Properties) == 0 {
+		out.Properties = nil
+	}
+	return out
+}
+
+// exported fields by their json name, embedded structs without a json name are flattened
+func (g *generator) fields(t reflect.Type, out *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.fields(embedded, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		schema := g.schema(field.Type)
+		applyValidate(schema, field.Tag.Get("validate"))
+		out.Properties[name] = schema
+		if isRequired(field.Tag.Get("validate")) {
+			out.Required = append(out.Required, name)
+		}
+	}
+}
+
+// query parameters from the form tags of a struct bound with ShouldBindQuery
+func (g *generator) queryParams(t reflect.Type) []parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var params []parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.SplitN(field.Tag.Get("form"), ",", 2)[0]
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		schema := g.schema(field.Type)
+		applyValidate(schema, field.Tag.Get("validate"))
+		params = append(params, parameter{Name: name, In: "query", Required: isRequired(field.Tag.Get("validate")), Schema: schema})
+	}
+	return params
+}
+
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name := strings.SplitN(tag, ",", 2)[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, false
+}
+
+func isRequired(validate string) bool {
+	for _, rule := range strings.Split(validate, ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// enums and formats the validator enforces
+func applyValidate(schema *Schema, validate string) {
+	if schema.Ref != "" {
+		return
+	}
+	for _, rule := range strings.Split(validate, ",") {
+		switch {
+		case rule == "email":
+			schema.Format = "email"
+		case strings.HasPrefix(rule, "oneof="):
+			schema.Enum = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+		}
+	}
+}
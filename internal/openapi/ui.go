@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+const initializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: %s,
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// swagger ui from the embedded dist, register it on a path ending in /*file
+func UI(specURL string) gin.HandlerFunc {
+	script := fmt.Sprintf(initializer, strconv.Quote(specURL))
+	files := http.FileServer(http.FS(swaggerFiles.FS))
+	return func(c *gin.Context) {
+		file := c.Param("file")
+		switch file {
+		case "/swagger-initializer.js":
+			//the bundled initializer points at the petstore example
+			c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(script))
+			return
+		case "/index.html":
+			//the file server redirects index.html to the directory
+			file = "/"
+		}
+		c.Request.URL.Path = file
+		files.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,137 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// position of a page, tables with an id page by the last seen id, the rest by offset
+type Cursor struct {
+	After  uint `json:"a,omitempty"`
+	Offset int  `json:"o,omitempty"`
+}
+
+type Params struct {
+	Limit  int
+	Cursor Cursor
+	Fields []string
+}
+
+func Encode(cursor Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func Decode(value string) (Cursor, error) {
+	var cursor Cursor
+	if value == "" {
+		return cursor, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Offset < 0 {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// read limit, cursor and fields from the query params
+func FromContext(c *gin.Context) (Params, error) {
+	params := Params{Limit: DefaultLimit}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return params, errors.New("limit should be a positive number")
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		params.Limit = limit
+	}
+
+	cursor, err := Decode(c.Query("cursor"))
+	if err != nil {
+		return params, err
+	}
+	params.Cursor = cursor
+
+	if value := c.Query("fields"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				params.Fields = append(params.Fields, field)
+			}
+		}
+	}
+	return params, nil
+}
+
+// keyset page ordered by the given id column, one extra row is fetched to find the next page
+func (p Params) Keyset(tx *gorm.DB, column string) *gorm.DB {
+	if p.Cursor.After != 0 {
+		tx = tx.Where(column+" > ?", p.Cursor.After)
+	}
+	return tx.Order(column + " ASC").Limit(p.Limit + 1)
+}
+
+// offset page for tables without an id, order should be stable across pages
+func (p Params) Offset(tx *gorm.DB, order string) *gorm.DB {
+	return tx.Order(order).Offset(p.Cursor.Offset).Limit(p.Limit + 1)
+}
+
+// trim the extra row and return the cursor of the next page, empty on the last page
+// key returns the id of a row for keyset pages and should be nil for offset pages
+func Trim[T any](p Params, rows []T, key func(T) uint) ([]T, string) {
+	if len(rows) <= p.Limit {
+		return rows, ""
+	}
+	rows = rows[:p.Limit]
+	if key != nil {
+		return rows, Encode(Cursor{After: key(rows[len(rows)-1])})
+	}
+	return rows, Encode(Cursor{Offset: p.Cursor.Offset + p.Limit})
+}
+
+// keep only the requested json fields of each row, rows are returned as they are without fields
+func (p Params) Project(rows interface{}) (interface{}, error) {
+	if len(p.Fields) == 0 {
+		return rows, nil
+	}
+
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, errors.New("fields can only be selected on lists")
+	}
+
+	projected := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		selected := make(map[string]json.RawMessage, len(p.Fields))
+		for _, field := range p.Fields {
+			value, ok := item[field]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", field)
+			}
+			selected[field] = value
+		}
+		projected = append(projected, selected)
+	}
+	return projected, nil
+}
@@ -0,0 +1,119 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	for _, cursor := range []Cursor{{}, {After: 42}, {Offset: 60}} {
+		got, err := Decode(Encode(cursor))
+		if err != nil || got != cursor {
+			t.Errorf("decode(encode(%+v)) = %+v, %v", cursor, got, err)
+		}
+	}
+	// the first page has no cursor
+	if got, err := Decode(""); err != nil || got != (Cursor{}) {
+		t.Errorf("empty cursor = %+v, %v", got, err)
+	}
+}
+
+func TestTamperedCursor(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	for name, value := range map[string]string{
+		"not base64":      "not a cursor!",
+		"padded base64":   base64.URLEncoding.EncodeToString([]byte(`{"a":1}`)),
+		"not json":        encode("after=5"),
+		"string id":       encode(`{"a":"5"}`),
+		"negative id":     encode(`{"a":-5}`),
+		"negative offset": encode(`{"o":-20}`),
+	} {
+		if _, err := Decode(value); err == nil {
+			t.Errorf("%v cursor %q was accepted", name, value)
+		}
+	}
+}
+
+func params(t *testing.T, query string) (Params, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+	return FromContext(c)
+}
+
+func TestFromContext(t *testing.T) {
+	got, err := params(t, "limit=5&cursor="+Encode(Cursor{After: 9})+"&fields=id,%20name,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Params{Limit: 5, Cursor: Cursor{After: 9}, Fields: []string{"id", "name"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("params = %+v, want %+v", got, want)
+	}
+
+	if got, _ := params(t, ""); got.Limit != DefaultLimit {
+		t.Errorf("default limit = %v", got.Limit)
+	}
+	if got, _ := params(t, "limit=1000"); got.Limit != MaxLimit {
+		t.Errorf("large limit = %v, want it capped at %v", got.Limit, MaxLimit)
+	}
+	for _, query := range []string{"limit=0", "limit=-1", "limit=ten", "cursor=%25%25"} {
+		if _, err := params(t, query); err == nil {
+			t.Errorf("%v was accepted", query)
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+	id := func(n int) uint { return uint(n) }
+
+	rows, next := Trim(Params{Limit: 3}, []int{1, 2, 3}, id)
+	if len(rows) != 3 || next != "" {
+		t.Errorf("last page = %v with cursor %q", rows, next)
+	}
+
+	rows, next = Trim(Params{Limit: 3}, []int{4, 5, 6, 7}, id)
+	if cursor, _ := Decode(next); len(rows) != 3 || cursor != (Cursor{After: 6}) {
+		t.Errorf("keyset page = %v with cursor %+v, want 3 rows after 6", rows, cursor)
+	}
+
+	rows, next = Trim(Params{Limit: 2, Cursor: Cursor{Offset: 4}}, []int{5, 6, 7}, nil)
+	if cursor, _ := Decode(next); len(rows) != 2 || cursor != (Cursor{Offset: 6}) {
+		t.Errorf("offset page = %v with cursor %+v, want 2 rows and offset 6", rows, cursor)
+	}
+}
+
+func TestProject(t *testing.T) {
+	type row struct {
+		ID    uint   `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	rows := []row{{1, "Anu", "anu@example.com"}, {2, "Rahul", "rahul@example.com"}}
+
+	if got, err := (Params{}).Project(rows); err != nil || !reflect.DeepEqual(got, rows) {
+		t.Errorf("without fields = %v, %v", got, err)
+	}
+
+	got, err := Params{Fields: []string{"id", "name"}}.Project(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	projected := got.([]map[string]json.RawMessage)
+	if len(projected) != 2 || len(projected[0]) != 2 || string(projected[1]["name"]) != `"Rahul"` {
+		t.Errorf("projected = %s", projected)
+	}
+
+	if _, err := (Params{Fields: []string{"password"}}).Project(rows); err == nil {
+		t.Error("unknown field was accepted")
+	}
+	if _, err := (Params{Fields: []string{"id"}}).Project(row{}); err == nil {
+		t.Error("fields were selected on a single object")
+	}
+}
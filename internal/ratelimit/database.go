@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"foodbuddy/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// store backed by the rate_limit_buckets table, every replica sharing the database shares the limits
+type DatabaseStore struct {
+	db *gorm.DB
+}
+
+func NewDatabaseStore(db *gorm.DB) *DatabaseStore {
+	return &DatabaseStore{db: db}
+}
+
+func (s *DatabaseStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	var result Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		//lock the row so concurrent requests on other replicas wait for this refill
+		var bucket model.RateLimitBucket
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("bucket_key = ?", key).Take(&bucket).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			bucket = model.RateLimitBucket{Key: key, Tokens: float64(policy.Burst), LastSeen: now}
+		} else if err != nil {
+			return err
+		}
+
+		bucket.Tokens, result = take(bucket.Tokens, bucket.LastSeen, policy, now)
+		bucket.LastSeen = now
+
+		//the first request of two replicas may both miss the row, the upsert keeps either refill
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&bucket).Error
+	})
+	return result, err
+}
+
+func (s *DatabaseStore) Sweep(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("last_seen < ?", before).Delete(&model.RateLimitBucket{}).Error
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// per process store, limits are not shared between replicas
+type MemoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), last: now}
+		m.buckets[key] = b
+	}
+	tokens, result := take(b.tokens, b.last, policy, now)
+	b.tokens = tokens
+	b.last = now
+	return result, nil
+}
+
+func (m *MemoryStore) Sweep(ctx context.Context, before time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for key, b := range m.buckets {
+		if b.last.Before(before) {
+			delete(m.buckets, key)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.buckets)
+}
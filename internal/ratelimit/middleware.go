@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/metrics"
+	"foodbuddy/internal/response"
+	"foodbuddy/internal/utils"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limit the requests of every client under the policy, signed in clients are keyed by their
+// account so they keep their budget across networks, everyone else by ip
+func Middleware(policy Policy) gin.HandlerFunc {
+	limit := strconv.Itoa(policy.Burst)
+	policyHeader := limit + ";w=" + strconv.Itoa(int(math.Ceil(policy.Window().Seconds())))
+
+	return func(c *gin.Context) {
+		store := getStore()
+		if store == nil {
+			c.Next()
+			return
+		}
+
+		result, err := store.Take(c.Request.Context(), key(c, policy), policy, time.Now())
+		if err != nil {
+			//a broken store must not take the api down with it
+			logging.From(c).Warn("rate limiter unavailable", "policy", policy.Name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policyHeader)
+		c.Header("RateLimit-Limit", limit)
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", seconds(result.Reset))
+
+		if !result.Allowed {
+			metrics.RateLimited(policy.Name)
+			c.Header("Retry-After", seconds(result.RetryAfter))
+			response.Error(c, response.CodeRateLimited, "rate limit exceeded, retry after "+seconds(result.RetryAfter)+" seconds")
+			return
+		}
+		c.Next()
+	}
+}
+
+// bucket key of the client, hashed so stores never hold emails or addresses
+func key(c *gin.Context, policy Policy) string {
+	identity := "ip:" + c.ClientIP()
+	if email, role, err := utils.GetJWTClaim(c); err == nil {
+		identity = role + ":" + email
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return policy.Name + ":" + hex.EncodeToString(sum[:16])
+}
+
+// whole seconds rounded up, clients waiting the advertised time must find a token
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// token bucket policy, a client may burst up to Burst requests and regains Rate tokens per second
+type Policy struct {
+	Name  string
+	Rate  float64
+	Burst int
+}
+
+// policies of the route groups
+var (
+	// login, signup, password reset and otp endpoints
+	Auth = Policy{Name: "auth", Rate: 5.0 / 60, Burst: 5}
+	// cached public catalogue
+	Public = Policy{Name: "public", Rate: 10, Burst: 40}
+	// authenticated user, restaurant and admin apis
+	Default = Policy{Name: "default", Rate: 5, Burst: 20}
+)
+
+// seconds needed to refill an empty bucket, advertised as the policy window
+func (p Policy) Window() time.Duration {
+	return time.Duration(float64(p.Burst) / p.Rate * float64(time.Second))
+}
+
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// time until the bucket is full again
+	Reset time.Duration
+	// time until the next token, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets, the memory store is per process and the database store
+// is shared by every replica using the same database
+type Store interface {
+	// take one token from the bucket of key
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+	// drop buckets untouched since before, they would be full by now
+	Sweep(ctx context.Context, before time.Time) error
+}
+
+// buckets idle for longer than this are full again under every policy and can be dropped
+const idleAfter = 10 * time.Minute
+
+var (
+	mu      sync.RWMutex
+	current Store = NewMemoryStore()
+)
+
+// replace the store used by the middleware, a nil store turns rate limiting off
+func SetStore(store Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = store
+}
+
+func getStore() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// drop idle buckets, run periodically by a single background worker
+func Sweep(ctx context.Context) error {
+	store := getStore()
+	if store == nil {
+		return nil
+	}
+	return store.Sweep(ctx, time.Now().Add(-idleAfter))
+}
+
+// refill the bucket for the time passed since last and take one token
+func take(tokens float64, last time.Time, policy Policy, now time.Time) (float64, Result) {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	tokens = math.Min(float64(policy.Burst), tokens+elapsed*policy.Rate)
+
+	result := Result{Allowed: tokens >= 1}
+	if result.Allowed {
+		tokens--
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / policy.Rate * float64(time.Second))
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = time.Duration((float64(policy.Burst) - tokens) / policy.Rate * float64(time.Second))
+	return tokens, result
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"context"
+	"foodbuddy/internal/config"
+	"foodbuddy/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTake(t *testing.T) {
+	policy := Policy{Name: "test", Rate: 1, Burst: 3}
+	now := time.Unix(1_700_000_000, 0)
+
+	tokens := float64(policy.Burst)
+	for i := 2; i >= 0; i-- {
+		var result Result
+		tokens, result = take(tokens, now, policy, now)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", 3-i, result, i)
+		}
+	}
+
+	tokens, result := take(tokens, now, policy, now)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Fatalf("empty bucket = %+v, want denied retrying after 1s and full after 3s", result)
+	}
+
+	//half a second refills half a token
+	_, result = take(tokens, now, policy, now.Add(500*time.Millisecond))
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("half refilled bucket = %+v, want denied retrying after 500ms", result)
+	}
+
+	//a long pause never refills past the burst
+	tokens, result = take(tokens, now, policy, now.Add(time.Hour))
+	if !result.Allowed || result.Remaining != policy.Burst-1 || tokens != float64(policy.Burst-1) {
+		t.Fatalf("refilled bucket = %+v with %v tokens, want %d remaining", result, tokens, policy.Burst-1)
+	}
+
+	//clocks going backwards do not refill
+	_, result = take(0, now, policy, now.Add(-time.Hour))
+	if result.Allowed {
+		t.Fatalf("bucket refilled by a clock going backwards: %+v", result)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	policy := Policy{Name: "test", Rate: 1, Burst: 2}
+	now := time.Unix(1_700_000_000, 0)
+
+	for i, allowed := range []bool{true, true, false} {
+		result, err := store.Take(ctx, "a", policy, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Allowed != allowed {
+			t.Fatalf("take %d allowed = %v, want %v", i+1, result.Allowed, allowed)
+		}
+	}
+	//buckets are per key
+	if result, _ := store.Take(ctx, "b", policy, now.Add(time.Minute)); !result.Allowed {
+		t.Fatal("a fresh key should start with a full bucket")
+	}
+
+	if err := store.Sweep(ctx, now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if store.Len() != 1 {
+		t.Fatalf("buckets after sweep = %d, want only the recent one", store.Len())
+	}
+}
+
+// context of a request from remote carrying the headers, served by a router trusting proxies
+func testContext(t *testing.T, proxies []string, remote string, headers map[string]string) *gin.Context {
+	t.Helper()
+	router := gin.New()
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = remote
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	c := gin.CreateTestContextOnly(httptest.NewRecorder(), router)
+	c.Request = request
+	return c
+}
+
+func TestKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	utils.Configure(&config.Config{JWT: config.JWT{Secret: "test-secret"}})
+
+	anonymous := key(testContext(t, nil, "203.0.113.7:5000", nil), Auth)
+	if !strings.HasPrefix(anonymous, Auth.Name+":") || strings.Contains(anonymous, "203.0.113.7") {
+		t.Fatalf("key = %q, want the hashed ip under the policy name", anonymous)
+	}
+	if other := key(testContext(t, nil, "203.0.113.7:6000", nil), Default); other == anonymous {
+		t.Fatal("policies should not share buckets")
+	}
+
+	//forwarded headers from untrusted peers do not change the bucket
+	spoofed := key(testContext(t, nil, "203.0.113.7:5000", map[string]string{"X-Forwarded-For": "198.51.100.1"}), Auth)
+	if spoofed != anonymous {
+		t.Fatal("a spoofed X-Forwarded-For got a fresh bucket")
+	}
+
+	//behind a trusted proxy the forwarded client is keyed
+	forwarded := key(testContext(t, []string{"10.0.0.0/8"}, "10.0.0.2:443", map[string]string{"X-Forwarded-For": "203.0.113.7"}), Auth)
+	if forwarded != anonymous {
+		t.Fatal("the client behind a trusted proxy should share the bucket of its ip")
+	}
+
+	//signed in clients are keyed by their account, not their network
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"email": "user@example.com",
+		"role":  "user",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := map[string]string{"Cookie": "Authorization=" + token}
+	home := key(testContext(t, nil, "203.0.113.7:5000", cookie), Auth)
+	mobile := key(testContext(t, nil, "198.51.100.9:5000", cookie), Auth)
+	if home != mobile || home == anonymous {
+		t.Fatal("signed in clients should keep one bucket across networks")
+	}
+	if strings.Contains(home, "user@example.com") {
+		t.Fatalf("key %q holds the email", home)
+	}
+}
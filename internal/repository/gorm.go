@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+
+	"gorm.io/gorm"
+)
+
+type gormUserRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepo) ByID(ID uint) (model.User, error) {
+	var User model.User
+	err := r.db.Where("id = ?", ID).First(&User).Error
+	return User, notFound(err)
+}
+
+func (r *gormUserRepo) ByEmail(Email string) (model.User, error) {
+	var User model.User
+	err := r.db.Where("email = ?", Email).First(&User).Error
+	return User, notFound(err)
+}
+
+func (r *gormUserRepo) List(Page pagination.Params) ([]model.User, error) {
+	var Users []model.User
+	err := Page.Keyset(r.db.Model(&model.User{}), "id").Find(&Users).Error
+	return Users, err
+}
+
+func (r *gormUserRepo) ListBlocked() ([]model.User, error) {
+	var Users []model.User
+	err := r.db.Where("blocked = ?", true).Find(&Users).Error
+	return Users, err
+}
+
+func (r *gormUserRepo) SetBlocked(ID uint, Blocked bool) error {
+	return r.db.Model(&model.User{}).Where("id = ?", ID).UpdateColumn("blocked", Blocked).Error
+}
+
+func (r *gormUserRepo) UpdateProfile(ID uint, Profile model.UpdateUserInformation) error {
+	return r.db.Model(&model.User{}).Where("id = ?", ID).Updates(&Profile).Error
+}
+
+func (r *gormUserRepo) Addresses(UserID uint) ([]model.Address, error) {
+	var Addresses []model.Address
+	err := r.db.Where("user_id = ?", UserID).Find(&Addresses).Error
+	return Addresses, err
+}
+
+func (r *gormUserRepo) Address(UserID uint, AddressID uint) (model.Address, error) {
+	var Address model.Address
+	err := r.db.Where("user_id = ? AND address_id = ?", UserID, AddressID).First(&Address).Error
+	return Address, notFound(err)
+}
+
+func (r *gormUserRepo) CreateAddress(Address *model.Address) error {
+	return r.db.Create(Address).Error
+}
+
+func (r *gormUserRepo) UpdateAddress(Address *model.Address) error {
+	return r.db.Updates(Address).Error
+}
+
+func (r *gormUserRepo) DeleteAddress(Address model.Address) error {
+	return r.db.Delete(&Address).Error
+}
+
+type gormRestaurantRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormRestaurantRepo) ByID(ID uint) (model.Restaurant, error) {
+	var Restaurant model.Restaurant
+	err := r.db.Where("id = ?", ID).First(&Restaurant).Error
+	return Restaurant, notFound(err)
+}
+
+func (r *gormRestaurantRepo) ByEmail(Email string) (model.Restaurant, error) {
+	var Restaurant model.Restaurant
+	err := r.db.Where("email = ?", Email).First(&Restaurant).Error
+	return Restaurant, notFound(err)
+}
+
+type gormProductRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormProductRepo) ByID(ID uint) (model.Product, error) {
+	var Product model.Product
+	err := r.db.Where("id = ?", ID).First(&Product).Error
+	return Product, notFound(err)
+}
+
+func (r *gormProductRepo) List(Page pagination.Params) ([]model.Product, error) {
+	var Products []model.Product
+	err := Page.Keyset(r.db.Scopes(ListedProducts), "id").Find(&Products).Error
+	return Products, err
+}
+
+func (r *gormProductRepo) ListByRestaurant(RestaurantID uint) ([]model.Product, error) {
+	var Products []model.Product
+	err := r.db.Scopes(ListedProducts).Where("restaurant_id = ?", RestaurantID).Find(&Products).Error
+	return Products, err
+}
+
+func (r *gormProductRepo) ByName(RestaurantID uint, Name string) (model.Product, error) {
+	var Product model.Product
+	err := r.db.Where("name = ? AND restaurant_id = ?", Name, RestaurantID).First(&Product).Error
+	return Product, notFound(err)
+}
+
+func (r *gormProductRepo) WithOffers() ([]model.Product, error) {
+	var Products []model.Product
+	err := r.db.Scopes(ListedProducts).Where("offer_amount > ?", 0).Find(&Products).Error
+	return Products, err
+}
+
+func (r *gormProductRepo) Veg() ([]model.Product, error) {
+	var Products []model.Product
+	err := r.db.Scopes(ListedProducts).Where("veg = ?", model.YES).Order("price ASC").Find(&Products).Error
+	return Products, err
+}
+
+func (r *gormProductRepo) Create(Product *model.Product) error {
+	return r.db.Create(Product).Error
+}
+
+func (r *gormProductRepo) Update(Product *model.Product) error {
+	return r.db.Where("id = ?", Product.ID).Updates(Product).Error
+}
+
+func (r *gormProductRepo) SetOffer(ID uint, OfferAmount float64) error {
+	return r.db.Model(&model.Product{}).Where("id = ?", ID).Update("offer_amount", OfferAmount).Error
+}
+
+func (r *gormProductRepo) Delete(ID uint) error {
+	return r.db.Delete(&model.Product{}, ID).Error
+}
+
+func (r *gormProductRepo) Favourites(UserID uint) ([]model.FavouriteProduct, error) {
+	var Favourites []model.FavouriteProduct
+	err := r.db.Where("user_id = ?", UserID).Find(&Favourites).Error
+	return Favourites, err
+}
+
+func (r *gormProductRepo) Favourite(UserID uint, ProductID uint) (model.FavouriteProduct, error) {
+	var Favourite model.FavouriteProduct
+	err := r.db.Where("user_id = ? AND product_id = ?", UserID, ProductID).First(&Favourite).Error
+	return Favourite, notFound(err)
+}
+
+func (r *gormProductRepo) AddFavourite(UserID uint, ProductID uint) error {
+	return r.db.Create(&model.FavouriteProduct{UserID: UserID, ProductID: ProductID}).Error
+}
+
+func (r *gormProductRepo) RemoveFavourite(UserID uint, ProductID uint) error {
+	return r.db.Where("user_id = ? AND product_id = ?", UserID, ProductID).Delete(&model.FavouriteProduct{}).Error
+}
+
+type gormCategoryRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormCategoryRepo) ByID(ID uint) (model.Category, error) {
+	var Category model.Category
+	err := r.db.Where("id = ?", ID).First(&Category).Error
+	return Category, notFound(err)
+}
+
+type gormOrderRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormOrderRepo) UserItems(UserID uint, OrderID string, Page pagination.Params) ([]model.OrderItem, error) {
+	tx := r.db.Where("user_id = ?", UserID)
+	if OrderID != "" {
+		tx = tx.Where("order_id = ?", OrderID)
+	}
+	var OrderItems []model.OrderItem
+	err := Page.Offset(tx, "order_id ASC, product_id ASC").Find(&OrderItems).Error
+	return OrderItems, err
+}
+
+func (r *gormOrderRepo) RestaurantItems(RestaurantID uint, OrderStatus string, Page pagination.Params) ([]model.OrderItem, error) {
+	tx := r.db.Where("restaurant_id = ?", RestaurantID)
+	if OrderStatus != "" {
+		tx = tx.Where("order_status = ?", OrderStatus)
+	}
+	var OrderItems []model.OrderItem
+	err := Page.Offset(tx, "order_id ASC, product_id ASC").Find(&OrderItems).Error
+	return OrderItems, err
+}
+
+func (r *gormOrderRepo) Payments(OrderID string, PaymentStatus string) ([]model.Payment, error) {
+	tx := r.db.Where("order_id = ?", OrderID)
+	if PaymentStatus != "" {
+		tx = tx.Where("payment_status = ?", PaymentStatus)
+	}
+	var Payments []model.Payment
+	err := tx.Find(&Payments).Error
+	return Payments, err
+}
+
+type gormWalletRepo struct {
+	db *gorm.DB
+}
+
+func (r *gormWalletRepo) UserHistory(UserID uint) ([]model.UserWalletHistory, error) {
+	var History []model.UserWalletHistory
+	err := r.db.Where("user_id = ?", UserID).Find(&History).Error
+	return History, err
+}
+
+func (r *gormWalletRepo) RestaurantHistory(RestaurantID uint) ([]model.RestaurantWalletHistory, error) {
+	var History []model.RestaurantWalletHistory
+	err := r.db.Where("restaurant_id = ?", RestaurantID).Find(&History).Error
+	return History, err
+}
@@ -0,0 +1,117 @@
+// Package repository holds the data access of the Handler methods behind interfaces, for the
+// aggregates in Repositories: users with their addresses, restaurants, products with favourites,
+// categories, orders and wallets.
+//
+// Only those go through it. The plain controller functions use database.DB directly, that is
+// the older handlers and the onboarding, blocks, support tickets, restaurant and product reviews,
+// coupons with their rules and campaigns, and audit log handlers, which change several of these
+// tables in one transaction. They move over when an aggregate of theirs is added here.
+package repository
+
+import (
+	"errors"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/pagination"
+
+	"gorm.io/gorm"
+)
+
+var ErrNotFound = errors.New("record not found")
+
+type UserRepo interface {
+	ByID(ID uint) (model.User, error)
+	ByEmail(Email string) (model.User, error)
+	// one page of users ordered by id, one extra row is returned to find the next page
+	List(Page pagination.Params) ([]model.User, error)
+	ListBlocked() ([]model.User, error)
+	SetBlocked(ID uint, Blocked bool) error
+	UpdateProfile(ID uint, Profile model.UpdateUserInformation) error
+	Addresses(UserID uint) ([]model.Address, error)
+	Address(UserID uint, AddressID uint) (model.Address, error)
+	CreateAddress(Address *model.Address) error
+	UpdateAddress(Address *model.Address) error
+	DeleteAddress(Address model.Address) error
+}
+
+type RestaurantRepo interface {
+	ByID(ID uint) (model.Restaurant, error)
+	ByEmail(Email string) (model.Restaurant, error)
+}
+
+// List, ListByRestaurant, WithOffers and Veg are the public catalogue, they leave out products of
+// restaurants that are blocked or not verified yet
+type ProductRepo interface {
+	ByID(ID uint) (model.Product, error)
+	List(Page pagination.Params) ([]model.Product, error)
+	ListByRestaurant(RestaurantID uint) ([]model.Product, error)
+	// the product of the restaurant with that name, deleted products don't count
+	ByName(RestaurantID uint, Name string) (model.Product, error)
+	// products with an offer, in no particular order
+	WithOffers() ([]model.Product, error)
+	// vegetarian products, cheapest first
+	Veg() ([]model.Product, error)
+	Create(Product *model.Product) error
+	Update(Product *model.Product) error
+	SetOffer(ID uint, OfferAmount float64) error
+	Delete(ID uint) error
+	Favourites(UserID uint) ([]model.FavouriteProduct, error)
+	Favourite(UserID uint, ProductID uint) (model.FavouriteProduct, error)
+	AddFavourite(UserID uint, ProductID uint) error
+	RemoveFavourite(UserID uint, ProductID uint) error
+}
+
+type CategoryRepo interface {
+	ByID(ID uint) (model.Category, error)
+}
+
+type OrderRepo interface {
+	// items of the user, all of them when OrderID is empty
+	UserItems(UserID uint, OrderID string, Page pagination.Params) ([]model.OrderItem, error)
+	// items assigned to the restaurant, all statuses when OrderStatus is empty
+	RestaurantItems(RestaurantID uint, OrderStatus string, Page pagination.Params) ([]model.OrderItem, error)
+	Payments(OrderID string, PaymentStatus string) ([]model.Payment, error)
+}
+
+type WalletRepo interface {
+	UserHistory(UserID uint) ([]model.UserWalletHistory, error)
+	RestaurantHistory(RestaurantID uint) ([]model.RestaurantWalletHistory, error)
+}
+
+type Repositories struct {
+	Users       UserRepo
+	Restaurants RestaurantRepo
+	Products    ProductRepo
+	Categories  CategoryRepo
+	Orders      OrderRepo
+	Wallets     WalletRepo
+}
+
+// gorm backed repositories, works with any dialect gorm supports
+func New(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:       &gormUserRepo{db: db},
+		Restaurants: &gormRestaurantRepo{db: db},
+		Products:    &gormProductRepo{db: db},
+		Categories:  &gormCategoryRepo{db: db},
+		Orders:      &gormOrderRepo{db: db},
+		Wallets:     &gormWalletRepo{db: db},
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ListedRestaurants scopes a query to the restaurants the public catalogue shows, verified and not blocked
+func ListedRestaurants(db *gorm.DB) *gorm.DB {
+	return db.Where("blocked = ? AND verification_status = ?", false, model.VerificationStatusVerified)
+}
+
+// ListedProducts scopes a query on products, or bundles, to the ones of ListedRestaurants
+func ListedProducts(db *gorm.DB) *gorm.DB {
+	Listed := db.Session(&gorm.Session{NewDB: true}).Model(&model.Restaurant{}).Select("id").Scopes(ListedRestaurants)
+	return db.Where("restaurant_id IN (?)", Listed)
+}
@@ -0,0 +1,147 @@
+package repository_test
+
+import (
+	"errors"
+	"foodbuddy/internal/model"
+	"foodbuddy/internal/repository"
+	"foodbuddy/internal/repository/sqlite"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newRepos(t *testing.T) (*repository.Repositories, *gorm.DB) {
+	t.Helper()
+	repos, db, err := sqlite.New(t.TempDir() + "/repository.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return repos, db
+}
+
+func TestUserAddresses(t *testing.T) {
+	repos, _ := newRepos(t)
+	users := repos.Users
+
+	if _, err := users.ByEmail("nobody@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("missing user error = %v, want ErrNotFound", err)
+	}
+
+	home := model.Address{UserID: 7, AddressType: "home", StreetName: "MG Road", StreetNumber: "12", City: "Kochi", State: "Kerala", PostalCode: "682001"}
+	if err := users.CreateAddress(&home); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.CreateAddress(&model.Address{UserID: 8, AddressType: "work", City: "Pune"}); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := users.Addresses(7)
+	if err != nil || len(addresses) != 1 || addresses[0].AddressID != home.AddressID {
+		t.Fatalf("addresses of 7 = %+v, %v, want only the home address", addresses, err)
+	}
+	// addresses of other users look missing
+	if _, err := users.Address(8, home.AddressID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("address of another user error = %v, want ErrNotFound", err)
+	}
+
+	home.City = "Thrissur"
+	if err := users.UpdateAddress(&home); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := users.Address(7, home.AddressID); err != nil || saved.City != "Thrissur" {
+		t.Fatalf("updated address = %+v, %v", saved, err)
+	}
+
+	if err := users.DeleteAddress(home); err != nil {
+		t.Fatal(err)
+	}
+	if addresses, _ := users.Addresses(7); len(addresses) != 0 {
+		t.Fatalf("addresses after delete = %+v", addresses)
+	}
+}
+
+func TestProducts(t *testing.T) {
+	repos, db := newRepos(t)
+	products := repos.Products
+
+	// only verified restaurants are listed
+	db.Create(&model.Restaurant{ID: 1, Name: "Listed", VerificationStatus: model.VerificationStatusVerified})
+	db.Create(&model.Restaurant{ID: 2, Name: "Onboarding", VerificationStatus: model.VerificationStatusInReview})
+	thali := model.Product{RestaurantID: 1, CategoryID: 1, Name: "Thali", Price: 200, Veg: model.YES}
+	tea := model.Product{RestaurantID: 1, CategoryID: 1, Name: "Tea", Price: 20, Veg: model.YES}
+	chicken := model.Product{RestaurantID: 1, CategoryID: 1, Name: "Chicken", Price: 300, OfferAmount: 50, Veg: model.NO}
+	unlisted := model.Product{RestaurantID: 2, CategoryID: 1, Name: "Lemon Tea", Price: 10, OfferAmount: 5, Veg: model.YES}
+	for _, product := range []*model.Product{&thali, &tea, &chicken, &unlisted} {
+		if err := products.Create(product); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if found, err := products.ByName(1, "Tea"); err != nil || found.ID != tea.ID {
+		t.Fatalf("by name = %+v, %v", found, err)
+	}
+	if _, err := products.ByName(2, "Tea"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("product of another restaurant error = %v, want ErrNotFound", err)
+	}
+
+	veg, err := products.Veg()
+	if err != nil || len(veg) != 2 || veg[0].ID != tea.ID || veg[1].ID != thali.ID {
+		t.Fatalf("veg = %+v, %v, want tea then thali", veg, err)
+	}
+	offers, err := products.WithOffers()
+	if err != nil || len(offers) != 1 || offers[0].ID != chicken.ID {
+		t.Fatalf("with offers = %+v, %v, want only chicken", offers, err)
+	}
+	if listed, err := products.ListByRestaurant(2); err != nil || len(listed) != 0 {
+		t.Fatalf("products of the unverified restaurant = %+v, %v", listed, err)
+	}
+
+	tea.Price = 25
+	if err := products.Update(&tea); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := products.ByID(tea.ID); saved.Price != 25 {
+		t.Fatalf("updated price = %v, want 25", saved.Price)
+	}
+
+	// deleted products free their name
+	if err := products.Delete(tea.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.ByName(1, "Tea"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("deleted product by name error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFavourites(t *testing.T) {
+	repos, _ := newRepos(t)
+	products := repos.Products
+
+	if _, err := products.Favourite(1, 5); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("missing favourite error = %v, want ErrNotFound", err)
+	}
+	for _, ProductID := range []uint{5, 6} {
+		if err := products.AddFavourite(1, ProductID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := products.AddFavourite(2, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := products.RemoveFavourite(1, 5); err != nil {
+		t.Fatal(err)
+	}
+	favourites, err := products.Favourites(1)
+	if err != nil || len(favourites) != 1 || favourites[0].ProductID != 6 {
+		t.Fatalf("favourites of 1 = %+v, %v, want only 6", favourites, err)
+	}
+	// other users keep theirs
+	if _, err := products.Favourite(2, 5); err != nil {
+		t.Fatalf("favourite of another user removed: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Package sqlite opens a migrated SQLite database behind the gorm repositories,
+// it is pure go so tests and local tools don't need cgo or a MySQL server.
+package sqlite
+
+import (
+	"foodbuddy/internal/database"
+	"foodbuddy/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// open the database at dsn, ":memory:" or "file::memory:?cache=shared" keep it in memory
+func Open(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func New(dsn string) (*repository.Repositories, *gorm.DB, error) {
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repository.New(db), db, nil
+}
@@ -0,0 +1,122 @@
+package response
+
+import (
+	"errors"
+	"foodbuddy/internal/logging"
+	"foodbuddy/internal/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Code is the machine readable reason of a failed request, clients should branch on it instead of the message
+type Code string
+
+const (
+	CodeBadRequest        Code = "bad_request"
+	CodeValidation        Code = "validation_failed"
+	CodeUnauthorized      Code = "unauthorized"
+	CodeForbidden         Code = "forbidden"
+	CodeEmailNotVerified  Code = "email_not_verified"
+	CodeAccountBlocked    Code = "account_blocked"
+	CodeNotFound          Code = "not_found"
+	CodeConflict          Code = "conflict"
+	CodeInvalidState      Code = "invalid_state"
+	CodeOutOfStock        Code = "out_of_stock"
+	CodeInsufficientFunds Code = "insufficient_funds"
+	CodeRateLimited       Code = "rate_limited"
+	CodeTooLarge          Code = "file_too_large"
+	CodeUnsupportedType   Code = "unsupported_media_type"
+	CodeInternal          Code = "internal_error"
+	CodeUpstream          Code = "upstream_failed"
+)
+
+var statuses = map[Code]int{
+	CodeBadRequest:        http.StatusBadRequest,
+	CodeValidation:        http.StatusBadRequest,
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeEmailNotVerified:  http.StatusForbidden,
+	CodeAccountBlocked:    http.StatusForbidden,
+	CodeNotFound:          http.StatusNotFound,
+	CodeConflict:          http.StatusConflict,
+	CodeInvalidState:      http.StatusConflict,
+	CodeOutOfStock:        http.StatusConflict,
+	CodeInsufficientFunds: http.StatusPaymentRequired,
+	CodeTooLarge:          http.StatusRequestEntityTooLarge,
+	CodeUnsupportedType:   http.StatusUnsupportedMediaType,
+	CodeRateLimited:       http.StatusTooManyRequests,
+	CodeInternal:          http.StatusInternalServerError,
+	CodeUpstream:          http.StatusBadGateway,
+}
+
+// http status sent with the code, unknown codes are server errors
+func (code Code) HTTPStatus() int {
+	if status, ok := statuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type ErrorBody struct {
+	Code   Code         `json:"code"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// Body is the envelope of every api response
+type Body struct {
+	Status     bool       `json:"status"`
+	Message    string     `json:"message"`
+	Data       any        `json:"data,omitempty"`
+	NextCursor *string    `json:"next_cursor,omitempty"`
+	Error      *ErrorBody `json:"error,omitempty"`
+}
+
+func OK(c *gin.Context, message string, data any) {
+	c.JSON(http.StatusOK, Body{Status: true, Message: message, Data: data})
+}
+
+func Created(c *gin.Context, message string, data any) {
+	c.JSON(http.StatusCreated, Body{Status: true, Message: message, Data: data})
+}
+
+// one page of a cursor paginated list, an empty cursor means it was the last page
+func Page(c *gin.Context, message string, data any, nextCursor string) {
+	c.JSON(http.StatusOK, Body{Status: true, Message: message, Data: data, NextCursor: &nextCursor})
+}
+
+// abort the request with the status mapped from code
+func Error(c *gin.Context, code Code, message string) {
+	c.AbortWithStatusJSON(code.HTTPStatus(), Body{Message: message, Error: &ErrorBody{Code: code}})
+}
+
+// abort with a server error, the cause is logged and never sent to the client
+func Internal(c *gin.Context, message string, err error) {
+	if err != nil {
+		logging.From(c).Error(message, "error", err)
+	}
+	Error(c, CodeInternal, message)
+}
+
+// abort with the field errors of utils.Validate, other errors are sent as a plain bad request
+func Invalid(c *gin.Context, err error) {
+	var validation utils.ValidationErrors
+	if !errors.As(err, &validation) {
+		Error(c, CodeBadRequest, err.Error())
+		return
+	}
+	fields := make([]FieldError, 0, len(validation))
+	for _, field := range validation {
+		fields = append(fields, FieldError{Field: field.Field, Rule: field.Rule, Message: field.Message})
+	}
+	c.AbortWithStatusJSON(CodeValidation.HTTPStatus(), Body{
+		Message: validation.Error(),
+		Error:   &ErrorBody{Code: CodeValidation, Fields: fields},
+	})
+}
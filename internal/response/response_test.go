@@ -0,0 +1,88 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"foodbuddy/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func respond(t *testing.T, handler gin.HandlerFunc) (int, Body, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler(c)
+	var body Body
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", w.Body, err)
+	}
+	return w.Code, body, w.Body.String()
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for code, want := range map[Code]int{
+		CodeValidation:        http.StatusBadRequest,
+		CodeEmailNotVerified:  http.StatusForbidden,
+		CodeOutOfStock:        http.StatusConflict,
+		CodeInsufficientFunds: http.StatusPaymentRequired,
+		CodeRateLimited:       http.StatusTooManyRequests,
+		CodeUpstream:          http.StatusBadGateway,
+		Code("made_up"):       http.StatusInternalServerError,
+	} {
+		if got := code.HTTPStatus(); got != want {
+			t.Errorf("%v = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	status, body, raw := respond(t, func(c *gin.Context) { OK(c, "done", gin.H{"id": 1}) })
+	if status != http.StatusOK || !body.Status || body.Error != nil || body.NextCursor != nil {
+		t.Errorf("ok = %d %s", status, raw)
+	}
+	//the last page still sends an empty cursor so clients can tell it apart from an unpaginated list
+	status, body, raw = respond(t, func(c *gin.Context) { Page(c, "page", []int{}, "") })
+	if status != http.StatusOK || body.NextCursor == nil || *body.NextCursor != "" {
+		t.Errorf("page = %d %s", status, raw)
+	}
+	status, body, raw = respond(t, func(c *gin.Context) { Error(c, CodeOutOfStock, "sold out") })
+	if status != http.StatusConflict || body.Status || body.Message != "sold out" || body.Error.Code != CodeOutOfStock || body.Data != nil {
+		t.Errorf("error = %d %s", status, raw)
+	}
+}
+
+func TestInternalHidesCause(t *testing.T) {
+	status, body, raw := respond(t, func(c *gin.Context) { Internal(c, "failed to place the order", errors.New("dial tcp 10.0.0.5:3306")) })
+	if status != http.StatusInternalServerError || body.Error.Code != CodeInternal || body.Message != "failed to place the order" {
+		t.Errorf("internal = %d %s", status, raw)
+	}
+	if strings.Contains(raw, "10.0.0.5") {
+		t.Errorf("the cause reached the client: %s", raw)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	validation := utils.ValidationErrors{
+		{Field: "Email", Rule: "email", Message: "Please enter a valid email address."},
+		{Field: "Name", Rule: "required", Message: "Please enter a name."},
+	}
+	status, body, raw := respond(t, func(c *gin.Context) { Invalid(c, validation) })
+	if status != http.StatusBadRequest || body.Error.Code != CodeValidation || len(body.Error.Fields) != 2 || body.Error.Fields[1].Rule != "required" {
+		t.Errorf("validation = %d %s", status, raw)
+	}
+	if body.Message != "Please enter a valid email address., Please enter a name." {
+		t.Errorf("message = %q", body.Message)
+	}
+	//anything else, like a malformed body, is a plain bad request
+	status, body, raw = respond(t, func(c *gin.Context) { Invalid(c, errors.New("unexpected EOF")) })
+	if status != http.StatusBadRequest || body.Error.Code != CodeBadRequest || body.Error.Fields != nil || body.Message != "unexpected EOF" {
+		t.Errorf("bad request = %d %s", status, raw)
+	}
+}
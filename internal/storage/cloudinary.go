@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"foodbuddy/internal/config"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// objects are kept in the foodbuddy folder, the key without its extension is the public id
+const cloudinaryFolder = "foodbuddy"
+
+type Cloudinary struct {
+	cfg config.Cloudinary
+	// upload for public objects, authenticated ones are only served by signed urls
+	delivery api.DeliveryType
+
+	mu     sync.Mutex
+	client *cloudinary.Cloudinary
+}
+
+func NewCloudinary(cfg config.Cloudinary) *Cloudinary {
+	return &Cloudinary{cfg: cfg, delivery: api.Upload}
+}
+
+// objects are stored as authenticated and read back through short lived signed download urls
+func NewPrivateCloudinary(cfg config.Cloudinary) *Cloudinary {
+	return &Cloudinary{cfg: cfg, delivery: api.Authenticated}
+}
+
+// the client is built on first use, the keys are not needed until something is uploaded
+func (c *Cloudinary) cld() (*cloudinary.Cloudinary, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	client, err := cloudinary.NewFromParams(c.cfg.CloudName, c.cfg.AccessKey, c.cfg.SecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cloudinary: %w", err)
+	}
+	c.client = client
+	return client, nil
+}
+
+func (c *Cloudinary) Put(ctx context.Context, key string, content io.Reader, contentType string) (string, error) {
+	cld, err := c.cld()
+	if err != nil {
+		return "", err
+	}
+	//images are resized before they get here, so they are stored as is
+	result, err := cld.Upload.Upload(ctx, content, uploader.UploadParams{
+		PublicID:     publicID(key),
+		Overwrite:    api.Bool(true),
+		ResourceType: "image",
+		Type:         c.delivery,
+	})
+	if err != nil {
+		return "", err
+	}
+	if result.Error.Message != "" {
+		return "", fmt.Errorf("cloudinary: %v", result.Error.Message)
+	}
+	return result.SecureURL, nil
+}
+
+func (c *Cloudinary) Delete(ctx context.Context, key string) error {
+	cld, err := c.cld()
+	if err != nil {
+		return err
+	}
+	result, err := cld.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: publicID(key), ResourceType: "image", Type: string(c.delivery)})
+	if err != nil {
+		return err
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("cloudinary: %v", result.Error.Message)
+	}
+	return nil
+}
+
+func (c *Cloudinary) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	cld, err := c.cld()
+	if err != nil {
+		return nil, err
+	}
+	expires := time.Now().Add(time.Minute)
+	url, err := cld.Upload.PrivateDownloadURL(uploader.PrivateDownloadURLParams{
+		PublicID:     publicID(key),
+		Format:       strings.TrimPrefix(path.Ext(key), "."),
+		DeliveryType: string(c.delivery),
+		ExpiresAt:    &expires,
+		ResourceType: api.Image,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("cloudinary: download of %v failed with %v", key, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func publicID(key string) string {
+	return cloudinaryFolder + "/" + strings.TrimSuffix(key, path.Ext(key))
+}
@@ -0,0 +1,53 @@
+// Package storage keeps uploaded files behind Blob, on Cloudinary or on the local disk,
+// and turns uploads into checked, resized variants before they are stored.
+package storage
+
+import (
+	"context"
+	"foodbuddy/internal/config"
+	"io"
+	"strings"
+)
+
+// path the local backend is served under
+const MediaPath = "/media"
+
+// path private uploads are served under, only to the callers allowed to see them
+const PrivatePath = "/api/v1/files"
+
+type Blob interface {
+	// store content under key, a slash separated path like products/<id>/card.jpg, and return its public url
+	Put(ctx context.Context, key string, content io.Reader, contentType string) (string, error)
+	// remove the object, removing a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Private keeps uploads that must not be reachable by their url alone, like restaurant documents,
+// the api reads them back for the callers allowed to see them
+type Private interface {
+	Blob
+	// content of the object stored under key, the caller closes it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// backend selected by the configuration
+func New(cfg config.Storage, cld config.Cloudinary) Blob {
+	if cfg.Backend == "local" {
+		return NewLocal(cfg.Dir, cfg.PublicURL)
+	}
+	return NewCloudinary(cld)
+}
+
+// private backend selected by the configuration, the local one writes outside the served directory
+func NewPrivate(cfg config.Storage, cld config.Cloudinary) Private {
+	if cfg.Backend == "local" {
+		return NewLocal(cfg.PrivateDir, PrivatePath)
+	}
+	return NewPrivateCloudinary(cld)
+}
+
+// key of a private upload from the url saved on its record
+func PrivateKey(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, PrivatePath+"/")
+	return key, ok && key != ""
+}
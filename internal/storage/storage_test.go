@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	for _, c := range []struct {
+		head []byte
+		want string
+	}{
+		{[]byte{0xFF, 0xD8, 0xFF, 0xE0}, JPEG},
+		{[]byte("\x89PNG\r\n\x1a\n...."), PNG},
+		{[]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), WebP},
+		{[]byte("%PDF-1.4"), PDF},
+		{[]byte("<svg xmlns="), ""},
+		{[]byte("GIF89a"), ""},
+		{nil, ""},
+	} {
+		if got := Sniff(c.head); got != c.want {
+			t.Errorf("Sniff(%q) = %q, want %q", c.head, got, c.want)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir, "/media/")
+	ctx := context.Background()
+
+	url, err := local.Put(ctx, "products/abc/card.jpg", bytes.NewReader([]byte("jpeg")), JPEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/media/products/abc/card.jpg" {
+		t.Errorf("url = %v", url)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "products", "abc", "card.jpg"))
+	if err != nil || string(content) != "jpeg" {
+		t.Fatalf("stored %q, %v", content, err)
+	}
+
+	if err := local.Delete(ctx, "products/abc/card.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Delete(ctx, "products/abc/card.jpg"); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+
+	for _, key := range []string{"../escape.jpg", "/etc/passwd", "products/../../escape.jpg", "products//card.jpg", ""} {
+		if _, err := local.Put(ctx, key, bytes.NewReader(nil), JPEG); err == nil {
+			t.Errorf("key %q was accepted", key)
+		}
+	}
+}
+
+func TestSaveVariants(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2000, 1000)), nil); err != nil {
+		t.Fatal(err)
+	}
+	local := NewLocal(t.TempDir(), "/media")
+
+	stored, err := Save(context.Background(), local, ProductImage, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.URL != stored.Variants["card"] {
+		t.Errorf("primary url = %v, variants = %v", stored.URL, stored.Variants)
+	}
+
+	//fit keeps the aspect ratio, crop fills the box
+	want := map[string][2]int{"thumb": {150, 150}, "card": {300, 300}, "large": {1080, 540}}
+	for name, size := range want {
+		key := stored.Variants[name][len("/media/"):]
+		file, err := os.Open(filepath.Join(local.dir, filepath.FromSlash(key)))
+		if err != nil {
+			t.Fatalf("variant %v: %v", name, err)
+		}
+		config, err := jpeg.DecodeConfig(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("variant %v: %v", name, err)
+		}
+		if config.Width != size[0] || config.Height != size[1] {
+			t.Errorf("variant %v is %vx%v, want %vx%v", name, config.Width, config.Height, size[0], size[1])
+		}
+	}
+
+	small := ProductImage
+	small.MaxBytes = 10
+	if _, err := Save(context.Background(), local, small, bytes.NewReader(buf.Bytes())); err != ErrTooLarge {
+		t.Errorf("oversized upload: %v", err)
+	}
+	if _, err := Save(context.Background(), local, ProductImage, bytes.NewReader([]byte("%PDF-1.4"))); err != ErrUnsupported {
+		t.Errorf("pdf as product image: %v", err)
+	}
+}
+
+func TestSavePrivate(t *testing.T) {
+	private := NewLocal(t.TempDir(), "/elsewhere")
+	ctx := context.Background()
+
+	stored, err := Save(ctx, private, Document, bytes.NewReader([]byte("%PDF-1.7\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, ok := PrivateKey(stored.URL)
+	if !ok || filepath.Dir(key) != Document.Name || ContentType(key) != PDF {
+		t.Fatalf("private upload linked at %v", stored.URL)
+	}
+	content, err := private.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	if data, _ := io.ReadAll(content); string(data) != "%PDF-1.7\n" {
+		t.Errorf("opened %q", data)
+	}
+
+	for _, url := range []string{"/media/documents/a.pdf", PrivatePath, PrivatePath + "/"} {
+		if _, ok := PrivateKey(url); ok {
+			t.Errorf("%v taken for a private url", url)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	for _, test := range []struct {
+		url  string
+		want []string
+	}{
+		{"/media/reviews/abc/large.jpg", []string{"reviews/abc/thumb.jpg", "reviews/abc/large.jpg"}},
+		{"https://res.cloudinary.com/demo/image/upload/v1712/foodbuddy/reviews/abc/thumb.png", []string{"reviews/abc/thumb.png", "reviews/abc/large.png"}},
+		//pdfs and images of kinds without variants are stored as they came
+		{"/media/reviews/abc.pdf", []string{"reviews/abc.pdf"}},
+		{"/media/products/abc/card.jpg", nil},
+		{"/media/reviews/../profiles/abc/card.jpg", nil},
+	} {
+		keys := Keys(ReviewPhoto, test.url)
+		if strings.Join(keys, ",") != strings.Join(test.want, ",") {
+			t.Errorf("keys of %v = %v, want %v", test.url, keys, test.want)
+		}
+	}
+	if keys := Keys(Certificate, "/media/certificates/abc.png"); len(keys) != 1 || keys[0] != "certificates/abc.png" {
+		t.Errorf("certificate keys = %v", keys)
+	}
+}
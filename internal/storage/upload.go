@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrTooLarge    = errors.New("file is too large")
+	ErrUnsupported = errors.New("unsupported file type")
+	ErrEmpty       = errors.New("file is empty")
+)
+
+// content types recognised by their magic bytes
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+	WebP = "image/webp"
+	PDF  = "application/pdf"
+)
+
+// decoded images above this many pixels are refused before they are decoded
+const maxPixels = 40_000_000
+
+// one resized copy of an uploaded image
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	// crop to fill the box, otherwise fit inside it keeping the aspect ratio
+	Crop bool
+}
+
+// Kind describes what an upload may be and what is stored for it
+type Kind struct {
+	// key prefix
+	Name     string
+	MaxBytes int64
+	Types    []string
+	// images are stored as these variants, the original is kept when there are none
+	Variants []Variant
+	// variant whose url is saved on the record
+	Primary string
+	// stored on the private backend and linked under PrivatePath, never publicly
+	Private bool
+}
+
+var (
+	ProfileImage = Kind{
+		Name:     "profiles",
+		MaxBytes: 5 << 20,
+		Types:    []string{JPEG, PNG, WebP},
+		Variants: []Variant{{Name: "thumb", Width: 96, Height: 96, Crop: true}, {Name: "card", Width: 300, Height: 300, Crop: true}},
+		Primary:  "card",
+	}
+	ProductImage = Kind{
+		Name:     "products",
+		MaxBytes: 8 << 20,
+		Types:    []string{JPEG, PNG, WebP},
+		Variants: []Variant{{Name: "thumb", Width: 150, Height: 150, Crop: true}, {Name: "card", Width: 300, Height: 300, Crop: true}, {Name: "large", Width: 1080, Height: 1080}},
+		Primary:  "card",
+	}
+	CategoryImage = Kind{
+		Name:     "categories",
+		MaxBytes: 5 << 20,
+		Types:    []string{JPEG, PNG, WebP},
+		Variants: []Variant{{Name: "thumb", Width: 150, Height: 150, Crop: true}, {Name: "card", Width: 300, Height: 300, Crop: true}},
+		Primary:  "card",
+	}
+	Certificate = Kind{
+		Name:     "certificates",
+		MaxBytes: 10 << 20,
+		Types:    []string{JPEG, PNG, PDF},
+	}
+	// licences, tax registrations and bank proofs of onboarding restaurants
+	Document = Kind{
+		Name:     "documents",
+		MaxBytes: 10 << 20,
+		Types:    []string{JPEG, PNG, PDF},
+		Private:  true,
+	}
+	// photos attached to reviews of order items
+	ReviewPhoto = Kind{
+		Name:     "reviews",
+		MaxBytes: 8 << 20,
+		Types:    []string{JPEG, PNG, WebP},
+		Variants: []Variant{{Name: "thumb", Width: 150, Height: 150, Crop: true}, {Name: "large", Width: 1080, Height: 1080}},
+		Primary:  "large",
+	}
+	// photos and receipts attached to support tickets
+	TicketAttachment = Kind{
+		Name:     "tickets",
+		MaxBytes: 10 << 20,
+		Types:    []string{JPEG, PNG, WebP, PDF},
+		Private:  true,
+	}
+)
+
+// urls of a stored upload
+type Stored struct {
+	URL      string            `json:"url"`
+	Variants map[string]string `json:"variants,omitempty"`
+}
+
+// content type by magic bytes, empty when it is none of the known types
+func Sniff(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return JPEG
+	case bytes.HasPrefix(head, []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}):
+		return PNG
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return WebP
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return PDF
+	}
+	return ""
+}
+
+// Save checks the upload against the kind and stores it, or its variants, on blob.
+// Objects already stored are removed again when a later one fails.
+// Private kinds go to a Private blob and are linked under PrivatePath whatever the backend returns.
+func Save(ctx context.Context, blob Blob, kind Kind, r io.Reader) (Stored, error) {
+	content, err := io.ReadAll(io.LimitReader(r, kind.MaxBytes+1))
+	if err != nil {
+		return Stored{}, err
+	}
+	if len(content) == 0 {
+		return Stored{}, ErrEmpty
+	}
+	if int64(len(content)) > kind.MaxBytes {
+		return Stored{}, ErrTooLarge
+	}
+	contentType := Sniff(content)
+	if !allowed(kind.Types, contentType) {
+		return Stored{}, ErrUnsupported
+	}
+
+	id := uuid.New().String()
+	if len(kind.Variants) == 0 || contentType == PDF {
+		key := fmt.Sprintf("%v/%v%v", kind.Name, id, extension(contentType))
+		url, err := blob.Put(ctx, key, bytes.NewReader(content), contentType)
+		if err != nil {
+			return Stored{}, err
+		}
+		if kind.Private {
+			url = PrivatePath + "/" + key
+		}
+		return Stored{URL: url}, nil
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || header.Width == 0 || header.Height == 0 {
+		return Stored{}, ErrUnsupported
+	}
+	if header.Width*header.Height > maxPixels {
+		return Stored{}, ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return Stored{}, ErrUnsupported
+	}
+
+	//png keeps its transparency, everything else is served as jpeg
+	outType := JPEG
+	if contentType == PNG {
+		outType = PNG
+	}
+
+	stored := Stored{Variants: make(map[string]string, len(kind.Variants))}
+	var keys []string
+	for _, variant := range kind.Variants {
+		var buf bytes.Buffer
+		if err := encode(&buf, resize(src, variant), outType); err != nil {
+			removeAll(ctx, blob, keys)
+			return Stored{}, err
+		}
+		key := fmt.Sprintf("%v/%v/%v%v", kind.Name, id, variant.Name, extension(outType))
+		url, err := blob.Put(ctx, key, &buf, outType)
+		if err != nil {
+			removeAll(ctx, blob, keys)
+			return Stored{}, err
+		}
+		keys = append(keys, key)
+		stored.Variants[variant.Name] = url
+	}
+	stored.URL = stored.Variants[kind.Primary]
+	return stored, nil
+}
+
+// keys of every object stored for the upload of kind linked at url, a variant url gives the keys of all its variants.
+// Both backends return urls ending in the key, nil when url is not one of them.
+func Keys(kind Kind, url string) []string {
+	i := strings.LastIndex(url, "/"+kind.Name+"/")
+	if i < 0 {
+		return nil
+	}
+	key := url[i+1:]
+	if path.Clean(key) != key {
+		return nil
+	}
+	dir, file := path.Split(key)
+	//originals are stored as <kind>/<id><ext>, variants as <kind>/<id>/<variant><ext>
+	if dir == kind.Name+"/" || len(kind.Variants) == 0 {
+		return []string{key}
+	}
+	keys := make([]string, 0, len(kind.Variants))
+	for _, variant := range kind.Variants {
+		keys = append(keys, dir+variant.Name+path.Ext(file))
+	}
+	return keys
+}
+
+// Remove deletes the upload of kind linked at url with its variants, failures are only logged
+func Remove(ctx context.Context, blob Blob, kind Kind, url string) {
+	for _, key := range Keys(kind, url) {
+		if err := blob.Delete(ctx, key); err != nil {
+			slog.Warn("failed to remove an upload", "key", key, "error", err)
+		}
+	}
+}
+
+// content type of a stored key by its extension, the inverse of extension
+func ContentType(key string) string {
+	switch path.Ext(key) {
+	case ".png":
+		return PNG
+	case ".webp":
+		return WebP
+	case ".pdf":
+		return PDF
+	}
+	return JPEG
+}
+
+// scale src into the variant box, images are never scaled up
+func resize(src image.Image, v Variant) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	srcRect := bounds
+	var dw, dh int
+	if v.Crop {
+		//the centered part of the source with the aspect ratio of the box
+		if w*v.Height > h*v.Width {
+			cw := h * v.Width / v.Height
+			srcRect = image.Rect(bounds.Min.X+(w-cw)/2, bounds.Min.Y, bounds.Min.X+(w-cw)/2+cw, bounds.Max.Y)
+		} else {
+			ch := w * v.Height / v.Width
+			srcRect = image.Rect(bounds.Min.X, bounds.Min.Y+(h-ch)/2, bounds.Max.X, bounds.Min.Y+(h-ch)/2+ch)
+		}
+		dw, dh = min(v.Width, srcRect.Dx()), min(v.Height, srcRect.Dy())
+	} else {
+		dw, dh = w, h
+		if dw > v.Width {
+			dw, dh = v.Width, h*v.Width/w
+		}
+		if dh > v.Height {
+			dw, dh = dw*v.Height/dh, v.Height
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(dw, 1), max(dh, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Src, nil)
+	return dst
+}
+
+func encode(w io.Writer, img image.Image, contentType string) error {
+	if contentType == PNG {
+		return png.Encode(w, img)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+func removeAll(ctx context.Context, blob Blob, keys []string) {
+	for _, key := range keys {
+		if err := blob.Delete(ctx, key); err != nil {
+			slog.Warn("failed to remove a partial upload", "key", key, "error", err)
+		}
+	}
+}
+
+func extension(contentType string) string {
+	switch contentType {
+	case PNG:
+		return ".png"
+	case WebP:
+		return ".webp"
+	case PDF:
+		return ".pdf"
+	}
+	return ".jpg"
+}
+
+func allowed(types []string, contentType string) bool {
+	for _, t := range types {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
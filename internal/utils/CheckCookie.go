@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"fmt"
+	"foodbuddy/internal/logging"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -12,11 +12,11 @@ func CheckCookie(c *gin.Context) {
 	//get the jwt token string
 	cookie, err := c.Cookie("Authorization")
 	if cookie == "" || err != nil {
-		fmt.Println("CHECKCOOKIE - no Authorization token available, redirecting to login/signup page")
+		logging.From(c).Debug("no authorization cookie, showing the page")
 		c.Next()
 		return
 	} else {
 		c.Redirect(http.StatusSeeOther, "/home")
-		fmt.Println("CHECKCOOKIE - user cookie is still present , redirecting to homepage")
+		logging.From(c).Debug("authorization cookie present, redirecting to home")
 	}
 }
@@ -0,0 +1,12 @@
+package utils
+
+import (
+	"foodbuddy/internal/config"
+)
+
+// secret used by the helpers, set once at startup by Configure
+var jwtSecret []byte
+
+func Configure(cfg *config.Config) {
+	jwtSecret = []byte(cfg.JWT.Secret)
+}
@@ -15,8 +15,7 @@ func GetJWTClaim(c *gin.Context) (email string, role string, err error) {
 		return "", "", errors.New("no authorization token available")
 	}
 
-	hmacSecretString := GetEnvVariables().JWTSecret
-	hmacSecret := []byte(hmacSecretString)
+	hmacSecret := jwtSecret
 
 	// Parse the token
 	token, err := jwt.Parse(JWTToken, func(token *jwt.Token) (interface{}, error) {
@@ -1,12 +1,44 @@
 package utils
 
 import (
-	"errors"
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// one failed rule of a request field, field is the json name the client sent
+type FieldError struct {
+	Field   string
+	Rule    string
+	Message string
+}
+
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, field := range v {
+		messages = append(messages, field.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks the validate tags of a request struct and returns ValidationErrors
 func Validate(value interface{}) error {
 	var customValidationMessages = map[string]string{
 		"Name_required":            "Please enter a name.",
@@ -53,22 +85,31 @@ func Validate(value interface{}) error {
 		"UserRating_required":      "Please provide a rating.",
 		"UserRating_number":        "Please enter a numerical value for rating.",
 		"RestaurantID_required":    "Please provide the restaurant_id for placing the orders of that particular cart",
+		"BundleID_required":        "Please enter a bundle ID.",
+		"BundleID_number":          "Please enter a numerical value for bundle ID.",
+		"BundlePrice_required":     "Please enter a bundle price.",
+		"BundlePrice_number":       "Please enter a numerical value for bundle price.",
+		"Items_required":           "Please add the component products of the bundle.",
+		"Items_min":                "A bundle should contain at least 2 products.",
 	}
 
 	// validate the struct body
-	validate := validator.New()
 	err := validate.Struct(value)
-	if err != nil {
-		var errs []string
-		for _, e := range err.(validator.ValidationErrors) {
-			translationKey := e.Field() + "_" + e.Tag()
-			errMsg := customValidationMessages[translationKey]
-			if errMsg == "" {
-				errMsg = e.Error()
-			}
-			errs = append(errs, errMsg)
+	if err == nil {
+		return nil
+	}
+	validationErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	var errs ValidationErrors
+	for _, e := range validationErrs {
+		translationKey := e.StructField() + "_" + e.Tag()
+		errMsg := customValidationMessages[translationKey]
+		if errMsg == "" {
+			errMsg = e.Error()
 		}
-		return errors.New(strings.Join(errs, ", "))
+		errs = append(errs, FieldError{Field: e.Field(), Rule: e.Tag(), Message: errMsg})
 	}
-	return nil
+	return errs
 }